@@ -8,11 +8,11 @@ import (
 	abrqlog "github.com/uccmisl/godash/qlog"
 )
 
-//DownloadFile This function downloads file at given url
-func DownloadFile(filepath string, url string) error {
+//DownloadFile This function downloads file at given url, the request is logged to the ABR qlog of tracer
+func DownloadFile(filepath string, url string, tracer *abrqlog.StreamTracer) error {
 
 	// TODO better media type?
-	abrqlog.MainTracer.Request(abrqlog.MediaTypeOther, url, "")
+	tracer.Request(abrqlog.MediaTypeOther, url, "")
 
 	//download data
 	response, err := http.Get(url)
@@ -21,7 +21,7 @@ func DownloadFile(filepath string, url string) error {
 		return err
 	}
 
-	abrqlog.MainTracer.RequestUpdate(url, response.ContentLength)
+	tracer.RequestUpdate(url, response.ContentLength)
 
 	defer response.Body.Close()
 
@@ -1,6 +1,7 @@
 package algorithms
 
 import (
+	"context"
 	//"fmt"
 
 	glob "github.com/uccmisl/godash/global"
@@ -9,35 +10,24 @@ import (
 	//"math"
 )
 
-var DEFAULT_EXPONENT float64 = 0.4
-var DEFAULT_MIN_BUFFER_FACTOR float64 = 0.75
-var DEFAULT_MAX_BUFFER_FACTOR float64 = 1.15
-var DEFAULT_HISTORIC_ESTIMATION_WINDOW int = 10
-var DEFAULT_MAXIMUM_SWITCH = 2
-var DEFAULT_PREDICTIVE_ESTIMATION_WINDOW = 5
+const DEFAULT_EXPONENT float64 = 0.4
+const DEFAULT_MIN_BUFFER_FACTOR float64 = 0.75
+const DEFAULT_MAX_BUFFER_FACTOR float64 = 1.15
+const DEFAULT_HISTORIC_ESTIMATION_WINDOW int = 10
+const DEFAULT_MAXIMUM_SWITCH = 2
+const DEFAULT_PREDICTIVE_ESTIMATION_WINDOW = 5
 
-var detectSuddenDrop = false
-var exponent float64
+// the arbiter+ options, every call works out its values from these
+const detectSuddenDrop = false
 
-var bufferScaling = true
-var bufferingFactor float64 = 1
-var minBufferFactor float64
-var maxBufferFactor float64
+const bufferScaling = true
 
-var selectedIndex int
+const netScaling = false
+const netScalingFactor float64 = 1
 
-var historicEstimationWindow int
+const switchingControl = true
 
-var netScaling = false
-var netScalingFactor float64 = 1
-
-var switchingControl = true
-
-var actualRateQuality = true
-
-var predictiveEstimationWindow int
-
-var maximumSwitch int
+const actualRateQuality = true
 
 // CalculateSelectedIndexArbiter :
 /*
@@ -48,7 +38,7 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 	lastRate int, thrList *[]int, mpdDuration int, currentMPD http.MPD, currentURL string,
 	currentMPDRepAdaptSet int, segmentNumber int, baseURL string, debugLog bool, downloadTime int, bufferLevel int,
 	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, bandwithList []int,
-	segmentSize int, quicBool bool, useTestbedBool bool) int {
+	segmentSize int, quicBool bool, useTestbedBool bool, segHeadValues map[int]map[int][]int, ctx context.Context) int {
 
 	//Does not work if repRatesReversed
 	//the typical default buffer should be 60 seconds, however this is set in the config json files
@@ -67,9 +57,9 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 	//fmt.Println(float64(bufferLevel)/float64(maxBufferLevel*1000))
 	bufferFullness := FloatMin(1.0, (float64(bufferLevel) / float64(maxBufferLevel*1000)))
 
-	exponent = DEFAULT_EXPONENT
+	exponent := DEFAULT_EXPONENT
 
-	historicEstimationWindow = DEFAULT_HISTORIC_ESTIMATION_WINDOW
+	historicEstimationWindow := DEFAULT_HISTORIC_ESTIMATION_WINDOW
 
 	//if playeractivity.predictedValue < 0
 	var exponentialAverageRate float64
@@ -86,8 +76,10 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 	//normally the values for these variables would be passed in via the class constructor
 	//For now the assumption is that the default values are used
 
-	minBufferFactor = DEFAULT_MIN_BUFFER_FACTOR
-	maxBufferFactor = DEFAULT_MAX_BUFFER_FACTOR
+	minBufferFactor := DEFAULT_MIN_BUFFER_FACTOR
+	maxBufferFactor := DEFAULT_MAX_BUFFER_FACTOR
+
+	bufferingFactor := 1.0
 
 	if bufferScaling {
 		bufferingFactor = minBufferFactor + (maxBufferFactor-minBufferFactor)*bufferFullness
@@ -99,7 +91,7 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 
 	if switchingControl {
 
-		maximumSwitch = DEFAULT_MAXIMUM_SWITCH
+		maximumSwitch := DEFAULT_MAXIMUM_SWITCH
 
 		//NOTE lastIndex is lastrate here
 
@@ -115,11 +107,11 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 	}
 	//fmt.Println("targetIndex 2: ", targetIndex)
 
-	predictiveEstimationWindow = DEFAULT_PREDICTIVE_ESTIMATION_WINDOW
+	predictiveEstimationWindow := DEFAULT_PREDICTIVE_ESTIMATION_WINDOW
 	//segHeadValues := http.GetNSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true)
 	//fmt.Println("test", http.SegHeadValues)
 
-	_, client, _ := http.GetHTTPClient(quicBool, glob.DebugFile, debugLog, useTestbedBool)
+	_, client, _, err := http.GetHTTPClient(quicBool, http.TransportFromContext(ctx).DebugFile(), debugLog, useTestbedBool, ctx)
 
 	if actualRateQuality {
 		videoChunks := mpdDuration / lastDuration
@@ -128,13 +120,14 @@ func CalculateSelectedIndexArbiter(newThr int, lastDuration int, lastIndex int,
 		videoWindow := utils.Min(videoChunks-lastIndex, predictiveEstimationWindow)
 
 		//fmt.Println("videoWindow", videoWindow)
-		if http.SegHeadValues == nil {
-			for targetIndex < lowestMPDrepRateIndex && !SmartConvHelper(targetIndex, videoWindow, targetRate, currentMPD, currentURL, currentMPDRepAdaptSet, lastRate, segmentNumber, baseURL, debugLog, lastDuration, client) {
+		if segHeadValues == nil {
+			// without a client there are no segment sizes to look ahead at
+			for err == nil && targetIndex < lowestMPDrepRateIndex && !SmartConvHelper(targetIndex, videoWindow, targetRate, currentMPD, currentURL, currentMPDRepAdaptSet, lastRate, segmentNumber, baseURL, debugLog, lastDuration, client, ctx) {
 				targetIndex++
 			}
 		} else {
 
-			for targetIndex < lowestMPDrepRateIndex && !SmartConvHelperFromFile(videoWindow, targetRate, targetIndex, segmentNumber-1, lastDuration) {
+			for targetIndex < lowestMPDrepRateIndex && !SmartConvHelperFromFile(segHeadValues, videoWindow, targetRate, targetIndex, segmentNumber-1, lastDuration) {
 				targetIndex++
 			}
 
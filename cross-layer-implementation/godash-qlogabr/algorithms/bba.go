@@ -6,9 +6,8 @@ package algorithms
 
 import (
 	"fmt"
-	"os"
+	"math"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/uccmisl/godash/logging"
@@ -25,12 +24,51 @@ type BBA2Data struct {
 	currSegmentNumber        int
 	maxAverageChunkRatioList []float32 // Indicates the ratio between the maximum chunk size and the average chunk size, for every representation
 	metricLogger             *logging.MetricLogger
+	params                   BBA2Params
+	fastStart                bool  // The first segment was not downloaded at the lowest representation
+	throughputs              []int // Packet-level throughputs of the last segments in bits/second, for the dynamic reservoir
+	rtt                      time.Duration
+	trajectory               []BBA2Reservoirs
+	segmentSizes             *SegmentSizes // The sizes of the segments of every representation, nil without the VBR layer
+}
+
+// bba2VariationWindow : the number of segments the dynamic reservoir measures the variation of the throughput over
+const bba2VariationWindow = 10
+
+/*
+ * The reservoir sizes of BBA-2
+ */
+type BBA2Params struct {
+	MinReservoir   int     // Minimum size of the lower reservoir, in segments
+	UpperReservoir float64 // Part of the buffer above which the highest representation is selected
+	Horizon        int     // Number of max buffers of segments the lower reservoir is calculated over
+	Dynamic        bool    // The reservoirs follow the variation of the throughput and the RTT of the connection
+	MaxReservoir   float64 // Part of the buffer the dynamic lower reservoir takes at most
+	VarianceWeight float64 // The dynamic lower reservoir grows by this part of the coefficient of variation of the throughput
+}
+
+/*
+ * The reservoirs BBA-2 selected the representation of a segment with
+ */
+type BBA2Reservoirs struct {
+	Segment   int
+	Lower     int     // In milliseconds
+	Upper     int     // In milliseconds
+	Variation float64 // Coefficient of variation of the throughput of the last segments, 0 for the static reservoirs
+	RTT       time.Duration
+}
+
+/*
+ * The reservoir sizes of the BBA-2 paper
+ */
+func DefaultBBA2Params() BBA2Params {
+	return BBA2Params{MinReservoir: 3, UpperReservoir: 0.1, Horizon: 2, MaxReservoir: 0.5, VarianceWeight: 1}
 }
 
 /*
  * Constructs and initializes a BBA2Data struct
  */
-func NewBBA2Data(lowestBitrateChunkListStr string, maxAvgRatioList []float32, logger *logging.MetricLogger) BBA2Data {
+func NewBBA2Data(lowestBitrateChunkList []int, maxAvgRatioList []float32, logger *logging.MetricLogger, params BBA2Params) BBA2Data {
 	data := BBA2Data{}
 	data.PreviousBufferLevel = 0
 	data.UsingRate = true
@@ -39,30 +77,51 @@ func NewBBA2Data(lowestBitrateChunkListStr string, maxAvgRatioList []float32, lo
 
 	data.metricLogger = logger
 
-	max := 0
-	sum := 0
+	data.lowestBitrateChunkList = lowestBitrateChunkList
 
-	chunkList := strings.Split(lowestBitrateChunkListStr, ",")
+	data.maxAverageChunkRatioList = maxAvgRatioList
 
-	for i := 0; i < len(chunkList); i++ {
-		intVal, err := strconv.Atoi(chunkList[i])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-		data.lowestBitrateChunkList = append(data.lowestBitrateChunkList, intVal)
-	}
+	data.params = params
 
-	for i := 0; i < len(data.lowestBitrateChunkList); i++ {
-		if data.lowestBitrateChunkList[i] > max {
-			max = data.lowestBitrateChunkList[i]
+	return data
+}
+
+/*
+ * Tells BBA-2 the fast start selected the representation of the first segment, BBA-2 ramps up from there
+ */
+func SetBBA2FastStart(data *BBA2Data) {
+	data.fastStart = true
+}
+
+/*
+ * Gives BBA-2 the sizes of the segments of every representation, the startup phase then steps up on the size of the next segment
+ * of the higher representation instead of on the ratio between its maximum and average chunk size
+ */
+func SetBBA2SegmentSizes(data *BBA2Data, sizes *SegmentSizes) {
+	data.segmentSizes = sizes
+}
+
+/*
+ * Gives the dynamic reservoir of BBA-2 the throughput of the packets of the last segment in bits/second and the smoothed RTT of the connection,
+ * call it after every segment, before BBA2
+ */
+func BBA2Measured(data *BBA2Data, throughput_bps int, rtt time.Duration) {
+	if throughput_bps > 0 {
+		data.throughputs = append(data.throughputs, throughput_bps)
+		if len(data.throughputs) > bba2VariationWindow {
+			data.throughputs = data.throughputs[len(data.throughputs)-bba2VariationWindow:]
 		}
-		sum += data.lowestBitrateChunkList[i]
 	}
+	if rtt > 0 {
+		data.rtt = rtt
+	}
+}
 
-	data.maxAverageChunkRatioList = maxAvgRatioList
-
-	return data
+/*
+ * Returns the reservoirs of every representation BBA-2 selected so far
+ */
+func BBA2Trajectory(data *BBA2Data) []BBA2Reservoirs {
+	return append([]BBA2Reservoirs(nil), data.trajectory...)
 }
 
 /*
@@ -134,6 +193,11 @@ func BBA2(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, highestMPDre
 
 	*thrList = append(*thrList, newThr)
 
+	// Keep the representation of the fast start until there is a segment to go on
+	if data.fastStart && data.currSegmentNumber <= 1 {
+		return previousRepRate
+	}
+
 	// Do not do anything if this algorithm is called unnecessarily
 	if data.currSegmentNumber == data.previousSegmentNumber {
 		// fmt.Println("Skipping")
@@ -143,17 +207,17 @@ func BBA2(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, highestMPDre
 	}
 
 	maxBufferLevel_Milliseconds := maxBufferLevel_Seconds * 1000
-	maxBufferLevel_Segments := maxBufferLevel_Milliseconds / segmentDuration_Milliseconds
 
 	// Lower reservoir size is calculated using chunk sizes (in milliseconds)
-	var reservoir_lower float64 = float64(calculateBBA2Reservoir(data.lowestBitrateChunkList, maxBufferLevel_Segments*2, currentSegmentNumber, int(LowestBitrate(bandwithList)), segmentDuration_Milliseconds/1000, maxBufferLevel_Milliseconds, data))
-	var reservoir_upper float64 = 0.1 * float64(maxBufferLevel_Milliseconds) // We reach Rmax at 90% buffer occupancy
+	reservoir_lower, reservoir_upper, variation := bba2Reservoirs(maxBufferLevel_Seconds, bandwithList, segmentDuration_Milliseconds, currentSegmentNumber, data)
 
-	data.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-		TimeStamp: time.Now(),
-		Tag:       "LOWERRESERVOIR",
-		Message:   strconv.Itoa(int(reservoir_lower)),
+	data.metricLogger.Log(logging.MetricLowerReservoir, float64(int(reservoir_lower)))
+	data.metricLogger.Log(logging.MetricUpperReservoir, float64(int(reservoir_upper)))
+	if data.params.Dynamic {
+		data.metricLogger.Log(logging.MetricThroughputVariation, variation)
+		data.metricLogger.Log(logging.MetricReservoirRTT, data.rtt.Seconds()*1000)
 	}
+	data.trajectory = append(data.trajectory, BBA2Reservoirs{Segment: currentSegmentNumber, Lower: int(reservoir_lower), Upper: int(reservoir_upper), Variation: variation, RTT: data.rtt})
 
 	fmt.Println("RESERVOIR: ", reservoir_lower)
 
@@ -194,16 +258,8 @@ func BBA2(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, highestMPDre
 		// Map to a bitrate
 		var desiredBitrate float64 = (percentage * Rmax)
 
-		data.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-			TimeStamp: time.Now(),
-			Tag:       "PERCENTAGE",
-			Message:   fmt.Sprintf("%v", percentage),
-		}
-		data.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-			TimeStamp: time.Now(),
-			Tag:       "DESIREDBITRATE",
-			Message:   strconv.Itoa(int(desiredBitrate)),
-		}
+		data.metricLogger.Log(logging.MetricPercentage, percentage)
+		data.metricLogger.Log(logging.MetricDesiredBitrate, float64(int(desiredBitrate)))
 
 		// Choose the representation that best fits this bitrate
 		chosenRep = SelectRepRateWithThroughtput(int(desiredBitrate), bandwithList, lowestMPDrepRateIndex)
@@ -231,11 +287,7 @@ func BBA2(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, highestMPDre
 			// If BBA-2 selected a higher rate than Rate, switch to BBA-2 and stop using Rate
 			data.UsingRate = false
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Switched from Rate to BBA2")
-			data.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-				TimeStamp: time.Now(),
-				Tag:       "LOGICSWITCH",
-				Message:   "RATE_TO_BBA",
-			}
+			data.metricLogger.LogMessage(logging.MetricLogicSwitch, "RATE_TO_BBA")
 		}
 	}
 
@@ -248,10 +300,54 @@ func BBA2(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, highestMPDre
 }
 
 /*
- *  Calculates the reservoir size using chunk sizes
+ *  Calculates the lower and the upper reservoir in milliseconds, and the variation of the throughput the dynamic reservoirs are sized on.
+ *  The dynamic lower reservoir also waits an RTT for every chunk and grows with the variation, up to MaxReservoir of the buffer,
+ *  and its upper reservoir is UpperReservoir of the cushion above the lower reservoir, shrunk by the variation
+ */
+func bba2Reservoirs(maxBufferLevel_Seconds int, bandwithList []int, segmentDuration_Milliseconds int, currentSegmentNumber int, data *BBA2Data) (float64, float64, float64) {
+	maxBufferLevel_Milliseconds := maxBufferLevel_Seconds * 1000
+	maxBufferLevel_Segments := maxBufferLevel_Milliseconds / segmentDuration_Milliseconds
+	predictionTimePeriod_segments := maxBufferLevel_Segments * data.params.Horizon
+
+	if !data.params.Dynamic {
+		lower := calculateBBA2Reservoir(data.lowestBitrateChunkList, predictionTimePeriod_segments, currentSegmentNumber, int(LowestBitrate(bandwithList)), segmentDuration_Milliseconds/1000, maxBufferLevel_Milliseconds, 0, data)
+		// We reach Rmax at 90% buffer occupancy by default
+		return float64(lower), data.params.UpperReservoir * float64(maxBufferLevel_Milliseconds), 0
+	}
+
+	variation := throughputVariation(data.throughputs)
+	growth := 1 + data.params.VarianceWeight*variation
+	lower := float64(calculateBBA2Reservoir(data.lowestBitrateChunkList, predictionTimePeriod_segments, currentSegmentNumber, int(LowestBitrate(bandwithList)), segmentDuration_Milliseconds/1000, maxBufferLevel_Milliseconds, data.rtt, data)) * growth
+	lower = math.Min(lower, data.params.MaxReservoir*float64(maxBufferLevel_Milliseconds))
+	upper := data.params.UpperReservoir * (float64(maxBufferLevel_Milliseconds) - lower) / growth
+	return lower, upper, variation
+}
+
+/*
+ *  Returns the coefficient of variation of the throughputs, 0 if there are less than two
+ */
+func throughputVariation(throughputs []int) float64 {
+	if len(throughputs) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, thr := range throughputs {
+		mean += float64(thr)
+	}
+	mean /= float64(len(throughputs))
+	variance := 0.0
+	for _, thr := range throughputs {
+		variance += (float64(thr) - mean) * (float64(thr) - mean)
+	}
+	variance /= float64(len(throughputs))
+	return math.Sqrt(variance) / mean
+}
+
+/*
+ *  Calculates the reservoir size using chunk sizes, every chunk waits rtt before it arrives
  *  Return Value in milliseconds
  */
-func calculateBBA2Reservoir(lowestBitrateChunkList []int, predictionTimePeriod_segments int, currentSegmetNumber int, lowestBitrate_bps int, segmentDuration_seconds int, buffersize_milli int, data *BBA2Data) float32 {
+func calculateBBA2Reservoir(lowestBitrateChunkList []int, predictionTimePeriod_segments int, currentSegmetNumber int, lowestBitrate_bps int, segmentDuration_seconds int, buffersize_milli int, rtt time.Duration, data *BBA2Data) float32 {
 	numberOfSegments := len(lowestBitrateChunkList)
 	sum := float32(0)
 
@@ -259,7 +355,7 @@ func calculateBBA2Reservoir(lowestBitrateChunkList []int, predictionTimePeriod_s
 	for i := currentSegmetNumber - 1; i < numberOfSegments && i < predictionTimePeriod_segments; i++ {
 		// The amount of time it is going to take to download chunk i at a rate of Rmin
 		chunkSize := float32(lowestBitrateChunkList[i])
-		expectedDownloadTimeChunk := chunkSize / float32(lowestBitrate_bps)
+		expectedDownloadTimeChunk := chunkSize/float32(lowestBitrate_bps) + float32(rtt.Seconds())
 		// The amount of time we gained or lost during the download. We are expectedDownloadTimeChunk seconds busy downloading a segment that will fill the buffer by segmentDuration_seconds.
 		bufferDelta := expectedDownloadTimeChunk - float32(segmentDuration_seconds)
 
@@ -270,15 +366,11 @@ func calculateBBA2Reservoir(lowestBitrateChunkList []int, predictionTimePeriod_s
 
 	fmt.Println("BUFFERSIZE: ", buffersize_milli)
 
-	data.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-		TimeStamp: time.Now(),
-		Tag:       "CHUNKSUM",
-		Message:   strconv.Itoa(int(sum_milli)),
-	}
+	data.metricLogger.Log(logging.MetricChunkSum, float64(int(sum_milli)))
 
-	// Clamp the reservoir between 3 * segmentsize and buffersize
-	if sum_milli < float32(3*segmentDuration_seconds*1000) {
-		sum_milli = float32(3 * segmentDuration_seconds * 1000)
+	// Clamp the reservoir between MinReservoir * segmentsize and buffersize
+	if sum_milli < float32(data.params.MinReservoir*segmentDuration_seconds*1000) {
+		sum_milli = float32(data.params.MinReservoir * segmentDuration_seconds * 1000)
 	}
 	if sum_milli > float32(buffersize_milli) {
 		sum_milli = float32(buffersize_milli)
@@ -289,9 +381,8 @@ func calculateBBA2Reservoir(lowestBitrateChunkList []int, predictionTimePeriod_s
 }
 
 func Get_BBA2_LowerReservoir(bufferLevel_Milliseconds int, maxBufferLevel_Seconds int, bandwithList []int, segmentDuration_Milliseconds int, currentSegmentNumber int, data *BBA2Data) int {
-	maxBufferLevel_Milliseconds := maxBufferLevel_Seconds * 1000
-	maxBufferLevel_Segments := maxBufferLevel_Milliseconds / segmentDuration_Milliseconds
-	return int(calculateBBA2Reservoir(data.lowestBitrateChunkList, maxBufferLevel_Segments*2, currentSegmentNumber, int(LowestBitrate(bandwithList)), segmentDuration_Milliseconds/1000, maxBufferLevel_Milliseconds, data))
+	reservoir_lower, _, _ := bba2Reservoirs(maxBufferLevel_Seconds, bandwithList, segmentDuration_Milliseconds, currentSegmentNumber, data)
+	return int(reservoir_lower)
 }
 
 /*
@@ -323,7 +414,14 @@ func rate(segmentDuration_seconds int, data *BBA2Data, lastThroughput int, curre
 	// Use previousSegmentNumber - 1 because segments start at 1
 	deltaB := float64(segmentDuration_seconds) - (float64(data.lowestBitrateChunkList[previousSegmentNumber-1]) / float64(lastThroughput))
 
-	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "DELTAB "+strconv.Itoa(int(deltaB))+" "+strconv.Itoa(int(data.maxAverageChunkRatioList[previousRepRate-1]*float32(bandwithList[previousRepRate-1])*float32(segmentDuration_seconds))))
+	// The size of the next segment of the higher representation relative to its bandwidth
+	// Without the VBR layer the ratio between its maximum and average chunk size
+	chunkRatio := float64(data.maxAverageChunkRatioList[previousRepRate-1])
+	if ratio, ok := data.segmentSizes.SizeRatio(previousRepRate-1, currentSegmentNumber+1, bandwithList[previousRepRate-1]); ok {
+		chunkRatio = ratio
+	}
+
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "DELTAB "+strconv.Itoa(int(deltaB))+" "+strconv.Itoa(int(chunkRatio*float64(bandwithList[previousRepRate-1])*float64(segmentDuration_seconds))))
 
 	// Below is wrong
 	// Threshold depens on buffer occupation
@@ -338,8 +436,7 @@ func rate(segmentDuration_seconds int, data *BBA2Data, lastThroughput int, curre
 	// V - (0.5 * V * Y)/e
 	// Y = Ri / Ri+1
 	Y := float64(bandwithList[previousRepRate]) / float64(bandwithList[previousRepRate-1])
-	threshold := float64(segmentDuration_seconds) - ((0.5 * float64(segmentDuration_seconds) * Y) / float64(data.maxAverageChunkRatioList[previousRepRate-1]))
-	// TODO maxaverageChunkRatio should be for each representation individually
+	threshold := float64(segmentDuration_seconds) - ((0.5 * float64(segmentDuration_seconds) * Y) / chunkRatio)
 
 	fmt.Println("Threshold ", threshold)
 
@@ -22,6 +22,7 @@
 package algorithms
 
 import (
+	"context"
 	"fmt"
 
 	glob "github.com/uccmisl/godash/global"
@@ -37,7 +38,7 @@ import (
 func CalculateSelectedIndexBba(newThr int, lastDuration int, lastIndex int, maxBufferLevel int,
 	lastRate int, thrList *[]int, mpdDuration int, currentMPD http.MPD, currentURL string,
 	currentMPDRepAdaptSet int, segmentNumber int, baseURL string, debugLog bool, downloadTime int, bufferLevel int,
-	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, bandwithList []int, quicBool bool, useTestbedBool bool) int {
+	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, bandwithList []int, quicBool bool, useTestbedBool bool, ctx context.Context) int {
 
 	//currTime := time.Now()
 
@@ -72,7 +73,7 @@ func CalculateSelectedIndexBba(newThr int, lastDuration int, lastIndex int, maxB
 	//fmt.Println("lowest bitrate...: ", lowest)
 
 	reservoir := bba1UpdateReservoir(lastRate, lastIndex, mpdDuration, lastDuration, maxBufferLevel,
-		currentMPD, currentURL, currentMPDRepAdaptSet, segmentNumber, baseURL, debugLog, bandwithList, quicBool, useTestbedBool)
+		currentMPD, currentURL, currentMPDRepAdaptSet, segmentNumber, baseURL, debugLog, bandwithList, quicBool, useTestbedBool, ctx)
 
 	//fmt.Println("reservoir: ", reservoir)
 	//fmt.Println("ret1:", retVal)
@@ -174,7 +175,7 @@ func CalculateSelectedIndexBba(newThr int, lastDuration int, lastIndex int, maxB
 
 func bba1UpdateReservoir(lastRate int, lastRateIndex int, mpdDuration int,
 	lastSegmentDuration int, maxBufferLevel int, currentMPD http.MPD, currentURL string, currentMPDRepAdaptSet int,
-	segmentNumber int, baseURL string, debugLog bool, bandwithList []int, quicBool bool, useTestbedBool bool) int {
+	segmentNumber int, baseURL string, debugLog bool, bandwithList []int, quicBool bool, useTestbedBool bool, ctx context.Context) int {
 	//currTime := time.Now()
 
 	//we need to convert the maxBufferLevel to milliseconds
@@ -196,18 +197,24 @@ func bba1UpdateReservoir(lastRate int, lastRateIndex int, mpdDuration int,
 
 	//fmt.Println("RESERVOIR TIME BEFORE HTTP: ", time.Since(currTime).Milliseconds())
 
-	_, client, _ := http.GetHTTPClient(quicBool, glob.DebugFile, debugLog, useTestbedBool)
+	_, client, _, err := http.GetHTTPClient(quicBool, http.TransportFromContext(ctx).DebugFile(), debugLog, useTestbedBool, ctx)
+	if err != nil {
+		// without a client the reservoir is the minimum
+		resvWin = 0
+	}
 
 	for i := 0; i < resvWin; i++ {
 		//do a func getSegBySize(lastSegNumber+i, lastRateIndex) and return the size of the segment
-		if http.GetContentLengthHeader(currentMPD,
-			currentURL, currentMPDRepAdaptSet, lastRate, segmentNumber+i, baseURL, debugLog, client) > avgSegSize {
-
-			largeSeg += http.GetContentLengthHeader(currentMPD,
-				currentURL, currentMPDRepAdaptSet, lastRate, segmentNumber+i, baseURL, debugLog, client)
+		segSize, err := http.GetContentLengthHeader(currentMPD,
+			currentURL, currentMPDRepAdaptSet, lastRate, segmentNumber+i, baseURL, debugLog, client, ctx)
+		if err != nil {
+			// the reservoir is only of the segments we know the size of
+			break
+		}
+		if segSize > avgSegSize {
+			largeSeg += segSize
 		} else {
-			smallSeg += http.GetContentLengthHeader(currentMPD,
-				currentURL, currentMPDRepAdaptSet, lastRate, segmentNumber+i, baseURL, debugLog, client)
+			smallSeg += segSize
 		}
 	}
 
@@ -0,0 +1,42 @@
+package algorithms
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBBA2Reservoirs(t *testing.T) {
+	// 10 chunks of 3 Mbit at a lowest rep_rate of 1 Mbps, each takes a second more than its 2 second segment
+	chunks := []int{3000000, 3000000, 3000000, 3000000, 3000000, 3000000, 3000000, 3000000, 3000000, 3000000}
+	bandwithList := []int{4000000, 2000000, 1000000}
+	params := DefaultBBA2Params()
+	params.Horizon = 1
+
+	data := NewBBA2Data(chunks, nil, nil, params)
+	lower, upper, _ := bba2Reservoirs(20, bandwithList, 2000, 1, &data)
+	if math.Abs(lower-10000) > 1 || math.Abs(upper-2000) > 1 {
+		t.Errorf("the static reservoirs are %g ms and %g ms, want 10000 ms and 2000 ms", lower, upper)
+	}
+
+	// every chunk waits 100 ms more, and the throughput varies by half its mean
+	params.Dynamic = true
+	params.MaxReservoir = 0.9
+	data = NewBBA2Data(chunks, nil, nil, params)
+	BBA2Measured(&data, 1000000, 100*time.Millisecond)
+	BBA2Measured(&data, 3000000, 0)
+	lower, upper, variation := bba2Reservoirs(20, bandwithList, 2000, 1, &data)
+	if math.Abs(variation-0.5) > 1e-9 {
+		t.Errorf("the variation is %g, want 0.5", variation)
+	}
+	if math.Abs(lower-16500) > 1 || math.Abs(upper-233.33) > 1 {
+		t.Errorf("the dynamic reservoirs are %g ms and %g ms, want 16500 ms and 233 ms", lower, upper)
+	}
+
+	// the lower reservoir takes at most MaxReservoir of the buffer
+	data.params.MaxReservoir = 0.5
+	lower, upper, _ = bba2Reservoirs(20, bandwithList, 2000, 1, &data)
+	if math.Abs(lower-10000) > 1 || math.Abs(upper-666.67) > 1 {
+		t.Errorf("the bounded reservoirs are %g ms and %g ms, want 10000 ms and 667 ms", lower, upper)
+	}
+}
@@ -0,0 +1,283 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	"math"
+	"sort"
+
+	"github.com/uccmisl/godash/utils"
+)
+
+// the buffer constants of BOLA in dash.js
+const (
+	// bolaMinimumBuffer_Seconds : the buffer level below which BOLA streams the lowest rep_rate
+	bolaMinimumBuffer_Seconds = 10.0
+	// bolaBufferPerLevel_Seconds : buffer added to the buffer target for every rep_rate
+	bolaBufferPerLevel_Seconds = 2.0
+	// bolaSafeThroughput : part of the throughput BOLA-E trusts in startup and in the oscillation rule
+	bolaSafeThroughput = 0.9
+	// bolaInsufficientBufferSafety : part of the throughput the insufficient buffer rule trusts
+	bolaInsufficientBufferSafety = 0.5
+)
+
+// BOLAData :
+/*
+ * the state of BOLA-BASIC and BOLA-E over the segments of an adaptation set
+ * the rep_rates are kept from the lowest to the highest bandwidth, whatever the order of the MPD
+ */
+type BOLAData struct {
+	bolaE bool
+	// the rep_rate index of every level, from the lowest to the highest bandwidth
+	repRates []int
+	// bandwidth in bps, utility and segment sizes in bits of every level
+	bitrates   []float64
+	utilities  []float64
+	chunkLists [][]int
+	// the BOLA parameters, Vp in seconds
+	gp float64
+	vp float64
+
+	segmentDuration_Seconds float64
+	// part of a segment that is downloaded before BOLA-E can abandon it
+	abandonWindow float64
+
+	// BOLA-E state
+	startup                  bool
+	placeholder_Milliseconds int
+	lastLevel                int
+
+	// the levels the ladder constraints allow, nil if they allow every level
+	allowed []bool
+}
+
+// NewBOLAData :
+/*
+ * the BOLA state of an adaptation set with rep_rates bandwithList and segment sizes chunkLists, indexed on the rep_rate
+ * bolaE adds the placeholder buffer, the insufficient buffer rule and segment abandonment of BOLA-E
+ * a segment is abandoned no sooner than when abandonWindow of it is downloaded
+ */
+func NewBOLAData(bandwithList []int, chunkLists [][]int, segmentDuration_Milliseconds int, maxBufferLevel_Seconds int, bolaE bool, abandonWindow float64) BOLAData {
+	data := BOLAData{
+		bolaE:                   bolaE,
+		segmentDuration_Seconds: float64(segmentDuration_Milliseconds) / 1000,
+		abandonWindow:           abandonWindow,
+		startup:                 true,
+	}
+
+	for i := range bandwithList {
+		data.repRates = append(data.repRates, i)
+	}
+	sort.SliceStable(data.repRates, func(a, b int) bool {
+		return bandwithList[data.repRates[a]] < bandwithList[data.repRates[b]]
+	})
+	for _, repRate := range data.repRates {
+		data.bitrates = append(data.bitrates, float64(bandwithList[repRate]))
+		// the lowest rep_rate has utility 1
+		data.utilities = append(data.utilities, math.Log(float64(bandwithList[repRate])/float64(bandwithList[data.repRates[0]]))+1)
+		var chunks []int
+		if repRate < len(chunkLists) {
+			chunks = chunkLists[repRate]
+		}
+		data.chunkLists = append(data.chunkLists, chunks)
+	}
+
+	// the highest rep_rate is streamed from the buffer target on, which leaves room for one more segment
+	target := math.Max(float64(maxBufferLevel_Seconds)-data.segmentDuration_Seconds, 2*data.segmentDuration_Seconds)
+	target = math.Min(target, bolaMinimumBuffer_Seconds+bolaBufferPerLevel_Seconds*float64(len(data.repRates)))
+	minimum := math.Min(bolaMinimumBuffer_Seconds, target/2)
+
+	data.gp = (data.utilities[len(data.utilities)-1] - 1) / (target/minimum - 1)
+	if data.gp <= 0 {
+		// a single rep_rate, every buffer level selects it
+		data.gp = 1
+	}
+	data.vp = minimum / data.gp
+
+	return data
+}
+
+// BOLA :
+/*
+ * the rep_rate index of segment nextSegmentNumber according to BOLA-BASIC, or BOLA-E
+ * newThr is the throughput of the last segment in bps
+ */
+func BOLA(bufferLevel_Milliseconds int, newThr int, nextSegmentNumber int, thrList *[]int, data *BOLAData) int {
+
+	*thrList = append(*thrList, newThr)
+
+	bufferLevel := float64(bufferLevel_Milliseconds) / 1000
+	if !data.bolaE {
+		data.lastLevel = data.levelFromBuffer(bufferLevel, nextSegmentNumber)
+		return data.repRates[data.lastLevel]
+	}
+
+	throughputLevel := data.levelFromThroughput(bolaSafeThroughput * float64(newThr))
+
+	var level int
+	if data.startup {
+		// stream at the throughput and fake the buffer BOLA needs for this rep_rate
+		level = throughputLevel
+		data.placeholder_Milliseconds = int(math.Max(0, data.minimumBufferForLevel(level)-bufferLevel) * 1000)
+		if bufferLevel >= data.segmentDuration_Seconds {
+			data.startup = false
+		}
+	} else {
+		level = data.levelFromBuffer(bufferLevel+float64(data.placeholder_Milliseconds)/1000, nextSegmentNumber)
+
+		// do not go up past the throughput, this stops BOLA from oscillating
+		if level > data.lastLevel && level > throughputLevel {
+			level = highestAllowedLevel(data.allowed, utils.Max(throughputLevel, data.lastLevel))
+		}
+
+		// the placeholder buffer above the buffer BOLA wants for this rep_rate is not needed
+		excess := bufferLevel + float64(data.placeholder_Milliseconds)/1000 - data.maximumBufferForLevel(level)
+		if excess > 0 {
+			data.placeholder_Milliseconds = int(math.Max(0, float64(data.placeholder_Milliseconds)/1000-excess) * 1000)
+		}
+	}
+
+	// insufficient buffer rule : the segment has to arrive before the buffer runs out
+	if bufferLevel <= 0 {
+		level = highestAllowedLevel(data.allowed, 0)
+	} else {
+		level = utils.Min(level, data.levelFromThroughput(bolaInsufficientBufferSafety*float64(newThr)*bufferLevel/data.segmentDuration_Seconds))
+	}
+
+	data.lastLevel = level
+	return data.repRates[level]
+}
+
+// BOLAAbandon :
+/*
+ * the segment abandonment rule of BOLA-E, for a download of segment segmentNumber at rep_rate repRate
+ * rate_bps is the rate of the download so far, elapsed_Milliseconds the time it runs
+ * returns the rep_rate to download the segment at instead, the predicted remaining download time in milliseconds
+ * and true if the download should be abandoned
+ */
+func BOLAAbandon(repRate int, segmentNumber int, rate_bps float64, elapsed_Milliseconds int, bufferLevelAtStart_Milliseconds int, data *BOLAData) (int, int, bool) {
+	if !data.bolaE || rate_bps <= 0 {
+		return repRate, 0, false
+	}
+	level := data.level(repRate)
+	if level <= 0 {
+		return repRate, 0, false
+	}
+
+	size := data.segmentSize(level, segmentNumber)
+	received := rate_bps * float64(elapsed_Milliseconds) / 1000
+	remaining := size - received
+	if received < data.abandonWindow*size || remaining <= 0 {
+		return repRate, 0, false
+	}
+	predicted_Milliseconds := int(remaining / rate_bps * 1000)
+
+	// the buffer BOLA sees now, the download is only abandoned while BOLA would download this rep_rate
+	bufferLevel := math.Max(0, float64(bufferLevelAtStart_Milliseconds-elapsed_Milliseconds)/1000) + float64(data.placeholder_Milliseconds)/1000
+	if bufferLevel >= data.maximumBufferForLevel(level) {
+		return repRate, predicted_Milliseconds, false
+	}
+
+	// finishing this download competes with downloading the whole segment at a lower rep_rate
+	best := level
+	bestScore := (data.vp*(data.utilities[level]+data.gp) - bufferLevel) / remaining
+	for i := level - 1; i >= 0; i-- {
+		if !levelAllowed(data.allowed, i) {
+			continue
+		}
+		score := (data.vp*(data.utilities[i]+data.gp) - bufferLevel) / data.segmentSize(i, segmentNumber)
+		if score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+	if best == level || data.segmentSize(best, segmentNumber) >= remaining {
+		return repRate, predicted_Milliseconds, false
+	}
+
+	data.lastLevel = best
+	return data.repRates[best], predicted_Milliseconds, true
+}
+
+// level : the BOLA level of rep_rate index repRate
+func (data *BOLAData) level(repRate int) int {
+	for level, r := range data.repRates {
+		if r == repRate {
+			return level
+		}
+	}
+	return 0
+}
+
+// segmentSize : the size in bits of segment segmentNumber of a level, from its bandwidth if the size is unknown
+func (data *BOLAData) segmentSize(level int, segmentNumber int) float64 {
+	chunks := data.chunkLists[level]
+	if segmentNumber >= 1 && segmentNumber <= len(chunks) && chunks[segmentNumber-1] > 0 {
+		return float64(chunks[segmentNumber-1])
+	}
+	return data.bitrates[level] * data.segmentDuration_Seconds
+}
+
+// levelFromBuffer : the allowed level with the highest BOLA score for segment segmentNumber at buffer level bufferLevel in seconds
+func (data *BOLAData) levelFromBuffer(bufferLevel float64, segmentNumber int) int {
+	best := 0
+	bestScore := math.Inf(-1)
+	for level := range data.repRates {
+		if !levelAllowed(data.allowed, level) {
+			continue
+		}
+		score := (data.vp*(data.utilities[level]+data.gp) - bufferLevel) / data.segmentSize(level, segmentNumber)
+		if score >= bestScore {
+			best = level
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// levelFromThroughput : the highest allowed level with a bandwidth below thr in bps, or the lowest allowed level
+func (data *BOLAData) levelFromThroughput(thr float64) int {
+	level := 0
+	for i, bitrate := range data.bitrates {
+		if bitrate <= thr {
+			level = i
+		}
+	}
+	return highestAllowedLevel(data.allowed, level)
+}
+
+// minimumBufferForLevel : the buffer level in seconds from which BOLA selects a level over the lower allowed levels
+func (data *BOLAData) minimumBufferForLevel(level int) float64 {
+	minimum := 0.0
+	for i := level - 1; i >= 0; i-- {
+		if levelAllowed(data.allowed, i) && data.utilities[i] < data.utilities[level] {
+			buffer := data.vp * (data.gp + (data.bitrates[level]*data.utilities[i]-data.bitrates[i]*data.utilities[level])/(data.bitrates[level]-data.bitrates[i]))
+			minimum = math.Max(minimum, buffer)
+		}
+	}
+	return minimum
+}
+
+// maximumBufferForLevel : the buffer level in seconds above which BOLA would rather wait than download a level
+func (data *BOLAData) maximumBufferForLevel(level int) float64 {
+	return data.vp * (data.utilities[level] + data.gp)
+}
@@ -0,0 +1,71 @@
+package algorithms
+
+import "testing"
+
+// the rep_rates in MPD order, the highest first
+var bolaBandwithList = []int{5000000, 2500000, 1000000, 500000}
+
+func TestBOLABufferLevels(t *testing.T) {
+	data := NewBOLAData(bolaBandwithList, nil, 2000, 30, false, 0.15)
+	var thrList []int
+
+	if repRate := BOLA(0, 20000000, 2, &thrList, &data); repRate != 3 {
+		t.Errorf("empty buffer selects rep_rate %d, want the lowest 3", repRate)
+	}
+	if repRate := BOLA(17000, 20000000, 3, &thrList, &data); repRate != 0 {
+		t.Errorf("buffer near the target selects rep_rate %d, want the highest 0", repRate)
+	}
+
+	previous := 3
+	for bufferLevel := 0; bufferLevel <= 18000; bufferLevel += 1000 {
+		repRate := BOLA(bufferLevel, 20000000, 4, &thrList, &data)
+		if bolaBandwithList[repRate] < bolaBandwithList[previous] {
+			t.Errorf("buffer level %d selects a lower rep_rate than a smaller buffer", bufferLevel)
+		}
+		previous = repRate
+	}
+}
+
+func TestBOLAEInsufficientBuffer(t *testing.T) {
+	data := NewBOLAData(bolaBandwithList, nil, 2000, 30, true, 0.15)
+	var thrList []int
+
+	// in startup BOLA-E streams at the throughput, but not faster than the buffer allows
+	if repRate := BOLA(2000, 6000000, 2, &thrList, &data); repRate != 1 {
+		t.Errorf("startup selects rep_rate %d, want 1", repRate)
+	}
+	if repRate := BOLA(500, 6000000, 3, &thrList, &data); repRate != 3 {
+		t.Errorf("half a second of buffer selects rep_rate %d, want the lowest 3", repRate)
+	}
+}
+
+func TestBOLAAbandon(t *testing.T) {
+	data := NewBOLAData(bolaBandwithList, [][]int{{10000000}, {5000000}, {2000000}, {1000000}}, 2000, 30, true, 0.15)
+
+	// a slow download with little buffer left is downloaded again at the lowest rep_rate
+	repRate, predicted, abandon := BOLAAbandon(0, 1, 1000000, 2000, 4000, &data)
+	if !abandon || repRate != 3 {
+		t.Errorf("slow download: rep_rate %d abandon %v, want 3 true", repRate, abandon)
+	}
+	if predicted != 8000 {
+		t.Errorf("predicted remaining time %d, want 8000", predicted)
+	}
+
+	// the rest of the download is smaller than the lowest segment
+	if _, _, abandon := BOLAAbandon(0, 1, 20000000, 450, 4000, &data); abandon {
+		t.Error("an almost finished download is abandoned")
+	}
+	// nothing is abandoned before the abandonment window, or at the lowest rep_rate
+	if _, _, abandon := BOLAAbandon(0, 1, 1000000, 1000, 4000, &data); abandon {
+		t.Error("a download is abandoned before the abandonment window")
+	}
+	if _, _, abandon := BOLAAbandon(3, 1, 100000, 5000, 0, &data); abandon {
+		t.Error("a download of the lowest rep_rate is abandoned")
+	}
+
+	// BOLA-BASIC never abandons
+	basic := NewBOLAData(bolaBandwithList, nil, 2000, 30, false, 0.15)
+	if _, _, abandon := BOLAAbandon(0, 1, 1000000, 2000, 4000, &basic); abandon {
+		t.Error("BOLA-BASIC abandons a download")
+	}
+}
@@ -21,9 +21,6 @@
 
 package algorithms
 
-// the throughtput is equal to -1 at the beginning by default
-var thr = -1
-
 //Conventional :
 /*
 * calculate of the throughtput with the ancient one and the new one
@@ -32,14 +29,16 @@ var thr = -1
  */
 func Conventional(thrList *[]int, newThr int, repRate *int, bandwithList []int, lowestMPDrepRateIndex int) {
 
+	// the list holds the smoothed throughtput of the stream, the last one is the current value
+	var thr int
 	//if it is the first throughtput in the list, add it to the list
-	if thr == -1 {
+	if len(*thrList) == 0 {
 		thr = newThr
 		*thrList = append(*thrList, thr)
 	} else {
 		//if there is already one thr, calculate the thr that will be added to the list
 		//with 80% of the last thr and 20% of the new one
-		thr = (8*thr)/10 + (2*newThr)/10
+		thr = (8*(*thrList)[len(*thrList)-1])/10 + (2*newThr)/10
 		*thrList = append(*thrList, thr)
 	}
 
@@ -0,0 +1,273 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	xlayer "github.com/uccmisl/godash/crosslayer"
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/ladder"
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/predictor"
+	"github.com/uccmisl/godash/utils"
+)
+
+// the gains of the elastic algorithm
+const (
+	elasticKP = 0.01
+	elasticKI = 0.001
+)
+
+// State : the state the algorithms keep over the segments of a stream
+type State struct {
+	BBA2     BBA2Data
+	BOLA     BOLAData
+	MPC      MPCData
+	Pensieve PensieveData
+	L2A      L2AData
+	LoLP     LoLPData
+	// SegmentSizes of every rep_rate for the VBR layer, nil without it
+	SegmentSizes       *SegmentSizes
+	ThrList            []int
+	StaticAlgParameter float64
+	// Predictor of the algorithm, nil for its own estimator, and its samples
+	Predictor predictor.Predictor
+	Samples   []predictor.Sample
+}
+
+// Decision :
+/*
+ * what the player knows after the download of a segment, the rep_rate of the next segment is decided on it
+ * the buffer level and the delivery and stall times are in milliseconds, the throughput in bps
+ */
+type Decision struct {
+	Adapt         string
+	SegmentNumber int
+	RepRate       int
+	BufferLevel   int
+	Throughput    int
+	DeliveryTime  int
+	StallTime     int
+	// RTT of the segment, and the smoothed RTT of the connection the dynamic reservoir of BBA-2 is sized on
+	RTT         time.Duration
+	SmoothedRTT time.Duration
+	StreamSpeed float64
+	// MaxBuffer of the stream in seconds, MaxBufferLevel the one of the MPD the buffer based algorithms fill
+	MaxBuffer                    int
+	MaxBufferLevel               int
+	SegmentDuration_Milliseconds int
+	Bandwidths                   []int
+	Highest                      int
+	Lowest                       int
+	// Allowed rep_rates of the ladder constraints, nil without constraints
+	Allowed          []bool
+	ExponentialRatio float64
+	VBR              VBRParams
+	BBA2Dynamic      bool
+	Accountant       *xlayer.CrossLayerAccountant
+	// Other decides for the algorithms that are not decided here, Arbiter+ and BBA request the segment sizes over HTTP
+	Other     func(throughput int, bandwidths []int) int
+	DebugFile string
+	DebugLog  bool
+}
+
+// Outcome : the rep_rate of the next segment and what it was decided on
+type Outcome struct {
+	RepRate int
+	// PlaybackRate LoL+ would play at, the stream speed for the other algorithms
+	PlaybackRate float64
+	// Prediction of the predictor and its confidence, 0 without a predictor
+	Prediction float64
+	Confidence float64
+	// Bitrates the throughput algorithms compared against, the bandwidths without the VBR layer
+	Bitrates []int
+}
+
+// Decide :
+/*
+ * the rep_rate of the next segment of the algorithm of d, the player and the simulation decide with it
+ * a predictor replaces the throughput estimate of the algorithm, the ladder constraints narrow the rep_rates it sees
+ * and the rep_rate is kept within them, a rep_rate outside the ladder is an error
+ * the algorithms keep their data on every rep_rate, so a change of the constraints keeps what they learnt
+ */
+func Decide(d Decision, state *State) (Outcome, error) {
+	out := Outcome{RepRate: d.RepRate, PlaybackRate: d.StreamSpeed, Bitrates: d.Bandwidths}
+	thr := d.Throughput
+
+	if state.Predictor != nil {
+		state.Samples = predictor.Append(state.Samples, predictor.Sample{Throughput: float64(thr), Duration: time.Duration(d.DeliveryTime) * time.Millisecond})
+		if packetThr := d.Accountant.SegmentThroughput(); packetThr > 0 {
+			state.Samples = predictor.Append(state.Samples, predictor.Sample{Throughput: packetThr, CrossLayer: true})
+		}
+		out.Prediction, out.Confidence = state.Predictor.Predict(state.Samples)
+	}
+	// the dynamic reservoir of BBA-2 is sized on the packets of the segment and the RTT of the connection
+	if d.BBA2Dynamic && BBA2Based(d.Adapt) {
+		packetThr := int(d.Accountant.SegmentThroughput())
+		if packetThr <= 0 {
+			packetThr = thr
+		}
+		BBA2Measured(&state.BBA2, packetThr, d.SmoothedRTT)
+	}
+	rateBased := RateBased(d.Adapt)
+	if out.Prediction > 0 && !rateBased && d.Adapt != glob.MPCAlg && d.Adapt != glob.MPCXLAlg {
+		// these algorithms decide on the prediction instead of the throughput of the last segment
+		thr = int(out.Prediction)
+	}
+
+	// the ladder constraints narrow the rep_rates the algorithms see
+	highest, lowest := d.Highest, d.Lowest
+	if d.Allowed != nil {
+		highest, lowest = ladder.Bounds(d.Allowed)
+	}
+	state.allow(d.Allowed)
+
+	// the throughput algorithms compare against the actual bitrate of the next segments of every rep_rate
+	if d.VBR.Enabled && ThroughputBased(d.Adapt) {
+		out.Bitrates = state.SegmentSizes.Bitrates(d.Bandwidths, d.SegmentNumber+1, d.VBR.Horizon)
+	}
+
+	repRate := d.RepRate
+	switch d.Adapt {
+	case glob.ConventionalAlg, glob.ProgressiveAlg:
+		Conventional(&state.ThrList, thr, &repRate, out.Bitrates, lowest)
+	case glob.ElasticAlg:
+		ElasticAlgo(&state.ThrList, thr, d.DeliveryTime, d.MaxBuffer, &repRate, out.Bitrates, &state.StaticAlgParameter, d.BufferLevel, elasticKP, elasticKI, lowest)
+	case glob.LogisticAlg:
+		Logistic(&state.ThrList, thr, &repRate, out.Bitrates, d.BufferLevel, highest, lowest, d.DebugFile, d.DebugLog, d.MaxBufferLevel)
+		logging.DebugPrint(d.DebugFile, d.DebugLog, "\nDEBUG: ", "reprate returned: "+strconv.Itoa(repRate))
+	case glob.MeanAverageAlg:
+		MeanAverageAlgo(&state.ThrList, thr, &repRate, out.Bitrates, lowest)
+	case glob.GeomAverageAlg:
+		GeomAverageAlgo(&state.ThrList, thr, &repRate, out.Bitrates, lowest)
+	case glob.EMWAAverageAlg:
+		EMWAAverageAlgo(&state.ThrList, &repRate, d.ExponentialRatio, 3, thr, out.Bitrates, lowest)
+	case glob.TestAlg:
+	case glob.MeanAverageXLAlg:
+		MeanAverageXLAlgo(d.Accountant, &state.ThrList, thr, &repRate, out.Bitrates, lowest)
+	case glob.MeanAverageRecentXLAlg:
+		MeanAverageRecentXLAlgo(d.Accountant, &state.ThrList, thr, &repRate, out.Bitrates, lowest)
+	case glob.BBA1Alg_AV, glob.BBA1Alg_AVXL:
+		repRate = BBA(d.BufferLevel, d.MaxBufferLevel, highest, lowest, d.Bandwidths, d.SegmentDuration_Milliseconds, d.DebugLog, d.DebugFile, &state.ThrList, thr, d.RepRate)
+	case glob.BBA2Alg_AV, glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_rate, glob.BBA2Alg_AVXL_double:
+		repRate = BBA2(d.BufferLevel, d.MaxBufferLevel, highest, lowest, d.Bandwidths, d.SegmentDuration_Milliseconds, d.DebugLog, d.DebugFile, &state.ThrList, thr, d.RepRate, d.SegmentNumber, &state.BBA2)
+	case glob.BOLABasicAlg, glob.BOLAEAlg, glob.BOLAEXLAlg:
+		repRate = BOLA(d.BufferLevel, thr, d.SegmentNumber+1, &state.ThrList, &state.BOLA)
+	case glob.MPCAlg, glob.MPCXLAlg:
+		// the segment throughput, if the accountant saw too few packets of the segment
+		packetThr := int(d.Accountant.SegmentThroughput())
+		if d.Adapt == glob.MPCAlg || packetThr <= 0 {
+			packetThr = thr
+		}
+		if out.Prediction > 0 {
+			repRate = MPCWithPrediction(d.BufferLevel, packetThr, out.Prediction, d.SegmentNumber+1, &state.ThrList, &state.MPC)
+		} else {
+			repRate = MPC(d.BufferLevel, packetThr, d.SegmentNumber+1, &state.ThrList, &state.MPC)
+		}
+	case glob.PensieveAlg, glob.PensieveXLAlg:
+		// pensieveXL also sees the throughput of the packets of the segment
+		crossLayer := PensieveCrossLayer{RTT: d.RTT}
+		if d.Adapt == glob.PensieveXLAlg {
+			crossLayer.PacketRate = d.Accountant.SegmentThroughput()
+		}
+		repRate = Pensieve(d.BufferLevel, thr, d.DeliveryTime, repRate, d.SegmentNumber+1, crossLayer, &state.ThrList, &state.Pensieve)
+	case glob.L2AAlg:
+		repRate = L2A(d.BufferLevel, thr, d.StreamSpeed, &state.ThrList, &state.L2A)
+	case glob.LoLPAlg:
+		repRate = LoLP(d.BufferLevel, thr, utils.Abs(d.StallTime), &state.ThrList, &state.LoLP)
+		out.PlaybackRate = LoLPPlaybackRate(d.BufferLevel, d.StreamSpeed, &state.LoLP)
+	default:
+		if d.Other != nil {
+			repRate = d.Other(thr, out.Bitrates)
+		}
+	}
+	// the rate based algorithms keep their own estimate, but stream the rep_rate of the prediction
+	if out.Prediction > 0 && rateBased {
+		repRate = SelectRepRateWithThroughtput(int(out.Prediction), out.Bitrates, lowest)
+	}
+	if repRate < 0 || repRate >= len(d.Bandwidths) {
+		return out, fmt.Errorf("%s selected rep_rate %d after segment %d, the stream has %d rep_rates", d.Adapt, repRate, d.SegmentNumber, len(d.Bandwidths))
+	}
+	// keep the rep_rate within the ladder constraints of now, so the switch is logged as it is streamed
+	if d.Allowed != nil {
+		repRate = ladder.Select(d.Allowed, repRate)
+	}
+	out.RepRate = repRate
+	return out, nil
+}
+
+// allow : mask the rep_rates the ladder constraints do not allow in the data of the algorithms that select among their levels
+func (state *State) allow(allowed []bool) {
+	state.BOLA.allowed = levelsAllowed(state.BOLA.repRates, allowed)
+	state.MPC.allowed = levelsAllowed(state.MPC.repRates, allowed)
+	state.Pensieve.allowed = levelsAllowed(state.Pensieve.actions, allowed)
+	state.L2A.allowed = levelsAllowed(state.L2A.repRates, allowed)
+	state.LoLP.allowed = levelsAllowed(state.LoLP.repRates, allowed)
+}
+
+// levelsAllowed :
+/*
+ * whether the rep_rate of every level of repRates is allowed, nil if every level is allowed or none is,
+ * in which case the rep_rate of the algorithm is kept within the constraints by Decide
+ */
+func levelsAllowed(repRates []int, allowed []bool) []bool {
+	if allowed == nil {
+		return nil
+	}
+	levels := make([]bool, len(repRates))
+	all, none := true, true
+	for level, repRate := range repRates {
+		levels[level] = repRate >= 0 && repRate < len(allowed) && allowed[repRate]
+		all = all && levels[level]
+		none = none && !levels[level]
+	}
+	if all || none {
+		return nil
+	}
+	return levels
+}
+
+// levelAllowed : true if level is allowed by levels of levelsAllowed
+func levelAllowed(levels []bool, level int) bool {
+	return levels == nil || levels[level]
+}
+
+// highestAllowedLevel : the highest allowed level up to level, or the lowest allowed level above it if there is none
+func highestAllowedLevel(levels []bool, level int) int {
+	if levels == nil {
+		return level
+	}
+	for i := level; i >= 0; i-- {
+		if levels[i] {
+			return i
+		}
+	}
+	for i := level + 1; i < len(levels); i++ {
+		if levels[i] {
+			return i
+		}
+	}
+	return level
+}
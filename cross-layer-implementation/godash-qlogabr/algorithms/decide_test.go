@@ -0,0 +1,135 @@
+package algorithms
+
+import (
+	"testing"
+
+	xlayer "github.com/uccmisl/godash/crosslayer"
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/predictor"
+)
+
+// decideBandwithList : the rep_rates in MPD order, the highest first
+var decideBandwithList = []int{5000000, 2500000, 1000000, 500000}
+
+// decision : the decision of adapt after segment 1, downloaded at throughput
+func decision(adapt string, throughput int) Decision {
+	return Decision{
+		Adapt:                        adapt,
+		SegmentNumber:                1,
+		RepRate:                      3,
+		BufferLevel:                  4000,
+		Throughput:                   throughput,
+		DeliveryTime:                 1000,
+		StreamSpeed:                  1,
+		MaxBuffer:                    30,
+		MaxBufferLevel:               30,
+		SegmentDuration_Milliseconds: 2000,
+		Bandwidths:                   decideBandwithList,
+		Lowest:                       3,
+		Accountant:                   &xlayer.CrossLayerAccountant{},
+	}
+}
+
+func TestDecideKeepsTheConstraints(t *testing.T) {
+	var state State
+	out, err := Decide(decision(glob.ConventionalAlg, 10000000), &state)
+	if err != nil || out.RepRate != 0 {
+		t.Fatalf("a fast link selects rep_rate %d, %v, want 0", out.RepRate, err)
+	}
+
+	state = State{}
+	d := decision(glob.ConventionalAlg, 10000000)
+	d.Allowed = []bool{false, false, true, true}
+	if out, _ := Decide(d, &state); out.RepRate != 2 {
+		t.Errorf("a fast link within the constraints selects rep_rate %d, want 2", out.RepRate)
+	}
+}
+
+func TestDecideOnThePrediction(t *testing.T) {
+	state := State{Predictor: predictor.Harmonic{Window: 2}}
+	// the harmonic mean of 10 and 0.8 Mbps streams the 1 Mbps rep_rate, the smoothed throughput would stream 5 Mbps
+	Decide(decision(glob.ConventionalAlg, 10000000), &state)
+	out, err := Decide(decision(glob.ConventionalAlg, 800000), &state)
+	if err != nil || out.Prediction <= 0 {
+		t.Fatalf("no prediction, %v", err)
+	}
+	if out.RepRate != 2 {
+		t.Errorf("the prediction %g selects rep_rate %d, want 2", out.Prediction, out.RepRate)
+	}
+}
+
+func TestDecideOther(t *testing.T) {
+	var state State
+	d := decision(glob.ArbiterAlg, 1000000)
+	if out, _ := Decide(d, &state); out.RepRate != d.RepRate {
+		t.Errorf("an algorithm without a decision changes rep_rate %d to %d", d.RepRate, out.RepRate)
+	}
+	d.Other = func(throughput int, bitrates []int) int { return len(bitrates) }
+	if _, err := Decide(d, &state); err == nil {
+		t.Error("a rep_rate outside the ladder is not an error")
+	}
+}
+
+func TestDecideMasksTheDisallowedRungs(t *testing.T) {
+	// the 2.5 and 0.5 Mbps rep_rates of a fast link, which leave a gap in the ladder
+	allowed := []bool{false, true, false, true}
+	for _, adapt := range []string{glob.BOLAEAlg, glob.MPCAlg, glob.L2AAlg, glob.LoLPAlg} {
+		state := State{
+			BOLA: NewBOLAData(decideBandwithList, nil, 2000, 30, true, 0.5),
+			MPC:  NewMPCData(decideBandwithList, nil, 2000, 30, DefaultMPCParams()),
+			L2A:  NewL2AData(decideBandwithList, 2000),
+			LoLP: NewLoLPData(decideBandwithList, 2000, 1, DefaultLoLPParams()),
+		}
+		d := decision(adapt, 10000000)
+		d.Allowed = allowed
+		for segment := 1; segment <= 10; segment++ {
+			d.SegmentNumber = segment
+			d.BufferLevel = 2000 * segment
+			out, err := Decide(d, &state)
+			if err != nil || !allowed[out.RepRate] {
+				t.Fatalf("%s selects rep_rate %d after segment %d, %v, which the constraints do not allow", adapt, out.RepRate, segment, err)
+			}
+			d.RepRate = out.RepRate
+		}
+		if d.RepRate != 1 {
+			t.Errorf("%s streams rep_rate %d on a fast link, want the highest allowed 1", adapt, d.RepRate)
+		}
+	}
+}
+
+func TestDecideKeepsTheStateOnAConstraintChange(t *testing.T) {
+	state := State{
+		BOLA: NewBOLAData(decideBandwithList, nil, 2000, 30, true, 0.5),
+		MPC:  NewMPCData(decideBandwithList, nil, 2000, 30, DefaultMPCParams()),
+		L2A:  NewL2AData(decideBandwithList, 2000),
+	}
+	decide := func(adapt string, allowed []bool) int {
+		d := decision(adapt, 3000000)
+		d.BufferLevel = 10000
+		d.Allowed = allowed
+		out, err := Decide(d, &state)
+		if err != nil {
+			t.Fatal(adapt, err)
+		}
+		return out.RepRate
+	}
+	for _, adapt := range []string{glob.BOLAEAlg, glob.MPCAlg, glob.L2AAlg} {
+		decide(adapt, nil)
+		decide(adapt, nil)
+	}
+	samples := len(state.MPC.samples)
+
+	// the constraints of a smaller screen only mask the rep_rates, what the algorithms learnt is kept
+	phone := []bool{false, false, true, true}
+	for _, adapt := range []string{glob.BOLAEAlg, glob.MPCAlg, glob.L2AAlg} {
+		if repRate := decide(adapt, phone); !phone[repRate] {
+			t.Errorf("%s selects rep_rate %d, which the constraints do not allow", adapt, repRate)
+		}
+	}
+	if state.BOLA.startup || !state.L2A.steady {
+		t.Error("BOLA-E or L2A start up again after the constraints change")
+	}
+	if len(state.MPC.samples) != samples+1 || len(state.MPC.errors) != samples {
+		t.Errorf("MPC has %d samples and %d errors after the constraints change, want %d and %d", len(state.MPC.samples), len(state.MPC.errors), samples+1, samples)
+	}
+}
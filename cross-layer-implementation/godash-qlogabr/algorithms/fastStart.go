@@ -0,0 +1,120 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	"math"
+	"time"
+
+	"github.com/uccmisl/godash/crosslayer"
+	"github.com/uccmisl/godash/utils"
+)
+
+// minStartupPackets : a startup sample needs two packets to have a transfer time
+const minStartupPackets = 2
+
+// FastStartParams :
+/*
+ * the fast start selects the first rep_rate and the initial buffer from the capacity
+ * measured on the downloads of the startup phase, instead of starting at the lowest rep_rate
+ */
+type FastStartParams struct {
+	Enabled      bool
+	Safety       float64 // part of the estimated capacity the bandwidth of the first rep_rate may take
+	Margin       float64 // the initial buffer covers the next segment when the capacity drops to this part of the estimate
+	StartupDelay float64 // seconds the initial buffer may take to download at the estimated capacity
+}
+
+// DefaultFastStartParams : the fast start is off, these are its parameters when it is turned on
+func DefaultFastStartParams() FastStartParams {
+	return FastStartParams{Safety: 0.8, Margin: 0.5, StartupDelay: 1}
+}
+
+// StartupCapacity :
+/*
+ * the capacity in bps of the downloads of the startup phase, 0 if no sample has enough packets
+ * a request waits a round trip for its first packet, and the request that opened the connection
+ * another one for the handshake unless it went out in 0-RTT, so the duration of a sample is
+ * its request time without these round trips, but never less than the time its packets took
+ */
+func StartupCapacity(samples []crosslayer.StartupSample, handshakeRTT time.Duration, zeroRTT bool) int {
+	bits := 0
+	var duration time.Duration
+	for _, sample := range samples {
+		if sample.Packets < minStartupPackets || sample.Bits <= 0 {
+			continue
+		}
+		overhead := handshakeRTT
+		if sample.Handshake && !zeroRTT {
+			overhead += handshakeRTT
+		}
+		// the transfer runs from the first to the last packet, the first packet took its share of the time too
+		sampleDuration := sample.Transfer * time.Duration(sample.Packets) / time.Duration(sample.Packets-1)
+		if sample.Request-overhead > sampleDuration {
+			sampleDuration = sample.Request - overhead
+		}
+		if sampleDuration <= 0 {
+			continue
+		}
+		bits += sample.Bits
+		duration += sampleDuration
+	}
+	if duration <= 0 {
+		return 0
+	}
+	return int(float64(bits) / duration.Seconds())
+}
+
+// FastStart :
+/*
+ * the rep_rate of the first segment and the initial buffer in segments for a capacity in bps
+ * the initial buffer of a rep_rate is the number of segments that covers the download of the next segment
+ * if the capacity drops to Margin of the estimate, it is at most initBuffer
+ * the first rep_rate is the highest one whose bandwidth fits in Safety of the capacity
+ * and whose initial buffer downloads within StartupDelay at the capacity
+ * without a capacity the stream starts at the lowest rep_rate with initBuffer
+ */
+func FastStart(capacity int, bandwithList []int, highestMPDrepRateIndex int, lowestMPDrepRateIndex int, segmentDuration_Milliseconds int, initBuffer int, params FastStartParams) (int, int) {
+	if !params.Enabled || capacity <= 0 {
+		return lowestMPDrepRateIndex, initBuffer
+	}
+
+	for repRate := highestMPDrepRateIndex; repRate <= lowestMPDrepRateIndex; repRate++ {
+		if float64(bandwithList[repRate]) > params.Safety*float64(capacity) {
+			continue
+		}
+		segments := int(math.Ceil(float64(bandwithList[repRate]) / (params.Margin * float64(capacity))))
+		if segments > initBuffer {
+			continue
+		}
+		segments = utils.Max(segments, 1)
+		// seconds to download the initial buffer at the capacity
+		startupDelay := float64(segments*segmentDuration_Milliseconds) / 1000 * float64(bandwithList[repRate]) / float64(capacity)
+		if startupDelay <= params.StartupDelay {
+			return repRate, utils.Min(segments, initBuffer)
+		}
+	}
+
+	// the lowest rep_rate, its initial buffer is the one the capacity needs
+	segments := int(math.Ceil(float64(bandwithList[lowestMPDrepRateIndex]) / (params.Margin * float64(capacity))))
+	return lowestMPDrepRateIndex, utils.Min(utils.Max(segments, 1), initBuffer)
+}
@@ -0,0 +1,68 @@
+package algorithms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uccmisl/godash/crosslayer"
+)
+
+// the rep_rates in MPD order, the highest first
+var fastStartBandwithList = []int{8000000, 4000000, 1000000, 500000}
+
+func TestStartupCapacity(t *testing.T) {
+	// 1 Mbit over 100 ms, on a connection with a 50 ms RTT
+	mpd := crosslayer.StartupSample{Bits: 1000000, Packets: 100, Transfer: 90 * time.Millisecond, Request: 200 * time.Millisecond, Handshake: true}
+	if capacity := StartupCapacity([]crosslayer.StartupSample{mpd}, 50*time.Millisecond, false); capacity != 10000000 {
+		t.Errorf("the handshake request has a capacity of %d bps, want 10000000", capacity)
+	}
+	// in 0-RTT the request does not wait for the handshake
+	if capacity := StartupCapacity([]crosslayer.StartupSample{mpd}, 50*time.Millisecond, true); capacity != 6666666 {
+		t.Errorf("the 0-RTT request has a capacity of %d bps, want 6666666", capacity)
+	}
+
+	// a sample is never shorter than the time its packets took
+	burst := crosslayer.StartupSample{Bits: 1000000, Packets: 11, Transfer: 200 * time.Millisecond, Request: 100 * time.Millisecond}
+	if capacity := StartupCapacity([]crosslayer.StartupSample{burst}, 50*time.Millisecond, false); capacity != 4545454 {
+		t.Errorf("the burst has a capacity of %d bps, want 4545454", capacity)
+	}
+
+	// a single packet has no transfer time
+	single := crosslayer.StartupSample{Bits: 10000, Packets: 1, Request: 51 * time.Millisecond}
+	if capacity := StartupCapacity([]crosslayer.StartupSample{single}, 50*time.Millisecond, false); capacity != 0 {
+		t.Errorf("a single packet has a capacity of %d bps, want 0", capacity)
+	}
+}
+
+func TestFastStart(t *testing.T) {
+	params := DefaultFastStartParams()
+	params.Enabled = true
+
+	// 4 Mbps fits in 80% of 6 Mbps, but its initial buffer of 2 segments of 2 seconds takes 2.7 seconds
+	if repRate, initBuffer := FastStart(6000000, fastStartBandwithList, 0, 3, 2000, 4, params); repRate != 2 || initBuffer != 1 {
+		t.Errorf("6 Mbps starts at rep_rate %d with %d segments, want 2 with 1", repRate, initBuffer)
+	}
+	params.StartupDelay = 3
+	if repRate, initBuffer := FastStart(6000000, fastStartBandwithList, 0, 3, 2000, 4, params); repRate != 1 || initBuffer != 2 {
+		t.Errorf("6 Mbps in 3 seconds starts at rep_rate %d with %d segments, want 1 with 2", repRate, initBuffer)
+	}
+	// the initial buffer of a rep_rate is at most initBuffer
+	if repRate, initBuffer := FastStart(6000000, fastStartBandwithList, 0, 3, 2000, 1, params); repRate != 2 || initBuffer != 1 {
+		t.Errorf("6 Mbps with 1 segment starts at rep_rate %d with %d segments, want 2 with 1", repRate, initBuffer)
+	}
+	// the highest rep_rate of the max height is kept
+	if repRate, initBuffer := FastStart(100000000, fastStartBandwithList, 1, 3, 2000, 4, params); repRate != 1 || initBuffer != 1 {
+		t.Errorf("100 Mbps starts at rep_rate %d with %d segments, want 1 with 1", repRate, initBuffer)
+	}
+	// a capacity below the lowest rep_rate keeps the initial buffer
+	if repRate, initBuffer := FastStart(300000, fastStartBandwithList, 0, 3, 2000, 4, params); repRate != 3 || initBuffer != 4 {
+		t.Errorf("300 kbps starts at rep_rate %d with %d segments, want 3 with 4", repRate, initBuffer)
+	}
+	// without an estimate, or turned off, the stream starts at the lowest rep_rate
+	if repRate, initBuffer := FastStart(0, fastStartBandwithList, 0, 3, 2000, 4, params); repRate != 3 || initBuffer != 4 {
+		t.Errorf("no capacity starts at rep_rate %d with %d segments, want 3 with 4", repRate, initBuffer)
+	}
+	if repRate, initBuffer := FastStart(6000000, fastStartBandwithList, 0, 3, 2000, 4, DefaultFastStartParams()); repRate != 3 || initBuffer != 4 {
+		t.Errorf("the disabled fast start starts at rep_rate %d with %d segments, want 3 with 4", repRate, initBuffer)
+	}
+}
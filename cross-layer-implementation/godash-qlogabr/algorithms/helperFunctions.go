@@ -22,6 +22,7 @@
 package algorithms
 
 import (
+	"context"
 	"math"
 	otherhttp "net/http"
 
@@ -36,6 +37,27 @@ func CalculateThroughtput(segmentSize, time int) int {
 	return int(float64(segmentSize) / (float64(time) / glob.Conversion1000))
 }
 
+// RateBased : true if algorithm adapt streams the rep_rate of its throughput estimate
+func RateBased(adapt string) bool {
+	switch adapt {
+	case glob.ConventionalAlg, glob.ProgressiveAlg, glob.MeanAverageAlg, glob.GeomAverageAlg, glob.EMWAAverageAlg, glob.MeanAverageXLAlg, glob.MeanAverageRecentXLAlg:
+		return true
+	}
+	return false
+}
+
+// BBA2Based :
+/*
+ * true if adapt selects its rep_rates with BBA-2, with or without the cross-layer stall predictor
+ */
+func BBA2Based(adapt string) bool {
+	switch adapt {
+	case glob.BBA2Alg_AV, glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_rate, glob.BBA2Alg_AVXL_double:
+		return true
+	}
+	return false
+}
+
 // SelectRepRateWithThroughtput :
 /*
  * Select the rate the nearest just below the throughtput
@@ -137,13 +159,18 @@ func ThroughputSamples(window int, thrList []int) []int {
 /*
  * Checks next "videoWindow" of segments and makes sure the average rate is less than the estimated rate
  */
-func SmartConvHelper(qIndex int, videoWindow int, estRate float64, currentMPD http.MPD, currentURL string, currentMPDRepAdaptSet int, lastRate int, segmentNumber int, baseURL string, debugLog bool, lastDuration int, client *otherhttp.Client) bool {
+func SmartConvHelper(qIndex int, videoWindow int, estRate float64, currentMPD http.MPD, currentURL string, currentMPDRepAdaptSet int, lastRate int, segmentNumber int, baseURL string, debugLog bool, lastDuration int, client *otherhttp.Client, ctx context.Context) bool {
 	var totSegSize int
 
 	for i := 0; i < videoWindow; i++ {
 
-		totSegSize += 8 * http.GetContentLengthHeader(currentMPD,
-			currentURL, currentMPDRepAdaptSet, qIndex, segmentNumber+i, baseURL, debugLog, client)
+		segSize, err := http.GetContentLengthHeader(currentMPD,
+			currentURL, currentMPDRepAdaptSet, qIndex, segmentNumber+i, baseURL, debugLog, client, ctx)
+		if err != nil {
+			// without the segment sizes we cannot look ahead, so keep this rate
+			return true
+		}
+		totSegSize += 8 * segSize
 
 	}
 	actualAvgRate := float64(float64(totSegSize) / (float64(lastDuration) / 1000 * float64(videoWindow)))
@@ -156,12 +183,12 @@ func SmartConvHelper(qIndex int, videoWindow int, estRate float64, currentMPD ht
 /*
  * Checks next "videoWindow" of segments and makes sure the average rate is less than the estimated rate
  */
-func SmartConvHelperFromFile(videoWindow int, estRate float64, qRate int, segmentNumber int, lastDuration int) bool {
+func SmartConvHelperFromFile(segHeadValues map[int]map[int][]int, videoWindow int, estRate float64, qRate int, segmentNumber int, lastDuration int) bool {
 	var totSegSize int
 
 	for i := 0; i < videoWindow; i++ {
 
-		totSegSize += http.SegHeadValues[0][qRate][segmentNumber+i] * 8
+		totSegSize += segHeadValues[0][qRate][segmentNumber+i] * 8
 
 	}
 	actualAvgRate := float64(float64(totSegSize) / (float64(lastDuration) / 1000.0 * float64(videoWindow)))
@@ -0,0 +1,200 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	"math"
+	"sort"
+)
+
+// the online learning constants of L2A-LL in dash.js
+const (
+	// l2aHorizon : the horizon of the online convex optimisation, in segments
+	l2aHorizon = 4
+	// l2aReact : how hard the Lagrangian multiplier reacts to a throughput drop
+	l2aReact = 2
+	// l2aMinThroughput_Mbps : the lowest throughput L2A learns from
+	l2aMinThroughput_Mbps = 0.001
+)
+
+// L2AData :
+/*
+ * the state of L2A-LL over the segments of an adaptation set
+ * L2A learns a probability of every rep_rate, kept from the lowest to the highest bandwidth
+ */
+type L2AData struct {
+	// the rep_rate index and bandwidth in Mbps of every level
+	repRates []int
+	bitrates []float64
+
+	segmentDuration_Seconds float64
+
+	// the learning parameters
+	vl    float64
+	alpha float64
+
+	// the probabilities of the levels, and the Lagrangian multiplier of the buffer constraint
+	steady    bool
+	w         []float64
+	prevW     []float64
+	q         float64
+	lastLevel int
+
+	// the levels the ladder constraints allow, nil if they allow every level
+	allowed []bool
+}
+
+// NewL2AData : the L2A-LL state of an adaptation set with rep_rates bandwithList
+func NewL2AData(bandwithList []int, segmentDuration_Milliseconds int) L2AData {
+	data := L2AData{
+		segmentDuration_Seconds: float64(segmentDuration_Milliseconds) / 1000,
+		vl:                      math.Pow(l2aHorizon, 0.99),
+	}
+	data.alpha = math.Max(l2aHorizon, data.vl*math.Sqrt(l2aHorizon))
+
+	for i := range bandwithList {
+		data.repRates = append(data.repRates, i)
+	}
+	sort.SliceStable(data.repRates, func(a, b int) bool {
+		return bandwithList[data.repRates[a]] < bandwithList[data.repRates[b]]
+	})
+	for _, repRate := range data.repRates {
+		data.bitrates = append(data.bitrates, float64(bandwithList[repRate])/1e6)
+	}
+	data.w = make([]float64, len(data.bitrates))
+	data.prevW = make([]float64, len(data.bitrates))
+
+	return data
+}
+
+// L2A :
+/*
+ * the rep_rate index of the next segment according to L2A-LL
+ * the probabilities are learnt on every level, the level is selected among the allowed levels
+ * newThr is the throughput of the last segment in bps, streamSpeed the speed of the playback
+ */
+func L2A(bufferLevel_Milliseconds int, newThr int, streamSpeed float64, thrList *[]int, data *L2AData) int {
+
+	*thrList = append(*thrList, newThr)
+
+	throughput := math.Max(float64(newThr)/1e6, l2aMinThroughput_Mbps)
+
+	if !data.steady {
+		// stream at the throughput until a segment is buffered
+		level := 0
+		for i, bitrate := range data.bitrates {
+			if bitrate <= throughput {
+				level = i
+			}
+		}
+		level = highestAllowedLevel(data.allowed, level)
+		for i := range data.prevW {
+			data.prevW[i] = 0
+		}
+		data.prevW[level] = 1
+		data.lastLevel = level
+		if float64(bufferLevel_Milliseconds)/1000 >= data.segmentDuration_Seconds {
+			data.steady = true
+		}
+		return data.repRates[level]
+	}
+
+	// Lagrangian descent, a rep_rate the throughput does not sustain drains the buffer and is learnt down
+	v := data.segmentDuration_Seconds
+	sign := 1.0
+	for i, bitrate := range data.bitrates {
+		if streamSpeed*bitrate > throughput {
+			sign = -1
+		}
+		data.w[i] = data.prevW[i] + sign*(v/(2*data.alpha))*((data.q+data.vl)*(streamSpeed*bitrate/throughput))
+	}
+	data.w = euclideanProjection(data.w)
+
+	diff := make([]float64, len(data.w))
+	for i := range data.w {
+		diff[i] = data.w[i] - data.prevW[i]
+		data.prevW[i] = data.w[i]
+	}
+	data.q = math.Max(0, data.q-v+v*streamSpeed*((dot(data.bitrates, data.prevW)+dot(data.bitrates, diff))/throughput))
+
+	// the allowed level closest to the bitrate L2A expects
+	expected := dot(data.w, data.bitrates)
+	level := highestAllowedLevel(data.allowed, 0)
+	for i, bitrate := range data.bitrates {
+		if levelAllowed(data.allowed, i) && math.Abs(bitrate-expected) < math.Abs(data.bitrates[level]-expected) {
+			level = i
+		}
+	}
+	// go up one allowed level at a time, as long as the throughput sustains it
+	if level > data.lastLevel {
+		up := data.lastLevel + 1
+		for !levelAllowed(data.allowed, up) {
+			up++
+		}
+		if data.bitrates[up] <= throughput {
+			level = up
+		}
+	}
+	// a rep_rate above the throughput makes the multiplier react
+	if data.bitrates[level] >= throughput {
+		data.q = l2aReact * math.Max(data.vl, data.q)
+	}
+
+	data.lastLevel = level
+	return data.repRates[level]
+}
+
+// euclideanProjection : the projection of w on the probability simplex
+func euclideanProjection(w []float64) []float64 {
+	sorted := append([]float64(nil), w...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	sum := 0.0
+	max := 0.0
+	found := false
+	for i := 0; i < len(sorted)-1; i++ {
+		sum += sorted[i]
+		max = (sum - 1) / float64(i+1)
+		if max >= sorted[i+1] {
+			found = true
+			break
+		}
+	}
+	if !found {
+		max = (sum + sorted[len(sorted)-1] - 1) / float64(len(sorted))
+	}
+
+	projection := make([]float64, len(w))
+	for i := range w {
+		projection[i] = math.Max(w[i]-max, 0)
+	}
+	return projection
+}
+
+// dot : the dot product of a and b
+func dot(a []float64, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,65 @@
+package algorithms
+
+import (
+	"math"
+	"testing"
+)
+
+// the rep_rates in MPD order, the highest first
+var l2aBandwithList = []int{5000000, 2500000, 1000000, 500000}
+
+func TestL2AStartupAndSteady(t *testing.T) {
+	data := NewL2AData(l2aBandwithList, 2000)
+	var thrList []int
+
+	// in startup L2A streams at the throughput
+	if repRate := L2A(0, 3000000, 1, &thrList, &data); repRate != 1 {
+		t.Errorf("startup selects rep_rate %d, want 1", repRate)
+	}
+	if repRate := L2A(2000, 3000000, 1, &thrList, &data); repRate != 1 || !data.steady {
+		t.Errorf("startup with a segment buffered selects rep_rate %d steady %v, want 1 true", repRate, data.steady)
+	}
+
+	// a throughput below the rep_rate is learnt down to the lowest rep_rate
+	repRate := 1
+	for i := 0; i < 10; i++ {
+		repRate = L2A(2000, 600000, 1, &thrList, &data)
+	}
+	if repRate != 3 {
+		t.Errorf("a slow link selects rep_rate %d, want the lowest 3", repRate)
+	}
+
+	// and a fast link up again, one rep_rate at a time
+	previous := repRate
+	for i := 0; i < 30; i++ {
+		repRate = L2A(4000, 20000000, 1, &thrList, &data)
+		if l2aLevel(previous)-l2aLevel(repRate) > 1 {
+			t.Errorf("rep_rate %d to %d goes up more than one rep_rate", previous, repRate)
+		}
+		previous = repRate
+	}
+	if repRate != 0 {
+		t.Errorf("a fast link selects rep_rate %d, want the highest 0", repRate)
+	}
+}
+
+// l2aLevel : the level of a rep_rate of l2aBandwithList, 0 is the lowest
+func l2aLevel(repRate int) int {
+	return len(l2aBandwithList) - 1 - repRate
+}
+
+func TestEuclideanProjection(t *testing.T) {
+	for _, w := range [][]float64{{0.5, 0.5, 0.5}, {-1, 2, 0}, {0.1, 0.2, 0.3}, {0, 0, 0}} {
+		projection := euclideanProjection(w)
+		sum := 0.0
+		for _, p := range projection {
+			if p < 0 {
+				t.Errorf("projection %v of %v has a negative probability", projection, w)
+			}
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("projection %v of %v sums to %g, want 1", projection, w, sum)
+		}
+	}
+}
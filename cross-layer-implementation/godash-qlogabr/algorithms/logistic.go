@@ -30,23 +30,17 @@ import (
 	"math"
 )
 
-var debugFile string
-var debugLog bool
-
 // Logistic :
 // add the last throughtput to the list and call CalculateSelectedIndex,
 // return the rate and throughtput list
 func Logistic(thrList *[]int, newThr int, repRate *int, bandwithList []int, bufferLevel int,
-	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, debugFiles string, debugLogs bool,
+	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, debugFile string, debugLog bool,
 	maxBufferLevel int) {
 
-	debugFile = debugFiles
-	debugLog = debugLogs
-
 	*thrList = append(*thrList, newThr)
 
 	*repRate = calculateSelectedIndex(*thrList, newThr, bandwithList, bufferLevel, *repRate, highestMPDrepRateIndex,
-		lowestMPDrepRateIndex, maxBufferLevel)
+		lowestMPDrepRateIndex, maxBufferLevel, debugFile, debugLog)
 
 }
 
@@ -56,7 +50,7 @@ func Logistic(thrList *[]int, newThr int, repRate *int, bandwithList []int, buff
 // calculateSelectedIndex :
 // call the func LogisticFunction(lastRateIndex, thrList, bufferLevel) to calculate the rate
 func calculateSelectedIndex(thrList []int, newThr int, bandwithList []int, bufferLevel int, repRate int,
-	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, maxBufferLevel int) int {
+	highestMPDrepRateIndex int, lowestMPDrepRateIndex int, maxBufferLevel int, debugFile string, debugLog bool) int {
 
 	//take the last rate
 	//current rep rate : repRate
@@ -64,8 +58,8 @@ func calculateSelectedIndex(thrList []int, newThr int, bandwithList []int, buffe
 	//find the index of the last rate ?
 	lastRateIndex := repRate
 
-	retVal := LogisticFunction(lastRateIndex, thrList, bufferLevel, highestMPDrepRateIndex, lowestMPDrepRateIndex,
-		maxBufferLevel, bandwithList)
+	retVal := logisticFunction(lastRateIndex, thrList, bufferLevel, highestMPDrepRateIndex, lowestMPDrepRateIndex,
+		maxBufferLevel, bandwithList, debugFile, debugLog)
 	//fmt.Println(retVal)
 	return retVal
 }
@@ -74,9 +68,17 @@ func calculateSelectedIndex(thrList []int, newThr int, bandwithList []int, buffe
 //----------------------------------------------------------------------------------------------------------
 
 // LogisticFunction :
-// calculate and return the rate index
+// calculate and return the rate index, without debug logs
 func LogisticFunction(lastRateIndex int, thrList []int, bufferLevel int, highestMPDrepRateIndex int,
 	lowestMPDrepRateIndex int, maxBufferLevel int, bandwithList []int) int {
+	return logisticFunction(lastRateIndex, thrList, bufferLevel, highestMPDrepRateIndex, lowestMPDrepRateIndex,
+		maxBufferLevel, bandwithList, "", false)
+}
+
+// logisticFunction :
+// calculate and return the rate index, the steps are written to the debug log
+func logisticFunction(lastRateIndex int, thrList []int, bufferLevel int, highestMPDrepRateIndex int,
+	lowestMPDrepRateIndex int, maxBufferLevel int, bandwithList []int, debugFile string, debugLog bool) int {
 
 	//len(tracks) = number of rates -> of representations in the MPD
 
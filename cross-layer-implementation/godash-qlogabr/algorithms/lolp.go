@@ -0,0 +1,270 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	"math"
+	"sort"
+)
+
+// the self-organising map and playback rate constants of LoL+ in dash.js
+const (
+	// lolpLearningRate and lolpNeighbourhood : how far a neuron and its neighbours move towards a sample
+	lolpLearningRate  = 0.01
+	lolpNeighbourhood = 0.1
+	// lolpThroughputDelta_bps : a rep_rate this close to the throughput is downshifted from
+	lolpThroughputDelta_bps = 10000
+	// lolpDownshiftWeight : the throughput weight that downshifts from a rep_rate above the throughput
+	lolpDownshiftWeight = 100
+	// lolpPlaybackBufferMin_Seconds : the buffer level below which the playback slows down
+	lolpPlaybackBufferMin_Seconds = 0.5
+	// lolpMinRateChange : the smallest change of the playback rate that is made
+	lolpMinRateChange = 0.02
+)
+
+// lolpWeightValues : the values of every weight of the weight vectors LoL+ selects from
+var lolpWeightValues = []float64{0.2, 0.4, 0.6, 0.8, 1}
+
+// LoLPParams :
+/*
+ * the latency target and playback rate controller of LoL+
+ * the latency of a segment is the buffer level once it arrives, which is
+ * the live latency of a client that downloads every segment at the live edge
+ */
+type LoLPParams struct {
+	TargetLatency float64 // in seconds
+	CatchupRate   float64 // the playback rate is kept within 1 +/- CatchupRate of the stream speed
+}
+
+// DefaultLoLPParams : the latency target and the catch-up playback rate of the low latency settings of dash.js
+func DefaultLoLPParams() LoLPParams {
+	return LoLPParams{TargetLatency: 3, CatchupRate: 0.5}
+}
+
+// lolpState : throughput, latency, rebuffering and switch of a neuron, normalised
+type lolpState [4]float64
+
+// LoLPData :
+/*
+ * the state of LoL+ over the segments of an adaptation set
+ * a neuron per rep_rate is kept from the lowest to the highest bandwidth, whatever the order of the MPD
+ */
+type LoLPData struct {
+	params LoLPParams
+	// the rep_rate index and bandwidth in bps of every level
+	repRates []int
+	bitrates []float64
+	neurons  []lolpState
+
+	segmentDuration_Seconds float64
+	streamSpeed             float64
+
+	lastLevel int
+	weights   []lolpState
+
+	// the levels the ladder constraints allow, nil if they allow every level
+	allowed []bool
+}
+
+// NewLoLPData :
+/*
+ * the LoL+ state of an adaptation set with rep_rates bandwithList
+ * the playback rate is changed around streamSpeed
+ */
+func NewLoLPData(bandwithList []int, segmentDuration_Milliseconds int, streamSpeed float64, params LoLPParams) LoLPData {
+	data := LoLPData{
+		params:                  params,
+		segmentDuration_Seconds: float64(segmentDuration_Milliseconds) / 1000,
+		streamSpeed:             streamSpeed,
+	}
+
+	for i := range bandwithList {
+		data.repRates = append(data.repRates, i)
+	}
+	sort.SliceStable(data.repRates, func(a, b int) bool {
+		return bandwithList[data.repRates[a]] < bandwithList[data.repRates[b]]
+	})
+	for _, repRate := range data.repRates {
+		data.bitrates = append(data.bitrates, float64(bandwithList[repRate]))
+	}
+	// a neuron starts at the throughput of its rep_rate, without latency, rebuffering or switches
+	for _, bitrate := range data.bitrates {
+		data.neurons = append(data.neurons, lolpState{bitrate / data.maxBitrate()})
+	}
+
+	// every combination of the weight values
+	for _, throughput := range lolpWeightValues {
+		for _, latency := range lolpWeightValues {
+			for _, rebuffer := range lolpWeightValues {
+				for _, change := range lolpWeightValues {
+					data.weights = append(data.weights, lolpState{throughput, latency, rebuffer, change})
+				}
+			}
+		}
+	}
+	return data
+}
+
+// LoLP :
+/*
+ * the rep_rate index of the next segment according to LoL+
+ * newThr is the throughput of the last segment in bps, stall_Milliseconds the rebuffering it caused
+ */
+func LoLP(bufferLevel_Milliseconds int, newThr int, stall_Milliseconds int, thrList *[]int, data *LoLPData) int {
+
+	*thrList = append(*thrList, newThr)
+
+	if newThr <= 0 {
+		data.lastLevel = highestAllowedLevel(data.allowed, 0)
+		return data.repRates[data.lastLevel]
+	}
+	throughput := float64(newThr)
+	bufferLevel := float64(bufferLevel_Milliseconds) / 1000
+
+	// the measured state, normalised like the neurons
+	measured := lolpState{
+		math.Min(throughput/data.maxBitrate(), 1),
+		math.Max(0, bufferLevel-data.params.TargetLatency) / data.params.TargetLatency,
+		float64(stall_Milliseconds) / 1000 / data.segmentDuration_Seconds,
+	}
+
+	// the weights of the distance are the weights of the highest predicted QoE
+	weights := data.weights[0]
+	bestQoE := math.Inf(-1)
+	for _, w := range data.weights {
+		level := data.bestNeuron(measured[0], throughput, bufferLevel, w)
+		qoe := data.predictedQoE(level, throughput, bufferLevel)
+		if qoe > bestQoE {
+			weights = w
+			bestQoE = qoe
+		}
+	}
+	level := data.bestNeuron(measured[0], throughput, bufferLevel, weights)
+
+	// the last neuron learns the measured state, the selected neuron the target state
+	measured[3] = math.Abs(data.bitrates[data.lastLevel]-data.bitrates[level]) / data.maxBitrate()
+	data.updateNeurons(data.lastLevel, measured)
+	data.updateNeurons(level, lolpState{measured[0], 0, 0, measured[3]})
+
+	data.lastLevel = level
+	return data.repRates[level]
+}
+
+// LoLPPlaybackRate :
+/*
+ * the playback rate of LoL+ at buffer level bufferLevel_Milliseconds, for a playback at streamSpeed
+ * the playback speeds up above the latency target and slows down when the buffer is almost empty
+ */
+func LoLPPlaybackRate(bufferLevel_Milliseconds int, streamSpeed float64, data *LoLPData) float64 {
+	bufferLevel := float64(bufferLevel_Milliseconds) / 1000
+	catchup := data.params.CatchupRate
+
+	var d float64
+	if bufferLevel > lolpPlaybackBufferMin_Seconds {
+		d = (bufferLevel - data.params.TargetLatency) * 5
+	} else {
+		d = (bufferLevel - lolpPlaybackBufferMin_Seconds) * 5
+	}
+	rate := data.streamSpeed * ((1 - catchup) + 2*catchup/(1+math.Exp(-d)))
+
+	if math.Abs(rate-streamSpeed) < lolpMinRateChange*data.streamSpeed {
+		return streamSpeed
+	}
+	return rate
+}
+
+// maxBitrate : the bandwidth of the highest level in bps
+func (data *LoLPData) maxBitrate() float64 {
+	return data.bitrates[len(data.bitrates)-1]
+}
+
+// bestNeuron :
+/*
+ * the allowed level of the neuron closest to the target state of the normalised throughput, with weights
+ * rep_rates above the lowest allowed one that exceed the throughput, or that empty the buffer, are pushed away on the throughput
+ */
+func (data *LoLPData) bestNeuron(normalisedThroughput float64, throughput float64, bufferLevel float64, weights lolpState) int {
+	target := lolpState{normalisedThroughput}
+	lowest := highestAllowedLevel(data.allowed, 0)
+	best := lowest
+	bestDistance := math.Inf(1)
+	for level, neuron := range data.neurons {
+		if !levelAllowed(data.allowed, level) {
+			continue
+		}
+		distanceWeights := weights
+		downloadTime := data.bitrates[level] * data.segmentDuration_Seconds / throughput
+		if level > lowest && (data.bitrates[level] > throughput-lolpThroughputDelta_bps || downloadTime > bufferLevel) {
+			distanceWeights[0] = lolpDownshiftWeight
+		}
+		distance := lolpDistance(neuron, target, distanceWeights)
+		if distance < bestDistance {
+			best = level
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// predictedQoE :
+/*
+ * the QoE of LoL+ for the next segment at a level, in Mbps
+ * rebuffering costs the highest bitrate per second and latency above the target a tenth of it
+ */
+func (data *LoLPData) predictedQoE(level int, throughput float64, bufferLevel float64) float64 {
+	downloadTime := data.bitrates[level] * data.segmentDuration_Seconds / throughput
+	rebuffer := math.Max(0, downloadTime-bufferLevel)
+	latency := math.Max(0, bufferLevel-downloadTime) + data.segmentDuration_Seconds
+
+	minBitrate := data.bitrates[0] / 1e6
+	maxBitrate := data.maxBitrate() / 1e6
+	latencyPenalty := minBitrate * 0.05
+	if latency > data.params.TargetLatency {
+		latencyPenalty = maxBitrate * 0.1
+	}
+
+	return data.bitrates[level]/1e6 -
+		maxBitrate*rebuffer -
+		latencyPenalty*latency -
+		math.Abs(data.bitrates[level]-data.bitrates[data.lastLevel])/1e6
+}
+
+// updateNeurons : move the neurons around the neuron of a level towards state
+func (data *LoLPData) updateNeurons(level int, state lolpState) {
+	winner := data.neurons[level]
+	for i := range data.neurons {
+		distance := lolpDistance(data.neurons[i], winner, lolpState{1, 1, 1, 1})
+		neighbourhood := math.Exp(-distance * distance / (2 * lolpNeighbourhood * lolpNeighbourhood))
+		for j := range state {
+			data.neurons[i][j] += (state[j] - data.neurons[i][j]) * lolpLearningRate * neighbourhood
+		}
+	}
+}
+
+// lolpDistance : the weighted euclidean distance of a and b
+func lolpDistance(a lolpState, b lolpState, weights lolpState) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += weights[i] * (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Sqrt(sum)
+}
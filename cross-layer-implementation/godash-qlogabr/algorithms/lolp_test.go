@@ -0,0 +1,54 @@
+package algorithms
+
+import "testing"
+
+// the rep_rates in MPD order, the highest first
+var lolpBandwithList = []int{5000000, 2500000, 1000000, 500000}
+
+func TestLoLPThroughput(t *testing.T) {
+	data := NewLoLPData(lolpBandwithList, 2000, 1, DefaultLoLPParams())
+	var thrList []int
+
+	if repRate := LoLP(2000, 600000, 0, &thrList, &data); repRate != 3 {
+		t.Errorf("a slow link selects rep_rate %d, want the lowest 3", repRate)
+	}
+	if repRate := LoLP(3000, 0, 0, &thrList, &data); repRate != 3 {
+		t.Errorf("no throughput selects rep_rate %d, want the lowest 3", repRate)
+	}
+
+	repRate := 3
+	for i := 0; i < 10; i++ {
+		repRate = LoLP(3000, 20000000, 0, &thrList, &data)
+	}
+	if repRate != 0 {
+		t.Errorf("a fast link selects rep_rate %d, want the highest 0", repRate)
+	}
+
+	// a rep_rate above the throughput is not selected
+	if repRate := LoLP(3000, 2000000, 0, &thrList, &data); lolpBandwithList[repRate] > 2000000 {
+		t.Errorf("a 2 Mbps link selects rep_rate %d", repRate)
+	}
+}
+
+func TestLoLPPlaybackRate(t *testing.T) {
+	data := NewLoLPData(lolpBandwithList, 2000, 1, DefaultLoLPParams())
+
+	if rate := LoLPPlaybackRate(3000, 1, &data); rate != 1 {
+		t.Errorf("playback rate %g at the latency target, want 1", rate)
+	}
+	if rate := LoLPPlaybackRate(8000, 1, &data); rate <= 1 || rate > 1.5 {
+		t.Errorf("playback rate %g above the latency target, want between 1 and 1.5", rate)
+	}
+	if rate := LoLPPlaybackRate(200, 1, &data); rate >= 1 || rate < 0.5 {
+		t.Errorf("playback rate %g with an empty buffer, want between 0.5 and 1", rate)
+	}
+
+	// the rate changes around the stream speed, small changes are not made
+	fast := NewLoLPData(lolpBandwithList, 2000, 2, LoLPParams{TargetLatency: 3, CatchupRate: 0.5})
+	if rate := LoLPPlaybackRate(3000, 2, &fast); rate != 2 {
+		t.Errorf("playback rate %g at the latency target, want the stream speed 2", rate)
+	}
+	if rate := LoLPPlaybackRate(3010, 2, &fast); rate != 2 {
+		t.Errorf("playback rate %g just above the latency target, want the stream speed 2", rate)
+	}
+}
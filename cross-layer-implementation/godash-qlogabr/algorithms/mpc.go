@@ -0,0 +1,352 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	"math"
+	"sort"
+)
+
+// the throughput prediction of RobustMPC
+const (
+	// mpcSampleWindow : number of throughput samples the harmonic mean and the prediction error are taken over
+	mpcSampleWindow = 5
+	// the FastMPC table has a throughput bin per mpcThroughputBinRatio, between half the lowest and twice the highest rep_rate
+	mpcThroughputBinRatio = 1.25
+	// mpcPlanBinsPerTableBin : the buffer bins of the FastMPC plans are this many times finer than those of its table
+	mpcPlanBinsPerTableBin = 16
+)
+
+// MPCParams :
+/*
+ * the lookahead and the linear QoE weights of MPC
+ * the QoE of a segment is its bitrate in Mbps, less RebufferPenalty per second of rebuffering
+ * and SwitchPenalty per Mbps of switch
+ */
+type MPCParams struct {
+	Horizon         int     // number of segments MPC plans over
+	RebufferPenalty float64 // the lambda of the QoE
+	SwitchPenalty   float64 // the mu of the QoE
+	Fast            bool    // select the rep_rate from the precomputed FastMPC table
+}
+
+// DefaultMPCParams : the horizon of the MPC paper and the QoE weights of its evaluation
+func DefaultMPCParams() MPCParams {
+	return MPCParams{Horizon: 5, RebufferPenalty: 4.3, SwitchPenalty: 1}
+}
+
+// MPCData :
+/*
+ * the state of RobustMPC and FastMPC over the segments of an adaptation set
+ * the rep_rates are kept from the lowest to the highest bandwidth, whatever the order of the MPD
+ */
+type MPCData struct {
+	params MPCParams
+	// the rep_rate index, bandwidth in bps and segment sizes in bits of every level
+	repRates   []int
+	bitrates   []float64
+	chunkLists [][]int
+
+	segmentDuration_Seconds float64
+	maxBuffer_Seconds       float64
+
+	// throughput samples in bps, and the relative errors of the predictions of the last samples
+	samples    []float64
+	prediction float64
+	errors     []float64
+
+	lastLevel int
+	table     *fastMPCTable
+	// the levels the ladder constraints allow, nil if they allow every level
+	allowed []bool
+}
+
+// NewMPCData :
+/*
+ * the MPC state of an adaptation set with rep_rates bandwithList and segment sizes chunkLists, indexed on the rep_rate
+ * with params.Fast the FastMPC table is computed here, once for the session, on every rep_rate
+ */
+func NewMPCData(bandwithList []int, chunkLists [][]int, segmentDuration_Milliseconds int, maxBufferLevel_Seconds int, params MPCParams) MPCData {
+	data := MPCData{
+		params:                  params,
+		segmentDuration_Seconds: float64(segmentDuration_Milliseconds) / 1000,
+		maxBuffer_Seconds:       float64(maxBufferLevel_Seconds),
+	}
+	if data.params.Horizon < 1 {
+		data.params.Horizon = 1
+	}
+
+	for i := range bandwithList {
+		data.repRates = append(data.repRates, i)
+	}
+	sort.SliceStable(data.repRates, func(a, b int) bool {
+		return bandwithList[data.repRates[a]] < bandwithList[data.repRates[b]]
+	})
+	for _, repRate := range data.repRates {
+		data.bitrates = append(data.bitrates, float64(bandwithList[repRate]))
+		var chunks []int
+		if repRate < len(chunkLists) {
+			chunks = chunkLists[repRate]
+		}
+		data.chunkLists = append(data.chunkLists, chunks)
+	}
+
+	if data.params.Fast {
+		data.table = newFastMPCTable(&data)
+	}
+	return data
+}
+
+// MPC :
+/*
+ * the rep_rate index of segment nextSegmentNumber according to RobustMPC, or FastMPC
+ * newThr is the last throughput sample in bps, of the segment or of its packets
+ */
+func MPC(bufferLevel_Milliseconds int, newThr int, nextSegmentNumber int, thrList *[]int, data *MPCData) int {
+
+	*thrList = append(*thrList, newThr)
+
+	return data.selectLevel(bufferLevel_Milliseconds, data.predictThroughput(float64(newThr)), nextSegmentNumber)
+}
+
+// MPCWithPrediction :
+/*
+ * the rep_rate index of segment nextSegmentNumber according to MPC, or FastMPC,
+ * which plans with the throughput prediction in bps of a predictor instead of its own
+ * newThr is the throughput of the last segment in bps
+ */
+func MPCWithPrediction(bufferLevel_Milliseconds int, newThr int, prediction float64, nextSegmentNumber int, thrList *[]int, data *MPCData) int {
+
+	*thrList = append(*thrList, newThr)
+
+	return data.selectLevel(bufferLevel_Milliseconds, prediction, nextSegmentNumber)
+}
+
+// selectLevel : the rep_rate index of the first level of the best plan on the allowed levels, with the throughput in bps
+func (data *MPCData) selectLevel(bufferLevel_Milliseconds int, throughput float64, nextSegmentNumber int) int {
+	if throughput <= 0 {
+		data.lastLevel = highestAllowedLevel(data.allowed, 0)
+		return data.repRates[data.lastLevel]
+	}
+	bufferLevel := float64(bufferLevel_Milliseconds) / 1000
+
+	// the table plans on every level, within the ladder constraints the plans are searched
+	if data.table != nil && data.allowed == nil {
+		data.lastLevel = data.table.level(bufferLevel, data.lastLevel, throughput)
+	} else {
+		data.lastLevel, _ = data.plan(bufferLevel, data.lastLevel, throughput, nextSegmentNumber, data.params.Horizon)
+	}
+	return data.repRates[data.lastLevel]
+}
+
+// predictThroughput :
+/*
+ * add throughput sample thr in bps and predict the throughput of the next segments
+ * the harmonic mean of the last samples, lowered by the largest error of the last predictions
+ */
+func (data *MPCData) predictThroughput(thr float64) float64 {
+	if thr > 0 {
+		if data.prediction > 0 {
+			data.errors = append(data.errors, math.Abs(data.prediction-thr)/thr)
+			if len(data.errors) > mpcSampleWindow {
+				data.errors = data.errors[1:]
+			}
+		}
+		data.samples = append(data.samples, thr)
+		if len(data.samples) > mpcSampleWindow {
+			data.samples = data.samples[1:]
+		}
+	}
+	if len(data.samples) == 0 {
+		return 0
+	}
+
+	inverse := 0.0
+	for _, sample := range data.samples {
+		inverse += 1 / sample
+	}
+	data.prediction = float64(len(data.samples)) / inverse
+
+	maxError := 0.0
+	for _, e := range data.errors {
+		maxError = math.Max(maxError, e)
+	}
+	return data.prediction / (1 + maxError)
+}
+
+// plan :
+/*
+ * the first level of the plan of allowed levels with the highest QoE over horizon segments from segment segmentNumber on,
+ * at buffer level bufferLevel in seconds after level lastLevel, with throughput in bps
+ */
+func (data *MPCData) plan(bufferLevel float64, lastLevel int, throughput float64, segmentNumber int, horizon int) (int, float64) {
+	bestLevel := 0
+	bestQoE := math.Inf(-1)
+	for level := range data.repRates {
+		if !levelAllowed(data.allowed, level) {
+			continue
+		}
+		qoe := data.planQoE(bufferLevel, lastLevel, level, throughput, segmentNumber, horizon)
+		if qoe > bestQoE {
+			bestLevel = level
+			bestQoE = qoe
+		}
+	}
+	return bestLevel, bestQoE
+}
+
+// planQoE : the highest QoE of the plans that download segment segmentNumber at level, and horizon-1 segments after it
+func (data *MPCData) planQoE(bufferLevel float64, lastLevel int, level int, throughput float64, segmentNumber int, horizon int) float64 {
+	qoe, bufferLevel := data.segmentQoE(bufferLevel, lastLevel, level, throughput, segmentNumber)
+	if horizon <= 1 {
+		return qoe
+	}
+	// segment number 0 is a segment of nominal size, and so are the segments after it
+	if segmentNumber > 0 {
+		segmentNumber++
+	}
+	_, future := data.plan(bufferLevel, level, throughput, segmentNumber, horizon-1)
+	return qoe + future
+}
+
+// segmentQoE :
+/*
+ * the QoE of downloading segment segmentNumber at level after lastLevel, at buffer level bufferLevel in seconds
+ * with throughput in bps, and the buffer level in seconds once it is downloaded
+ */
+func (data *MPCData) segmentQoE(bufferLevel float64, lastLevel int, level int, throughput float64, segmentNumber int) (float64, float64) {
+	downloadTime := data.segmentSize(level, segmentNumber) / throughput
+	rebuffer := math.Max(0, downloadTime-bufferLevel)
+	qoe := data.bitrates[level]/1e6 -
+		data.params.RebufferPenalty*rebuffer -
+		data.params.SwitchPenalty*math.Abs(data.bitrates[level]-data.bitrates[lastLevel])/1e6
+	return qoe, math.Min(math.Max(0, bufferLevel-downloadTime)+data.segmentDuration_Seconds, data.maxBuffer_Seconds)
+}
+
+// segmentSize : the size in bits of segment segmentNumber of a level, from its bandwidth if the size is unknown
+func (data *MPCData) segmentSize(level int, segmentNumber int) float64 {
+	chunks := data.chunkLists[level]
+	if segmentNumber >= 1 && segmentNumber <= len(chunks) && chunks[segmentNumber-1] > 0 {
+		return float64(chunks[segmentNumber-1])
+	}
+	return data.bitrates[level] * data.segmentDuration_Seconds
+}
+
+// fastMPCTable :
+/*
+ * the FastMPC decisions, per buffer level, previous level and throughput bin
+ * the table plans with the nominal segment sizes, so it does not depend on the segment number
+ */
+type fastMPCTable struct {
+	bufferStep_Seconds float64
+	throughputs        []float64
+	// decisions[buffer bin][previous level][throughput bin]
+	decisions [][][]int
+}
+
+// newFastMPCTable :
+/*
+ * the FastMPC table of data, planned with dynamic programming over the buffer bins and the previous level,
+ * the QoE of the segments after the first is interpolated between the buffer bins their download ends in,
+ * so a table costs horizon * buffer bins * levels^2 per throughput bin instead of a search of every plan per entry
+ */
+func newFastMPCTable(data *MPCData) *fastMPCTable {
+	table := &fastMPCTable{bufferStep_Seconds: math.Max(data.segmentDuration_Seconds/2, 0.5)}
+	for thr := data.bitrates[0] / 2; thr <= data.bitrates[len(data.bitrates)-1]*2; thr *= mpcThroughputBinRatio {
+		table.throughputs = append(table.throughputs, thr)
+	}
+
+	bufferBins := int(data.maxBuffer_Seconds/table.bufferStep_Seconds) + 1
+	levels := len(data.repRates)
+	table.decisions = make([][][]int, bufferBins)
+	for b := range table.decisions {
+		table.decisions[b] = make([][]int, levels)
+		for lastLevel := range table.decisions[b] {
+			table.decisions[b][lastLevel] = make([]int, len(table.throughputs))
+		}
+	}
+
+	// the plans run on buffer bins mpcPlanBinsPerTableBin times finer than those of the table
+	// future[b][lastLevel] : the highest QoE of the segments left in the horizon, at plan bin b after lastLevel
+	planStep_Seconds := table.bufferStep_Seconds / mpcPlanBinsPerTableBin
+	planBins := (bufferBins-1)*mpcPlanBinsPerTableBin + 1
+	future := make([][]float64, planBins)
+	next := make([][]float64, planBins)
+	for b := range future {
+		future[b] = make([]float64, levels)
+		next[b] = make([]float64, levels)
+	}
+	for t, thr := range table.throughputs {
+		for depth := 1; depth <= data.params.Horizon; depth++ {
+			for b := range next {
+				// the first segment of the plan is only decided for the bins of the table
+				if depth == data.params.Horizon && b%mpcPlanBinsPerTableBin != 0 {
+					continue
+				}
+				for lastLevel := range next[b] {
+					bestLevel, bestQoE := 0, math.Inf(-1)
+					for level := range data.repRates {
+						// segment number 0 has no size, so the plans use the nominal sizes
+						qoe, bufferLevel := data.segmentQoE(float64(b)*planStep_Seconds, lastLevel, level, thr, 0)
+						if depth > 1 {
+							qoe += interpolateQoE(future, bufferLevel/planStep_Seconds, level)
+						}
+						if qoe > bestQoE {
+							bestLevel, bestQoE = level, qoe
+						}
+					}
+					next[b][lastLevel] = bestQoE
+					if depth == data.params.Horizon {
+						table.decisions[b/mpcPlanBinsPerTableBin][lastLevel][t] = bestLevel
+					}
+				}
+			}
+			future, next = next, future
+		}
+	}
+	return table
+}
+
+// interpolateQoE : the QoE of values, per bin and level, between the bins around position after level
+func interpolateQoE(values [][]float64, position float64, level int) float64 {
+	b := int(position)
+	if b >= len(values)-1 {
+		return values[len(values)-1][level]
+	}
+	weight := position - float64(b)
+	return values[b][level]*(1-weight) + values[b+1][level]*weight
+}
+
+// level : the decision of the bins below buffer level bufferLevel in seconds and throughput in bps
+func (table *fastMPCTable) level(bufferLevel float64, lastLevel int, throughput float64) int {
+	b := int(bufferLevel / table.bufferStep_Seconds)
+	if b >= len(table.decisions) {
+		b = len(table.decisions) - 1
+	}
+	t := 0
+	for i, thr := range table.throughputs {
+		if thr <= throughput {
+			t = i
+		}
+	}
+	return table.decisions[b][lastLevel][t]
+}
@@ -0,0 +1,98 @@
+package algorithms
+
+import "testing"
+
+// the rep_rates in MPD order, the highest first
+var mpcBandwithList = []int{5000000, 2500000, 1000000, 500000}
+
+// mpcLongBandwithList : a ladder of 10 rep_rates, the highest first
+var mpcLongBandwithList = []int{10000000, 7500000, 5000000, 3500000, 2500000, 1750000, 1200000, 800000, 500000, 300000}
+
+func TestMPCThroughputAndBuffer(t *testing.T) {
+	data := NewMPCData(mpcBandwithList, nil, 2000, 30, DefaultMPCParams())
+	var thrList []int
+
+	if repRate := MPC(20000, 50000000, 2, &thrList, &data); repRate != 0 {
+		t.Errorf("a fast link selects rep_rate %d, want the highest 0", repRate)
+	}
+
+	data = NewMPCData(mpcBandwithList, nil, 2000, 30, DefaultMPCParams())
+	if repRate := MPC(0, 600000, 2, &thrList, &data); repRate != 3 {
+		t.Errorf("a slow link with an empty buffer selects rep_rate %d, want the lowest 3", repRate)
+	}
+	if len(thrList) != 2 {
+		t.Errorf("%d throughputs in the list, want 2", len(thrList))
+	}
+}
+
+func TestMPCRobustPrediction(t *testing.T) {
+	data := NewMPCData(mpcBandwithList, nil, 2000, 30, DefaultMPCParams())
+
+	if prediction := data.predictThroughput(4000000); prediction != 4000000 {
+		t.Errorf("first prediction %g, want the sample 4000000", prediction)
+	}
+	// the sample is half the prediction, so the next predictions are lowered by an error of 1
+	data.predictThroughput(2000000)
+	if len(data.errors) != 1 || data.errors[0] != 1 {
+		t.Fatalf("prediction errors %v, want [1]", data.errors)
+	}
+	if prediction := data.predictThroughput(2000000); prediction >= 2000000 {
+		t.Errorf("prediction %g after a wrong prediction, want less than the samples", prediction)
+	}
+}
+
+func TestFastMPCAgreesWithMPC(t *testing.T) {
+	robust := NewMPCData(mpcBandwithList, nil, 2000, 30, DefaultMPCParams())
+	params := DefaultMPCParams()
+	params.Fast = true
+	fast := NewMPCData(mpcBandwithList, nil, 2000, 30, params)
+
+	for _, thr := range []float64{300000, 1200000, 3000000, 20000000} {
+		for bufferLevel := 0.0; bufferLevel <= 30; bufferLevel += 5 {
+			want, _ := robust.plan(bufferLevel, 0, thr, 0, params.Horizon)
+			got := fast.table.level(bufferLevel, 0, thr)
+			// the table rounds the throughput down to its bin, so it may select one rep_rate lower
+			if got != want && got != want-1 {
+				t.Errorf("throughput %g buffer %g: FastMPC selects level %d, MPC %d", thr, bufferLevel, got, want)
+			}
+		}
+	}
+}
+
+func TestFastMPCPlansNearTheBestPlan(t *testing.T) {
+	params := DefaultMPCParams()
+	params.Fast = true
+	fast := NewMPCData(mpcLongBandwithList, nil, 2000, 30, params)
+
+	// on the throughputs of the table, the buffer bins of the plans round the buffer of the later segments,
+	// which may only cost a near tie
+	for bin := 0; bin < len(fast.table.throughputs); bin += 5 {
+		thr := fast.table.throughputs[bin]
+		for bufferLevel := 0.0; bufferLevel <= 30; bufferLevel += 5 {
+			for _, lastLevel := range []int{0, 5, 9} {
+				_, best := fast.plan(bufferLevel, lastLevel, thr, 0, params.Horizon)
+				level := fast.table.level(bufferLevel, lastLevel, thr)
+				if qoe := fast.planQoE(bufferLevel, lastLevel, level, thr, 0, params.Horizon); best-qoe > 0.1 {
+					t.Errorf("throughput %g buffer %g after level %d: FastMPC plans a QoE of %g, the best plan %g", thr, bufferLevel, lastLevel, qoe, best)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkNewFastMPCTable(b *testing.B) {
+	params := DefaultMPCParams()
+	params.Fast = true
+	for i := 0; i < b.N; i++ {
+		NewMPCData(mpcLongBandwithList, nil, 2000, 30, params)
+	}
+}
+
+func BenchmarkMPC(b *testing.B) {
+	data := NewMPCData(mpcLongBandwithList, nil, 2000, 30, DefaultMPCParams())
+	var thrList []int
+	for i := 0; i < b.N; i++ {
+		MPC(10000, 3000000, 2, &thrList, &data)
+		thrList = thrList[:0]
+	}
+}
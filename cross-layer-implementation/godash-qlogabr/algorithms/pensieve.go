@@ -0,0 +1,401 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// the inputs of the state of a Pensieve policy, the names of the branches of a PensieveModel
+const (
+	// PensieveLastBitrate : bitrate of the last segment over the highest bitrate
+	PensieveLastBitrate = "lastBitrate"
+	// PensieveBuffer : buffer level in BufferNorm seconds
+	PensieveBuffer = "buffer"
+	// PensieveThroughput : throughput of the last History segments in MB/s
+	PensieveThroughput = "throughput"
+	// PensieveDownloadTime : download time of the last History segments in BufferNorm seconds
+	PensieveDownloadTime = "downloadTime"
+	// PensieveNextSizes : size of the next segment of every action in MB
+	PensieveNextSizes = "nextSizes"
+	// PensieveRemaining : segments left in the stream over SegmentsCap
+	PensieveRemaining = "remaining"
+	// PensievePacketRate : packet-level throughput of the last segment in MB/s, measured by the cross-layer accountant
+	PensievePacketRate = "packetRate"
+	// PensieveRTT : round trip time of the last segment in seconds
+	PensieveRTT = "rtt"
+)
+
+// the normalisation of the state in the Pensieve paper
+const (
+	pensieveHistory     = 8
+	pensieveBufferNorm  = 10
+	pensieveSegmentsCap = 48
+)
+
+// PensieveLayer :
+/*
+ * a layer of the actor network
+ * a dense layer has weights [out][in], a conv1d layer weights [filters][kernel] over a single input channel,
+ * without padding, and its output is flattened step by step, the filters of a step next to each other
+ */
+type PensieveLayer struct {
+	Type       string      `json:"type"`
+	Weights    [][]float64 `json:"weights"`
+	Bias       []float64   `json:"bias"`
+	Activation string      `json:"activation"`
+
+	dense *mat.Dense
+}
+
+// PensieveBranch : a layer over one input of the state, see the Pensieve* inputs
+type PensieveBranch struct {
+	Input string `json:"input"`
+	PensieveLayer
+}
+
+// PensieveModel :
+/*
+ * the actor network of a learned ABR policy, exported as json
+ * the outputs of the branches are concatenated in order and passed through Layers, the last of which scores every action
+ * the actions are the Bitrates the policy was trained on in kbps, from the lowest, or the rep_rates of the stream if there are none
+ */
+type PensieveModel struct {
+	Bitrates []int `json:"bitrates"`
+	// History : segments of the throughput and download time inputs, 8 by default
+	History int `json:"history"`
+	// BufferNorm : seconds the buffer and the download times are divided by, 10 by default
+	BufferNorm float64 `json:"bufferNorm"`
+	// SegmentsCap : the remaining segments are counted up to SegmentsCap, 48 by default
+	SegmentsCap int              `json:"segmentsCap"`
+	Branches    []PensieveBranch `json:"branches"`
+	Layers      []PensieveLayer  `json:"layers"`
+}
+
+// PensieveCrossLayer : the cross-layer inputs of the last segment, its packet-level throughput in bps and its round trip time
+type PensieveCrossLayer struct {
+	PacketRate float64
+	RTT        time.Duration
+}
+
+// LoadPensieveModel : the actor network of the json file fileName, checked for the sizes of its inputs and layers
+func LoadPensieveModel(fileName string) (*PensieveModel, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	model := &PensieveModel{}
+	if err := json.Unmarshal(data, model); err != nil {
+		return nil, errors.New(fileName + ": " + err.Error())
+	}
+	if err := model.prepare(); err != nil {
+		return nil, errors.New(fileName + ": " + err.Error())
+	}
+	return model, nil
+}
+
+// Actions : the number of rep_rates the policy scores
+func (m *PensieveModel) Actions() int {
+	if len(m.Layers) == 0 {
+		return 0
+	}
+	return len(m.Layers[len(m.Layers)-1].Weights)
+}
+
+// prepare : the defaults of the normalisation, and a check that every layer fits the output of the layer before it
+func (m *PensieveModel) prepare() error {
+	if m.History == 0 {
+		m.History = pensieveHistory
+	}
+	if m.BufferNorm == 0 {
+		m.BufferNorm = pensieveBufferNorm
+	}
+	if m.SegmentsCap == 0 {
+		m.SegmentsCap = pensieveSegmentsCap
+	}
+	if m.History < 1 || m.BufferNorm <= 0 || m.SegmentsCap < 1 {
+		return errors.New("history, bufferNorm and segmentsCap must be positive")
+	}
+	if len(m.Branches) == 0 || len(m.Layers) == 0 {
+		return errors.New("the model needs branches over the state and layers after them")
+	}
+	actions := m.Actions()
+	if len(m.Bitrates) > 0 && len(m.Bitrates) != actions {
+		return fmt.Errorf("the model scores %d actions for %d bitrates", actions, len(m.Bitrates))
+	}
+
+	merged := 0
+	for i := range m.Branches {
+		branch := &m.Branches[i]
+		size := m.inputSize(branch.Input, actions)
+		if size == 0 {
+			return errors.New("there is no input " + branch.Input)
+		}
+		out, err := branch.prepare(size)
+		if err != nil {
+			return fmt.Errorf("branch %d (%s): %w", i, branch.Input, err)
+		}
+		merged += out
+	}
+	in := merged
+	for i := range m.Layers {
+		out, err := m.Layers[i].prepare(in)
+		if err != nil {
+			return fmt.Errorf("layer %d: %w", i, err)
+		}
+		in = out
+	}
+	if m.Layers[len(m.Layers)-1].Type != "dense" {
+		return errors.New("the last layer must be dense")
+	}
+	return nil
+}
+
+// inputSize : the length of input, 0 if there is no such input
+func (m *PensieveModel) inputSize(input string, actions int) int {
+	switch input {
+	case PensieveLastBitrate, PensieveBuffer, PensieveRemaining, PensievePacketRate, PensieveRTT:
+		return 1
+	case PensieveThroughput, PensieveDownloadTime:
+		return m.History
+	case PensieveNextSizes:
+		return actions
+	}
+	return 0
+}
+
+// prepare : the output length of the layer over in values
+func (l *PensieveLayer) prepare(in int) (int, error) {
+	switch l.Activation {
+	case "", "linear", "relu", "softmax":
+	default:
+		return 0, errors.New("there is no activation " + l.Activation)
+	}
+	if len(l.Weights) == 0 || len(l.Bias) != len(l.Weights) {
+		return 0, fmt.Errorf("%d weight rows and %d biases", len(l.Weights), len(l.Bias))
+	}
+	width := len(l.Weights[0])
+	for _, row := range l.Weights {
+		if len(row) != width || width == 0 {
+			return 0, errors.New("the weight rows are not of the same length")
+		}
+	}
+
+	switch l.Type {
+	case "dense":
+		if width != in {
+			return 0, fmt.Errorf("dense weights over %d values and not %d", width, in)
+		}
+		flat := make([]float64, 0, len(l.Weights)*width)
+		for _, row := range l.Weights {
+			flat = append(flat, row...)
+		}
+		l.dense = mat.NewDense(len(l.Weights), width, flat)
+		return len(l.Weights), nil
+	case "conv1d":
+		if width > in {
+			return 0, fmt.Errorf("a kernel of %d over %d values", width, in)
+		}
+		return (in - width + 1) * len(l.Weights), nil
+	}
+	return 0, errors.New("there is no layer type " + l.Type)
+}
+
+// forward : the output of the layer for x
+func (l *PensieveLayer) forward(x []float64) []float64 {
+	var out []float64
+	if l.dense != nil {
+		y := mat.NewVecDense(len(l.Bias), nil)
+		y.MulVec(l.dense, mat.NewVecDense(len(x), x))
+		out = make([]float64, len(l.Bias))
+		for i := range out {
+			out[i] = y.AtVec(i) + l.Bias[i]
+		}
+	} else {
+		kernel := len(l.Weights[0])
+		for step := 0; step+kernel <= len(x); step++ {
+			for filter, weights := range l.Weights {
+				sum := l.Bias[filter]
+				for k, w := range weights {
+					sum += w * x[step+k]
+				}
+				out = append(out, sum)
+			}
+		}
+	}
+
+	switch l.Activation {
+	case "relu":
+		for i, v := range out {
+			out[i] = math.Max(v, 0)
+		}
+	case "softmax":
+		highest := math.Inf(-1)
+		for _, v := range out {
+			highest = math.Max(highest, v)
+		}
+		sum := 0.0
+		for i, v := range out {
+			out[i] = math.Exp(v - highest)
+			sum += out[i]
+		}
+		for i := range out {
+			out[i] /= sum
+		}
+	}
+	return out
+}
+
+// policy : the score of every action for the state inputs
+func (m *PensieveModel) policy(inputs map[string][]float64) []float64 {
+	var merged []float64
+	for i := range m.Branches {
+		merged = append(merged, m.Branches[i].forward(inputs[m.Branches[i].Input])...)
+	}
+	for i := range m.Layers {
+		merged = m.Layers[i].forward(merged)
+	}
+	return merged
+}
+
+// PensieveData :
+/*
+ * the state of a Pensieve policy over the segments of an adaptation set
+ * the actions are mapped to rep_rates from the lowest to the highest bandwidth, whatever the order of the MPD
+ */
+type PensieveData struct {
+	model *PensieveModel
+	// the rep_rate index of every action, and the segment sizes in bits of every rep_rate
+	actions    []int
+	chunkLists [][]int
+	// bitrate in bps of every rep_rate, and the highest bitrate of the policy
+	bandwithList []int
+	maxBitrate   float64
+	segments     int
+
+	// throughput and download time of the last History segments, the oldest first
+	throughputs   []float64
+	downloadTimes []float64
+
+	// the actions the ladder constraints allow, nil if they allow every action
+	allowed []bool
+}
+
+// NewPensieveData :
+/*
+ * the Pensieve state of an adaptation set with rep_rates bandwithList, segment sizes chunkLists and segments segments
+ * the policy either has an action per rep_rate, or the bitrates it was trained on, which select the highest rep_rate below them
+ */
+func NewPensieveData(bandwithList []int, chunkLists [][]int, segments int, model *PensieveModel) (PensieveData, error) {
+	data := PensieveData{
+		model:         model,
+		chunkLists:    chunkLists,
+		bandwithList:  bandwithList,
+		segments:      segments,
+		throughputs:   make([]float64, model.History),
+		downloadTimes: make([]float64, model.History),
+	}
+	if len(bandwithList) == 0 {
+		return data, errors.New("the adaptation set has no rep_rates")
+	}
+
+	lowest := 0
+	for i := range bandwithList {
+		data.maxBitrate = math.Max(data.maxBitrate, float64(bandwithList[i]))
+		if bandwithList[i] < bandwithList[lowest] {
+			lowest = i
+		}
+	}
+	if len(model.Bitrates) == 0 {
+		if model.Actions() != len(bandwithList) {
+			return data, fmt.Errorf("the policy scores %d actions and the adaptation set has %d rep_rates", model.Actions(), len(bandwithList))
+		}
+		for i := range bandwithList {
+			data.actions = append(data.actions, i)
+		}
+		sort.SliceStable(data.actions, func(a, b int) bool {
+			return bandwithList[data.actions[a]] < bandwithList[data.actions[b]]
+		})
+		return data, nil
+	}
+
+	data.maxBitrate = 0
+	for _, bitrate := range model.Bitrates {
+		data.actions = append(data.actions, SelectRepRateWithThroughtput(bitrate*1000, bandwithList, lowest))
+		data.maxBitrate = math.Max(data.maxBitrate, float64(bitrate*1000))
+	}
+	return data, nil
+}
+
+// Pensieve :
+/*
+ * the rep_rate index of segment nextSegmentNumber, the allowed action the policy scores highest
+ * after a segment at lastRepRate with a throughput of newThr bps, downloaded in deliveryTime_Milliseconds
+ */
+func Pensieve(bufferLevel_Milliseconds int, newThr int, deliveryTime_Milliseconds int, lastRepRate int, nextSegmentNumber int, crossLayer PensieveCrossLayer, thrList *[]int, data *PensieveData) int {
+	*thrList = append(*thrList, newThr)
+	model := data.model
+
+	data.throughputs = append(data.throughputs[1:], float64(newThr)/8/1e6)
+	data.downloadTimes = append(data.downloadTimes[1:], float64(deliveryTime_Milliseconds)/1000/model.BufferNorm)
+
+	nextSizes := make([]float64, len(data.actions))
+	for i, repRate := range data.actions {
+		if repRate < len(data.chunkLists) && nextSegmentNumber >= 1 && nextSegmentNumber <= len(data.chunkLists[repRate]) {
+			nextSizes[i] = float64(data.chunkLists[repRate][nextSegmentNumber-1]) / 8 / 1e6
+		}
+	}
+	remaining := data.segments - nextSegmentNumber + 1
+	if remaining > model.SegmentsCap {
+		remaining = model.SegmentsCap
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	scores := model.policy(map[string][]float64{
+		PensieveLastBitrate:  {float64(data.bandwithList[lastRepRate]) / data.maxBitrate},
+		PensieveBuffer:       {float64(bufferLevel_Milliseconds) / 1000 / model.BufferNorm},
+		PensieveThroughput:   data.throughputs,
+		PensieveDownloadTime: data.downloadTimes,
+		PensieveNextSizes:    nextSizes,
+		PensieveRemaining:    {float64(remaining) / float64(model.SegmentsCap)},
+		PensievePacketRate:   {crossLayer.PacketRate / 8 / 1e6},
+		PensieveRTT:          {crossLayer.RTT.Seconds()},
+	})
+
+	best := highestAllowedLevel(data.allowed, 0)
+	for i, score := range scores {
+		if levelAllowed(data.allowed, i) && score > scores[best] {
+			best = i
+		}
+	}
+	return data.actions[best]
+}
@@ -0,0 +1,95 @@
+package algorithms
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pensieveTestModel : scores the lowest action at 0.5, the middle one at the last throughput in MB/s and the highest at twice it less 1
+const pensieveTestModel = `{
+	"branches": [
+		{"input": "throughput", "type": "conv1d", "weights": [[0, 0, 0, 0, 0, 0, 0, 1]], "bias": [0]}
+	],
+	"layers": [
+		{"type": "dense", "weights": [[0], [1], [2]], "bias": [0.5, 0, -1], "activation": "softmax"}
+	]
+}`
+
+func writePensieveModel(t *testing.T, model string) string {
+	fileName := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(fileName, []byte(model), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestPensieveActions(t *testing.T) {
+	model, err := LoadPensieveModel(writePensieveModel(t, pensieveTestModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the rep_rates in MPD order, the highest first
+	data, err := NewPensieveData([]int{3000000, 1000000, 300000}, nil, 10, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var thrList []int
+	for _, test := range []struct {
+		thr     int
+		repRate int
+	}{{800000, 2}, {5600000, 1}, {16000000, 0}} {
+		if repRate := Pensieve(10000, test.thr, 1000, 2, 2, PensieveCrossLayer{}, &thrList, &data); repRate != test.repRate {
+			t.Errorf("a throughput of %d bps selects rep_rate %d, want %d", test.thr, repRate, test.repRate)
+		}
+	}
+	if len(thrList) != 3 {
+		t.Errorf("%d throughputs in the list, want 3", len(thrList))
+	}
+
+	if _, err := NewPensieveData([]int{3000000, 300000}, nil, 10, model); err == nil {
+		t.Error("a policy of 3 actions streams 2 rep_rates")
+	}
+}
+
+func TestPensieveBitrates(t *testing.T) {
+	model, err := LoadPensieveModel(writePensieveModel(t, `{"bitrates": [300, 1200, 4300],`+pensieveTestModel[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewPensieveData([]int{5000000, 2500000, 1000000, 500000}, nil, 10, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 300 kbps is below every rep_rate, 1200 kbps streams 1 Mbps and 4300 kbps 2.5 Mbps
+	if want := []int{3, 2, 1}; len(data.actions) != 3 || data.actions[0] != want[0] || data.actions[1] != want[1] || data.actions[2] != want[2] {
+		t.Errorf("the actions stream rep_rates %v, want %v", data.actions, want)
+	}
+}
+
+func TestPensieveConv1D(t *testing.T) {
+	layer := PensieveLayer{Type: "conv1d", Weights: [][]float64{{1, 1}, {1, -1}}, Bias: []float64{0, 1}, Activation: "relu"}
+	if out, err := layer.prepare(3); err != nil || out != 4 {
+		t.Fatalf("a kernel of 2 over 3 values with 2 filters has %d outputs (%v), want 4", out, err)
+	}
+	// step by step, the filters of a step next to each other
+	out := layer.forward([]float64{1, 2, 4})
+	want := []float64{3, 0, 6, 0}
+	for i := range want {
+		if out[i] != want[i] {
+			t.Fatalf("conv1d output %v, want %v", out, want)
+		}
+	}
+}
+
+func TestPensieveModelSizes(t *testing.T) {
+	for _, model := range []string{
+		`{"branches": [{"input": "buffer", "type": "dense", "weights": [[1, 1]], "bias": [0]}], "layers": [{"type": "dense", "weights": [[1]], "bias": [0]}]}`,
+		`{"branches": [{"input": "latency", "type": "dense", "weights": [[1]], "bias": [0]}], "layers": [{"type": "dense", "weights": [[1]], "bias": [0]}]}`,
+		`{"bitrates": [300, 750], "branches": [{"input": "buffer", "type": "dense", "weights": [[1]], "bias": [0]}], "layers": [{"type": "dense", "weights": [[1]], "bias": [0]}]}`,
+	} {
+		if _, err := LoadPensieveModel(writePensieveModel(t, model)); err == nil {
+			t.Errorf("%s was loaded", model)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package algorithms
+
+import (
+	glob "github.com/uccmisl/godash/global"
+)
+
+// VBRParams :
+/*
+ * the throughput algorithms compare their throughput against the actual bitrate of the next segments,
+ * their size over their duration, instead of the bandwidth of the MPD
+ */
+type VBRParams struct {
+	Enabled bool
+	Horizon int // the actual bitrate of a rep_rate is the one of its next Horizon segments
+}
+
+// DefaultVBRParams : the VBR layer is off, these are its parameters when it is turned on
+func DefaultVBRParams() VBRParams {
+	return VBRParams{Horizon: 1}
+}
+
+// SegmentSizes :
+/*
+ * the sizes in bits of the segments of every rep_rate, in the order of the rep_rate indexes
+ * the VBR layer every algorithm can read the next segments of a rep_rate from
+ * the methods of a nil SegmentSizes know no sizes
+ */
+type SegmentSizes struct {
+	sizes                        [][]int
+	segmentDuration_Milliseconds int
+}
+
+// NewSegmentSizes : the sizes of the chunk lists of every rep_rate, of segments of segmentDuration_Milliseconds
+func NewSegmentSizes(chunkLists [][]int, segmentDuration_Milliseconds int) *SegmentSizes {
+	return &SegmentSizes{sizes: chunkLists, segmentDuration_Milliseconds: segmentDuration_Milliseconds}
+}
+
+// Size : the size in bits of a segment of the rep_rate, false if it is not known
+func (s *SegmentSizes) Size(repRate int, segmentNumber int) (int, bool) {
+	if s == nil || repRate < 0 || repRate >= len(s.sizes) || segmentNumber < 1 || segmentNumber > len(s.sizes[repRate]) {
+		return 0, false
+	}
+	size := s.sizes[repRate][segmentNumber-1]
+	return size, size > 0
+}
+
+// Next :
+/*
+ * the sizes of the next n segments of every rep_rate from segmentNumber on
+ * a list is shorter at the end of the stream, and empty for a rep_rate without sizes
+ */
+func (s *SegmentSizes) Next(segmentNumber int, n int) [][]int {
+	if s == nil {
+		return nil
+	}
+	next := make([][]int, len(s.sizes))
+	for repRate := range s.sizes {
+		for i := 0; i < n; i++ {
+			size, ok := s.Size(repRate, segmentNumber+i)
+			if !ok {
+				break
+			}
+			next[repRate] = append(next[repRate], size)
+		}
+	}
+	return next
+}
+
+// Bitrates :
+/*
+ * the actual bitrate in bps of every rep_rate of bandwithList, the size of its next horizon segments
+ * from segmentNumber on over their duration, or its bandwidth if the sizes are not known
+ * a rep_rate is never below a lower one, so the list keeps the order of bandwithList
+ */
+func (s *SegmentSizes) Bitrates(bandwithList []int, segmentNumber int, horizon int) []int {
+	bitrates := append([]int(nil), bandwithList...)
+	if s == nil || len(s.sizes) != len(bandwithList) || s.segmentDuration_Milliseconds <= 0 {
+		return bitrates
+	}
+	next := s.Next(segmentNumber, horizon)
+	for repRate, sizes := range next {
+		if len(sizes) == 0 {
+			continue
+		}
+		bits := 0
+		for _, size := range sizes {
+			bits += size
+		}
+		bitrates[repRate] = bits * 1000 / (len(sizes) * s.segmentDuration_Milliseconds)
+	}
+	// index 0 is the highest rep_rate
+	for repRate := len(bitrates) - 2; repRate >= 0; repRate-- {
+		if bitrates[repRate] < bitrates[repRate+1] {
+			bitrates[repRate] = bitrates[repRate+1]
+		}
+	}
+	return bitrates
+}
+
+// SizeRatio : the size of a segment of the rep_rate over its size at the bandwidth, false if it is not known
+func (s *SegmentSizes) SizeRatio(repRate int, segmentNumber int, bandwidth int) (float64, bool) {
+	size, ok := s.Size(repRate, segmentNumber)
+	if !ok || bandwidth <= 0 || s.segmentDuration_Milliseconds <= 0 {
+		return 0, false
+	}
+	return float64(size) * 1000 / (float64(bandwidth) * float64(s.segmentDuration_Milliseconds)), true
+}
+
+// ThroughputBased :
+/*
+ * true if adapt selects its rep_rate by comparing a throughput against the bandwidths of the rep_rates,
+ * so it can compare against the actual bitrates of the VBR layer instead
+ */
+func ThroughputBased(adapt string) bool {
+	switch adapt {
+	case glob.ElasticAlg, glob.LogisticAlg, glob.ArbiterAlg:
+		return true
+	}
+	return RateBased(adapt)
+}
@@ -0,0 +1,51 @@
+package algorithms
+
+import (
+	"reflect"
+	"testing"
+)
+
+// vbrBandwithList : the rep_rates in MPD order, the highest first, of 2 second segments
+var vbrBandwithList = []int{4000000, 2000000, 1000000}
+
+func TestSegmentSizesBitrates(t *testing.T) {
+	sizes := NewSegmentSizes([][]int{
+		{10000000, 6000000, 8000000},
+		{3000000, 5000000, 4000000},
+		{2000000, 2000000, 0},
+	}, 2000)
+
+	// the next segment of every rep_rate over its duration
+	if got := sizes.Bitrates(vbrBandwithList, 1, 1); !reflect.DeepEqual(got, []int{5000000, 1500000, 1000000}) {
+		t.Errorf("segment 1 has the bitrates %v", got)
+	}
+	// the lower rep_rate is larger, so the higher one is raised to it
+	if got := sizes.Bitrates(vbrBandwithList, 2, 1); !reflect.DeepEqual(got, []int{3000000, 2500000, 1000000}) {
+		t.Errorf("segment 2 has the bitrates %v", got)
+	}
+	// the horizon averages the next segments, and stops at the end of the stream
+	if got := sizes.Bitrates(vbrBandwithList, 2, 5); !reflect.DeepEqual(got, []int{3500000, 2250000, 1000000}) {
+		t.Errorf("segments 2 and 3 have the bitrates %v", got)
+	}
+	// without sizes the bandwidths are kept
+	if got := sizes.Bitrates(vbrBandwithList, 4, 1); !reflect.DeepEqual(got, vbrBandwithList) {
+		t.Errorf("segment 4 has the bitrates %v", got)
+	}
+	var none *SegmentSizes
+	if got := none.Bitrates(vbrBandwithList, 1, 1); !reflect.DeepEqual(got, vbrBandwithList) {
+		t.Errorf("no sizes have the bitrates %v", got)
+	}
+}
+
+func TestSegmentSizesSizeRatio(t *testing.T) {
+	sizes := NewSegmentSizes([][]int{{10000000}, {3000000}}, 2000)
+	if ratio, ok := sizes.SizeRatio(0, 1, 4000000); !ok || ratio != 1.25 {
+		t.Errorf("the ratio of segment 1 is %g, %v and not 1.25", ratio, ok)
+	}
+	if _, ok := sizes.SizeRatio(1, 2, 2000000); ok {
+		t.Errorf("segment 2 has a ratio")
+	}
+	if _, ok := sizes.SizeRatio(2, 1, 2000000); ok {
+		t.Errorf("rep_rate 2 has a ratio")
+	}
+}
@@ -0,0 +1,368 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package config : the options of a goDASH run
+/*
+ * one schema holds every option, the json of the -config files, the flags and the
+ * GODASH_ environment variables are all read into it, so each option has all three
+ */
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/output"
+)
+
+// SchemaVersion : the version of the config files written by this goDASH
+// * files without a version are the config files of goDASH 2.0, read as version 0
+const SchemaVersion = 1
+
+// Config :
+/*
+ * every option of a run, the flag of an option is in its flag tag
+ * the json keys of the version 0 options are kept, new options are in their own blocks
+ */
+type Config struct {
+	Version int `json:"version"`
+
+	URL            string  `json:"url" flag:"url"`
+	Adapt          string  `json:"adapt" flag:"adapt"`
+	Codec          string  `json:"codec" flag:"codec"`
+	MaxHeight      int     `json:"maxHeight" flag:"maxHeight"`
+	StreamDuration int     `json:"streamDuration" flag:"streamDuration"`
+	StreamSpeed    float64 `json:"streamSpeed" flag:"streamSpeed"`
+	MaxBuffer      int     `json:"maxBuffer" flag:"maxBuffer"`
+	InitBuffer     int     `json:"initBuffer" flag:"initBuffer"`
+	HLS            string  `json:"hls" flag:"hls"`
+	Quic           Switch  `json:"quic" flag:"quic"`
+	UseTestbed     Switch  `json:"useTestbed" flag:"useTestbed"`
+	QoE            Switch  `json:"QoE" flag:"QoE"`
+	GetHeaders     string  `json:"getHeaders" flag:"getHeaders"`
+	StoreDash      Switch  `json:"storeDash" flag:"storeDASH"`
+	OutputFolder   string  `json:"outputFolder" flag:"outputFolder"`
+	TerminalPrint  Switch  `json:"terminalPrint" flag:"terminalPrint"`
+	// PrintHeader : the extra columns of the segment log, a json object or, in version 0, a json string
+	PrintHeader PrintHeaders `json:"printHeader" flag:"printHeader"`
+	Debug       Switch       `json:"debug" flag:"debug"`
+	LogFile     string       `json:"logFile" flag:"logFile"`
+	Collab      Switch       `json:"serveraddr" flag:"serveraddr"`
+
+	Output     Output     `json:"output"`
+	Metrics    Metrics    `json:"metrics"`
+	Clients    Clients    `json:"clients"`
+	Ladder     Ladder     `json:"ladder"`
+	Algorithms Algorithms `json:"algorithms"`
+}
+
+// Output : where the files of the run are written
+type Output struct {
+	Root     string `json:"root" flag:"outputRoot"`
+	RunID    string `json:"runID" flag:"runID"`
+	Template string `json:"template" flag:"outputTemplate"`
+}
+
+// Metrics : the metric logger of the run
+type Metrics struct {
+	Sinks StringList `json:"sinks" flag:"metrics"`
+	// Path of the file sinks, without extension, metrics_log beneath the output of the run if empty
+	Path string `json:"path" flag:"metricsPath"`
+	Addr string `json:"addr" flag:"metricsAddr"`
+	// PollInterval of the buffer level, in milliseconds
+	PollInterval int `json:"pollInterval" flag:"metricsPoll"`
+}
+
+// Clients : the clients of a multi-client run
+type Clients struct {
+	Count int `json:"count" flag:"clients"`
+	// Stagger between the start of consecutive clients, in seconds
+	Stagger float64 `json:"stagger" flag:"clientStagger"`
+	// Starts of every client in seconds, replaces Stagger
+	Starts FloatList `json:"starts" flag:"clientStarts"`
+}
+
+// Ladder : the constraints on the rep_rates the algorithms may select, a zero limit is no limit
+type Ladder struct {
+	MaxWidth     int `json:"maxWidth" flag:"maxWidth"`
+	MaxFrameRate int `json:"maxFrameRate" flag:"maxFrameRate"`
+	// MaxBitrate in kbps
+	MaxBitrate int `json:"maxBitrate" flag:"maxBitrate"`
+	// MaxPixels : the largest width times height
+	MaxPixels int `json:"maxPixels" flag:"maxPixels"`
+	// CodecPreference : the codecs to stream in order of preference, replaces Codec
+	CodecPreference StringList `json:"codecPreference" flag:"codecPreference"`
+	// DynamicRange : any, sdr or hdr
+	DynamicRange string `json:"dynamicRange" flag:"dynamicRange"`
+	// Screen : a screen profile or "<width>x<height>"
+	Screen string `json:"screen" flag:"screen"`
+	// ScreenSchedule : the screens of the stream over time, "<seconds>:<screen>"
+	ScreenSchedule StringList `json:"screenSchedule" flag:"screenSchedule"`
+}
+
+// Algorithms : the parameters of the ABR algorithms
+type Algorithms struct {
+	// Predictor : the throughput predictor of the algorithm, its own estimator if empty
+	Predictor   string      `json:"predictor" flag:"predictor"`
+	Exponential Exponential `json:"exponential"`
+	BBA2        BBA2        `json:"bba2"`
+	CrossLayer  CrossLayer  `json:"crossLayer"`
+	MPC         MPC         `json:"mpc"`
+	LoLP        LoLP        `json:"lolp"`
+	Pensieve    Pensieve    `json:"pensieve"`
+	FastStart   FastStart   `json:"fastStart"`
+	VBR         VBR         `json:"vbr"`
+}
+
+// Exponential : the parameters of the exponential average algorithm
+type Exponential struct {
+	Ratio float64 `json:"ratio" flag:"expRatio"`
+}
+
+// BBA2 : the reservoirs of the BBA-2 algorithms
+type BBA2 struct {
+	// MinReservoir : the lower reservoir holds at least this many segments
+	MinReservoir int `json:"minReservoir" flag:"bba2MinReservoir"`
+	// UpperReservoir : the part of the buffer above which the highest rep_rate is streamed
+	UpperReservoir float64 `json:"upperReservoir" flag:"bba2UpperReservoir"`
+	// Horizon : the lower reservoir looks this many max buffers of segments ahead
+	Horizon int `json:"horizon" flag:"bba2Horizon"`
+	// Dynamic : size the reservoirs on the throughput variation and the RTT measured by the cross-layer accountant
+	Dynamic Switch `json:"dynamic" flag:"bba2Dynamic"`
+	// MaxReservoir : the part of the buffer the dynamic lower reservoir takes at most
+	MaxReservoir float64 `json:"maxReservoir" flag:"bba2MaxReservoir"`
+	// VarianceWeight : the dynamic lower reservoir grows by this part of the coefficient of variation of the throughput
+	VarianceWeight float64 `json:"varianceWeight" flag:"bba2VarianceWeight"`
+}
+
+// CrossLayer : the stall predictor of the cross-layer algorithms
+type CrossLayer struct {
+	// PredictionWindow : the part of a segment that is downloaded before a download can be aborted
+	PredictionWindow float64 `json:"predictionWindow" flag:"xlPredictionWindow"`
+	// AbortLogic : base, rate or double, the logic of the -adapt algorithm if empty
+	AbortLogic string `json:"abortLogic" flag:"xlAbortLogic"`
+}
+
+// MPC : the lookahead and the QoE weights of the MPC algorithms
+type MPC struct {
+	// Horizon : the number of segments MPC plans over
+	Horizon int `json:"horizon" flag:"mpcHorizon"`
+	// RebufferPenalty : the QoE lost per second of rebuffering, in Mbps
+	RebufferPenalty float64 `json:"rebufferPenalty" flag:"mpcRebufferPenalty"`
+	// SwitchPenalty : the QoE lost per Mbps of rep_rate switch
+	SwitchPenalty float64 `json:"switchPenalty" flag:"mpcSwitchPenalty"`
+	// Fast : select the rep_rate from the precomputed FastMPC table
+	Fast Switch `json:"fast" flag:"mpcFast"`
+}
+
+// LoLP : the latency target and the playback rate controller of LoL+
+type LoLP struct {
+	// TargetLatency : the latency in seconds LoL+ keeps the stream at
+	TargetLatency float64 `json:"targetLatency" flag:"lolpTargetLatency"`
+	// CatchupRate : the playback rate is kept within 1 +/- CatchupRate of the stream speed
+	CatchupRate float64 `json:"catchupRate" flag:"lolpCatchupRate"`
+}
+
+// Pensieve : the learned policy of the Pensieve algorithms
+type Pensieve struct {
+	// Model : the json file of the weights of the actor network
+	Model string `json:"model" flag:"pensieveModel"`
+}
+
+// FastStart : the first rep_rate and the initial buffer from the capacity measured during startup
+type FastStart struct {
+	// Enabled : select the first rep_rate from the MPD and init segment downloads instead of the lowest rep_rate
+	Enabled Switch `json:"enabled" flag:"fastStart"`
+	// Safety : the part of the estimated capacity the first rep_rate may take
+	Safety float64 `json:"safety" flag:"fastStartSafety"`
+	// Margin : the initial buffer covers the next segment when the capacity drops to this part of the estimate
+	Margin float64 `json:"margin" flag:"fastStartMargin"`
+	// StartupDelay : the seconds the initial buffer may take to download at the estimated capacity
+	StartupDelay float64 `json:"startupDelay" flag:"fastStartDelay"`
+}
+
+// VBR : the actual bitrate of the next segments for the throughput algorithms
+type VBR struct {
+	// Enabled : the throughput algorithms compare against the size of the next segments instead of the bandwidth of the MPD
+	Enabled Switch `json:"enabled" flag:"vbr"`
+	// Horizon : the actual bitrate of a rep_rate is the one of its next Horizon segments
+	Horizon int `json:"horizon" flag:"vbrHorizon"`
+}
+
+// Default :
+// * the config of a run without config files, environment variables or flags
+func Default() Config {
+	return Config{
+		Version:     SchemaVersion,
+		Adapt:       glob.ConventionalAlg,
+		Codec:       glob.RepRateCodecAVC,
+		MaxHeight:   2160,
+		StreamSpeed: 1,
+		MaxBuffer:   30,
+		InitBuffer:  2,
+		HLS:         glob.HlsOff,
+		GetHeaders:  glob.GetHeaderOff,
+		PrintHeader: PrintHeaders{},
+		LogFile:     glob.DebugTextFile,
+		Output:      Output{Root: ".", Template: output.DefaultTemplate},
+		Metrics:     Metrics{Sinks: StringList{glob.MetricsSinkText}, Addr: glob.MetricsPrometheusAddr, PollInterval: 100},
+		Clients:     Clients{Count: 1},
+		Ladder:      Ladder{DynamicRange: glob.DynamicRangeAny},
+		Algorithms: Algorithms{
+			BBA2:       BBA2{MinReservoir: 3, UpperReservoir: 0.1, Horizon: 2, MaxReservoir: 0.5, VarianceWeight: 1},
+			CrossLayer: CrossLayer{PredictionWindow: 0.15},
+			MPC:        MPC{Horizon: 5, RebufferPenalty: 4.3, SwitchPenalty: 1},
+			LoLP:       LoLP{TargetLatency: 3, CatchupRate: 0.5},
+			FastStart:  FastStart{Safety: 0.8, Margin: 0.5, StartupDelay: 1},
+			VBR:        VBR{Horizon: 1},
+		},
+	}
+}
+
+// Switch : an on or off option, "on" and "off" in json and flags, json booleans are read too
+type Switch bool
+
+// String : on or off
+func (s Switch) String() string {
+	if s {
+		return "on"
+	}
+	return "off"
+}
+
+// Set : set the switch from on or off
+func (s *Switch) Set(value string) error {
+	switch value {
+	case "on":
+		*s = true
+	case "off":
+		*s = false
+	default:
+		return errors.New("must be set to on or off and not " + value)
+	}
+	return nil
+}
+
+// MarshalJSON : on or off
+func (s Switch) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON : on, off or a json boolean
+func (s *Switch) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = Switch(b)
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("must be on or off")
+	}
+	return s.Set(value)
+}
+
+// PrintHeaders : the print headers and whether they are on or off
+type PrintHeaders map[string]string
+
+// String : the print headers as a json object
+func (p PrintHeaders) String() string {
+	if len(p) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(map[string]string(p))
+	return string(data)
+}
+
+// Set : set the print headers from a json object, they replace the print headers set before
+func (p *PrintHeaders) Set(value string) error {
+	headers := make(map[string]string)
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), &headers); err != nil {
+			return errors.New("must be a json object of print headers - " + err.Error())
+		}
+	}
+	*p = headers
+	return nil
+}
+
+// UnmarshalJSON : a json object, or the json string of one as in version 0
+func (p *PrintHeaders) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		return p.Set(value)
+	}
+	return p.Set(string(data))
+}
+
+// StringList : a comma separated list in flags, a json array in json
+type StringList []string
+
+// String : the comma separated list
+func (l StringList) String() string {
+	return strings.Join(l, ",")
+}
+
+// Set : set the list from a comma separated list
+func (l *StringList) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// FloatList : a list of numbers, "[<n>,<n>]" in flags, a json array in json
+type FloatList []float64
+
+// String : the list as "[<n>,<n>]"
+func (l FloatList) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	values := make([]string, len(l))
+	for i, value := range l {
+		values[i] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return "[" + strings.Join(values, ",") + "]"
+}
+
+// Set : set the list from "[<n>,<n>]", the brackets are optional
+func (l *FloatList) Set(value string) error {
+	*l = nil
+	value = strings.Trim(strings.TrimSpace(value), "[]")
+	if value == "" {
+		return nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		number, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil {
+			return errors.New("must be a list of numbers and not " + value)
+		}
+		*l = append(*l, number)
+	}
+	return nil
+}
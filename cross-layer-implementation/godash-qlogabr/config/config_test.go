@@ -0,0 +1,171 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// env : a getenv of the variables in vars
+func env(vars map[string]string) func(string) string {
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+// load : the config of args, with the flags on a new flag set
+func load(t *testing.T, args []string, vars map[string]string) (Config, Command, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("godash", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args, env(vars))
+}
+
+func TestEveryOptionHasFlagKeyAndUsage(t *testing.T) {
+	c := Default()
+	flags := map[string]bool{}
+	keys := map[string]bool{}
+	for _, f := range c.fields() {
+		if f.flag == "" || f.key == "" {
+			t.Errorf("option %+v has no flag or json key", f)
+		}
+		if flags[f.flag] || keys[f.key] {
+			t.Errorf("option -%s (%s) is not unique", f.flag, f.key)
+		}
+		flags[f.flag], keys[f.key] = true, true
+		if usage(f.flag) == f.flag {
+			t.Errorf("option -%s has no usage", f.flag)
+		}
+	}
+}
+
+func TestDumpedConfigLoadsBack(t *testing.T) {
+	c, _, err := load(t, []string{"-url", "[http://a/b.mpd]", "-bba2Horizon", "3", "-clientStarts", "[0,5]", "-clients", "2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(t.TempDir(), "config.json")
+	if err := c.Save(fileName); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _, err := load(t, []string{"-config", fileName}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, got bytes.Buffer
+	c.Write(&want)
+	loaded.Write(&got)
+	if want.String() != got.String() {
+		t.Errorf("loaded config\n%s\nwant\n%s", got.String(), want.String())
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(fileName, []byte(`{"version": 1, "maxBuffer": 20, "codec": "h265", "algorithms": {"bba2": {"minReservoir": 5}}}`), 0644)
+
+	c, command, err := load(t, []string{"-maxBuffer", "40"}, map[string]string{
+		"GODASH_CONFIG":           fileName,
+		"GODASH_MAXBUFFER":        "10",
+		"GODASH_BBA2MINRESERVOIR": "6",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(command.Files) != 1 || command.Files[0] != fileName {
+		t.Errorf("config files %v, want %s", command.Files, fileName)
+	}
+	if c.MaxBuffer != 40 {
+		t.Errorf("maxBuffer %d, want the flag 40", c.MaxBuffer)
+	}
+	if c.Algorithms.BBA2.MinReservoir != 6 {
+		t.Errorf("bba2MinReservoir %d, want the env 6", c.Algorithms.BBA2.MinReservoir)
+	}
+	if c.Codec != "h265" {
+		t.Errorf("codec %s, want the file h265", c.Codec)
+	}
+	if c.InitBuffer != Default().InitBuffer {
+		t.Errorf("initBuffer %d, want the default %d", c.InitBuffer, Default().InitBuffer)
+	}
+}
+
+func TestLegacyFile(t *testing.T) {
+	c, _, err := load(t, []string{"-config", "configure_old_working.json"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != SchemaVersion {
+		t.Errorf("version %d, want %d", c.Version, SchemaVersion)
+	}
+	if c.Algorithms.Exponential.Ratio != 0.2 {
+		t.Errorf("expRatio %v, want 0.2", c.Algorithms.Exponential.Ratio)
+	}
+	if c.PrintHeader["Width"] != "on" || c.PrintHeader["Codec"] != "off" {
+		t.Errorf("printHeader %v", c.PrintHeader)
+	}
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(fileName, []byte(`{"version": 1, "maxBufer": 20}`), 0644)
+	if _, _, err := load(t, []string{"-config", fileName}, nil); err == nil {
+		t.Error("unknown key maxBufer is accepted")
+	}
+	os.WriteFile(fileName, []byte(`{"version": 2}`), 0644)
+	if _, _, err := load(t, []string{"-config", fileName}, nil); err == nil {
+		t.Error("version 2 is accepted")
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	c, _, err := load(t, []string{"-url", "[http://a/b.mpd]", "-adapt", "nope", "-maxBuffer", "-1", "-bba2UpperReservoir", "1.5", "-xlAbortLogic", "never"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs, ok := c.Validate().(Errors)
+	if !ok {
+		t.Fatal("invalid config is valid")
+	}
+	for _, name := range []string{"adapt", "maxBuffer", "bba2UpperReservoir", "xlAbortLogic"} {
+		if !strings.Contains(errs.Error(), "-"+name+" ") {
+			t.Errorf("no error for -%s in\n%s", name, errs.Error())
+		}
+	}
+	if len(errs) != 4 {
+		t.Errorf("%d errors, want 4:\n%s", len(errs), errs.Error())
+	}
+}
+
+func TestBadFlagsAreErrors(t *testing.T) {
+	_, _, err := load(t, []string{"-maxBufer", "20"}, nil)
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatal("unknown flag -maxBufer is accepted")
+	}
+	if len(errs) != 1 {
+		t.Errorf("%d errors, want the flag error once:\n%s", len(errs), errs.Error())
+	}
+	if _, command, err := load(t, []string{"-dumpConfig"}, nil); err != nil || !command.DumpConfig {
+		t.Errorf("-dumpConfig is %v, %v", command.DumpConfig, err)
+	}
+}
+
+func TestValidateVersionRead(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(fileName, []byte(`{"version": 1}`), 0644)
+	c, _, err := load(t, []string{"-config", fileName}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Version = SchemaVersion + 1
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "version") {
+		t.Errorf("version %d is valid", c.Version)
+	}
+}
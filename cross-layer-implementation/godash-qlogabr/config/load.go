@@ -0,0 +1,281 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// EnvPrefix : the environment variable of an option is EnvPrefix and its flag in upper case
+const EnvPrefix = "GODASH_"
+
+// Command : the flags of the command that are not options of the run
+type Command struct {
+	// Files : the -config files, in the order they were read
+	Files []string
+	// DumpConfig : print the effective config and stop
+	DumpConfig bool
+}
+
+// Errors : every error of a config, so they can be reported at once
+type Errors []error
+
+// Error : one error per line
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// errorOrNil : nil if there are no errors
+func (e Errors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Load :
+/*
+ * the effective config of a run, the defaults overridden by the -config files in order,
+ * then by the GODASH_ environment variables and last by the flags of args
+ * the flags are registered on fs, so its usage shows the options
+ * every value that can not be read is in the returned Errors, the config is not validated
+ */
+func Load(fs *flag.FlagSet, args []string, getenv func(string) string) (Config, Command, error) {
+
+	// find the config files first, their values are overridden by the flags
+	scratch := Default()
+	var command Command
+	first := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	first.SetOutput(io.Discard)
+	scratch.register(first)
+	files := first.String(glob.ConfigName, "", "")
+	first.Bool(glob.DumpConfigName, false, "")
+	var errs Errors
+	firstErr := first.Parse(args)
+	if firstErr != nil {
+		errs = append(errs, firstErr)
+	}
+	if *files == "" {
+		*files = getenv(EnvPrefix + strings.ToUpper(glob.ConfigName))
+	}
+
+	cfg := Default()
+	for _, file := range strings.Split(*files, ",") {
+		if file = strings.TrimSpace(file); file == "" {
+			continue
+		}
+		command.Files = append(command.Files, file)
+		if err := cfg.ReadFile(file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, f := range cfg.fields() {
+		name := EnvPrefix + strings.ToUpper(f.flag)
+		if value := getenv(name); value != "" {
+			if err := f.value.Set(value); err != nil {
+				errs = append(errs, errors.New(name+" "+err.Error()))
+			}
+		}
+	}
+
+	cfg.register(fs)
+	fs.String(glob.ConfigName, *files, "comma separated list of config files - \"[path/to/config/file]\" - later files override earlier files, the environment variables and flags override the files")
+	fs.BoolVar(&command.DumpConfig, glob.DumpConfigName, false, "print the effective config as json and stop")
+	// the flags were read twice, an error of both reads is reported once
+	if err := fs.Parse(args); err != nil && (firstErr == nil || err.Error() != firstErr.Error()) {
+		errs = append(errs, err)
+	}
+
+	return cfg, command, errs.errorOrNil()
+}
+
+// ReadFile :
+/*
+ * override the options of c with the options of json config file fileName
+ * version 0 files are the config files of goDASH 2.0, unknown options are errors
+ */
+func (c *Config) ReadFile(fileName string) error {
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return errors.New("config file " + fileName + " can not be read - " + err.Error())
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return errors.New("config file " + fileName + " is empty")
+	}
+
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return errors.New("config file " + fileName + " is not json - " + err.Error())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	switch probe.Version {
+	case 0:
+		// the exponential ratio was not in the algorithms block
+		legacy := struct {
+			*Config
+			ExpRatio *float64 `json:"expRatio"`
+		}{Config: c}
+		err = decoder.Decode(&legacy)
+		if err == nil && legacy.ExpRatio != nil {
+			c.Algorithms.Exponential.Ratio = *legacy.ExpRatio
+		}
+		// the options are now in the blocks of this version
+		c.Version = SchemaVersion
+	case SchemaVersion:
+		err = decoder.Decode(c)
+	default:
+		return errors.New("config file " + fileName + " has version " + strconv.Itoa(probe.Version) + ", this goDASH reads versions 0 to " + strconv.Itoa(SchemaVersion))
+	}
+	if err != nil {
+		return errors.New("config file " + fileName + " - " + err.Error())
+	}
+	return nil
+}
+
+// Write : write c as indented json
+func (c Config) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Save : save c as json to fileName, so the run can be repeated with -config fileName
+func (c Config) Save(fileName string) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Write(f)
+}
+
+// field : an option of the config
+type field struct {
+	// flag of the option and its json key, with the keys of its blocks
+	flag  string
+	key   string
+	value flag.Value
+}
+
+// fields : the options of c, in schema order
+func (c *Config) fields() []field {
+	return structFields(reflect.ValueOf(c).Elem(), "")
+}
+
+// structFields : the options of struct v and of its blocks
+func structFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	for i := 0; i < v.NumField(); i++ {
+		structField := v.Type().Field(i)
+		key := prefix + strings.Split(structField.Tag.Get("json"), ",")[0]
+		name, isOption := structField.Tag.Lookup("flag")
+		switch {
+		case isOption:
+			fields = append(fields, field{flag: name, key: key, value: newValue(v.Field(i))})
+		case structField.Type.Kind() == reflect.Struct:
+			fields = append(fields, structFields(v.Field(i), key+".")...)
+		}
+	}
+	return fields
+}
+
+// register : add a flag for every option of c to fs
+func (c *Config) register(fs *flag.FlagSet) {
+	for _, f := range c.fields() {
+		fs.Var(f.value, f.flag, usage(f.flag)+" - json "+f.key+", env "+EnvPrefix+strings.ToUpper(f.flag))
+	}
+}
+
+// value : the flag.Value of an option
+type value struct {
+	v reflect.Value
+}
+
+// newValue : the flag.Value of option v, the option types have their own
+func newValue(v reflect.Value) flag.Value {
+	if fv, ok := v.Addr().Interface().(flag.Value); ok {
+		return fv
+	}
+	return value{v}
+}
+
+// String : the value of the option
+func (o value) String() string {
+	if !o.v.IsValid() {
+		return ""
+	}
+	switch o.v.Kind() {
+	case reflect.Int:
+		return strconv.FormatInt(o.v.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(o.v.Float(), 'f', -1, 64)
+	}
+	return o.v.String()
+}
+
+// Set : set the option from its flag value
+func (o value) Set(s string) error {
+	switch o.v.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return errors.New("must be a whole number and not " + s)
+		}
+		o.v.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return errors.New("must be a number and not " + s)
+		}
+		o.v.SetFloat(n)
+	default:
+		o.v.SetString(s)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/ladder"
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/output"
+	"github.com/uccmisl/godash/predictor"
+	"github.com/uccmisl/godash/utils"
+)
+
+// the values of the options with a fixed set of values
+var (
+	// Codecs : the values of -codec
+	Codecs = []string{glob.RepRateCodecAVC, glob.RepRateCodecHEVC, glob.RepRateCodecVP9, glob.RepRateCodecAV1}
+	// AdaptAlgorithms : the values of -adapt
+	AdaptAlgorithms = []string{glob.ConventionalAlg, glob.ElasticAlg, glob.LogisticAlg, glob.TestAlg, glob.ProgressiveAlg, glob.MeanAverageAlg, glob.GeomAverageAlg, glob.EMWAAverageAlg, glob.ArbiterAlg, glob.BBAAlg, glob.MeanAverageXLAlg, glob.MeanAverageRecentXLAlg, glob.BBA1Alg_AV, glob.BBA1Alg_AVXL, glob.BBA2Alg_AV, glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_rate, glob.BBA2Alg_AVXL_double, glob.BOLABasicAlg, glob.BOLAEAlg, glob.BOLAEXLAlg, glob.MPCAlg, glob.MPCXLAlg, glob.L2AAlg, glob.LoLPAlg, glob.PensieveAlg, glob.PensieveXLAlg}
+	// HLSPolicies : the values of -hls
+	HLSPolicies = []string{glob.HlsOff, glob.HlsOn, glob.HlsPassive, glob.HlsCompetitive, glob.HlsAggressive, glob.HlsDynamic}
+	// GetHeaderModes : the values of -getHeaders
+	GetHeaderModes = []string{glob.GetHeaderOff, glob.GetHeaderOn, glob.GetHeaderOnline, glob.GetHeaderOffline}
+	// PrintHeaderNames : the columns of -printHeader
+	PrintHeaderNames = []string{glob.AlgoHeader, glob.SegDurHeader, glob.CodecHeader, glob.HeightHeader, glob.WidthHeader, glob.FpsHeader, glob.PlayHeader, glob.RttHeader, glob.SegReplaceHeader, glob.HTTPProtocolHeader, glob.P1203Header, glob.ClaeHeader, glob.DuanmuHeader, glob.YinHeader, glob.YuHeader, glob.AbortRateHeader, glob.AbortBytesHeader, glob.AbortTimeHeader, glob.AbortPredHeader, glob.AbortBuffHeader}
+	// AbortLogics : the values of -xlAbortLogic, empty uses the logic of -adapt
+	AbortLogics = []string{"", "base", "rate", "double"}
+	// Predictors : the values of -predictor, empty uses the estimator of -adapt
+	Predictors = append([]string{""}, predictor.Names...)
+)
+
+// MaxMPCHorizon : MPC plans over every combination of rep_rates, so the horizon is kept short
+const MaxMPCHorizon = 8
+
+// Validate :
+/*
+ * check every option of c, and the options that can not be used together
+ * all errors are returned at once, as Errors
+ */
+func (c Config) Validate() error {
+
+	var errs Errors
+	check := func(ok bool, flagName string, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, errors.New("-"+flagName+" "+fmt.Sprintf(format, args...)))
+		}
+	}
+	oneOf := func(values []string, value string, flagName string) {
+		used, _ := utils.FindInStringArray(values, value)
+		check(used, flagName, "must be one of %v and not %q", values, value)
+	}
+
+	check(c.Version == SchemaVersion, "version", "must be %d and not %d", SchemaVersion, c.Version)
+	check(c.URL != "" && !strings.HasPrefix(c.URL, "-"), glob.URLName, "is needed for the MPD location")
+	oneOf(AdaptAlgorithms, c.Adapt, glob.AdaptName)
+	oneOf(Codecs, c.Codec, glob.CodecName)
+	check(c.MaxHeight >= 1, glob.MaxHeightName, "must be a positive number and not %d", c.MaxHeight)
+	check(c.StreamDuration >= 0, glob.StreamDurationName, "must be a positive number and not %d", c.StreamDuration)
+	check(c.StreamSpeed > 0, glob.StreamSpeedName, "must be a positive number and not %g", c.StreamSpeed)
+	check(c.MaxBuffer >= 1, glob.MaxBufferName, "must be a positive number (in seconds) and not %d", c.MaxBuffer)
+	check(c.InitBuffer >= 0, glob.InitBufferName, "must be a positive number and not %d", c.InitBuffer)
+	oneOf(HLSPolicies, c.HLS, glob.HlsName)
+	oneOf(GetHeaderModes, c.GetHeaders, glob.GetHeaderName)
+	check(c.LogFile != "" && !strings.ContainsAny(c.LogFile, `/\`), glob.DebugFileName, "must be a file name and not %q", c.LogFile)
+	for header, value := range c.PrintHeader {
+		used, _ := utils.FindInStringArray(PrintHeaderNames, header)
+		check(used, glob.PrintHeaderName, "column %q must be one of %v", header, PrintHeaderNames)
+		check(value == "on" || value == "off", glob.PrintHeaderName, "column %s must be on or off and not %q", header, value)
+	}
+
+	if _, err := output.New(c.Output.Root, c.Output.RunID, c.Output.Template); err != nil {
+		errs = append(errs, errors.New("-"+glob.OutputTemplateName+" "+err.Error()))
+	}
+
+	for _, sink := range c.Metrics.Sinks {
+		oneOf(logging.MetricSinkNames, sink, glob.MetricsName)
+	}
+	check(c.Metrics.PollInterval > 0, glob.MetricsPollName, "must be a positive number (in milliseconds) and not %d", c.Metrics.PollInterval)
+
+	check(c.Clients.Count >= 1, glob.ClientsName, "must be a positive number and not %d", c.Clients.Count)
+	check(c.Clients.Stagger >= 0, glob.ClientStaggerName, "must be a positive number and not %g", c.Clients.Stagger)
+	check(len(c.Clients.Starts) == 0 || len(c.Clients.Starts) == c.Clients.Count, glob.ClientStartsName, "must have a start time for each of the %d clients", c.Clients.Count)
+	for _, start := range c.Clients.Starts {
+		check(start >= 0, glob.ClientStartsName, "must be a list of positive numbers and not %v", c.Clients.Starts)
+	}
+	if c.Clients.Count > 1 {
+		// every client needs its own consul node and metrics endpoint
+		check(!bool(c.Collab), glob.ClientsName, "can not be used with -%s on", glob.CollabPrintName)
+		prometheus, _ := utils.FindInStringArray(c.Metrics.Sinks, glob.MetricsSinkPrometheus)
+		check(!prometheus, glob.ClientsName, "can not be used with the %s metric sink", glob.MetricsSinkPrometheus)
+	}
+
+	check(c.Ladder.MaxWidth >= 0, glob.MaxWidthName, "must not be negative and not %d", c.Ladder.MaxWidth)
+	check(c.Ladder.MaxFrameRate >= 0, glob.MaxFrameRateName, "must not be negative and not %d", c.Ladder.MaxFrameRate)
+	check(c.Ladder.MaxBitrate >= 0, glob.MaxBitrateName, "must not be negative (in kbps) and not %d", c.Ladder.MaxBitrate)
+	check(c.Ladder.MaxPixels >= 0, glob.MaxPixelsName, "must not be negative and not %d", c.Ladder.MaxPixels)
+	for _, codec := range c.Ladder.CodecPreference {
+		oneOf(Codecs, codec, glob.CodecPreferenceName)
+	}
+	oneOf(ladder.DynamicRanges, c.Ladder.DynamicRange, glob.DynamicRangeName)
+	if c.Ladder.Screen != "" {
+		_, ok := ladder.Screen(c.Ladder.Screen)
+		check(ok, glob.ScreenName, "must be one of %v or <width>x<height> and not %q", ladder.ScreenNames, c.Ladder.Screen)
+	}
+	if _, err := ladder.Schedule(ladder.Constraints{}, c.Ladder.ScreenSchedule); err != nil {
+		errs = append(errs, errors.New("-"+glob.ScreenScheduleName+" "+err.Error()))
+	}
+
+	check(c.Algorithms.Exponential.Ratio >= 0 && c.Algorithms.Exponential.Ratio <= 1, glob.ExpRatioName, "must be between 0 and 1 and not %g", c.Algorithms.Exponential.Ratio)
+	check(c.Algorithms.BBA2.MinReservoir >= 1, glob.BBA2MinReservoirName, "must be a positive number (in segments) and not %d", c.Algorithms.BBA2.MinReservoir)
+	check(c.Algorithms.BBA2.UpperReservoir > 0 && c.Algorithms.BBA2.UpperReservoir < 1, glob.BBA2UpperReservoirName, "must be between 0 and 1 and not %g", c.Algorithms.BBA2.UpperReservoir)
+	check(c.Algorithms.BBA2.Horizon >= 1, glob.BBA2HorizonName, "must be a positive number and not %d", c.Algorithms.BBA2.Horizon)
+	check(c.Algorithms.BBA2.MaxReservoir > 0 && c.Algorithms.BBA2.MaxReservoir < 1, glob.BBA2MaxReservoirName, "must be between 0 and 1 and not %g", c.Algorithms.BBA2.MaxReservoir)
+	check(c.Algorithms.BBA2.VarianceWeight >= 0, glob.BBA2VarianceWeightName, "must not be negative and not %g", c.Algorithms.BBA2.VarianceWeight)
+	check(c.Algorithms.CrossLayer.PredictionWindow > 0 && c.Algorithms.CrossLayer.PredictionWindow <= 1, glob.XLPredictionWindowName, "must be above 0 and at most 1 and not %g", c.Algorithms.CrossLayer.PredictionWindow)
+	oneOf(AbortLogics, c.Algorithms.CrossLayer.AbortLogic, glob.XLAbortLogicName)
+	oneOf(Predictors, c.Algorithms.Predictor, glob.PredictorName)
+	check(c.Algorithms.MPC.Horizon >= 1 && c.Algorithms.MPC.Horizon <= MaxMPCHorizon, glob.MPCHorizonName, "must be between 1 and %d (in segments) and not %d", MaxMPCHorizon, c.Algorithms.MPC.Horizon)
+	check(c.Algorithms.MPC.RebufferPenalty >= 0, glob.MPCRebufferPenaltyName, "must not be negative and not %g", c.Algorithms.MPC.RebufferPenalty)
+	check(c.Algorithms.MPC.SwitchPenalty >= 0, glob.MPCSwitchPenaltyName, "must not be negative and not %g", c.Algorithms.MPC.SwitchPenalty)
+	check(c.Algorithms.LoLP.TargetLatency > 0, glob.LoLPTargetLatencyName, "must be a positive number (in seconds) and not %g", c.Algorithms.LoLP.TargetLatency)
+	check(c.Algorithms.LoLP.CatchupRate >= 0 && c.Algorithms.LoLP.CatchupRate <= 0.5, glob.LoLPCatchupRateName, "must be between 0 and 0.5 and not %g", c.Algorithms.LoLP.CatchupRate)
+	pensieve := c.Adapt == glob.PensieveAlg || c.Adapt == glob.PensieveXLAlg
+	check(!pensieve || c.Algorithms.Pensieve.Model != "", glob.PensieveModelName, "is needed by -%s %s", glob.AdaptName, c.Adapt)
+	check(c.Algorithms.FastStart.Safety > 0 && c.Algorithms.FastStart.Safety <= 1, glob.FastStartSafetyName, "must be above 0 and at most 1 and not %g", c.Algorithms.FastStart.Safety)
+	check(c.Algorithms.FastStart.Margin > 0 && c.Algorithms.FastStart.Margin <= 1, glob.FastStartMarginName, "must be above 0 and at most 1 and not %g", c.Algorithms.FastStart.Margin)
+	check(c.Algorithms.FastStart.StartupDelay > 0, glob.FastStartDelayName, "must be a positive number (in seconds) and not %g", c.Algorithms.FastStart.StartupDelay)
+	check(c.Algorithms.VBR.Horizon >= 1, glob.VBRHorizonName, "must be at least 1 (in segments) and not %d", c.Algorithms.VBR.Horizon)
+
+	return errs.errorOrNil()
+}
+
+// usage :
+// * the help of the flag of an option
+func usage(flagName string) string {
+	switch flagName {
+	case glob.URLName:
+		return "a list of urls specifying the location of the video clip MPD files or HLS master playlists - \"[<url>,<url>]\""
+	case glob.AdaptName:
+		return "DASH algorithms - \"" + strings.Join(AdaptAlgorithms, "|") + "\""
+	case glob.CodecName:
+		return "codec to use - used when accessing multi-codec MPD files - \"[" + strings.Join(Codecs, "|") + "]\""
+	case glob.MaxHeightName:
+		return "maximum height resolution to stream - defaults to maximum resolution height in MPD file"
+	case glob.MaxWidthName:
+		return "maximum width resolution to stream - 0 streams every width"
+	case glob.MaxFrameRateName:
+		return "maximum frame rate to stream - 0 streams every frame rate"
+	case glob.MaxBitrateName:
+		return "maximum rep_rate to stream in kbps - 0 streams every rep_rate"
+	case glob.MaxPixelsName:
+		return "maximum width times height to stream - 0 streams every size"
+	case glob.CodecPreferenceName:
+		return "comma separated list of codecs in order of preference, the first one in the MPD is streamed - replaces -" + glob.CodecName + " - \"[" + strings.Join(Codecs, "|") + "]\""
+	case glob.DynamicRangeName:
+		return "dynamic range of the video to stream - \"[" + strings.Join(ladder.DynamicRanges, "|") + "]\""
+	case glob.ScreenName:
+		return "screen size profile, limits the resolution and frame rate to stream - \"[" + strings.Join(ladder.ScreenNames, "|") + "|<width>x<height>]\""
+	case glob.ScreenScheduleName:
+		return "comma separated list of screens over the stream, \"<seconds>:<screen>\" - every screen replaces -" + glob.ScreenName + " from that time on, e.g. to simulate a window resize"
+	case glob.StreamDurationName:
+		return "number of seconds to stream - defaults to maximum stream duration in MPD file"
+	case glob.StreamSpeedName:
+		return "multiplier for speed of stream"
+	case glob.MaxBufferName:
+		return "maximum stream buffer in seconds"
+	case glob.InitBufferName:
+		return "initial number of segments to download before stream starts"
+	case glob.HlsName:
+		return "HLS setting - used for redownloading chunks at a higher quality rep_rate - \"" + strings.Join(HLSPolicies, "|") + "\""
+	case glob.QuicName:
+		return "download the stream using the QUIC transport protocol - \"[on|off]\""
+	case glob.UseTestBedName:
+		return "setup https certs and use goDASHbed testbed - \"[on|off]\""
+	case glob.QoEName:
+		return "print per segment QoE values (P1203 mode 0 and Claye) - \"[on|off]\""
+	case glob.GetHeaderName:
+		return "get the header information for all segments across all of the MPD urls - \"[" + strings.Join(GetHeaderModes, "|") + "]\" " + glob.GetHeaderOff + ": do not get headers, " + glob.GetHeaderOn + ": get all headers defined by MPD and stop the client, " + glob.GetHeaderOnline + ": get headers from webserver based on algorithm input and " + glob.GetHeaderOffline + ": get headers from header file based on algorithm input (file created by " + glob.GetHeaderOn + ")"
+	case glob.StoreFiles:
+		return "store the streamed DASH files, and associated files - \"[on|off]\""
+	case glob.FileStoreName:
+		return "folder location within the files folder of the run to store the streamed DASH files - if no folder is passed, output defaults to the files folder"
+	case glob.TerminalPrintName:
+		return "extend the output logs to provide additional information - \"[on|off]\""
+	case glob.PrintHeaderName:
+		return "print columns based on selected print headers, a json object of \"[" + strings.Join(PrintHeaderNames, "|") + "]\" and on or off"
+	case glob.DebugName:
+		return "set debug information for this video stream - \"[on|off]\""
+	case glob.DebugFileName:
+		return "name of the debug log, saved beneath the output of the run"
+	case glob.CollabPrintName:
+		return "implement Collaborative framework for streaming clients - \"[on|off]\""
+	case glob.OutputRootName:
+		return "folder every log, qlog and stored segment of the run is written beneath"
+	case glob.RunIDName:
+		return "id of the run, fills {run} of the output template"
+	case glob.OutputTemplateName:
+		return "location of the files of the run - {root} is the output root, {run} the run id, {dir} logs or files, {kind} the kind of file and {name} the file name"
+	case glob.MetricsName:
+		return "comma separated list of metric log sinks - \"[" + strings.Join(logging.MetricSinkNames, "|") + "]\""
+	case glob.MetricsPathName:
+		return "location of the metric logs, without extension - each file sink adds its own extension, defaults to " + glob.MetricsLogFile + " beneath the output of the run"
+	case glob.MetricsAddrName:
+		return "listen address of the " + glob.MetricsSinkPrometheus + " metric sink, serves /metrics while streaming"
+	case glob.MetricsPollName:
+		return "milliseconds between two buffer level metrics"
+	case glob.ClientsName:
+		return "number of clients to stream at the same time, every client has its own connection, ABR and output folder beneath the output of the run"
+	case glob.ClientStaggerName:
+		return "seconds between the start of consecutive clients of -" + glob.ClientsName
+	case glob.ClientStartsName:
+		return "start of every client of -" + glob.ClientsName + " in seconds after the first client - \"[<seconds>,<seconds>]\" - replaces -" + glob.ClientStaggerName
+	case glob.ExpRatioName:
+		return "download the stream with exponential parameter : ratio - this only works with the " + glob.EMWAAverageAlg + " algorithm"
+	case glob.BBA2MinReservoirName:
+		return "minimum size of the lower reservoir of the BBA-2 algorithms, in segments"
+	case glob.BBA2UpperReservoirName:
+		return "part of the buffer the upper reservoir of the BBA-2 algorithms takes, the highest rep_rate is streamed above it"
+	case glob.BBA2HorizonName:
+		return "number of max buffers of segments the lower reservoir of the BBA-2 algorithms is calculated over"
+	case glob.BBA2DynamicName:
+		return "size the reservoirs of the BBA-2 algorithms on the throughput variation and the RTT measured by the cross-layer accountant - \"[on|off]\""
+	case glob.BBA2MaxReservoirName:
+		return "part of the buffer the dynamic lower reservoir of -" + glob.BBA2DynamicName + " takes at most"
+	case glob.BBA2VarianceWeightName:
+		return "the dynamic lower reservoir of -" + glob.BBA2DynamicName + " grows by this part of the coefficient of variation of the throughput"
+	case glob.XLPredictionWindowName:
+		return "part of a segment the cross-layer stall predictor, or the abandonment rule of BOLA-E, waits for before it can abort the download"
+	case glob.MPCHorizonName:
+		return "number of segments the MPC algorithms plan over"
+	case glob.MPCRebufferPenaltyName:
+		return "QoE the MPC algorithms lose per second of rebuffering, in Mbps"
+	case glob.MPCSwitchPenaltyName:
+		return "QoE the MPC algorithms lose per Mbps of rep_rate switch"
+	case glob.MPCFastName:
+		return "select the rep_rate of the MPC algorithms from the precomputed FastMPC table - \"[on|off]\""
+	case glob.PredictorName:
+		return "throughput predictor of the algorithm - \"[" + strings.Join(Predictors[1:], "|") + "]\" - defaults to the estimator of -" + glob.AdaptName
+	case glob.LoLPTargetLatencyName:
+		return "latency in seconds the LoL+ algorithm keeps the stream at, the latency of a segment is the buffer level once it arrives"
+	case glob.LoLPCatchupRateName:
+		return "the LoL+ algorithm plays the stream at between 1 - rate and 1 + rate times -" + glob.StreamSpeedName + ", 0 keeps the speed"
+	case glob.PensieveModelName:
+		return "json file of the weights of the learned policy of the " + glob.PensieveAlg + " and " + glob.PensieveXLAlg + " algorithms"
+	case glob.FastStartName:
+		return "select the first rep_rate and the initial buffer from the capacity of the MPD and init segment downloads, needs -" + glob.QuicName + " on - \"[on|off]\""
+	case glob.FastStartSafetyName:
+		return "part of the capacity estimated by -" + glob.FastStartName + " the first rep_rate may take"
+	case glob.FastStartMarginName:
+		return "the initial buffer of -" + glob.FastStartName + " covers the next segment when the capacity drops to this part of the estimate, up to -" + glob.InitBufferName
+	case glob.FastStartDelayName:
+		return "seconds the initial buffer of -" + glob.FastStartName + " may take to download at the estimated capacity, the first rep_rate is the highest one that fits"
+	case glob.VBRName:
+		return "the throughput algorithms compare their throughput against the size of the next segments of every rep_rate instead of its bandwidth in the MPD - \"[on|off]\""
+	case glob.VBRHorizonName:
+		return "number of next segments the actual bitrate of a rep_rate is averaged over by -" + glob.VBRName
+	case glob.XLAbortLogicName:
+		return "abort logic of the cross-layer stall predictor - \"[base|rate|double]\" - defaults to the logic of -" + glob.AdaptName
+	}
+	return flagName
+}
@@ -3,12 +3,12 @@ package crosslayer
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/qlog"
 	"github.com/uccmisl/godash/logging"
+	abrqlog "github.com/uccmisl/godash/qlog"
 )
 
 type AbortLogic int
@@ -19,6 +19,31 @@ const (
 	Double AbortLogic = iota
 )
 
+// Minimum time between two throughput and completion events in the ABR qlog
+const qlogUpdateInterval = 50 * time.Millisecond
+
+func (l AbortLogic) String() string {
+	switch l {
+	case Base:
+		return "base"
+	case Rate:
+		return "rate"
+	case Double:
+		return "double"
+	}
+	return "unknown"
+}
+
+// Returns the abort logic of base, rate or double
+func ParseAbortLogic(name string) (AbortLogic, bool) {
+	for _, l := range []AbortLogic{Base, Rate, Double} {
+		if l.String() == name {
+			return l, true
+		}
+	}
+	return Base, false
+}
+
 type CrossLayerAccountant struct {
 	metricLogger *logging.MetricLogger
 
@@ -49,16 +74,46 @@ type CrossLayerAccountant struct {
 	m_nextSegmentLowerRepChunksize_bits int
 	m_predictionWindowPercentage        float32 // indicates the portion of the current segment that has to be downloaded before an bort can be called
 	m_lowerReservoir_ms                 int
+	m_abortPredictedTime_ms             int // predicted remaining download time when the segment was aborted
+	m_abortBufferLevel_ms               int // buffer level when the segment was aborted
 
 	m_abortLogic AbortLogic
+
+	// Arrival of the first and the last packet of the current segment, see SegmentStart
+	m_firstPacket time.Time
+	m_lastPacket  time.Time
+
+	// Variables for the crosslayer events of the ABR qlog
+	m_requestURL         string                // URL of the segment that is being downloaded
+	m_connectionID       string                // original destination connection ID of the QUIC connection, set on the listening accountant
+	m_inPredictionWindow bool                  // the current segment entered the prediction window
+	m_lastQlogUpdate     time.Time             // time of the last throughput and completion events
+	m_tracer             *abrqlog.StreamTracer // ABR qlog of the stream, nil drops the events
+
+	// Clock of the packet arrivals and the timers, nil is the wall clock
+	m_clock func() time.Time
+
+	// Startup phase of the connection, see startupAccounting.go
+	m_connected      bool // a packet of the connection has arrived
+	m_startupSamples []StartupSample
+	m_handshakeRTT   time.Duration
+	m_smoothedRTT    time.Duration
+	m_zeroRTT        bool
+
+	// Per-stream accounting, see streamAccounting.go
+	parent         *CrossLayerAccountant
+	streamMu       sync.Mutex
+	streamOwners   map[int64]*CrossLayerAccountant
+	pendingStreams map[int64][]streamPacket
 }
 
-func (a *CrossLayerAccountant) InitialisePredictor(metricLogger *logging.MetricLogger, abortLogic AbortLogic) {
+// Enables the stall predictor, predictionWindowPercentage of a segment is downloaded before it can be aborted
+func (a *CrossLayerAccountant) InitialisePredictor(metricLogger *logging.MetricLogger, abortLogic AbortLogic, predictionWindowPercentage float32) {
 	fmt.Println("Stall prediction enabled")
 	//a.predictionWindow = 20
 	a.predictStall = false
 	a.metricLogger = metricLogger
-	a.m_predictionWindowPercentage = 0.15
+	a.m_predictionWindowPercentage = predictionWindowPercentage
 	a.m_abortLogic = abortLogic
 }
 
@@ -72,7 +127,7 @@ func (a *CrossLayerAccountant) SegmentStart_predictStall(segDuration_s int, repL
 	a.m_lowestBit_bps = lowestBit_bps
 	a.StartTiming()
 	a.bufferLevel_atStartOfSegment_Milliseconds = currBufferLevel
-	a.time_atStartOfSegment = time.Now()
+	a.time_atStartOfSegment = a.now()
 	//fmt.Println("PREDICTORBUFFER: ", a.bufferLevel_atStartOfSegment_Milliseconds)
 	a.m_lowerReservoir_ms = lowerReservoir_ms
 
@@ -81,12 +136,96 @@ func (a *CrossLayerAccountant) SegmentStart_predictStall(segDuration_s int, repL
 	//fmt.Println("NUMBEROFPACKETS: ", len(a.throughputList))
 	a.throughputList = nil
 	a.arrivalTimes = nil
+	a.m_firstPacket = time.Time{}
+	a.m_lastPacket = time.Time{}
+	a.m_inPredictionWindow = false
+	a.m_lastQlogUpdate = time.Time{}
 	a.mu.Unlock()
 
 	a.segmentDuration_seconds = segDuration_s
 	a.representationBitrate = repLevel_kbps
 }
 
+// Starts the accounting of a segment without predicting stalls, ReceivedBytes counts the bytes of this segment
+func (a *CrossLayerAccountant) SegmentStart() {
+	a.mu.Lock()
+	a.throughputList = nil
+	a.arrivalTimes = nil
+	a.m_firstPacket = time.Time{}
+	a.m_lastPacket = time.Time{}
+	a.mu.Unlock()
+	a.StartTiming()
+}
+
+// Returns the packet-level throughput of the current segment in bits/second, between its first and its last packet
+// 0 if the segment has less than two packets
+func (a *CrossLayerAccountant) SegmentThroughput() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	elapsed := a.m_lastPacket.Sub(a.m_firstPacket).Seconds()
+	if len(a.throughputList) < 2 || elapsed <= 0 {
+		return 0
+	}
+	// the first packet arrives at the start of the interval
+	total := 0
+	for _, el := range a.throughputList[1:] {
+		total += el
+	}
+	return float64(total*8) / elapsed
+}
+
+// Sets the URL of the segment request the next predictions are about
+func (a *CrossLayerAccountant) SetRequestURL(url string) {
+	a.mu.Lock()
+	a.m_requestURL = url
+	a.mu.Unlock()
+}
+
+// Sets the QUIC connection ID the accountant listens to, as a hex string
+func (a *CrossLayerAccountant) SetConnectionID(connectionID string) {
+	a.mu.Lock()
+	a.m_connectionID = connectionID
+	a.mu.Unlock()
+}
+
+// Returns the QUIC connection ID, stream accountants share the one of their parent
+func (a *CrossLayerAccountant) ConnectionID() string {
+	if a.parent != nil {
+		return a.parent.ConnectionID()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.m_connectionID
+}
+
+func (a *CrossLayerAccountant) requestURL() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.m_requestURL
+}
+
+// Sets the ABR qlog the crosslayer events of the stream are written to
+func (a *CrossLayerAccountant) SetTracer(tracer *abrqlog.StreamTracer) {
+	a.m_tracer = tracer
+}
+
+// Sets the clock of the packet arrivals and the timers, the simulation runs the accountant on its own clock
+func (a *CrossLayerAccountant) SetClock(clock func() time.Time) {
+	a.m_clock = clock
+}
+
+func (a *CrossLayerAccountant) now() time.Time {
+	if a.m_clock != nil {
+		return a.m_clock()
+	}
+	return time.Now()
+}
+
+// Accounts a packet of length bytes that arrived now, for accountants that do not listen to a QUIC connection
+func (a *CrossLayerAccountant) PacketReceived(length int) {
+	a.packetReceived(length, a.now())
+}
+
 func (a *CrossLayerAccountant) SetTrackingEvents(trackEvents bool) {
 	a.trackEvents = trackEvents
 }
@@ -106,9 +245,17 @@ func (a *CrossLayerAccountant) stallPredictor() {
 	}
 	sum_bits := totalBytes * 8
 	// Time since first packet of this segment
-	windowTotalTime_ms := time.Since(a.arrivalTimes[0]).Milliseconds()
+	windowTotalTime_ms := a.now().Sub(a.arrivalTimes[0]).Milliseconds()
+	// Throughput and completion events are written at most every qlogUpdateInterval
+	logUpdate := windowTotalTime_ms > 0 && a.now().Sub(a.m_lastQlogUpdate) >= qlogUpdateInterval
+	if logUpdate {
+		a.m_lastQlogUpdate = a.now()
+	}
 	a.mu.Unlock()
 
+	requestURL := a.requestURL()
+	connectionID := a.ConnectionID()
+
 	var bitsToDownload int
 	var windowBitrate int
 
@@ -117,34 +264,28 @@ func (a *CrossLayerAccountant) stallPredictor() {
 		// bits / ms  := bits / ms
 		windowBitrate = sum_bits / int(windowTotalTime_ms)
 
-		a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-			TimeStamp: time.Now(),
-			Tag:       "WINDOWTHROUGHPUT",
-			Message:   strconv.Itoa(windowBitrate),
+		a.metricLogger.Log(logging.MetricWindowThroughput, float64(windowBitrate))
+		if logUpdate {
+			a.m_tracer.ThroughputEstimate(requestURL, connectionID, int64(windowBitrate)*1000, int64(sum_bits), time.Duration(windowTotalTime_ms)*time.Millisecond)
 		}
 	}
 
-	a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-		TimeStamp: time.Now(),
-		Tag:       "SUMBITS",
-		Message:   strconv.Itoa(sum_bits),
-	}
+	a.metricLogger.Log(logging.MetricSumBits, float64(sum_bits))
 
-	a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-		TimeStamp: time.Now(),
-		Tag:       "WINDOWTHRESHOLD",
-		Message:   fmt.Sprintf("%v", a.m_predictionWindowPercentage*float32(a.m_currentSegmentChunksize_bits)),
-	}
+	a.metricLogger.Log(logging.MetricWindowThreshold, float64(a.m_predictionWindowPercentage*float32(a.m_currentSegmentChunksize_bits)))
 
-	a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-		TimeStamp: time.Now(),
-		Tag:       "SEGMENTCHUNKSIZE",
-		Message:   strconv.Itoa(a.m_currentSegmentChunksize_bits),
-	}
+	a.metricLogger.Log(logging.MetricSegmentChunkSize, float64(a.m_currentSegmentChunksize_bits))
 
 	// Only do predictions when we have received enough packets
 	if float32(sum_bits) > a.m_predictionWindowPercentage*float32(a.m_currentSegmentChunksize_bits) && a.segmentDuration_seconds > 0 {
 		//fmt.Println("IN PREDICTION WINDOW")
+		a.mu.Lock()
+		enteredWindow := !a.m_inPredictionWindow
+		a.m_inPredictionWindow = true
+		a.mu.Unlock()
+		if enteredWindow {
+			a.m_tracer.PredictionWindowEntered(requestURL, connectionID, int64(sum_bits), int64(a.m_predictionWindowPercentage*float32(a.m_currentSegmentChunksize_bits)), int64(a.m_currentSegmentChunksize_bits))
+		}
 		/*
 				a.mu.Lock()
 
@@ -168,7 +309,7 @@ func (a *CrossLayerAccountant) stallPredictor() {
 		*/
 
 		// Only do predictions when we have received less bytes than we expect to receive
-		if sum_bits < a.m_currentSegmentChunksize_bits && windowTotalTime_ms > 0 {
+		if sum_bits < a.m_currentSegmentChunksize_bits && windowTotalTime_ms > 0 && windowBitrate > 0 {
 			// Time it will take in ms to download the remaining bits at this rate
 			requiredTime_ms := bitsToDownload / windowBitrate
 
@@ -178,34 +319,24 @@ func (a *CrossLayerAccountant) stallPredictor() {
 
 			level := a.calculateCurrentBufferLevel()
 
-			a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-				TimeStamp: time.Now(),
-				Tag:       "ABORTLOGIC_REQUIREDTIME",
-				Message:   strconv.Itoa(requiredTime_ms),
-			}
+			a.metricLogger.Log(logging.MetricAbortRequiredTime, float64(requiredTime_ms))
+
+			a.metricLogger.Log(logging.MetricAbortLevel, float64(level))
 
-			a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-				TimeStamp: time.Now(),
-				Tag:       "ABORTLOGIC_LEVEL",
-				Message:   strconv.Itoa(level),
+			if logUpdate {
+				a.m_tracer.CompletionPredicted(requestURL, connectionID, time.Duration(requiredTime_ms)*time.Millisecond, time.Duration(requiredTimeLowestThrough_ms)*time.Millisecond, time.Duration(level)*time.Millisecond)
 			}
 
-			/*a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-				TimeStamp: time.Now(),
-				Tag:       "DEBUGINFO",
-				Message:   "RequiredTime_ms " + strconv.Itoa(requiredTime_ms) + " level " + strconv.Itoa(level) + " requiredTimeLowestThrough_ms " + strconv.Itoa(requiredTimeLowestThrough_ms) + " sumbits " + strconv.Itoa(sum_bits) + " currChunk " + strconv.Itoa(a.m_currentSegmentChunksize_bits) + " bitstodownload " + strconv.Itoa(bitsToDownload) + " windowbitrate " + strconv.Itoa(windowBitrate) + " segmentsizelowestthrough " + strconv.Itoa(segmentSizeLowestThrough),
-			}*/
+			/*a.metricLogger.LogMessage(logging.MetricStallPredictorDetails, "RequiredTime_ms " + strconv.Itoa(requiredTime_ms) + " level " + strconv.Itoa(level) + " requiredTimeLowestThrough_ms " + strconv.Itoa(requiredTimeLowestThrough_ms) + " sumbits " + strconv.Itoa(sum_bits) + " currChunk " + strconv.Itoa(a.m_currentSegmentChunksize_bits) + " bitstodownload " + strconv.Itoa(bitsToDownload) + " windowbitrate " + strconv.Itoa(windowBitrate) + " segmentsizelowestthrough " + strconv.Itoa(segmentSizeLowestThrough))*/
 
 			if level <= a.m_lowerReservoir_ms {
 				if requiredTime_ms > level && requiredTimeLowestThrough_ms < requiredTime_ms {
 					// Report stall prediction
 					//fmt.Println("STALLPREDICTOR ", time.Now().UnixMilli())
-					a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-						TimeStamp: time.Now(),
-						Tag:       "STALLPREDICTOR",
-						Message:   "STALLPREDICTOR",
-					}
+					a.metricLogger.Log(logging.MetricStallPredictor)
 
+					a.recordAbort(requiredTime_ms, level)
+					a.m_tracer.AbortDecision(requestURL, connectionID, Base.String(), time.Duration(requiredTime_ms)*time.Millisecond, time.Duration(level)*time.Millisecond)
 					*a.m_aborted = true
 					a.m_cancel()
 				} else {
@@ -214,12 +345,10 @@ func (a *CrossLayerAccountant) stallPredictor() {
 						requiredTimeNext_ms := a.m_nextSegmentLowerRepChunksize_bits / windowBitrate
 						// We predict that the current segment will be downloaded in time, but if the buffer is filled with one segment and we scale one representation downn, will the next segment be downloaded in time at the current rate?
 						if requiredTimeNext_ms+requiredTime_ms > level+(a.segmentDuration_seconds*1000) {
-							a.metricLogger.WriteChannel <- logging.MetricLoggingFormat{
-								TimeStamp: time.Now(),
-								Tag:       "STALLPREDICTOR",
-								Message:   "STALLPREDICTOR",
-							}
+							a.metricLogger.Log(logging.MetricStallPredictor)
 
+							a.recordAbort(requiredTimeNext_ms+requiredTime_ms, level)
+							a.m_tracer.AbortDecision(requestURL, connectionID, Double.String(), time.Duration(requiredTimeNext_ms+requiredTime_ms)*time.Millisecond, time.Duration(level)*time.Millisecond)
 							*a.m_aborted = true
 							a.m_cancel()
 						}
@@ -230,8 +359,23 @@ func (a *CrossLayerAccountant) stallPredictor() {
 	}
 }
 
+// Saves the prediction the stall predictor aborted the current segment on
+func (a *CrossLayerAccountant) recordAbort(predictedTime_ms int, bufferLevel_ms int) {
+	a.mu.Lock()
+	a.m_abortPredictedTime_ms = predictedTime_ms
+	a.m_abortBufferLevel_ms = bufferLevel_ms
+	a.mu.Unlock()
+}
+
+// Returns the predicted remaining download time and the buffer level in milliseconds when the current segment was aborted
+func (a *CrossLayerAccountant) AbortPrediction() (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.m_abortPredictedTime_ms, a.m_abortBufferLevel_ms
+}
+
 func (a *CrossLayerAccountant) calculateCurrentBufferLevel() int {
-	passedTime := time.Since(a.time_atStartOfSegment).Milliseconds()
+	passedTime := a.now().Sub(a.time_atStartOfSegment).Milliseconds()
 	level := a.bufferLevel_atStartOfSegment_Milliseconds - int(passedTime)
 
 	// Buffer cannot go below 0
@@ -254,24 +398,50 @@ func (a *CrossLayerAccountant) channelListenerThread() {
 				//fmt.Println(eventType)
 				packetReceivedPointer := details.(*qlog.EventPacketReceived)
 				//fmt.Println(packetReceivedPointer.Length)
-				a.mu.Lock()
-				a.throughputList = append(a.throughputList, int(packetReceivedPointer.Length))
-				a.mu.Unlock()
-
-				// If we are doing stall predictions, calculate prediction after this packet is received
-				if a.predictStall {
-					// Measure arrival time as well
-					a.mu.Lock()
-					a.arrivalTimes = append(a.arrivalTimes, time.Now())
-					a.mu.Unlock()
+				a.packetReceived(int(packetReceivedPointer.Length), a.now())
 
-					a.stallPredictor()
-				}
+				// Hand the packet to the accountants of the streams it carries
+				a.dispatchStreamPacket(packetReceivedPointer)
+			} else {
+				a.connectionEvent(msg)
 			}
 		}
 	}
 }
 
+// Accounts one received packet of the given length in bytes
+func (a *CrossLayerAccountant) packetReceived(length int, arrival time.Time) {
+	a.mu.Lock()
+	a.throughputList = append(a.throughputList, length)
+	if a.m_firstPacket.IsZero() {
+		a.m_firstPacket = arrival
+	}
+	a.m_lastPacket = arrival
+	a.m_connected = true
+	a.mu.Unlock()
+
+	// If we are doing stall predictions, calculate prediction after this packet is received
+	if a.predictStall {
+		// Measure arrival time as well
+		a.mu.Lock()
+		a.arrivalTimes = append(a.arrivalTimes, arrival)
+		a.mu.Unlock()
+
+		a.stallPredictor()
+	}
+}
+
+// Returns the number of bytes received since the start of the current segment
+func (a *CrossLayerAccountant) ReceivedBytes() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	total := 0
+	for _, el := range a.throughputList {
+		total += el
+	}
+	return total
+}
+
 /**
 * Returns average measured throughput in bits/second
  */
@@ -323,7 +493,7 @@ func (a *CrossLayerAccountant) GetRecentAverageThroughput() float64 {
 
 // Should be called when we start downloading a segment
 func (a *CrossLayerAccountant) StartTiming() {
-	a.currStartTime = time.Now()
+	a.currStartTime = a.now()
 	a.currentlyTiming = true
 }
 
@@ -331,7 +501,7 @@ func (a *CrossLayerAccountant) StartTiming() {
 func (a *CrossLayerAccountant) StopTiming() int {
 	a.predictStall = false
 	if a.currentlyTiming {
-		currPassedTime := time.Since(a.currStartTime)
+		currPassedTime := a.now().Sub(a.currStartTime)
 		currPassedTime_ms := currPassedTime.Milliseconds()
 
 		a.totalPassed_ms += currPassedTime_ms
@@ -347,7 +517,7 @@ func (a *CrossLayerAccountant) StopTiming() int {
 func (a *CrossLayerAccountant) getTotalTime() int64 {
 	// If we are currently timing, calculate the current passed time and add it to the total before returning
 	if a.currentlyTiming {
-		currPassedTime := time.Since(a.currStartTime)
+		currPassedTime := a.now().Sub(a.currStartTime)
 		currPassedTime_ms := currPassedTime.Milliseconds()
 		return a.totalPassed_ms + currPassedTime_ms
 	} else {
@@ -0,0 +1,120 @@
+package crosslayer
+
+import (
+	"time"
+
+	quiclogging "github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+// A download of the startup phase (the MPD, an init segment), measured on the packets of its QUIC stream
+type StartupSample struct {
+	Bits    int // bits of the packets of the response
+	Packets int
+	// Time between the first and the last packet of the response
+	Transfer time.Duration
+	// Time between the request and the last packet of the response
+	Request time.Duration
+	// The request opened the QUIC connection, so it waited for the handshake
+	Handshake bool
+}
+
+// Starts a startup sample, the packets the returned accountant receives until done is called are the sample.
+// Put the probe in the context of the request with WithAccountant, it claims the QUIC stream of the response.
+func (a *CrossLayerAccountant) StartupProbe() (*CrossLayerAccountant, func()) {
+	root := a
+	if a.parent != nil {
+		root = a.parent
+	}
+	root.mu.Lock()
+	handshake := !root.m_connected
+	root.mu.Unlock()
+
+	probe := root.NewStreamAccountant()
+	probe.SegmentStart()
+	done := func() {
+		request := time.Duration(probe.StopTiming()) * time.Millisecond
+		probe.mu.Lock()
+		sample := StartupSample{
+			Packets:   len(probe.throughputList),
+			Transfer:  probe.m_lastPacket.Sub(probe.m_firstPacket),
+			Request:   request,
+			Handshake: handshake,
+		}
+		for _, el := range probe.throughputList {
+			sample.Bits += el * 8
+		}
+		probe.mu.Unlock()
+
+		root.mu.Lock()
+		root.m_startupSamples = append(root.m_startupSamples, sample)
+		root.mu.Unlock()
+	}
+	return probe, done
+}
+
+// Returns the startup samples of the connection, in the order they were taken
+func (a *CrossLayerAccountant) StartupSamples() []StartupSample {
+	if a.parent != nil {
+		return a.parent.StartupSamples()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]StartupSample(nil), a.m_startupSamples...)
+}
+
+// Returns the first RTT sample of the connection, which QUIC takes during the handshake, 0 if there is none yet
+func (a *CrossLayerAccountant) HandshakeRTT() time.Duration {
+	if a.parent != nil {
+		return a.parent.HandshakeRTT()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.m_handshakeRTT
+}
+
+// Returns the smoothed RTT of the connection, 0 if there is none yet
+func (a *CrossLayerAccountant) SmoothedRTT() time.Duration {
+	if a.parent != nil {
+		return a.parent.SmoothedRTT()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.m_smoothedRTT
+}
+
+// Returns true if the connection sent 0-RTT packets, its first request did not wait for the handshake
+func (a *CrossLayerAccountant) ZeroRTT() bool {
+	if a.parent != nil {
+		return a.parent.ZeroRTT()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.m_zeroRTT
+}
+
+// Accounts an RTT sample of the connection, for accountants that do not listen to a QUIC connection
+func (a *CrossLayerAccountant) RTTMeasured(latest time.Duration, smoothed time.Duration) {
+	a.mu.Lock()
+	if a.m_handshakeRTT == 0 {
+		a.m_handshakeRTT = latest
+	}
+	a.m_smoothedRTT = smoothed
+	a.mu.Unlock()
+}
+
+// Keeps the RTT and the 0-RTT use of the connection from its QUIC events
+func (a *CrossLayerAccountant) connectionEvent(details qlog.Event) {
+	switch event := details.GetEventDetails().(type) {
+	case *qlog.EventMetricsUpdated:
+		if event.Current != nil && event.Current.LatestRTT > 0 {
+			a.RTTMeasured(event.Current.LatestRTT, event.Current.SmoothedRTT)
+		}
+	case *qlog.EventPacketSent:
+		if event.Header.PacketType == quiclogging.PacketType0RTT {
+			a.mu.Lock()
+			a.m_zeroRTT = true
+			a.mu.Unlock()
+		}
+	}
+}
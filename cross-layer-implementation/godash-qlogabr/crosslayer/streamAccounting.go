@@ -0,0 +1,112 @@
+package crosslayer
+
+import (
+	"context"
+	"time"
+
+	quiclogging "github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+// Packets of streams nobody claimed are dropped after this time
+const pendingStreamTimeout = 10 * time.Second
+
+// A packet (or the part of it) that belongs to one QUIC stream
+type streamPacket struct {
+	length  int
+	arrival time.Time
+}
+
+type accountantKey struct{}
+
+// Creates an accountant for one adaptation set that shares the QUIC connection of a.
+// It does not listen to the event channel itself, a hands it the packets of the streams it claimed.
+func (a *CrossLayerAccountant) NewStreamAccountant() *CrossLayerAccountant {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+
+	if a.streamOwners == nil {
+		a.streamOwners = make(map[int64]*CrossLayerAccountant)
+		a.pendingStreams = make(map[int64][]streamPacket)
+	}
+
+	return &CrossLayerAccountant{
+		parent:                       a,
+		metricLogger:                 a.metricLogger,
+		trackEvents:                  true,
+		m_predictionWindowPercentage: a.m_predictionWindowPercentage,
+		m_abortLogic:                 a.m_abortLogic,
+		m_tracer:                     a.m_tracer,
+		m_clock:                      a.m_clock,
+	}
+}
+
+// Attributes all packets of the QUIC stream to this accountant, including the ones that arrived
+// before the response was returned to us
+func (a *CrossLayerAccountant) ClaimStream(streamID int64) {
+	root := a.parent
+	if root == nil {
+		// The listening accountant already counts every packet
+		return
+	}
+
+	root.streamMu.Lock()
+	defer root.streamMu.Unlock()
+
+	root.streamOwners[streamID] = a
+	for _, packet := range root.pendingStreams[streamID] {
+		a.packetReceived(packet.length, packet.arrival)
+	}
+	delete(root.pendingStreams, streamID)
+
+	// Forget the streams that were never claimed (manifests, probes, ...)
+	for id, packets := range root.pendingStreams {
+		if time.Since(packets[len(packets)-1].arrival) > pendingStreamTimeout {
+			delete(root.pendingStreams, id)
+		}
+	}
+}
+
+// Splits a received packet over the streams it carries.
+// A packet that carries a single stream is counted with its full length, like the listening accountant does,
+// otherwise every stream gets the length of its own STREAM frames.
+func (a *CrossLayerAccountant) dispatchStreamPacket(packet *qlog.EventPacketReceived) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+
+	if a.streamOwners == nil {
+		return
+	}
+
+	streamBytes := make(map[int64]int)
+	for _, f := range packet.Frames {
+		if streamFrame, ok := f.Frame.(*quiclogging.StreamFrame); ok {
+			streamBytes[int64(streamFrame.StreamID)] += int(streamFrame.Length)
+		}
+	}
+	if len(streamBytes) == 1 {
+		for id := range streamBytes {
+			streamBytes[id] = int(packet.Length)
+		}
+	}
+
+	arrival := time.Now()
+	for id, length := range streamBytes {
+		if owner, ok := a.streamOwners[id]; ok {
+			owner.packetReceived(length, arrival)
+		} else {
+			a.pendingStreams[id] = append(a.pendingStreams[id], streamPacket{length: length, arrival: arrival})
+		}
+	}
+}
+
+// Returns a copy of ctx that carries the accountant, so the HTTP client can hand it the stream of the request
+func WithAccountant(ctx context.Context, a *CrossLayerAccountant) context.Context {
+	return context.WithValue(ctx, accountantKey{}, a)
+}
+
+// Returns the accountant added by WithAccountant, or nil
+func AccountantFromContext(ctx context.Context) *CrossLayerAccountant {
+	a, _ := ctx.Value(accountantKey{}).(*CrossLayerAccountant)
+	return a
+}
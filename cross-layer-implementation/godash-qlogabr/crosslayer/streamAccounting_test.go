@@ -0,0 +1,79 @@
+package crosslayer
+
+import (
+	"testing"
+
+	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+type nopWriteCloser struct{}
+
+func (nopWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (nopWriteCloser) Close() error                { return nil }
+
+// a received packet of the length that carries the STREAM frames of the stream ids
+type testPacket struct {
+	length  logging.ByteCount
+	streams map[logging.StreamID]logging.ByteCount
+}
+
+func TestStreamAccounting(t *testing.T) {
+	root := &CrossLayerAccountant{EventChannel: make(chan qlog.Event, 16), trackEvents: true}
+	video := root.NewStreamAccountant()
+	audio := root.NewStreamAccountant()
+	// the video response is returned before its packets arrive, the audio response after
+	video.ClaimStream(0)
+
+	// the packets of the video stream 0 and the audio stream 4 are interleaved on the connection,
+	// stream 8 is a manifest nobody claims
+	packets := []testPacket{
+		{1200, map[logging.StreamID]logging.ByteCount{0: 1150}},
+		{600, map[logging.StreamID]logging.ByteCount{4: 560}},
+		{1200, map[logging.StreamID]logging.ByteCount{0: 1150}},
+		{800, map[logging.StreamID]logging.ByteCount{0: 300, 4: 400}},
+		{50, nil},
+		{900, map[logging.StreamID]logging.ByteCount{4: 850}},
+		{300, map[logging.StreamID]logging.ByteCount{8: 250}},
+		{1200, map[logging.StreamID]logging.ByteCount{0: 1150}},
+	}
+	tracer := qlog.NewConnectionTracer(nopWriteCloser{}, logging.PerspectiveClient, logging.ConnectionID{}, root.EventChannel)
+	for _, packet := range packets {
+		frames := []logging.Frame{&logging.PingFrame{}}
+		for id, length := range packet.streams {
+			frames = append(frames, &logging.StreamFrame{StreamID: id, Length: length})
+		}
+		tracer.ReceivedShortHeaderPacket(&logging.ShortHeader{}, packet.length, frames)
+	}
+	tracer.Close()
+	close(root.EventChannel)
+	root.channelListenerThread()
+
+	audio.ClaimStream(4)
+
+	// a packet of a single stream counts with its full length, a shared packet with the length of the frames
+	for _, test := range []struct {
+		name       string
+		accountant *CrossLayerAccountant
+		want       int
+	}{
+		{"connection", root, 1200 + 600 + 1200 + 800 + 50 + 900 + 300 + 1200},
+		{"video", video, 1200 + 1200 + 300 + 1200},
+		{"audio", audio, 600 + 400 + 900},
+	} {
+		if got := test.accountant.ReceivedBytes(); got != test.want {
+			t.Errorf("the %s accountant received %d bytes, want %d", test.name, got, test.want)
+		}
+	}
+	if len(audio.throughputList) != 3 {
+		t.Errorf("the audio accountant received %d packets, want 3", len(audio.throughputList))
+	}
+
+	// the claimed streams are no longer pending, the manifest still is
+	if _, ok := root.pendingStreams[4]; ok {
+		t.Errorf("the claimed audio stream is still pending")
+	}
+	if packets := root.pendingStreams[8]; len(packets) != 1 || packets[0].length != 300 {
+		t.Errorf("the unclaimed stream has the pending packets %+v", packets)
+	}
+}
@@ -30,24 +30,26 @@ const Conversion1024 = 1024
 // DebugFileName : debug log name
 var DebugFileName = "logFile"
 
-// DebugFolder : debug log folder location
-const DebugFolder = "./logs/"
-
 // DebugTextFile : debug log file location
 const DebugTextFile = "log_file"
 
 // FileFormat : debug file format
 const FileFormat = ".txt"
 
-// DebugFile : debug log folder + file + FileFormat
-var DebugFile = DebugFolder + DebugTextFile + FileFormat
-
 // Contains metrics for post-run analysis
 var MetricsLogFile = "metrics_log"
-var MetricsLogLoctation = DebugFolder + MetricsLogFile + FileFormat
 
-// DownloadFileStoreName : where to save the downloaded files
-var DownloadFileStoreName = "./files/"
+// MetricsPrometheusAddr : default listen address of the prometheus metric sink
+const MetricsPrometheusAddr = ":9464"
+
+// SessionSummaryFile : QoE report of the stream, saved at the end of the stream
+const SessionSummaryFile = "session_summary.json"
+
+// ConfigFile : the effective config of the run, saved before the stream starts
+const ConfigFile = "config.json"
+
+// FairnessReportFile : Jain's fairness index of the clients of a multi-client run
+const FairnessReportFile = "fairness_report.json"
 
 // LogDownload : where to save the log download text
 const LogDownload = "logDownload.txt"
@@ -112,6 +114,48 @@ const BBA2Alg_AVXL_rate = "bba2XL-rate"
 // Cross-layer version with double segment prediction
 const BBA2Alg_AVXL_double = "bba2XL-double"
 
+// BOLABasicAlg : BOLA-BASIC, the Lyapunov-based buffer algorithm
+const BOLABasicAlg = "bola"
+
+// BOLAEAlg : BOLA-E, with placeholder buffer, insufficient buffer rule and segment abandonment
+const BOLAEAlg = "bolaE"
+
+// BOLAEXLAlg : BOLA-E with the mid-download rate of the cross-layer accountant
+const BOLAEXLAlg = "bolaEXL"
+
+// MPCAlg : RobustMPC, or FastMPC with -mpcFast, on the throughput of the segments
+const MPCAlg = "mpc"
+
+// MPCXLAlg : MPC on the packet-level throughput of the cross-layer accountant
+const MPCXLAlg = "mpcXL"
+
+// L2AAlg : L2A-LL, the online learning low latency algorithm of dash.js
+const L2AAlg = "l2a"
+
+// LoLPAlg : LoL+, the self-organising map low latency algorithm of dash.js, which also sets the playback rate
+const LoLPAlg = "lolp"
+
+// PensieveAlg : a learned Pensieve policy, loaded from -pensieveModel
+const PensieveAlg = "pensieve"
+
+// PensieveXLAlg : a learned Pensieve policy with the packet-level throughput of the cross-layer accountant as an input
+const PensieveXLAlg = "pensieveXL"
+
+// PredictorHarmonic : throughput predictor, the harmonic mean of the last segments
+const PredictorHarmonic = "harmonic"
+
+// PredictorEWMA : throughput predictor, the fast and slow exponential averages of dash.js
+const PredictorEWMA = "ewma"
+
+// PredictorHoltWinters : throughput predictor, exponential smoothing of the level and trend
+const PredictorHoltWinters = "holtWinters"
+
+// PredictorQuantile : throughput predictor, the 10th percentile of the last segments
+const PredictorQuantile = "quantile"
+
+// PredictorKalman : throughput predictor, a Kalman filter of the segment and cross-layer samples
+const PredictorKalman = "kalman"
+
 // TestAlg : test constants for our algorithms
 const TestAlg = "test"
 
@@ -122,12 +166,51 @@ const ArbiterAlg = "arbiter"
 const MeanAverageXLAlg = "averageXL"
 const MeanAverageRecentXLAlg = "averageRecentXL"
 
+// DynamicRangeAny : constants for the dynamic range of the representations
+const DynamicRangeAny = "any"
+
+// DynamicRangeSDR : constants for the dynamic range of the representations
+const DynamicRangeSDR = "sdr"
+
+// DynamicRangeHDR : constants for the dynamic range of the representations
+const DynamicRangeHDR = "hdr"
+
+// ScreenPhone : constants for the screen profiles
+const ScreenPhone = "phone"
+
+// ScreenTablet : constants for the screen profiles
+const ScreenTablet = "tablet"
+
+// ScreenLaptop : constants for the screen profiles
+const ScreenLaptop = "laptop"
+
+// ScreenTV : constants for the screen profiles
+const ScreenTV = "tv"
+
 // HlsOff : constants for HLS
 const HlsOff = "off"
 
-// HlsOn : constants for HLS
+// HlsOn : constants for HLS - same as the passive replacement policy
 const HlsOn = "on"
 
+// HlsPassive : constants for HLS - least amount of segment replacement
+const HlsPassive = "passive"
+
+// HlsCompetitive : constants for HLS - only replace with spare bandwidth, for when clients compete
+const HlsCompetitive = "competitive"
+
+// HlsAggressive : constants for HLS - highest amount of segment replacement
+const HlsAggressive = "aggressive"
+
+// HlsDynamic : constants for HLS - switch between the other policies based on the buffer level
+const HlsDynamic = "dynamic"
+
+// SegReplaceYes : SegReplace value for a segment replaced by HLS
+const SegReplaceYes = "yes"
+
+// SegReplaceNo : SegReplace value for a segment not replaced by HLS
+const SegReplaceNo = "no"
+
 // TrueBool : true string for booleans
 const TrueBool = "true"
 
@@ -245,6 +328,141 @@ const QuicOff = "off"
 // QuicOn : constants for Extend
 const QuicOn = "on"
 
+// MetricsName : parameter variables
+const MetricsName = "metrics"
+
+// MetricsPathName : parameter variables
+const MetricsPathName = "metricsPath"
+
+// MetricsAddrName : parameter variables
+const MetricsAddrName = "metricsAddr"
+
+// OutputRootName : parameter variables
+const OutputRootName = "outputRoot"
+
+// RunIDName : parameter variables
+const RunIDName = "runID"
+
+// OutputTemplateName : parameter variables
+const OutputTemplateName = "outputTemplate"
+
+// MetricsPollName : parameter variables
+const MetricsPollName = "metricsPoll"
+
+// ClientsName : parameter variables
+const ClientsName = "clients"
+
+// ClientStaggerName : parameter variables
+const ClientStaggerName = "clientStagger"
+
+// ClientStartsName : parameter variables
+const ClientStartsName = "clientStarts"
+
+// DumpConfigName : parameter variables
+const DumpConfigName = "dumpConfig"
+
+// BBA2MinReservoirName : parameter variables
+const BBA2MinReservoirName = "bba2MinReservoir"
+
+// BBA2UpperReservoirName : parameter variables
+const BBA2UpperReservoirName = "bba2UpperReservoir"
+
+// BBA2HorizonName : parameter variables
+const BBA2HorizonName = "bba2Horizon"
+
+// MaxWidthName : parameter variables
+const MaxWidthName = "maxWidth"
+
+// MaxFrameRateName : parameter variables
+const MaxFrameRateName = "maxFrameRate"
+
+// MaxBitrateName : parameter variables
+const MaxBitrateName = "maxBitrate"
+
+// MaxPixelsName : parameter variables
+const MaxPixelsName = "maxPixels"
+
+// CodecPreferenceName : parameter variables
+const CodecPreferenceName = "codecPreference"
+
+// DynamicRangeName : parameter variables
+const DynamicRangeName = "dynamicRange"
+
+// ScreenName : parameter variables
+const ScreenName = "screen"
+
+// ScreenScheduleName : parameter variables
+const ScreenScheduleName = "screenSchedule"
+
+// BBA2DynamicName : parameter variables
+const BBA2DynamicName = "bba2Dynamic"
+
+// BBA2MaxReservoirName : parameter variables
+const BBA2MaxReservoirName = "bba2MaxReservoir"
+
+// BBA2VarianceWeightName : parameter variables
+const BBA2VarianceWeightName = "bba2VarianceWeight"
+
+// XLPredictionWindowName : parameter variables
+const XLPredictionWindowName = "xlPredictionWindow"
+
+// XLAbortLogicName : parameter variables
+const XLAbortLogicName = "xlAbortLogic"
+
+// MPCHorizonName : parameter variables
+const MPCHorizonName = "mpcHorizon"
+
+// MPCRebufferPenaltyName : parameter variables
+const MPCRebufferPenaltyName = "mpcRebufferPenalty"
+
+// MPCSwitchPenaltyName : parameter variables
+const MPCSwitchPenaltyName = "mpcSwitchPenalty"
+
+// MPCFastName : parameter variables
+const MPCFastName = "mpcFast"
+
+// PredictorName : parameter variables
+const PredictorName = "predictor"
+
+// LoLPTargetLatencyName : parameter variables
+const LoLPTargetLatencyName = "lolpTargetLatency"
+
+// LoLPCatchupRateName : parameter variables
+const LoLPCatchupRateName = "lolpCatchupRate"
+
+// PensieveModelName : parameter variables
+const PensieveModelName = "pensieveModel"
+
+// FastStartName : parameter variables
+const FastStartName = "fastStart"
+
+// FastStartSafetyName : parameter variables
+const FastStartSafetyName = "fastStartSafety"
+
+// FastStartMarginName : parameter variables
+const FastStartMarginName = "fastStartMargin"
+
+// FastStartDelayName : parameter variables
+const FastStartDelayName = "fastStartDelay"
+
+// VBRName : parameter variables
+const VBRName = "vbr"
+
+// VBRHorizonName : parameter variables
+const VBRHorizonName = "vbrHorizon"
+
+// MetricsSinkText : metric sink for the "<ms> <TAG> <values>" text log
+const MetricsSinkText = "text"
+
+// MetricsSinkCSV : metric sink for a csv file
+const MetricsSinkCSV = "csv"
+
+// MetricsSinkNDJSON : metric sink for a newline delimited json file
+const MetricsSinkNDJSON = "ndjson"
+
+// MetricsSinkPrometheus : metric sink for a prometheus endpoint
+const MetricsSinkPrometheus = "prometheus"
+
 // UseTestBedName : parameter variables
 const UseTestBedName = "useTestbed"
 
@@ -275,9 +493,6 @@ const P1203maxWidth = 1920
 // P1203maxHeight : P1203 standard max Height
 const P1203maxHeight = 1080
 
-// P1203exec : executable for P1203
-const P1203exec = "p1203-standalone"
-
 // InsecureSSL :  "Accept/Ignore all server SSL certificates"
 const InsecureSSL = true
 
@@ -362,3 +577,18 @@ const YinHeader = "Yin"
 
 // YuHeader : header for
 const YuHeader = "Yu"
+
+// AbortRateHeader : header for the rep_rate of a download cancelled by the stall predictor
+const AbortRateHeader = "Abort_Rate"
+
+// AbortBytesHeader : header for the bytes received before the download was cancelled
+const AbortBytesHeader = "Abort_Bytes"
+
+// AbortTimeHeader : header for the time the cancelled download ran
+const AbortTimeHeader = "Abort_Time"
+
+// AbortPredHeader : header for the predicted remaining download time when the download was cancelled
+const AbortPredHeader = "Abort_Pred"
+
+// AbortBuffHeader : header for the buffer level when the download was cancelled
+const AbortBuffHeader = "Abort_Buff"
@@ -0,0 +1,498 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package godash : embed the goDASH player in a Go program
+/*
+ * a Client streams one DASH or HLS stream, with its own HTTP client, qlog and ABR state,
+ * so several clients can stream in the same process
+ *
+ *	client, err := godash.New(godash.Options{URL: url, Adapt: "conventional"})
+ *	if err != nil { ... }
+ *	err = client.Run(ctx)
+ *	stats := client.Stats()
+ */
+package godash
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/qlog"
+	"github.com/uccmisl/godash/P2Pconsul"
+	algo "github.com/uccmisl/godash/algorithms"
+	xlayer "github.com/uccmisl/godash/crosslayer"
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/http"
+	"github.com/uccmisl/godash/ladder"
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/output"
+	"github.com/uccmisl/godash/player"
+	"github.com/uccmisl/godash/predictor"
+	abrqlog "github.com/uccmisl/godash/qlog"
+	"github.com/uccmisl/godash/qoe"
+	"github.com/uccmisl/godash/utils"
+)
+
+// Options : the settings of a client, the zero values are the defaults of the goDASH flags
+type Options struct {
+	// URL of the MPD files or HLS master playlists - "[<url>,<url>]"
+	URL string
+	// Adapt is the ABR algorithm, "conventional" by default
+	Adapt string
+	// Codec to use with multi-codec MPD files, "h264" by default
+	Codec string
+	// CodecPreference : the codecs in order of preference, the first one in the first MPD replaces Codec
+	CodecPreference []string
+	// MaxHeight of the representations, 2160 by default
+	MaxHeight int
+	// Constraints on the rep_rates of the video, the zero Constraints streams every rep_rate
+	Constraints ladder.Constraints
+	// ScreenSchedule replaces the constraints during the stream, see ladder.Schedule
+	ScreenSchedule []ladder.Change
+	// StreamDuration to stream, 0 streams the whole MPD
+	StreamDuration time.Duration
+	// StreamSpeed is the playback speed, 1 by default
+	StreamSpeed float64
+	// MaxBuffer in seconds, 30 by default
+	MaxBuffer int
+	// InitBuffer is the number of segments downloaded before the playback starts, 2 by default
+	InitBuffer int
+	// HLS is the segment replacement policy, "off" by default
+	HLS string
+	// QUIC downloads the stream over HTTP/3
+	QUIC       bool
+	UseTestbed bool
+	// QoE computes the P.1203 and Claye values of every segment
+	QoE       bool
+	SaveFiles bool
+	// FileDownloadLocation of the stored segments, the segments folder of Output by default
+	FileDownloadLocation string
+	// PrintHeaders are the extra columns of the segment log
+	PrintHeaders   map[string]string
+	PrintLog       bool
+	ExtendPrintLog bool
+	// ExponentialRatio of the exponential average algorithm
+	ExponentialRatio float64
+	// GetHeader is one of the -getHeaders values, "off" by default
+	GetHeader string
+	Debug     bool
+	// DebugFile is the debug log, the debug log of Output by default
+	DebugFile string
+
+	// MPDs of URL, read from URL when nil
+	MPDs []http.MPD
+	// Output is the run the files of the client are written beneath, the current folder by default
+	Output output.Run
+	// Transport of the stream, when nil the client creates one, with its own qlog and accountant
+	Transport *http.Transport
+	Metrics   logging.MetricSinkConfig
+	// MetricsPollInterval of the buffer level, 100ms by default
+	MetricsPollInterval time.Duration
+	// BBA2 reservoirs, the zero fields are those of algorithms.DefaultBBA2Params
+	BBA2 algo.BBA2Params
+	// PredictionWindow is the part of a segment the stall predictor waits for, 0.15 by default
+	PredictionWindow float64
+	// AbortLogic of the stall predictor, the logic of Adapt by default
+	AbortLogic string
+	// MPC lookahead and QoE weights, the zero fields are those of algorithms.DefaultMPCParams
+	MPC algo.MPCParams
+	// LoLP latency target and playback rate controller, the zero fields are those of algorithms.DefaultLoLPParams
+	LoLP algo.LoLPParams
+	// Predictor of the throughput of Adapt, one of predictor.Names, the estimator of Adapt by default
+	Predictor string
+	// PensieveModel is the json file of the learned policy of the Pensieve algorithms
+	PensieveModel string
+	// FastStart selects the first rep_rate and the initial buffer from the startup downloads when Enabled,
+	// its zero Safety, Margin and StartupDelay are those of algorithms.DefaultFastStartParams
+	FastStart algo.FastStartParams
+	// VBR makes the throughput algorithms compare against the size of the next segments when Enabled,
+	// a zero Horizon is the one of algorithms.DefaultVBRParams
+	VBR algo.VBRParams
+	// Node is the consul node of a collaborative client
+	Node   P2Pconsul.NodeUrl
+	Events player.Events
+}
+
+// Client : one goDASH player
+type Client struct {
+	opts     Options
+	pensieve *algo.PensieveModel
+
+	mu      sync.Mutex
+	started bool
+	player  *player.Player
+}
+
+// New :
+// * check the options and create a client, the client streams once Run is called
+func New(opts Options) (*Client, error) {
+
+	if opts.URL == "" && opts.MPDs == nil {
+		return nil, errors.New("godash: a URL is needed for the MPD location")
+	}
+	if opts.Adapt == "" {
+		opts.Adapt = glob.ConventionalAlg
+	}
+	if opts.Codec == "" {
+		opts.Codec = glob.RepRateCodecAVC
+	}
+	if opts.MaxHeight == 0 {
+		opts.MaxHeight = 2160
+	}
+	if opts.StreamSpeed == 0 {
+		opts.StreamSpeed = 1
+	}
+	if opts.MaxBuffer == 0 {
+		opts.MaxBuffer = 30
+	}
+	if opts.InitBuffer == 0 {
+		opts.InitBuffer = 2
+	}
+	if opts.HLS == "" {
+		opts.HLS = glob.HlsOff
+	}
+	if opts.GetHeader == "" {
+		opts.GetHeader = glob.GetHeaderOff
+	}
+	if opts.MetricsPollInterval == 0 {
+		opts.MetricsPollInterval = 100 * time.Millisecond
+	}
+	defaultBBA2 := algo.DefaultBBA2Params()
+	if opts.BBA2.MinReservoir == 0 {
+		opts.BBA2.MinReservoir = defaultBBA2.MinReservoir
+	}
+	if opts.BBA2.UpperReservoir == 0 {
+		opts.BBA2.UpperReservoir = defaultBBA2.UpperReservoir
+	}
+	if opts.BBA2.Horizon == 0 {
+		opts.BBA2.Horizon = defaultBBA2.Horizon
+	}
+	if opts.BBA2.MaxReservoir == 0 {
+		opts.BBA2.MaxReservoir = defaultBBA2.MaxReservoir
+	}
+	if opts.BBA2.VarianceWeight == 0 {
+		opts.BBA2.VarianceWeight = defaultBBA2.VarianceWeight
+	}
+	if opts.PredictionWindow == 0 {
+		opts.PredictionWindow = 0.15
+	}
+	defaultMPC := algo.DefaultMPCParams()
+	if opts.MPC.Horizon == 0 {
+		opts.MPC.Horizon = defaultMPC.Horizon
+	}
+	if opts.MPC.RebufferPenalty == 0 {
+		opts.MPC.RebufferPenalty = defaultMPC.RebufferPenalty
+	}
+	if opts.MPC.SwitchPenalty == 0 {
+		opts.MPC.SwitchPenalty = defaultMPC.SwitchPenalty
+	}
+	defaultLoLP := algo.DefaultLoLPParams()
+	if opts.LoLP.TargetLatency == 0 {
+		opts.LoLP.TargetLatency = defaultLoLP.TargetLatency
+	}
+	if opts.LoLP.CatchupRate == 0 {
+		opts.LoLP.CatchupRate = defaultLoLP.CatchupRate
+	}
+	defaultFastStart := algo.DefaultFastStartParams()
+	if opts.FastStart.Safety == 0 {
+		opts.FastStart.Safety = defaultFastStart.Safety
+	}
+	if opts.FastStart.Margin == 0 {
+		opts.FastStart.Margin = defaultFastStart.Margin
+	}
+	if opts.FastStart.StartupDelay == 0 {
+		opts.FastStart.StartupDelay = defaultFastStart.StartupDelay
+	}
+	if opts.VBR.Horizon == 0 {
+		opts.VBR.Horizon = algo.DefaultVBRParams().Horizon
+	}
+	if opts.Output.Root == "" {
+		opts.Output = output.Default()
+	}
+	if opts.DebugFile == "" {
+		opts.DebugFile = opts.Output.Path(output.DebugLog, glob.DebugTextFile+glob.FileFormat)
+	}
+	if opts.FileDownloadLocation == "" {
+		opts.FileDownloadLocation = opts.Output.Path(output.Segments, "")
+	}
+
+	switch {
+	case opts.MaxHeight < 1:
+		return nil, errors.New("godash: MaxHeight must be a positive number and not " + strconv.Itoa(opts.MaxHeight))
+	case !opts.Constraints.Valid():
+		return nil, errors.New("godash: Constraints need limits that are not negative and a DynamicRange of " + strings.Join(ladder.DynamicRanges, ", "))
+	case opts.StreamDuration < 0:
+		return nil, errors.New("godash: StreamDuration must not be negative")
+	case opts.StreamSpeed < 0:
+		return nil, errors.New("godash: StreamSpeed must be a positive number")
+	case opts.MaxBuffer < 1:
+		return nil, errors.New("godash: MaxBuffer must be a positive number and not " + strconv.Itoa(opts.MaxBuffer))
+	case opts.InitBuffer < 0 || opts.InitBuffer > opts.MaxBuffer:
+		return nil, errors.New("godash: InitBuffer must be between 0 and MaxBuffer and not " + strconv.Itoa(opts.InitBuffer))
+	case opts.MetricsPollInterval < time.Millisecond:
+		return nil, errors.New("godash: MetricsPollInterval must be at least a millisecond")
+	case opts.PredictionWindow < 0 || opts.PredictionWindow > 1:
+		return nil, errors.New("godash: PredictionWindow must be between 0 and 1")
+	case opts.BBA2.MaxReservoir < 0 || opts.BBA2.MaxReservoir >= 1 || opts.BBA2.VarianceWeight < 0:
+		return nil, errors.New("godash: BBA2 needs a MaxReservoir between 0 and 1 and a VarianceWeight that is not negative")
+	case opts.MPC.Horizon < 1 || opts.MPC.RebufferPenalty < 0 || opts.MPC.SwitchPenalty < 0:
+		return nil, errors.New("godash: MPC needs a positive Horizon and penalties that are not negative")
+	case opts.LoLP.TargetLatency < 0 || opts.LoLP.CatchupRate < 0 || opts.LoLP.CatchupRate > 0.5:
+		return nil, errors.New("godash: LoLP needs a positive TargetLatency and a CatchupRate between 0 and 0.5")
+	case opts.FastStart.Safety < 0 || opts.FastStart.Safety > 1 || opts.FastStart.Margin < 0 || opts.FastStart.Margin > 1 || opts.FastStart.StartupDelay < 0:
+		return nil, errors.New("godash: FastStart needs a Safety and a Margin between 0 and 1 and a positive StartupDelay")
+	case opts.VBR.Horizon < 0:
+		return nil, errors.New("godash: VBR needs a positive Horizon")
+	case opts.Predictor != "" && predictor.New(opts.Predictor) == nil:
+		return nil, errors.New("godash: Predictor must be one of " + strings.Join(predictor.Names, ", ") + " and not " + opts.Predictor)
+	case (opts.Adapt == glob.PensieveAlg || opts.Adapt == glob.PensieveXLAlg) && opts.PensieveModel == "":
+		return nil, errors.New("godash: " + opts.Adapt + " needs a PensieveModel")
+	}
+
+	client := &Client{opts: opts}
+	if opts.PensieveModel != "" {
+		model, err := algo.LoadPensieveModel(opts.PensieveModel)
+		if err != nil {
+			return nil, errors.New("godash: PensieveModel " + err.Error())
+		}
+		client.pensieve = model
+	}
+	return client, nil
+}
+
+// Run :
+/*
+ * stream until the stream duration is reached or ctx is cancelled
+ * a client runs once, the qlog it created is closed when Run returns
+ */
+func (c *Client) Run(ctx context.Context) error {
+
+	c.mu.Lock()
+	if c.started {
+		c.mu.Unlock()
+		return errors.New("godash: the client has already streamed")
+	}
+	c.started = true
+	opts := c.opts
+	c.mu.Unlock()
+
+	// the HTTP client, qlog and accountant of this stream
+	transport := opts.Transport
+	if transport == nil {
+		tracer := abrqlog.StartRunTracer(opts.Output, abrqlog.NewStreamID(opts.URL, opts.Adapt, time.Now()))
+		defer tracer.Close()
+		tracer.InitialiseStream(true)
+		tracer.ChangeReadyState(abrqlog.ReadyStateHaveNothing)
+
+		accountant := &xlayer.CrossLayerAccountant{EventChannel: make(chan qlog.Event)}
+		accountant.SetTracer(tracer)
+		accountant.Listen(true)
+
+		transport = http.NewTransport(opts.Output, accountant, tracer)
+	}
+	if opts.Node.ClientName != "" && opts.Node.ClientName != glob.CollabPrintOff {
+		transport.SetNoden(opts.Node)
+	}
+	// the requests of this client log to its own debug log
+	transport.SetDebugFile(opts.DebugFile)
+	ctx = http.WithTransport(ctx, transport)
+
+	mpds := opts.MPDs
+	if mpds == nil {
+		var err error
+		if mpds, err = readMPDs(&opts, ctx); err != nil {
+			return err
+		}
+		transport.Tracer().ChangeReadyState(abrqlog.ReadyStateHaveMetadata)
+	} else if err := preferredCodec(mpds, &opts); err != nil {
+		return err
+	}
+
+	// stream the whole MPD, unless we have a shorter stream duration
+	_, _, audioContent := http.GetCodec(mpds, opts.Codec, opts.DebugFile, opts.Debug)
+	mpdStreamDuration := http.GetMPDStreamDuration(mpds, audioContent)
+	if mpdStreamDuration < 0 {
+		return errors.New("godash: unable to get the stream duration of the MPD")
+	}
+	streamDuration := mpdStreamDuration * glob.Conversion1000
+	if opts.StreamDuration > 0 {
+		if opts.StreamDuration > time.Duration(mpdStreamDuration)*time.Second {
+			return errors.New("godash: StreamDuration must not be larger than the MPD stream duration of " + strconv.Itoa(mpdStreamDuration) + " seconds")
+		}
+		streamDuration = int(opts.StreamDuration.Milliseconds())
+	}
+
+	transport.Accountant().SetTrackingEvents(true)
+
+	// the constraints may have been replaced since Run was called
+	c.mu.Lock()
+	c.player = player.New(player.Config{
+		MPDs:                  mpds,
+		DebugFile:             opts.DebugFile,
+		DebugLog:              opts.Debug,
+		Codec:                 opts.Codec,
+		CodecName:             glob.CodecName,
+		MaxHeight:             opts.MaxHeight,
+		Constraints:           c.opts.Constraints,
+		ScreenSchedule:        opts.ScreenSchedule,
+		StreamDuration:        streamDuration,
+		StreamSpeed:           opts.StreamSpeed,
+		MaxBuffer:             opts.MaxBuffer,
+		InitBuffer:            opts.InitBuffer,
+		Adapt:                 opts.Adapt,
+		URL:                   opts.URL,
+		FileDownloadLocation:  opts.FileDownloadLocation,
+		ExtendPrintLog:        opts.ExtendPrintLog,
+		HLS:                   opts.HLS,
+		HLSBool:               opts.HLS != glob.HlsOff,
+		Quic:                  onOff(opts.QUIC),
+		QuicBool:              opts.QUIC,
+		GetHeaderBool:         opts.GetHeader == glob.GetHeaderOn,
+		GetHeaderReadFromFile: opts.GetHeader,
+		ExponentialRatio:      opts.ExponentialRatio,
+		PrintHeadersData:      opts.PrintHeaders,
+		PrintLog:              opts.PrintLog,
+		UseTestbed:            opts.UseTestbed,
+		GetQoE:                opts.QoE,
+		SaveFiles:             opts.SaveFiles,
+		Noden:                 opts.Node,
+		Metrics:               opts.Metrics,
+		MetricsPollInterval:   int(opts.MetricsPollInterval.Milliseconds()),
+		BBA2:                  opts.BBA2,
+		PredictionWindow:      opts.PredictionWindow,
+		AbortLogic:            opts.AbortLogic,
+		MPC:                   opts.MPC,
+		LoLP:                  opts.LoLP,
+		Predictor:             opts.Predictor,
+		Pensieve:              c.pensieve,
+		FastStart:             opts.FastStart,
+		VBR:                   opts.VBR,
+		Run:                   opts.Output,
+		Events:                opts.Events,
+	})
+	p := c.player
+	c.mu.Unlock()
+
+	return p.Stream(ctx)
+}
+
+// SetConstraints :
+/*
+ * replace the ladder constraints of the stream, e.g. when the window of the player is resized
+ * the next segment is selected within them, before Run they replace the Constraints of the options
+ */
+func (c *Client) SetConstraints(constraints ladder.Constraints) error {
+	if !constraints.Valid() {
+		return errors.New("godash: Constraints need limits that are not negative and a DynamicRange of " + strings.Join(ladder.DynamicRanges, ", "))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.player == nil {
+		c.opts.Constraints = constraints
+		return nil
+	}
+	c.player.SetConstraints(constraints)
+	return nil
+}
+
+// Stats :
+// * the totals of the stream so far, zero before Run is called
+func (c *Client) Stats() player.Stats {
+	c.mu.Lock()
+	p := c.player
+	c.mu.Unlock()
+	if p == nil {
+		return player.Stats{}
+	}
+	return p.Stats()
+}
+
+// Summary :
+// * the QoE report of the stream, false until the stream has ended
+func (c *Client) Summary() (qoe.SessionSummary, bool) {
+	c.mu.Lock()
+	p := c.player
+	c.mu.Unlock()
+	if p == nil {
+		return qoe.SessionSummary{}, false
+	}
+	return p.Summary()
+}
+
+// readMPDs :
+/*
+ * read the MPDs of the url, with the transport of ctx
+ * the representations of the first MPD are reversed if index 0 has the lowest rate
+ */
+func readMPDs(opts *Options, ctx context.Context) ([]http.MPD, error) {
+
+	mpds, err := http.ReadURLArray(opts.URL, opts.Debug, opts.UseTestbed, opts.QUIC, ctx)
+	if err != nil {
+		return nil, errors.New("godash: " + err.Error())
+	}
+	if len(mpds) == 0 {
+		return nil, errors.New("godash: unable to read the MPD of " + opts.URL)
+	}
+	if err := preferredCodec(mpds, opts); err != nil {
+		return nil, err
+	}
+
+	// get the current adaptation set of the codec
+	codecList, codecIndexList, _ := http.GetCodec(mpds, opts.Codec, opts.DebugFile, opts.Debug)
+	usedVideoCodec, codecIndex := utils.FindInStringArray(codecList[0], opts.Codec)
+	if codecList[0][0] == glob.RepRateCodecAudio && len(codecList[0]) == 1 {
+		// audio only, ignore the video codec
+		codecIndex = 0
+	} else if !usedVideoCodec {
+		return nil, errors.New("godash: " + opts.Codec + " is not in the provided MPD, please check " + opts.URL)
+	}
+
+	http.ReverseRepresentations(&mpds[0], codecIndexList[0][codecIndex])
+	return mpds, nil
+}
+
+// preferredCodec :
+// * replace the codec of opts with the first codec of its preference in the first MPD
+func preferredCodec(mpds []http.MPD, opts *Options) error {
+	if len(opts.CodecPreference) == 0 {
+		return nil
+	}
+	offered, _, _ := http.GetCodec(mpds, opts.Codec, opts.DebugFile, opts.Debug)
+	codec, ok := ladder.Codec(offered[0], opts.CodecPreference)
+	if !ok {
+		return errors.New("godash: none of the CodecPreference " + strings.Join(opts.CodecPreference, ", ") + " is in the provided MPD, please check " + opts.URL)
+	}
+	opts.Codec = codec
+	return nil
+}
+
+// onOff :
+// * the flag value of a bool
+func onOff(on bool) string {
+	if on {
+		return glob.QuicOn
+	}
+	return glob.QuicOff
+}
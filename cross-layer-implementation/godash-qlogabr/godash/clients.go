@@ -0,0 +1,88 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package godash
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/uccmisl/godash/qoe"
+)
+
+// ClientName :
+// * the name of client i of a multi-client run, also the folder of its files
+func ClientName(i int) string {
+	return "client_" + strconv.Itoa(i+1)
+}
+
+// RunClients :
+/*
+ * run every client at the same time, client i starts starts[i] after the first client
+ * every client needs its own transport, so its own QUIC connection, accountant and qlog
+ * a client that stops on an error does not stop the others, its error is in the report
+ * return the fairness report once every client has ended
+ */
+func RunClients(ctx context.Context, clients []*Client, starts []time.Duration) (qoe.FairnessReport, error) {
+
+	if len(starts) != len(clients) {
+		return qoe.FairnessReport{}, errors.New("godash: " + strconv.Itoa(len(clients)) + " clients need as many start times and not " + strconv.Itoa(len(starts)))
+	}
+	for i, client := range clients {
+		if client.opts.Transport != nil {
+			return qoe.FairnessReport{}, errors.New("godash: " + ClientName(i) + " shares a transport, the clients of a run need their own")
+		}
+		if starts[i] < 0 {
+			return qoe.FairnessReport{}, errors.New("godash: the start time of " + ClientName(i) + " must not be negative")
+		}
+	}
+
+	sessions := make([]qoe.ClientSession, len(clients))
+	runStart := time.Now()
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+
+			sessions[i].Name = ClientName(i)
+			select {
+			case <-time.After(starts[i]):
+			case <-ctx.Done():
+				sessions[i].Err = ctx.Err()
+				return
+			}
+
+			start := time.Now()
+			sessions[i].Start = start.Sub(runStart)
+			sessions[i].Err = client.Run(ctx)
+			sessions[i].Duration = time.Since(start)
+			sessions[i].Summary, _ = client.Summary()
+		}(i, client)
+	}
+	wg.Wait()
+
+	return qoe.NewFairnessReport(sessions), nil
+}
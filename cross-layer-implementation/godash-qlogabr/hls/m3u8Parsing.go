@@ -0,0 +1,430 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package hls parses HLS (RFC 8216) master and media playlists.
+// The parser has no knowledge of the player, the http package maps the
+// result onto the representation ladder used by the ABR algorithms.
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PlaylistExtension : file extension used to detect HLS playlists
+const PlaylistExtension = ".m3u8"
+
+// tag names used in the playlists
+const (
+	tagHeader         = "#EXTM3U"
+	tagStreamInf      = "#EXT-X-STREAM-INF:"
+	tagTargetDuration = "#EXT-X-TARGETDURATION:"
+	tagMediaSequence  = "#EXT-X-MEDIA-SEQUENCE:"
+	tagPlaylistType   = "#EXT-X-PLAYLIST-TYPE:"
+	tagMap            = "#EXT-X-MAP:"
+	tagByteRange      = "#EXT-X-BYTERANGE:"
+	tagInf            = "#EXTINF:"
+	tagEndList        = "#EXT-X-ENDLIST"
+	tagDiscontinuity  = "#EXT-X-DISCONTINUITY"
+)
+
+// ByteRange : a sub-range of a resource, Offset is the first byte
+type ByteRange struct {
+	Length int
+	Offset int
+}
+
+// End : the last byte (inclusive) of this range
+func (b ByteRange) End() int {
+	return b.Offset + b.Length - 1
+}
+
+// MediaInit : the EXT-X-MAP media initialisation section
+type MediaInit struct {
+	URI       string
+	ByteRange *ByteRange
+}
+
+// Variant : an EXT-X-STREAM-INF entry of a master playlist
+type Variant struct {
+	Bandwidth        int
+	AverageBandwidth int
+	Codecs           string
+	Width            int
+	Height           int
+	FrameRate        float64
+	// VideoRange is SDR, PQ or HLG, empty if the playlist does not say
+	VideoRange string
+	URI        string
+}
+
+// MasterPlaylist : the list of variant streams
+type MasterPlaylist struct {
+	Variants []Variant
+}
+
+// Segment : a single media segment of a media playlist
+type Segment struct {
+	URI            string
+	Duration       float64
+	SequenceNumber int
+	ByteRange      *ByteRange
+	Map            *MediaInit
+	Discontinuity  bool
+}
+
+// MediaPlaylist : the list of media segments of one variant
+type MediaPlaylist struct {
+	TargetDuration int
+	MediaSequence  int
+	PlaylistType   string
+	EndList        bool
+	Segments       []Segment
+}
+
+// IsPlaylistURL :
+// * returns true if the url points to a m3u8 playlist
+func IsPlaylistURL(playlistURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(playlistURL))
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Path), PlaylistExtension)
+}
+
+// IsPlaylist :
+// * returns true if the body starts with the #EXTM3U header
+func IsPlaylist(body []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte(tagHeader))
+}
+
+// IsMasterPlaylist :
+// * returns true if the body contains at least one variant stream
+func IsMasterPlaylist(body []byte) bool {
+	return bytes.Contains(body, []byte(tagStreamInf))
+}
+
+// ParseMaster :
+/*
+ * parse a master playlist into its variant streams
+ * variant URIs are returned as they appear in the playlist
+ */
+func ParseMaster(body []byte) (MasterPlaylist, error) {
+
+	var master MasterPlaylist
+
+	if !IsPlaylist(body) {
+		return master, errors.New("hls: missing " + tagHeader + " header")
+	}
+
+	var pending *Variant
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, tagStreamInf):
+			attributes := parseAttributes(strings.TrimPrefix(line, tagStreamInf))
+			variant := Variant{}
+			variant.Bandwidth, _ = strconv.Atoi(attributes["BANDWIDTH"])
+			variant.AverageBandwidth, _ = strconv.Atoi(attributes["AVERAGE-BANDWIDTH"])
+			variant.Codecs = attributes["CODECS"]
+			variant.FrameRate, _ = strconv.ParseFloat(attributes["FRAME-RATE"], 64)
+			variant.VideoRange = attributes["VIDEO-RANGE"]
+			if resolution, ok := attributes["RESOLUTION"]; ok {
+				wh := strings.Split(strings.ToLower(resolution), "x")
+				if len(wh) == 2 {
+					variant.Width, _ = strconv.Atoi(wh[0])
+					variant.Height, _ = strconv.Atoi(wh[1])
+				}
+			}
+			if variant.Bandwidth == 0 {
+				return master, errors.New("hls: " + tagStreamInf + " without BANDWIDTH")
+			}
+			pending = &variant
+		case strings.HasPrefix(line, "#"):
+			// other tags and comments are not used for the ladder
+			continue
+		default:
+			// the first URI line after EXT-X-STREAM-INF is the variant playlist
+			if pending != nil {
+				pending.URI = line
+				master.Variants = append(master.Variants, *pending)
+				pending = nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return master, err
+	}
+	if len(master.Variants) == 0 {
+		return master, errors.New("hls: master playlist has no variant streams")
+	}
+
+	return master, nil
+}
+
+// ParseMedia :
+/*
+ * parse a media playlist into its segments
+ * sequence numbers start at EXT-X-MEDIA-SEQUENCE, byte-range offsets
+ * are filled in from the previous range when they are omitted
+ */
+func ParseMedia(body []byte) (MediaPlaylist, error) {
+
+	var media MediaPlaylist
+
+	if !IsPlaylist(body) {
+		return media, errors.New("hls: missing " + tagHeader + " header")
+	}
+
+	var err error
+	var currentMap *MediaInit
+	var duration float64
+	var inf bool
+	var byteRange *ByteRange
+	var discontinuity bool
+	// the next byte after the previous byte range, per URI
+	nextOffset := make(map[string]int)
+	var lastRangeURI string
+	var pendingRange *ByteRange
+	var pendingRangeHasOffset bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, tagTargetDuration):
+			media.TargetDuration, err = strconv.Atoi(strings.TrimPrefix(line, tagTargetDuration))
+			if err != nil {
+				return media, fmt.Errorf("hls: bad %s %v", tagTargetDuration, err)
+			}
+		case strings.HasPrefix(line, tagMediaSequence):
+			media.MediaSequence, err = strconv.Atoi(strings.TrimPrefix(line, tagMediaSequence))
+			if err != nil {
+				return media, fmt.Errorf("hls: bad %s %v", tagMediaSequence, err)
+			}
+		case strings.HasPrefix(line, tagPlaylistType):
+			media.PlaylistType = strings.TrimPrefix(line, tagPlaylistType)
+		case strings.HasPrefix(line, tagEndList):
+			media.EndList = true
+		case strings.HasPrefix(line, tagDiscontinuity):
+			discontinuity = true
+		case strings.HasPrefix(line, tagMap):
+			attributes := parseAttributes(strings.TrimPrefix(line, tagMap))
+			currentMap = &MediaInit{URI: attributes["URI"]}
+			if value, ok := attributes["BYTERANGE"]; ok {
+				mapRange, hasOffset, err := parseByteRange(value)
+				if err != nil {
+					return media, err
+				}
+				// the map range must always carry its offset, default 0
+				if !hasOffset {
+					mapRange.Offset = 0
+				}
+				currentMap.ByteRange = &mapRange
+			}
+		case strings.HasPrefix(line, tagByteRange):
+			parsed, hasOffset, err := parseByteRange(strings.TrimPrefix(line, tagByteRange))
+			if err != nil {
+				return media, err
+			}
+			pendingRange = &parsed
+			pendingRangeHasOffset = hasOffset
+		case strings.HasPrefix(line, tagInf):
+			value := strings.TrimPrefix(line, tagInf)
+			value = strings.Split(value, ",")[0]
+			duration, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return media, fmt.Errorf("hls: bad %s %v", tagInf, err)
+			}
+			inf = true
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			// a URI line closes the current segment
+			if !inf {
+				return media, errors.New("hls: segment " + line + " without " + tagInf)
+			}
+			byteRange = nil
+			if pendingRange != nil {
+				segmentRange := *pendingRange
+				if !pendingRangeHasOffset {
+					// offset is the byte after the previous sub-range of the same resource
+					if lastRangeURI != line {
+						return media, errors.New("hls: " + tagByteRange + " without offset for " + line)
+					}
+					segmentRange.Offset = nextOffset[line]
+				}
+				nextOffset[line] = segmentRange.Offset + segmentRange.Length
+				lastRangeURI = line
+				byteRange = &segmentRange
+			}
+			media.Segments = append(media.Segments, Segment{
+				URI:            line,
+				Duration:       duration,
+				SequenceNumber: media.MediaSequence + len(media.Segments),
+				ByteRange:      byteRange,
+				Map:            currentMap,
+				Discontinuity:  discontinuity,
+			})
+			inf = false
+			discontinuity = false
+			pendingRange = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return media, err
+	}
+	if media.TargetDuration == 0 {
+		return media, errors.New("hls: missing " + tagTargetDuration)
+	}
+
+	return media, nil
+}
+
+// IsLive :
+// * a playlist without EXT-X-ENDLIST can still grow
+func (m *MediaPlaylist) IsLive() bool {
+	return !m.EndList
+}
+
+// Duration :
+// * the sum of all segment durations in seconds
+func (m *MediaPlaylist) Duration() float64 {
+	var total float64
+	for _, segment := range m.Segments {
+		total += segment.Duration
+	}
+	return total
+}
+
+// Merge :
+/*
+ * add the segments of a reloaded live playlist to this playlist
+ * the sliding window of the server may have dropped old segments,
+ * we keep them and only append sequence numbers we have not seen yet
+ */
+func (m *MediaPlaylist) Merge(newer MediaPlaylist) int {
+
+	lastSequence := m.MediaSequence - 1
+	if len(m.Segments) > 0 {
+		lastSequence = m.Segments[len(m.Segments)-1].SequenceNumber
+	}
+
+	added := 0
+	for _, segment := range newer.Segments {
+		if segment.SequenceNumber > lastSequence {
+			m.Segments = append(m.Segments, segment)
+			lastSequence = segment.SequenceNumber
+			added++
+		}
+	}
+	if newer.TargetDuration > 0 {
+		m.TargetDuration = newer.TargetDuration
+	}
+	m.EndList = newer.EndList
+
+	return added
+}
+
+// ResolveURI :
+// * resolve a playlist URI against the URL of the playlist that contains it
+func ResolveURI(playlistURL string, uri string) string {
+	base, err := url.Parse(strings.TrimSpace(playlistURL))
+	if err != nil {
+		return uri
+	}
+	ref, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return uri
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// parseByteRange :
+// * parse "<n>[@<o>]", returns the range and if an offset was present
+func parseByteRange(value string) (ByteRange, bool, error) {
+	var byteRange ByteRange
+	var err error
+
+	parts := strings.Split(strings.TrimSpace(value), "@")
+	byteRange.Length, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return byteRange, false, fmt.Errorf("hls: bad byte range %s %v", value, err)
+	}
+	if len(parts) > 1 {
+		byteRange.Offset, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return byteRange, false, fmt.Errorf("hls: bad byte range %s %v", value, err)
+		}
+		return byteRange, true, nil
+	}
+	return byteRange, false, nil
+}
+
+// parseAttributes :
+// * split an attribute list into a map, quoted values may contain commas
+func parseAttributes(list string) map[string]string {
+	attributes := make(map[string]string)
+
+	var key strings.Builder
+	var value strings.Builder
+	inKey := true
+	inQuotes := false
+
+	store := func() {
+		name := strings.TrimSpace(key.String())
+		if name != "" {
+			attributes[name] = strings.Trim(strings.TrimSpace(value.String()), "\"")
+		}
+		key.Reset()
+		value.Reset()
+		inKey = true
+	}
+
+	for _, r := range list {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			value.WriteRune(r)
+		case r == '=' && inKey:
+			inKey = false
+		case r == ',' && !inQuotes:
+			store()
+		case inKey:
+			key.WriteRune(r)
+		default:
+			value.WriteRune(r)
+		}
+	}
+	store()
+
+	return attributes
+}
@@ -0,0 +1,124 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package hls
+
+import (
+	"testing"
+)
+
+// ------------------------------------------------------------------------------------------------
+
+// ----------------------------- Test ParseMaster -------------------------------------------------
+
+func TestParseMaster(t *testing.T) {
+
+	body := []byte(`#EXTM3U
+#EXT-X-VERSION:7
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,AVERAGE-BANDWIDTH=1000000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=640x360,FRAME-RATE=25.000
+360p/playlist.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=5000000,CODECS="avc1.640028,mp4a.40.2",RESOLUTION=1920x1080,VIDEO-RANGE=PQ
+http://cdn.example.com/1080p/playlist.m3u8
+`)
+
+	master, err := ParseMaster(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(master.Variants) != 2 {
+		t.Fatal("expected 2 variants but got", len(master.Variants))
+	}
+	first := master.Variants[0]
+	if first.Bandwidth != 1280000 || first.AverageBandwidth != 1000000 {
+		t.Error("wrong bandwidth values", first.Bandwidth, first.AverageBandwidth)
+	}
+	if first.Codecs != "avc1.4d401f,mp4a.40.2" {
+		t.Error("quoted CODECS should keep its comma, got", first.Codecs)
+	}
+	if first.Width != 640 || first.Height != 360 || first.FrameRate != 25 {
+		t.Error("wrong resolution or frame rate", first.Width, first.Height, first.FrameRate)
+	}
+	if first.VideoRange != "" || master.Variants[1].VideoRange != "PQ" {
+		t.Error("wrong video ranges", first.VideoRange, master.Variants[1].VideoRange)
+	}
+	if first.URI != "360p/playlist.m3u8" {
+		t.Error("wrong variant URI", first.URI)
+	}
+	if ResolveURI("http://example.com/video/master.m3u8", first.URI) != "http://example.com/video/360p/playlist.m3u8" {
+		t.Error("relative URI should be resolved against the master playlist")
+	}
+}
+
+// ----------------------------- Test ParseMedia --------------------------------------------------
+
+func TestParseMediaByteRange(t *testing.T) {
+
+	body := []byte(`#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-MEDIA-SEQUENCE:10
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-MAP:URI="video.mp4",BYTERANGE="800@0"
+#EXTINF:4.000,
+#EXT-X-BYTERANGE:1000@800
+video.mp4
+#EXTINF:3.840,
+#EXT-X-BYTERANGE:1500
+video.mp4
+#EXT-X-ENDLIST
+`)
+
+	media, err := ParseMedia(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.TargetDuration != 4 || media.IsLive() || len(media.Segments) != 2 {
+		t.Fatal("wrong playlist values", media.TargetDuration, media.IsLive(), len(media.Segments))
+	}
+	if media.Segments[0].SequenceNumber != 10 || media.Segments[1].SequenceNumber != 11 {
+		t.Error("sequence numbers should start at EXT-X-MEDIA-SEQUENCE")
+	}
+	if media.Segments[0].Map == nil || media.Segments[0].Map.ByteRange.Length != 800 {
+		t.Error("EXT-X-MAP byte range not parsed")
+	}
+	second := media.Segments[1].ByteRange
+	if second == nil || second.Offset != 1800 || second.End() != 3299 {
+		t.Error("byte range without offset should follow the previous range, got", second)
+	}
+}
+
+func TestMediaMergeSlidingWindow(t *testing.T) {
+
+	first, err := ParseMedia([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:5\n#EXTINF:2,\ns5.m4s\n#EXTINF:2,\ns6.m4s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := ParseMedia([]byte("#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:6\n#EXTINF:2,\ns6.m4s\n#EXTINF:2,\ns7.m4s\n#EXT-X-ENDLIST\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if added := first.Merge(newer); added != 1 {
+		t.Error("only segment 7 is new, but added", added)
+	}
+	if len(first.Segments) != 3 || first.Segments[2].URI != "s7.m4s" || first.IsLive() {
+		t.Error("merged playlist is wrong", first.Segments)
+	}
+}
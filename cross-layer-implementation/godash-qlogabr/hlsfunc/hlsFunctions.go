@@ -30,23 +30,12 @@
  */
 // if HLS is to be used - how dynamic must it be...
 // only use HLS if we have at least one segment to replacement
-/*
-   switch hls {
-   // passive - least amount of replacement
-   case hlsPassive:
-     // competitive - maybe used for gRPC- for when clients compete
-   case hlsCompetitive:
-     // aggressive - highest amount of replacement
-   case hlsAggressive:
-     // dynamic - permit the player to switch between the other cases as needed
-   case hlsDynamic:
-
-   }
-*/
+// the passive, competitive, aggressive and dynamic policies are in replacementPolicy.go
 
 package hlsfunc
 
 import (
+	"context"
 	"strings"
 	"time"
 
@@ -70,8 +59,8 @@ import (
 // hlsBool bool, mapSegmentLogPrintout map[int]logging.SegPrintLogInformation, numSeg int, extendPrintLog bool,
 // hlsUsed bool, bufferLevel int, segmentDurationTotal int, quic string, quicBool bool, baseURL string, debugLog bool, audioContent bool, repRate int)
 func GetHlsSegment(
-	f func(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant, metricsLogger *logging.MetricLogger) (int, []map[int]logging.SegPrintLogInformation), hlsChunkNumber int,
-	mapSegmentLogPrintout []map[int]logging.SegPrintLogInformation, maxHeight int, urlInput []string, initBuffer int, maxBuffer int, codecName string, codec string, urlString string, mpdList []http.MPD, nextSegmentNumber int, extendPrintLog bool, startTime time.Time, nextRunTime time.Time, arrivalTime int, hlsUsed bool, quic string, quicBool bool, baseURL string, debugFile string, debugLog bool, repRateBaseURL string, audioContent bool, repRate int, mimeTypeIndex int, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant, metricsLogger *logging.MetricLogger) (int, []map[int]logging.SegPrintLogInformation, int, int, time.Time) {
+	f func(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant, metricsLogger *logging.MetricLogger, ctx context.Context) (int, []map[int]logging.SegPrintLogInformation), hlsChunkNumber int,
+	mapSegmentLogPrintout []map[int]logging.SegPrintLogInformation, maxHeight int, urlInput []string, initBuffer int, maxBuffer int, codecName string, codec string, urlString string, mpdList []http.MPD, nextSegmentNumber int, extendPrintLog bool, startTime time.Time, nextRunTime time.Time, arrivalTime int, hlsUsed bool, quic string, quicBool bool, baseURL string, debugFile string, debugLog bool, repRateBaseURL string, audioContent bool, repRate int, mimeTypeIndex int, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant, metricsLogger *logging.MetricLogger, ctx context.Context) (int, []map[int]logging.SegPrintLogInformation, int, int, time.Time) {
 
 	// store the segment map details
 	previousChunk := mapSegmentLogPrintout[mimeTypeIndex][hlsChunkNumber]
@@ -144,7 +133,7 @@ func GetHlsSegment(
 	streamStructs = append(streamStructs, streaminfo)
 
 	// reduce the initBuffer to zero, so we are constantly counting segment number
-	_, newChunkMap := f(streamStructs, Noden, accountant, metricsLogger)
+	_, newChunkMap := f(streamStructs, Noden, accountant, metricsLogger, ctx)
 
 	// reset the buffer to a previous level for this hls chunk
 	newBuffer := mapSegmentLogPrintout[mimeTypeIndex][hlsChunkNumber].BufferLevel
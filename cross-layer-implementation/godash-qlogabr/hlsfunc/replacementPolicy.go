@@ -0,0 +1,185 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package hlsfunc
+
+import (
+	"sort"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+)
+
+// ReplacementCandidate : a buffered segment that can be upgraded before its playout deadline
+type ReplacementCandidate struct {
+	SegmentNumber int
+	OldRepRate    int
+	RepRate       int
+	// time in milliseconds before this segment starts to play
+	Deadline int
+	// predicted download time in milliseconds of the new representation
+	DownloadTime int
+}
+
+// replacementPolicy : the limits of one hls replacement policy
+type replacementPolicy struct {
+	// maximum replacements per streamLoop call, 0 for no limit
+	maxReplacements int
+	// part of the maximum buffer that must remain after all replacements
+	minBufferRatio float64
+	// part of the measured throughput a replacement may use
+	throughputShare float64
+}
+
+// the replacement policies
+var replacementPolicies = map[string]replacementPolicy{
+	// passive - least amount of replacement
+	glob.HlsPassive: {maxReplacements: 1, minBufferRatio: 0.5, throughputShare: 0.8},
+	// competitive - leave bandwidth for other clients
+	glob.HlsCompetitive: {maxReplacements: 1, minBufferRatio: 0.3, throughputShare: 0.5},
+	// aggressive - highest amount of replacement
+	glob.HlsAggressive: {maxReplacements: 0, minBufferRatio: 0.2, throughputShare: 1.0},
+}
+
+// GetReplacementPolicy :
+/*
+ * return the replacement policy to use for this segment
+ * "on" is the passive policy, the dynamic policy switches between the
+ * other policies based on the current buffer level
+ */
+func GetReplacementPolicy(hls string, bufferLevel int, maxBuffer int) string {
+
+	switch hls {
+	case glob.HlsOn:
+		return glob.HlsPassive
+	case glob.HlsDynamic:
+		bufferRatio := float64(bufferLevel) / float64(maxBuffer*glob.Conversion1000)
+		switch {
+		case bufferRatio >= 0.75:
+			return glob.HlsAggressive
+		case bufferRatio >= 0.5:
+			return glob.HlsPassive
+		default:
+			return glob.HlsCompetitive
+		}
+	}
+	return hls
+}
+
+// SelectReplacementSegments :
+/*
+ * find the buffered segments that can be replaced at a higher quality
+ * bufferLevel is in milliseconds, maxBuffer and segmentDuration in seconds
+ * throughput and bandwithList are in bits per second
+ * a segment is only replaced if the new representation can be downloaded
+ * before it starts to play, and the buffer stays above the policy reserve
+ */
+func SelectReplacementSegments(hls string, mapSegmentLogPrintout map[int]logging.SegPrintLogInformation, segmentNumber int,
+	bufferLevel int, maxBuffer int, segmentDuration int, throughput int, bandwithList []int) []ReplacementCandidate {
+
+	policy, ok := replacementPolicies[GetReplacementPolicy(hls, bufferLevel, maxBuffer)]
+	if !ok || throughput <= 0 || segmentDuration <= 0 {
+		return nil
+	}
+
+	segmentDurationMs := segmentDuration * glob.Conversion1000
+	usableThroughput := float64(throughput) * policy.throughputShare
+
+	// the time we can spend on replacements without going below the reserve
+	reserve := int(policy.minBufferRatio * float64(maxBuffer*glob.Conversion1000))
+	if reserve < segmentDurationMs {
+		reserve = segmentDurationMs
+	}
+	budget := bufferLevel - reserve
+	if budget <= 0 {
+		return nil
+	}
+
+	var candidates []ReplacementCandidate
+
+	// the last downloaded segment plays last, so work back through the buffer
+	for k := segmentNumber - 1; k >= 1; k-- {
+
+		// time until segment k starts to play
+		deadline := bufferLevel - (segmentNumber-k)*segmentDurationMs
+		if deadline <= 0 {
+			break
+		}
+
+		segment, ok := mapSegmentLogPrintout[k]
+		if !ok || segment.SegReplace == glob.SegReplaceYes {
+			continue
+		}
+
+		// pick the highest representation we can get in time
+		best := -1
+		bestDownloadTime := 0
+		for j, bandwidth := range bandwithList {
+			if bandwidth <= segment.Bandwidth || float64(bandwidth) > usableThroughput {
+				continue
+			}
+			downloadTime := int(float64(bandwidth) * float64(segmentDurationMs) / usableThroughput)
+			if downloadTime >= deadline || downloadTime > budget {
+				continue
+			}
+			if best == -1 || bandwidth > bandwithList[best] {
+				best = j
+				bestDownloadTime = downloadTime
+			}
+		}
+		if best != -1 {
+			candidates = append(candidates, ReplacementCandidate{
+				SegmentNumber: k,
+				OldRepRate:    segment.RepIndex,
+				RepRate:       best,
+				Deadline:      deadline,
+				DownloadTime:  bestDownloadTime,
+			})
+		}
+	}
+
+	// replace the lowest quality first, then the segment that plays last
+	sort.SliceStable(candidates, func(i, j int) bool {
+		oldI := mapSegmentLogPrintout[candidates[i].SegmentNumber].Bandwidth
+		oldJ := mapSegmentLogPrintout[candidates[j].SegmentNumber].Bandwidth
+		if oldI != oldJ {
+			return oldI < oldJ
+		}
+		return candidates[i].Deadline > candidates[j].Deadline
+	})
+
+	// every replacement delays the segments that follow it
+	var selected []ReplacementCandidate
+	spent := 0
+	for _, candidate := range candidates {
+		if policy.maxReplacements > 0 && len(selected) >= policy.maxReplacements {
+			break
+		}
+		if spent+candidate.DownloadTime >= candidate.Deadline || spent+candidate.DownloadTime > budget {
+			continue
+		}
+		spent += candidate.DownloadTime
+		candidate.Deadline -= spent - candidate.DownloadTime
+		selected = append(selected, candidate)
+	}
+
+	return selected
+}
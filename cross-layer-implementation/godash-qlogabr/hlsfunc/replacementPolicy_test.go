@@ -0,0 +1,82 @@
+package hlsfunc
+
+import (
+	"reflect"
+	"testing"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+)
+
+// the rep_rates in MPD order, the highest first
+var replacementBandwithList = []int{4000000, 2000000, 1000000, 500000}
+
+// replacementLog : segments 1 to 5 of 2 seconds, the next segment is 6
+func replacementLog() map[int]logging.SegPrintLogInformation {
+	log := make(map[int]logging.SegPrintLogInformation)
+	for segment, repRate := range []int{3, 3, 2, 1, 2} {
+		log[segment+1] = logging.SegPrintLogInformation{RepIndex: repRate, Bandwidth: replacementBandwithList[repRate]}
+	}
+	return log
+}
+
+func TestGetReplacementPolicy(t *testing.T) {
+	tests := []struct {
+		hls         string
+		bufferLevel int
+		want        string
+	}{
+		{glob.HlsOn, 0, glob.HlsPassive},
+		{glob.HlsOff, 0, glob.HlsOff},
+		{glob.HlsAggressive, 0, glob.HlsAggressive},
+		{glob.HlsDynamic, 16000, glob.HlsAggressive},
+		{glob.HlsDynamic, 12000, glob.HlsPassive},
+		{glob.HlsDynamic, 6000, glob.HlsCompetitive},
+	}
+	for _, test := range tests {
+		if got := GetReplacementPolicy(test.hls, test.bufferLevel, 20); got != test.want {
+			t.Errorf("%s at %d ms is %s, want %s", test.hls, test.bufferLevel, got, test.want)
+		}
+	}
+}
+
+func TestSelectReplacementSegments(t *testing.T) {
+	// 12 seconds of a 20 second buffer at 4 Mbps, segment k plays in 12 - 2 * (6 - k) seconds
+	tests := []struct {
+		hls         string
+		bufferLevel int
+		want        []ReplacementCandidate
+	}{
+		// 0.8 of the throughput for a single segment, the lowest quality that plays last first
+		{glob.HlsPassive, 12000, []ReplacementCandidate{{SegmentNumber: 2, OldRepRate: 3, RepRate: 1, Deadline: 4000, DownloadTime: 1250}}},
+		// half of the throughput leaves 2 Mbps, in 2 seconds
+		{glob.HlsCompetitive, 12000, []ReplacementCandidate{{SegmentNumber: 2, OldRepRate: 3, RepRate: 1, Deadline: 4000, DownloadTime: 2000}}},
+		// every replacement delays the next one, segment 1 and 3 no longer make their deadline
+		{glob.HlsAggressive, 12000, []ReplacementCandidate{
+			{SegmentNumber: 2, OldRepRate: 3, RepRate: 0, Deadline: 4000, DownloadTime: 2000},
+			{SegmentNumber: 5, OldRepRate: 2, RepRate: 0, Deadline: 8000, DownloadTime: 2000},
+			{SegmentNumber: 4, OldRepRate: 1, RepRate: 0, Deadline: 4000, DownloadTime: 2000},
+		}},
+		// 60% of the buffer is the passive policy
+		{glob.HlsDynamic, 12000, []ReplacementCandidate{{SegmentNumber: 2, OldRepRate: 3, RepRate: 1, Deadline: 4000, DownloadTime: 1250}}},
+		// the buffer is below the reserve of half the buffer
+		{glob.HlsPassive, 9000, nil},
+		{glob.HlsOff, 12000, nil},
+	}
+	for _, test := range tests {
+		got := SelectReplacementSegments(test.hls, replacementLog(), 6, test.bufferLevel, 20, 2, 4000000, replacementBandwithList)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s at %d ms replaces %+v, want %+v", test.hls, test.bufferLevel, got, test.want)
+		}
+	}
+
+	// a segment is only replaced once
+	log := replacementLog()
+	replaced := log[2]
+	replaced.SegReplace = glob.SegReplaceYes
+	log[2] = replaced
+	got := SelectReplacementSegments(glob.HlsPassive, log, 6, 12000, 20, 2, 4000000, replacementBandwithList)
+	if len(got) != 1 || got[0].SegmentNumber != 1 {
+		t.Errorf("with segment 2 replaced, passive replaces %+v, want segment 1", got)
+	}
+}
@@ -0,0 +1,399 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/hls"
+	"github.com/uccmisl/godash/logging"
+)
+
+// HLSProfile : profile string given to MPDs built from a HLS playlist
+// the player uses the second last field ("hls") as the profile name
+const HLSProfile = "urn:ietf:rfc:8216:hls:2017"
+
+// hlsMaxReloads : number of live playlist reloads before we give up on a segment
+const hlsMaxReloads = 10
+
+// hlsLiveDuration : a live playlist has no end, so the stream lasts until -streamDuration or until the playlist ends
+const hlsLiveDuration = 24 * 60 * 60
+
+// hlsTransferCharacteristics : the cicp transfer characteristics of a HLS VIDEO-RANGE
+var hlsTransferCharacteristics = map[string]string{
+	"SDR": "1",
+	"PQ":  "16",
+	"HLG": "18",
+}
+
+// hlsPlaylist : the media playlist of a HLS representation
+type hlsPlaylist struct {
+	media *hls.MediaPlaylist
+	// the media sequence number of segment 1, the same for every variant of the master playlist
+	firstSequence int
+	// download the playlist again, with the client and transport of the stream
+	reload    func() (*hls.MediaPlaylist, error)
+	debugFile string
+	debugLog  bool
+}
+
+// hlsParser :
+/*
+ * take a master playlist and its url
+ * download and parse every variant media playlist
+ * map the variants onto the representations of an MPD struct,
+ * one adaptation set per codec, so the ABR algorithms can use the ladder as is
+ * return an error if the playlist is not a master playlist or cannot be loaded
+ */
+func hlsParser(body []byte, playlistURL string, debugFile string, debugLog bool, useTestbedBool bool, quicBool bool, ctx context.Context) (MPD, error) {
+
+	if !hls.IsMasterPlaylist(body) {
+		return MPD{}, errors.New(playlistURL + " is a HLS media playlist, please pass the master playlist to goDASH")
+	}
+
+	master, err := hls.ParseMaster(body)
+	if err != nil {
+		return MPD{}, errors.New("problem parsing the HLS master playlist " + playlistURL + ": " + err.Error())
+	}
+
+	load := func(mediaURL string) (*hls.MediaPlaylist, error) {
+		return loadHLSMediaPlaylist(mediaURL, debugFile, debugLog, useTestbedBool, quicBool, ctx)
+	}
+	return hlsMPD(master, playlistURL, load, debugFile, debugLog)
+}
+
+// hlsMPD :
+/*
+ * map the variants of a master playlist onto the representations of an MPD struct,
+ * load gets the media playlist of a variant url, for the first time and on every live reload
+ * segment 1 is the first media sequence number every variant has, so the variants line up
+ */
+func hlsMPD(master hls.MasterPlaylist, playlistURL string, load func(mediaURL string) (*hls.MediaPlaylist, error), debugFile string, debugLog bool) (MPD, error) {
+
+	var mpd MPD
+	mpd.Profiles = HLSProfile
+	mpd.Type = "static"
+
+	// load every media playlist first, the windows of live variants may start at different sequence numbers
+	mediaURLs := make([]string, len(master.Variants))
+	medias := make([]*hls.MediaPlaylist, len(master.Variants))
+	firstSequence := 0
+	for i, variant := range master.Variants {
+		mediaURLs[i] = hls.ResolveURI(playlistURL, variant.URI)
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "HLS variant "+strconv.Itoa(variant.Bandwidth)+" @ "+mediaURLs[i])
+		media, err := load(mediaURLs[i])
+		if err != nil {
+			return MPD{}, err
+		}
+		medias[i] = media
+		if medias[i].MediaSequence > firstSequence {
+			firstSequence = medias[i].MediaSequence
+		}
+	}
+
+	// adaptation set index per codec family
+	adaptIndex := make(map[string]int)
+	var period Period
+	// the duration of the stream and the longest segment duration
+	var streamDuration float64
+	var targetDuration int
+	live := false
+
+	for i, variant := range master.Variants {
+
+		mediaURL := mediaURLs[i]
+		media := medias[i]
+		// drop the segments before segment 1
+		for len(media.Segments) > 0 && media.Segments[0].SequenceNumber < firstSequence {
+			media.Segments = media.Segments[1:]
+		}
+		playlist := &hlsPlaylist{
+			media:         media,
+			firstSequence: firstSequence,
+			reload: func() (*hls.MediaPlaylist, error) {
+				return load(mediaURL)
+			},
+			debugFile: debugFile,
+			debugLog:  debugLog,
+		}
+
+		if media.IsLive() {
+			live = true
+			mpd.Type = "dynamic"
+			mpd.MinimumUpdatePeriod = hlsDuration(float64(media.TargetDuration))
+		}
+		if media.TargetDuration > targetDuration {
+			targetDuration = media.TargetDuration
+		}
+		if i == 0 || media.Duration() < streamDuration {
+			streamDuration = media.Duration()
+		}
+
+		rep := hlsRepresentation(variant, mediaURL, playlist)
+		rep.ID = strconv.Itoa(i + 1)
+
+		// keep each codec in its own adaptation set
+		family := hlsCodecFamily(variant.Codecs)
+		index, ok := adaptIndex[family]
+		if !ok {
+			index = len(period.AdaptationSet)
+			adaptIndex[family] = index
+			period.AdaptationSet = append(period.AdaptationSet, AdaptationSet{
+				MimeType:    rep.MimeType,
+				ContentType: strings.Split(rep.MimeType, "/")[0],
+			})
+		}
+		period.AdaptationSet[index].Representation = append(period.AdaptationSet[index].Representation, rep)
+	}
+
+	for i := range period.AdaptationSet {
+		adapt := &period.AdaptationSet[i]
+
+		// same order as fileParser, lowest bandwidth first
+		sort.SliceStable(adapt.Representation, func(i, j int) bool {
+			return adapt.Representation[i].BandWidth < adapt.Representation[j].BandWidth
+		})
+
+		// the player only downloads the initialisation of the lowest representation
+		lowest := adapt.Representation[0]
+		adapt.SegmentTemplate = []SegmentTemplate{lowest.SegmentTemplate}
+		adapt.SegmentList.SegmentInitization.SourceURL = lowest.SegmentTemplate.Initialization
+		for _, rep := range adapt.Representation {
+			if rep.Width > adapt.MaxWidth {
+				adapt.MaxWidth = rep.Width
+			}
+			if rep.Height > adapt.MaxHeight {
+				adapt.MaxHeight = rep.Height
+			}
+		}
+	}
+
+	mpd.Periods = append(mpd.Periods, period)
+	// the window of a live playlist is not the length of the stream, its segments are reloaded as they are needed
+	if live {
+		streamDuration = hlsLiveDuration
+	}
+	mpd.MediaPresentationDuration = hlsDuration(streamDuration)
+	mpd.MaxSegmentDuration = hlsDuration(float64(targetDuration))
+	mpd.MinBufferTime = hlsDuration(float64(targetDuration))
+
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "HLS playlist type: "+mpd.Type+", duration: "+mpd.MediaPresentationDuration)
+
+	return mpd, nil
+}
+
+// hlsRepresentation :
+/*
+ * create a representation from a variant and its media playlist
+ * segments are stored in the SegmentList, with the media url and byte-range,
+ * if the playlist uses byte-ranges the chunk sizes are known for BBA2
+ */
+func hlsRepresentation(variant hls.Variant, mediaURL string, playlist *hlsPlaylist) Representation {
+
+	media := playlist.media
+	rep := Representation{
+		Codecs:      variant.Codecs,
+		Width:       variant.Width,
+		Height:      variant.Height,
+		FrameRate:   int(math.Round(variant.FrameRate)),
+		BandWidth:   variant.Bandwidth,
+		PlaylistURL: mediaURL,
+		playlist:    playlist,
+	}
+	// the video range of the variant is the transfer characteristics property of a DASH representation
+	if transfer, ok := hlsTransferCharacteristics[variant.VideoRange]; ok {
+		rep.SupplementalProperty = append(rep.SupplementalProperty, Descriptor{SchemeIDURI: TransferCharacteristicsScheme, Value: transfer})
+	}
+	rep.MimeType = glob.RepRateCodecVideo
+	if hlsCodecFamily(variant.Codecs) == "mp4a" || hlsCodecFamily(variant.Codecs) == "ac-3" {
+		rep.MimeType = glob.RepRateCodecAudio
+	}
+
+	// all durations are in milliseconds, the target duration is only the longest a segment may be,
+	// so a segment lasts the mean of the EXTINF durations, in whole seconds like the segments of an MPD
+	duration := media.TargetDuration * glob.Conversion1000
+	if len(media.Segments) > 0 {
+		duration = int(math.Round(media.Duration()/float64(len(media.Segments)))) * glob.Conversion1000
+		if duration < glob.Conversion1000 {
+			duration = glob.Conversion1000
+		}
+	}
+	rep.SegmentTemplate.Duration = duration
+	rep.SegmentTemplate.Timescale = glob.Conversion1000
+	rep.SegmentTemplate.StartNumber = 1
+	rep.SegmentList.Duration = duration
+	rep.SegmentList.Timescale = glob.Conversion1000
+
+	if len(media.Segments) > 0 {
+		first := media.Segments[0]
+		if first.Map != nil {
+			rep.SegmentTemplate.Initialization = first.Map.URI
+		} else {
+			// MPEG-TS variants have no initialisation section,
+			// so the first segment takes the place of the stream header
+			rep.SegmentTemplate.Initialization = first.URI
+		}
+		// byte-range playlists use the media url as the representation base url
+		if first.ByteRange != nil {
+			rep.BaseURL = first.URI
+		}
+	}
+
+	var chunks []string
+	var sum, max int
+	for _, segment := range media.Segments {
+		segURL := segmentURL{Media: segment.URI}
+		if segment.ByteRange != nil {
+			segURL.MediaRange = strconv.Itoa(segment.ByteRange.Offset) + "-" + strconv.Itoa(segment.ByteRange.End())
+			// chunk sizes are in bits
+			bits := segment.ByteRange.Length * 8
+			chunks = append(chunks, strconv.Itoa(bits))
+			sum += bits
+			if bits > max {
+				max = bits
+			}
+		}
+		rep.SegmentList.SegmentURL = append(rep.SegmentList.SegmentURL, segURL)
+	}
+	if len(chunks) == len(media.Segments) && sum > 0 {
+		rep.Chunks = strings.Join(chunks, ",")
+		rep.MaxAvgRatio = float32(float64(max) / (float64(sum) / float64(len(chunks))))
+	}
+
+	return rep
+}
+
+// loadHLSMediaPlaylist :
+// * download and parse a media playlist, all URIs are resolved to full urls
+func loadHLSMediaPlaylist(mediaURL string, debugFile string, debugLog bool, useTestbedBool bool, quicBool bool, ctx context.Context) (*hls.MediaPlaylist, error) {
+
+	body, _, _, err := GetURL(mediaURL, false, 0, 0, quicBool, debugFile, debugLog, useTestbedBool, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := hls.ParseMedia(body)
+	if err != nil {
+		return nil, errors.New("problem parsing the HLS media playlist " + mediaURL + ": " + err.Error())
+	}
+
+	for i := range media.Segments {
+		media.Segments[i].URI = hls.ResolveURI(mediaURL, media.Segments[i].URI)
+		if media.Segments[i].Map != nil && !strings.HasPrefix(media.Segments[i].Map.URI, "http") {
+			resolved := *media.Segments[i].Map
+			resolved.URI = hls.ResolveURI(mediaURL, resolved.URI)
+			media.Segments[i].Map = &resolved
+		}
+	}
+
+	return &media, nil
+}
+
+// getHLSSegment :
+/*
+ * return the media url and byte-range of a segment of a HLS representation
+ * segment numbers start at 1 with the first media sequence number of the master playlist,
+ * live playlists are reloaded until the segment is available or ctx is cancelled
+ */
+func getHLSSegment(rep Representation, segNumber int, ctx context.Context) (string, string, error) {
+
+	playlist := rep.playlist
+	if playlist == nil {
+		return "", "", errors.New("HLS media playlist " + rep.PlaylistURL + " was not loaded")
+	}
+
+	media := playlist.media
+	sequence := playlist.firstSequence + segNumber - 1
+	for reload := 0; hlsLastSequence(media) < sequence && media.IsLive() && reload < hlsMaxReloads; reload++ {
+		newer, err := playlist.reload()
+		if err != nil {
+			return "", "", err
+		}
+		added := media.Merge(*newer)
+		logging.DebugPrint(playlist.debugFile, playlist.debugLog, "DEBUG: ", "HLS live reload of "+rep.PlaylistURL+" added "+strconv.Itoa(added)+" segments")
+		if added == 0 {
+			// wait half a target duration before reloading an unchanged playlist
+			select {
+			case <-ctx.Done():
+				return "", "", errors.New("HLS live reload of " + rep.PlaylistURL + " stopped: " + ctx.Err().Error())
+			case <-time.After(time.Duration(media.TargetDuration) * time.Second / 2):
+			}
+		}
+	}
+
+	index := -1
+	if len(media.Segments) > 0 {
+		index = sequence - media.Segments[0].SequenceNumber
+	}
+	if index < 0 || index >= len(media.Segments) || media.Segments[index].SequenceNumber != sequence {
+		return "", "", errors.New("HLS segment " + strconv.Itoa(segNumber) + " is not in " + rep.PlaylistURL)
+	}
+
+	segment := media.Segments[index]
+	if segment.ByteRange == nil {
+		return segment.URI, "", nil
+	}
+	return segment.URI, strconv.Itoa(segment.ByteRange.Offset) + "-" + strconv.Itoa(segment.ByteRange.End()), nil
+}
+
+// hlsLastSequence :
+// * the media sequence number of the last segment of a playlist, one before its media sequence if it has no segments
+func hlsLastSequence(media *hls.MediaPlaylist) int {
+	if len(media.Segments) == 0 {
+		return media.MediaSequence - 1
+	}
+	return media.Segments[len(media.Segments)-1].SequenceNumber
+}
+
+// hlsSegmentCount :
+/*
+ * the number of segments of a HLS representation, false for a live playlist,
+ * whose segments are counted from the stream duration
+ */
+func hlsSegmentCount(rep Representation) (int, bool) {
+	if rep.playlist == nil || rep.playlist.media.IsLive() {
+		return 0, false
+	}
+	return len(rep.playlist.media.Segments), true
+}
+
+// hlsCodecFamily :
+// * the first codec of a CODECS attribute without its profile, "avc1.640028,mp4a.40.2" is "avc1"
+func hlsCodecFamily(codecs string) string {
+	first := strings.TrimSpace(strings.Split(codecs, ",")[0])
+	return strings.Split(first, ".")[0]
+}
+
+// hlsDuration :
+// * convert seconds to the "PT0H0M0.000S" format of the MPD
+func hlsDuration(seconds float64) string {
+	total := int(math.Ceil(seconds))
+	return fmt.Sprintf("PT%dH%dM%d.000S", total/3600, (total%3600)/60, total%60)
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uccmisl/godash/hls"
+)
+
+const hlsTestMaster = "http://example.com/master.m3u8"
+
+// hlsTestLoader : parse the media playlists of the map, every load of a url takes its next playlist
+func hlsTestLoader(t *testing.T, playlists map[string][]string) func(mediaURL string) (*hls.MediaPlaylist, error) {
+	return func(mediaURL string) (*hls.MediaPlaylist, error) {
+		bodies := playlists[mediaURL]
+		if len(bodies) == 0 {
+			t.Fatalf("no playlist for %s", mediaURL)
+		}
+		media, err := hls.ParseMedia([]byte(bodies[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bodies) > 1 {
+			playlists[mediaURL] = bodies[1:]
+		}
+		for i := range media.Segments {
+			media.Segments[i].URI = hls.ResolveURI(mediaURL, media.Segments[i].URI)
+		}
+		return &media, nil
+	}
+}
+
+// hlsTestMedia : a media playlist of 4 second segments from the media sequence on, with an end list if vod
+func hlsTestMedia(sequence int, segments int, vod bool) string {
+	body := fmt.Sprintf("#EXTM3U\n#EXT-X-TARGETDURATION:6\n#EXT-X-MEDIA-SEQUENCE:%d\n", sequence)
+	for i := 0; i < segments; i++ {
+		body += fmt.Sprintf("#EXTINF:4.0,\nseg%03d.m4s\n", sequence+i)
+	}
+	if vod {
+		body += "#EXT-X-ENDLIST\n"
+	}
+	return body
+}
+
+func hlsTestMPD(t *testing.T, playlists map[string][]string) MPD {
+	master := hls.MasterPlaylist{Variants: []hls.Variant{
+		{URI: "low/media.m3u8", Bandwidth: 500000, Codecs: "avc1.4d401f"},
+		{URI: "high/media.m3u8", Bandwidth: 2000000, Codecs: "avc1.640028"},
+	}}
+	mpd, err := hlsMPD(master, hlsTestMaster, hlsTestLoader(t, playlists), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mpd
+}
+
+func TestHLSSegmentDuration(t *testing.T) {
+	// segments of 4 seconds under a target duration of 6 seconds
+	mpd := hlsTestMPD(t, map[string][]string{
+		"http://example.com/low/media.m3u8":  {hlsTestMedia(0, 5, true)},
+		"http://example.com/high/media.m3u8": {hlsTestMedia(0, 5, true)},
+	})
+	if mpd.Type != "static" || mpd.MediaPresentationDuration != "PT0H0M20.000S" {
+		t.Errorf("the vod playlist is %s of %s", mpd.Type, mpd.MediaPresentationDuration)
+	}
+	if duration := mpd.Periods[0].AdaptationSet[0].Representation[0].SegmentTemplate.Duration; duration != 4000 {
+		t.Errorf("the segments last %d ms and not 4000", duration)
+	}
+	segments, durations := GetSegmentDetails([]MPD{mpd}, 0)
+	if segments != 5 || durations[0] != 4 {
+		t.Errorf("%d segments of %d seconds, want 5 of 4", segments, durations[0])
+	}
+}
+
+func TestHLSMediaSequence(t *testing.T) {
+	// the window of the high variant starts a segment later, and the playlists grow on every reload
+	mpd := hlsTestMPD(t, map[string][]string{
+		"http://example.com/low/media.m3u8":  {hlsTestMedia(10, 3, false), hlsTestMedia(11, 4, false)},
+		"http://example.com/high/media.m3u8": {hlsTestMedia(11, 3, false), hlsTestMedia(13, 3, false)},
+	})
+	if mpd.Type != "dynamic" || SplitMPDSegmentDuration(mpd.MediaPresentationDuration) != hlsLiveDuration {
+		t.Errorf("the live playlist is %s of %s", mpd.Type, mpd.MediaPresentationDuration)
+	}
+
+	reps := mpd.Periods[0].AdaptationSet[0].Representation
+	for _, test := range []struct {
+		rep     int
+		segment int
+		want    string
+	}{
+		// segment 1 is the first sequence number of every variant
+		{0, 1, "seg011.m4s"},
+		{1, 1, "seg011.m4s"},
+		{1, 2, "seg012.m4s"},
+		// after the window, the playlist is reloaded
+		{0, 4, "seg014.m4s"},
+		{1, 5, "seg015.m4s"},
+		// the segments the reload dropped from the window are kept
+		{1, 3, "seg013.m4s"},
+	} {
+		if got, _, err := getHLSSegment(reps[test.rep], test.segment, context.Background()); err != nil || !strings.HasSuffix(got, test.want) {
+			t.Errorf("segment %d of representation %d is %s and not %s", test.segment, test.rep, got, test.want)
+		}
+	}
+}
+
+func TestHLSLiveReloadStopsOnCancel(t *testing.T) {
+	// the live playlist never grows, a cancelled stream does not wait for it
+	mpd := hlsTestMPD(t, map[string][]string{
+		"http://example.com/low/media.m3u8":  {hlsTestMedia(10, 3, false)},
+		"http://example.com/high/media.m3u8": {hlsTestMedia(10, 3, false)},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := getHLSSegment(mpd.Periods[0].AdaptationSet[0].Representation[0], 5, ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("a cancelled live reload returns a segment")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a cancelled live reload is still waiting")
+	}
+}
@@ -0,0 +1,547 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"encoding/binary"
+	"errors"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// the ISO-BMFF (ISO/IEC 14496-12) boxes we need to inspect a fragmented mp4 segment
+
+// trun flags
+const trunDataOffsetPresent = 0x000001
+const trunFirstSampleFlagsPresent = 0x000004
+const trunSampleDurationPresent = 0x000100
+const trunSampleSizePresent = 0x000200
+const trunSampleFlagsPresent = 0x000400
+const trunSampleCompositionPresent = 0x000800
+
+// tfhd flags
+const tfhdBaseDataOffsetPresent = 0x000001
+const tfhdSampleDescriptionPresent = 0x000002
+const tfhdDefaultDurationPresent = 0x000008
+const tfhdDefaultSizePresent = 0x000010
+const tfhdDefaultFlagsPresent = 0x000020
+
+// sampleIsNonSync : sample_is_non_sync_sample bit of the sample flags
+const sampleIsNonSync = 0x00010000
+
+// mp4MaxTrunSamples : the most samples a track fragment run may have, over a minute of samples at 2000 per second
+const mp4MaxTrunSamples = 1 << 17
+
+// MP4Sample : one sample (frame) of a movie fragment
+type MP4Sample struct {
+	// size in bytes
+	Size int
+	// duration in timescale units
+	Duration uint32
+	// true for a sync sample (I-frame)
+	Keyframe bool
+}
+
+// mp4TrackDefaults : the trex/tfhd defaults of a track
+type mp4TrackDefaults struct {
+	duration uint32
+	size     uint32
+	flags    uint32
+}
+
+// MP4InitInfo : the per track details of an initialisation segment
+type MP4InitInfo struct {
+	// mdhd timescale per track ID
+	Timescales map[uint32]uint32
+	// trex defaults per track ID
+	defaults map[uint32]mp4TrackDefaults
+}
+
+// SegmentInfo : the content of a media segment, read from its boxes
+type SegmentInfo struct {
+	// payload bytes of all mdat boxes
+	MediaBytes int64
+	// bytes of everything else (styp, sidx, moof and the mdat headers)
+	HeaderBytes int64
+	// the first track of the first moof
+	TrackID uint32
+	// timescale of TrackID, from the init segment or the sidx, 0 if unknown
+	Timescale uint32
+	// samples of TrackID in decode order
+	Samples []MP4Sample
+}
+
+// walkMP4Boxes :
+/*
+ * call boxFunc for every box in data, with the box type and payload
+ * stop when boxFunc returns false, return an error if a box is truncated
+ */
+func walkMP4Boxes(data []byte, boxFunc func(boxType string, payload []byte) bool) error {
+
+	for offset := 0; offset < len(data); {
+		if offset+8 > len(data) {
+			return errors.New("truncated box header")
+		}
+		boxSize := uint64(binary.BigEndian.Uint32(data[offset:]))
+		boxType := string(data[offset+4 : offset+8])
+		headerSize := uint64(8)
+
+		switch boxSize {
+		case 0:
+			// the box runs to the end of the data
+			boxSize = uint64(len(data) - offset)
+		case 1:
+			// 64 bit largesize
+			if offset+16 > len(data) {
+				return errors.New("truncated box header " + boxType)
+			}
+			boxSize = binary.BigEndian.Uint64(data[offset+8:])
+			headerSize = 16
+		}
+		if boxSize < headerSize || uint64(offset)+boxSize > uint64(len(data)) {
+			return errors.New("truncated box " + boxType)
+		}
+
+		if !boxFunc(boxType, data[uint64(offset)+headerSize:uint64(offset)+boxSize]) {
+			return nil
+		}
+		offset += int(boxSize)
+	}
+	return nil
+}
+
+// parseSidx :
+/*
+ * read the referenced sizes (in bits) of a Segment Index Box (ISO/IEC 14496-12 8.16.3)
+ * the data may start with other boxes, the first sidx box is used
+ */
+func parseSidx(data []byte) ([]int, error) {
+
+	var sizes []int
+	found := false
+	var sidxErr error
+
+	err := walkMP4Boxes(data, func(boxType string, payload []byte) bool {
+		if boxType != "sidx" {
+			return true
+		}
+		found = true
+		_, sizes, sidxErr = parseSidxBox(payload)
+		return false
+	})
+	if !found {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("no sidx box")
+	}
+	return sizes, sidxErr
+}
+
+// parseSidxBox :
+// * return the timescale and referenced sizes (in bits) of a sidx payload
+func parseSidxBox(box []byte) (uint32, []int, error) {
+
+	if len(box) < 12 {
+		return 0, nil, errors.New("truncated sidx box")
+	}
+	version := box[0]
+	timescale := binary.BigEndian.Uint32(box[8:])
+	// version, flags, reference_ID and timescale
+	pos := 12
+	// earliest_presentation_time and first_offset
+	if version == 0 {
+		pos += 8
+	} else {
+		pos += 16
+	}
+	// reserved
+	pos += 2
+	if pos+2 > len(box) {
+		return 0, nil, errors.New("truncated sidx box")
+	}
+	referenceCount := int(binary.BigEndian.Uint16(box[pos:]))
+	pos += 2
+	if pos+referenceCount*12 > len(box) {
+		return 0, nil, errors.New("truncated sidx references")
+	}
+
+	var sizes []int
+	for i := 0; i < referenceCount; i++ {
+		reference := binary.BigEndian.Uint32(box[pos:])
+		// the top bit is reference_type, 1 points to another sidx box
+		if reference>>31 == 0 {
+			sizes = append(sizes, int(reference&0x7fffffff)*8)
+		}
+		pos += 12
+	}
+	return timescale, sizes, nil
+}
+
+// ParseMP4Init :
+/*
+ * read the track timescales (moov/trak/mdia/mdhd) and the
+ * fragment defaults (moov/mvex/trex) of an initialisation segment
+ */
+func ParseMP4Init(data []byte) (MP4InitInfo, error) {
+
+	info := MP4InitInfo{
+		Timescales: make(map[uint32]uint32),
+		defaults:   make(map[uint32]mp4TrackDefaults),
+	}
+	foundMoov := false
+
+	err := walkMP4Boxes(data, func(boxType string, moov []byte) bool {
+		if boxType != "moov" {
+			return true
+		}
+		foundMoov = true
+		walkMP4Boxes(moov, func(boxType string, payload []byte) bool {
+			switch boxType {
+			case "trak":
+				trackID, timescale := parseTrak(payload)
+				if trackID != 0 {
+					info.Timescales[trackID] = timescale
+				}
+			case "mvex":
+				walkMP4Boxes(payload, func(boxType string, trex []byte) bool {
+					// version, flags, track_ID, sample_description_index and the three defaults
+					if boxType == "trex" && len(trex) >= 24 {
+						info.defaults[binary.BigEndian.Uint32(trex[4:])] = mp4TrackDefaults{
+							duration: binary.BigEndian.Uint32(trex[12:]),
+							size:     binary.BigEndian.Uint32(trex[16:]),
+							flags:    binary.BigEndian.Uint32(trex[20:]),
+						}
+					}
+					return true
+				})
+			}
+			return true
+		})
+		return false
+	})
+	if !foundMoov {
+		if err != nil {
+			return info, err
+		}
+		return info, errors.New("no moov box")
+	}
+	return info, nil
+}
+
+// parseTrak :
+// * return the track ID (tkhd) and timescale (mdia/mdhd) of a trak payload
+func parseTrak(trak []byte) (trackID uint32, timescale uint32) {
+
+	walkMP4Boxes(trak, func(boxType string, payload []byte) bool {
+		switch boxType {
+		case "tkhd":
+			// version 1 has 64 bit creation and modification times
+			pos := 12
+			if len(payload) > 0 && payload[0] == 1 {
+				pos = 20
+			}
+			if len(payload) >= pos+4 {
+				trackID = binary.BigEndian.Uint32(payload[pos:])
+			}
+		case "mdia":
+			walkMP4Boxes(payload, func(boxType string, mdhd []byte) bool {
+				if boxType != "mdhd" {
+					return true
+				}
+				pos := 12
+				if len(mdhd) > 0 && mdhd[0] == 1 {
+					pos = 20
+				}
+				if len(mdhd) >= pos+4 {
+					timescale = binary.BigEndian.Uint32(mdhd[pos:])
+				}
+				return false
+			})
+		}
+		return true
+	})
+	return
+}
+
+// ParseMP4Segment :
+/*
+ * walk the styp/sidx/moof/mdat boxes of a media segment
+ * return the media (mdat payload) bytes and the samples of the first track
+ * init may be nil, the timescale then comes from the sidx box if there is one
+ * an error means the data is not a (complete) fragmented mp4 segment, e.g. MPEG-TS
+ */
+func ParseMP4Segment(data []byte, init *MP4InitInfo) (SegmentInfo, error) {
+
+	var info SegmentInfo
+	foundMdat := false
+	var parseErr error
+
+	err := walkMP4Boxes(data, func(boxType string, payload []byte) bool {
+		switch boxType {
+		case "mdat":
+			foundMdat = true
+			info.MediaBytes += int64(len(payload))
+			return true
+		case "sidx":
+			if timescale, _, err := parseSidxBox(payload); err == nil && info.Timescale == 0 {
+				info.Timescale = timescale
+			}
+		case "moof":
+			walkMP4Boxes(payload, func(boxType string, traf []byte) bool {
+				if boxType == "traf" && parseErr == nil {
+					parseErr = parseTraf(traf, init, &info)
+				}
+				return true
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return info, err
+	}
+	if parseErr != nil {
+		return info, parseErr
+	}
+	if !foundMdat {
+		return info, errors.New("no mdat box")
+	}
+	// add the box headers of the mdat boxes to the header bytes
+	info.HeaderBytes = int64(len(data)) - info.MediaBytes
+
+	// the track timescale of the init segment wins over the sidx timescale
+	if init != nil {
+		if timescale, ok := init.Timescales[info.TrackID]; ok && timescale > 0 {
+			info.Timescale = timescale
+		}
+	}
+
+	return info, nil
+}
+
+// parseTraf :
+/*
+ * add the samples of a track fragment (tfhd and trun boxes) to info
+ * only the track of the first traf is kept, other tracks are skipped
+ */
+func parseTraf(traf []byte, init *MP4InitInfo, info *SegmentInfo) error {
+
+	var defaults mp4TrackDefaults
+	var trackID uint32
+	var err error
+
+	walkMP4Boxes(traf, func(boxType string, payload []byte) bool {
+		switch boxType {
+		case "tfhd":
+			if len(payload) < 8 {
+				err = errors.New("truncated tfhd box")
+				return false
+			}
+			flags := binary.BigEndian.Uint32(payload) & 0xffffff
+			trackID = binary.BigEndian.Uint32(payload[4:])
+			if info.TrackID == 0 {
+				info.TrackID = trackID
+			}
+			if init != nil {
+				defaults = init.defaults[trackID]
+			}
+			pos := 8
+			if flags&tfhdBaseDataOffsetPresent != 0 {
+				pos += 8
+			}
+			if flags&tfhdSampleDescriptionPresent != 0 {
+				pos += 4
+			}
+			readDefault := func(present uint32, value *uint32) {
+				if flags&present != 0 && pos+4 <= len(payload) {
+					*value = binary.BigEndian.Uint32(payload[pos:])
+					pos += 4
+				}
+			}
+			readDefault(tfhdDefaultDurationPresent, &defaults.duration)
+			readDefault(tfhdDefaultSizePresent, &defaults.size)
+			readDefault(tfhdDefaultFlagsPresent, &defaults.flags)
+		case "trun":
+			if trackID != info.TrackID {
+				return true
+			}
+			var samples []MP4Sample
+			samples, err = parseTrun(payload, defaults)
+			info.Samples = append(info.Samples, samples...)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// parseTrun :
+// * return the samples of a track fragment run, missing values come from the defaults
+func parseTrun(trun []byte, defaults mp4TrackDefaults) ([]MP4Sample, error) {
+
+	if len(trun) < 8 {
+		return nil, errors.New("truncated trun box")
+	}
+	flags := binary.BigEndian.Uint32(trun) & 0xffffff
+	sampleCount := int(binary.BigEndian.Uint32(trun[4:]))
+	pos := 8
+	if flags&trunDataOffsetPresent != 0 {
+		pos += 4
+	}
+	firstSampleFlags := defaults.flags
+	hasFirstSampleFlags := flags&trunFirstSampleFlagsPresent != 0
+	if hasFirstSampleFlags {
+		if pos+4 > len(trun) {
+			return nil, errors.New("truncated trun box")
+		}
+		firstSampleFlags = binary.BigEndian.Uint32(trun[pos:])
+		pos += 4
+	}
+
+	// bytes per sample entry
+	entrySize := 0
+	for _, present := range []uint32{trunSampleDurationPresent, trunSampleSizePresent, trunSampleFlagsPresent, trunSampleCompositionPresent} {
+		if flags&present != 0 {
+			entrySize += 4
+		}
+	}
+	// a sample without fields takes no bytes, so the count of a trun with only defaults is not bounded by the box
+	if pos > len(trun) || sampleCount > mp4MaxTrunSamples || (entrySize > 0 && sampleCount > (len(trun)-pos)/entrySize) {
+		return nil, errors.New("truncated trun samples")
+	}
+
+	samples := make([]MP4Sample, sampleCount)
+	for i := range samples {
+		duration := defaults.duration
+		size := defaults.size
+		sampleFlags := defaults.flags
+		if i == 0 && hasFirstSampleFlags {
+			sampleFlags = firstSampleFlags
+		}
+		if flags&trunSampleDurationPresent != 0 {
+			duration = binary.BigEndian.Uint32(trun[pos:])
+			pos += 4
+		}
+		if flags&trunSampleSizePresent != 0 {
+			size = binary.BigEndian.Uint32(trun[pos:])
+			pos += 4
+		}
+		if flags&trunSampleFlagsPresent != 0 {
+			sampleFlags = binary.BigEndian.Uint32(trun[pos:])
+			pos += 4
+		}
+		if flags&trunSampleCompositionPresent != 0 {
+			pos += 4
+		}
+		samples[i] = MP4Sample{
+			Size:     int(size),
+			Duration: duration,
+			Keyframe: sampleFlags&sampleIsNonSync == 0,
+		}
+	}
+	return samples, nil
+}
+
+// SampleCount :
+// * the number of samples (frames) of the segment
+func (s SegmentInfo) SampleCount() int {
+	return len(s.Samples)
+}
+
+// Duration :
+// * the duration of all samples in seconds, 0 if the timescale is unknown
+func (s SegmentInfo) Duration() float64 {
+
+	if s.Timescale == 0 {
+		return 0
+	}
+	var total uint64
+	for _, sample := range s.Samples {
+		total += uint64(sample.Duration)
+	}
+	return float64(total) / float64(s.Timescale)
+}
+
+// FrameRate :
+// * samples per second, 0 if the duration is unknown
+func (s SegmentInfo) FrameRate() float64 {
+
+	duration := s.Duration()
+	if duration <= 0 {
+		return 0
+	}
+	return float64(len(s.Samples)) / duration
+}
+
+// Keyframes :
+// * the sample positions of the sync samples
+func (s SegmentInfo) Keyframes() []int {
+
+	var keyframes []int
+	for i, sample := range s.Samples {
+		if sample.Keyframe {
+			keyframes = append(keyframes, i)
+		}
+	}
+	return keyframes
+}
+
+// inspectSegment :
+/*
+ * parse a downloaded file, an init segment is saved for its media type,
+ * the details of a media segment are saved for the player under fileName
+ */
+func (t *Transport) inspectSegment(data []byte, mediaType abrqlog.MediaType, fileName string) (SegmentInfo, error) {
+
+	t.mp4Mutex.Lock()
+	defer t.mp4Mutex.Unlock()
+
+	// an init segment (or a self-initialising segment)
+	if init, err := ParseMP4Init(data); err == nil {
+		t.mp4InitInfos[mediaType] = init
+	}
+
+	var init *MP4InitInfo
+	if saved, ok := t.mp4InitInfos[mediaType]; ok {
+		init = &saved
+	}
+	info, err := ParseMP4Segment(data, init)
+	if err != nil {
+		return info, err
+	}
+	t.segmentInfos[fileName] = info
+	return info, nil
+}
+
+// TakeSegmentInfo :
+/*
+ * return and forget the details of a downloaded segment,
+ * false if the segment was not a fragmented mp4 segment
+ */
+func (t *Transport) TakeSegmentInfo(fileName string) (SegmentInfo, bool) {
+
+	t.mp4Mutex.Lock()
+	defer t.mp4Mutex.Unlock()
+
+	info, ok := t.segmentInfos[fileName]
+	delete(t.segmentInfos, fileName)
+	return info, ok
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// mp4Box : a box of the type with the payloads, a full box has its version and flags as the first payload
+func mp4Box(boxType string, payloads ...[]byte) []byte {
+	var payload []byte
+	for _, p := range payloads {
+		payload = append(payload, p...)
+	}
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	return append(append(box, boxType...), payload...)
+}
+
+// mp4Uint32s : the big endian bytes of the values
+func mp4Uint32s(values ...uint32) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+// mp4TestInit : the init segment of a packager, a video track 1 of timescale 15360
+// with trex defaults of 512 ticks and non-sync samples
+func mp4TestInit() []byte {
+	ftyp := mp4Box("ftyp", []byte("iso5"), mp4Uint32s(512), []byte("iso5iso6mp41"))
+	mvhd := mp4Box("mvhd", mp4Uint32s(0, 0, 0, 1000, 0), make([]byte, 80))
+	tkhd := mp4Box("tkhd", mp4Uint32s(0x00000003, 0, 0, 1, 0, 0), make([]byte, 60))
+	mdhd := mp4Box("mdhd", mp4Uint32s(0, 0, 0, 15360, 0), []byte{0x55, 0xc4, 0, 0})
+	hdlr := mp4Box("hdlr", mp4Uint32s(0, 0), []byte("vide"), make([]byte, 12), []byte("VideoHandler\x00"))
+	trak := mp4Box("trak", tkhd, mp4Box("mdia", mdhd, hdlr, mp4Box("minf")))
+	trex := mp4Box("trex", mp4Uint32s(0, 1, 1, 512, 0, 0x01010000))
+	return append(ftyp, mp4Box("moov", mvhd, trak, mp4Box("mvex", trex))...)
+}
+
+// mp4TestSegment : a media segment of track 1 with 3 samples of 100, 50 and 60 bytes, the first a keyframe
+func mp4TestSegment() []byte {
+	mfhd := mp4Box("mfhd", mp4Uint32s(0, 1))
+	// default-base-is-moof
+	tfhd := mp4Box("tfhd", mp4Uint32s(0x020000, 1))
+	tfdt := mp4Box("tfdt", mp4Uint32s(0x01000000, 0, 0))
+	// data offset, first sample flags and sample sizes
+	trun := mp4Box("trun", mp4Uint32s(0x000205, 3, 0, 0x02000000, 100, 50, 60))
+	moof := mp4Box("moof", mfhd, mp4Box("traf", tfhd, tfdt, trun))
+	mdat := mp4Box("mdat", make([]byte, 210))
+	sidx := mp4Box("sidx", mp4Uint32s(0, 1, 15360, 0, 0), []byte{0, 0, 0, 1}, mp4Uint32s(uint32(len(moof)+len(mdat)), 1536, 0x90000000))
+	return append(append(append(mp4Box("styp", []byte("msdh"), mp4Uint32s(0), []byte("msdhmsix")), sidx...), moof...), mdat...)
+}
+
+func TestParseMP4Init(t *testing.T) {
+	init, err := ParseMP4Init(mp4TestInit())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if init.Timescales[1] != 15360 {
+		t.Errorf("the timescales are %v", init.Timescales)
+	}
+	if init.defaults[1] != (mp4TrackDefaults{duration: 512, flags: 0x01010000}) {
+		t.Errorf("the defaults are %+v", init.defaults[1])
+	}
+	if _, err := ParseMP4Init(mp4Box("ftyp", []byte("iso5"))); err == nil {
+		t.Errorf("an init segment without moov has no error")
+	}
+}
+
+func TestParseMP4Segment(t *testing.T) {
+	init, err := ParseMP4Init(mp4TestInit())
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment := mp4TestSegment()
+	info, err := ParseMP4Segment(segment, &init)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.MediaBytes != 210 || info.HeaderBytes != int64(len(segment)-210) {
+		t.Errorf("%d media and %d header bytes", info.MediaBytes, info.HeaderBytes)
+	}
+	want := []MP4Sample{{Size: 100, Duration: 512, Keyframe: true}, {Size: 50, Duration: 512}, {Size: 60, Duration: 512}}
+	if info.TrackID != 1 || info.Timescale != 15360 || !reflect.DeepEqual(info.Samples, want) {
+		t.Errorf("track %d of timescale %d has the samples %+v", info.TrackID, info.Timescale, info.Samples)
+	}
+	if info.Duration() != 0.1 {
+		t.Errorf("the segment lasts %g seconds", info.Duration())
+	}
+
+	// without the init segment the timescale is the one of the sidx box
+	info, err = ParseMP4Segment(segment, nil)
+	if err != nil || info.Timescale != 15360 || len(info.Samples) != 3 {
+		t.Errorf("without init, timescale %d and %d samples, %v", info.Timescale, len(info.Samples), err)
+	}
+
+	// the sidx reference is the moof and mdat after the styp and sidx boxes of 24 and 44 bytes
+	sizes, err := parseSidx(segment)
+	if err != nil || !reflect.DeepEqual(sizes, []int{(len(segment) - 24 - 44) * 8}) {
+		t.Errorf("the sidx sizes are %v, %v", sizes, err)
+	}
+}
+
+func TestParseMP4Truncated(t *testing.T) {
+	segment := mp4TestSegment()
+	// every cut of the segment, inside a box header or a box, is an error
+	for _, length := range []int{0, 4, 20, 40, 60, 100, len(segment) - 1} {
+		if _, err := ParseMP4Segment(segment[:length], nil); err == nil {
+			t.Errorf("the segment cut at %d bytes has no error", length)
+		}
+	}
+	// an MPEG-TS segment is not a fragmented mp4 segment
+	if _, err := ParseMP4Segment(append([]byte{0x47}, make([]byte, 187)...), nil); err == nil {
+		t.Errorf("an MPEG-TS packet has no error")
+	}
+	init := mp4TestInit()
+	if _, err := ParseMP4Init(init[:len(init)-10]); err == nil {
+		t.Errorf("a truncated init segment has no error")
+	}
+	if _, err := parseSidx(mp4Box("sidx", mp4Uint32s(0, 1, 15360, 0, 0), []byte{0, 0, 0, 2}, mp4Uint32s(100, 0, 0))); err == nil {
+		t.Errorf("a sidx box with a missing reference has no error")
+	}
+}
+
+func TestParseTrun(t *testing.T) {
+	defaults := mp4TrackDefaults{duration: 512, size: 10}
+	tests := []struct {
+		name    string
+		trun    []byte
+		samples int
+		ok      bool
+	}{
+		{"sizes", mp4Uint32s(0x000200, 2, 100, 50), 2, true},
+		{"defaults only", mp4Uint32s(0, 25), 25, true},
+		{"missing sizes", mp4Uint32s(0x000200, 3, 100, 50), 0, false},
+		// 4G samples without fields would take no bytes of the box
+		{"count of defaults", mp4Uint32s(0, 0xffffffff), 0, false},
+		{"count of sizes", mp4Uint32s(0x000200, 0xffffffff, 100), 0, false},
+		{"missing data offset", mp4Uint32s(0x000001, 0), 0, false},
+		{"header", []byte{0, 0, 0}, 0, false},
+	}
+	for _, test := range tests {
+		samples, err := parseTrun(test.trun, defaults)
+		if (err == nil) != test.ok || len(samples) != test.samples {
+			t.Errorf("%s: %d samples, %v", test.name, len(samples), err)
+		}
+	}
+}
+
+func TestWalkMP4Boxes(t *testing.T) {
+	// a largesize box, and a last box of size 0 that runs to the end of the data
+	large := append(mp4Uint32s(1), "free"...)
+	large = append(append(large, mp4Uint32s(0, 20)...), 0, 0, 0, 0)
+	data := append(large, append(mp4Uint32s(0), append([]byte("mdat"), 1, 2, 3)...)...)
+	var types []string
+	var sizes []int
+	err := walkMP4Boxes(data, func(boxType string, payload []byte) bool {
+		types = append(types, boxType)
+		sizes = append(sizes, len(payload))
+		return true
+	})
+	if err != nil || !reflect.DeepEqual(types, []string{"free", "mdat"}) || !reflect.DeepEqual(sizes, []int{4, 3}) {
+		t.Errorf("boxes %v of %v, %v", types, sizes, err)
+	}
+	// a box larger than the data
+	if err := walkMP4Boxes(append(mp4Uint32s(100), "moof"...), func(string, []byte) bool { return true }); err == nil {
+		t.Errorf("a box larger than the data has no error")
+	}
+}
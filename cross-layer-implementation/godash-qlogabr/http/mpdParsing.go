@@ -23,20 +23,25 @@ package http
 
 import (
 	"bufio"
+	"context"
 	"encoding/csv"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	xlayer "github.com/uccmisl/godash/crosslayer"
 	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/hls"
 	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/output"
 	"github.com/uccmisl/godash/utils"
 
 	abrqlog "github.com/uccmisl/godash/qlog"
@@ -146,6 +151,10 @@ type AdaptationSet struct {
 	Height    string `xml:"height,attr"`
 	ScanType  string `xml:"scanType,attr"`
 	Width     int    `xml:"width,attr"`
+
+	// the properties of all representations of the set, e.g. the transfer characteristics of HDR video
+	EssentialProperty    []Descriptor `xml:"EssentialProperty"`
+	SupplementalProperty []Descriptor `xml:"SupplementalProperty"`
 }
 
 // Representation in MPD
@@ -168,6 +177,21 @@ type Representation struct {
 	AudioChannelConfiguration AudioChannelConfiguration `xml:"AudioChannelConfiguration"`
 	Chunks                    string                    `xml:"chunks"`
 	MaxAvgRatio               float32                   `xml:"maxAvgRatio,attr"`
+	EssentialProperty         []Descriptor              `xml:"EssentialProperty"`
+	SupplementalProperty      []Descriptor              `xml:"SupplementalProperty"`
+	// PlaylistURL is only set for representations built from a HLS media playlist
+	PlaylistURL string `xml:"-"`
+	playlist    *hlsPlaylist
+}
+
+// TransferCharacteristicsScheme : scheme of the descriptor giving the transfer characteristics
+// of the video, 16 (PQ) and 18 (HLG) are HDR
+const TransferCharacteristicsScheme = "urn:mpeg:mpegB:cicp:TransferCharacteristics"
+
+// Descriptor in MPD, an EssentialProperty or SupplementalProperty
+type Descriptor struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
 }
 
 // SegmentTemplate in MPD
@@ -220,39 +244,44 @@ type Initialization struct {
 // segmentURL in MPD
 type segmentURL struct {
 	XMLName    xml.Name `xml:"SegmentURL"`
+	Media      string   `xml:"media,attr"`
 	MediaRange string   `xml:"mediaRange,attr"`
 	IndexRange string   `xml:"indexRange,attr"`
 }
 
-// the current Codec
-var mpdCodec string
-var mpdCodecIndex int
-var repRateCodec string
-
-// SegHeadValues store the seg header maps
-var SegHeadValues map[int]map[int][]int
-
 // getStructList :
 // * Take an array of string that might correspond to URLs
 // * For each URL, call the method GET and parse the result with the function fileParser()
-// * If the URL doesn't match, displays an error, then continue with the other strings
+// * Return an error if a URL cannot be downloaded or parsed
 // * Add each structure
-func getStructList(requestedURLs []string, debugFile string, debugLog bool, useTestbedBool bool, quicbool bool) (mpds []MPD) {
+func getStructList(requestedURLs []string, debugFile string, debugLog bool, useTestbedBool bool, quicbool bool, ctx context.Context) (mpds []MPD, err error) {
 
 	// for each of the requested URLs
 	for i := 0; i < len(requestedURLs); i++ {
 
-		urls, _, _ := GetURL(requestedURLs[i], false, 0, 0, quicbool, debugFile, debugLog, useTestbedBool)
+		urls, _, _, err := GetURL(requestedURLs[i], false, 0, 0, quicbool, debugFile, debugLog, useTestbedBool, ctx)
+		if err != nil {
+			return nil, err
+		}
 
-		// Call the fileParser in parser.go
-		mpd := fileParser(urls)
+		var mpd MPD
+		// HLS playlists are mapped onto the same MPD structure
+		if hls.IsPlaylistURL(requestedURLs[i]) || hls.IsPlaylist(urls) {
+			mpd, err = hlsParser(urls, requestedURLs[i], debugFile, debugLog, useTestbedBool, quicbool, ctx)
+		} else {
+			// Call the fileParser in parser.go
+			mpd, err = ParseMPD(urls)
+		}
+		if err != nil {
+			return nil, errors.New("problem parsing " + requestedURLs[i] + ": " + err.Error())
+		}
 
 		//Add the list of mpd structures to the list that will be returned
 		mpds = append(mpds, mpd)
 
 	}
 	// return the MPD list
-	return
+	return mpds, nil
 }
 
 /*
@@ -270,6 +299,16 @@ func fileParser(mpdBody []byte) MPD {
 	//extract everything from the file read in bytes to the structures
 	xml.Unmarshal(mpdBody, &mpd)
 
+	sortRepresentations(mpd)
+
+	//fmt.Println(mpd.Periods[0].AdaptationSet[0].Representation[0].Chunks)
+
+	return *mpd
+}
+
+// sortRepresentations :
+// * sort the representations of every adaptation set of the MPD by bandwidth, the lowest first
+func sortRepresentations(mpd *MPD) {
 	for _, period := range mpd.Periods {
 		for _, adapt := range period.AdaptationSet {
 			sort.SliceStable(adapt.Representation, func(i, j int) bool {
@@ -277,10 +316,27 @@ func fileParser(mpdBody []byte) MPD {
 			})
 		}
 	}
+}
 
-	//fmt.Println(mpd.Periods[0].AdaptationSet[0].Representation[0].Chunks)
-
-	return *mpd
+// ParseMPD :
+// * the MPD of an xml body, with the representations sorted like the MPDs read from a url
+// * return an error if the body is not an MPD or its durations cannot be read
+func ParseMPD(mpdBody []byte) (MPD, error) {
+	var mpd MPD
+	if err := xml.Unmarshal(mpdBody, &mpd); err != nil {
+		return mpd, err
+	}
+	// the durations are split again and again while streaming, so check them once here
+	for _, duration := range []string{mpd.MediaPresentationDuration, mpd.MaxSegmentDuration} {
+		if duration == "" {
+			continue
+		}
+		if _, err := parseMPDDuration(duration); err != nil {
+			return mpd, err
+		}
+	}
+	sortRepresentations(&mpd)
+	return mpd, nil
 }
 
 // func getSegmentSizes() {
@@ -371,7 +427,7 @@ func GetAllSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 	segmentNumber int, streamDuration int,
 	isByteRangeMPD bool,
 	maxBuffer int,
-	headerURL string, codec string, urlInput []string, debugLog bool, printToFile bool, client *http.Client) map[int]map[int][]int {
+	headerURL string, codec string, urlInput []string, debugLog bool, printToFile bool, client *http.Client, ctx context.Context) (map[int]map[int][]int, error) {
 
 	// store the seg header maps
 	var segHeadValues map[int]map[int][]int
@@ -381,16 +437,13 @@ func GetAllSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 	for mpdListIndex := 0; mpdListIndex < len(mpdList); mpdListIndex++ {
 
 		// check if the codec is in the MPD urls passed in
-		codecList, codecIndexList, _ := GetCodec(mpdList, codec, debugLog)
+		codecList, codecIndexList, _ := GetCodec(mpdList, codec, TransportFromContext(ctx).DebugFile(), debugLog)
 		// determine if the passed in codec is one of the codecs we use
 		usedCodec, codecIndex := utils.FindInStringArray(codecList[mpdListIndex], codec)
 
-		// check the codec and print error is false
+		// check the codec and return an error if false
 		if !usedCodec {
-			// print error message
-			fmt.Printf("*** - " + codec + " is not in the provided MPD\n")
-			// stop the app
-			utils.StopApp()
+			return nil, errors.New(codec + " is not in the provided MPD")
 		}
 		// save the current MPD Rep_rate Adaptation Set
 		currentMPDRepAdaptSet := codecIndexList[mpdListIndex][codecIndex]
@@ -399,9 +452,13 @@ func GetAllSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 		currentURL := strings.TrimSpace(urlInput[mpdListIndex])
 
 		// get the segment headers for this MPD url
-		segHeadValues[mpdListIndex] = getSegmentHeaders(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, printToFile, client)
+		headers, err := getSegmentHeaders(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, printToFile, client, ctx)
+		if err != nil {
+			return nil, err
+		}
+		segHeadValues[mpdListIndex] = headers
 	}
-	return segHeadValues
+	return segHeadValues, nil
 }
 
 // GetNSegmentHeaders :
@@ -411,7 +468,7 @@ func GetNSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 	segmentNumber int, streamDuration int,
 	isByteRangeMPD bool,
 	maxBuffer int,
-	headerURL string, codec string, urlInput []string, debugLog bool, useHeaderFile bool, client *http.Client) map[int]map[int][]int {
+	headerURL string, codec string, urlInput []string, debugLog bool, useHeaderFile bool, client *http.Client, ctx context.Context) (map[int]map[int][]int, error) {
 
 	// store the seg header maps
 	var segHeadValues map[int]map[int][]int
@@ -421,16 +478,13 @@ func GetNSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 	for mpdListIndex := 0; mpdListIndex < len(mpdList); mpdListIndex++ {
 
 		// check if the codec is in the MPD urls passed in
-		codecList, codecIndexList, _ := GetCodec(mpdList, codec, debugLog)
+		codecList, codecIndexList, _ := GetCodec(mpdList, codec, TransportFromContext(ctx).DebugFile(), debugLog)
 		// determine if the passed in codec is one of the codecs we use
 		usedCodec, codecIndex := utils.FindInStringArray(codecList[mpdListIndex], codec)
 
-		// check the codec and print error is false
+		// check the codec and return an error if false
 		if !usedCodec {
-			// print error message
-			fmt.Printf("*** - " + codec + " is not in the provided MPD\n")
-			// stop the app
-			utils.StopApp()
+			return nil, errors.New(codec + " is not in the provided MPD")
 		}
 
 		// save the current MPD Rep_rate Adaptation Set
@@ -440,14 +494,19 @@ func GetNSegmentHeaders(mpdList []MPD, codecIndexList [][]int,
 		currentURL := strings.TrimSpace(urlInput[mpdListIndex])
 
 		// get the segment headers for this MPD url from a file or from the webserver
+		var headers map[int][]int
+		var err error
 		if useHeaderFile {
-			segHeadValues[mpdListIndex] = getNSegmentHeadersFromFile(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog)
+			headers, err = getNSegmentHeadersFromFile(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, ctx)
 		} else {
-			segHeadValues[mpdListIndex] = getSegmentHeaders(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, useHeaderFile, client)
+			headers, err = getSegmentHeaders(mpdList, mpdListIndex, currentMPDRepAdaptSet, maxHeight, segmentNumber, streamDuration, isByteRangeMPD, maxBuffer, currentURL, headerURL, debugLog, useHeaderFile, client, ctx)
 		}
+		if err != nil {
+			return nil, err
+		}
+		segHeadValues[mpdListIndex] = headers
 	}
-	SegHeadValues = segHeadValues
-	return segHeadValues
+	return segHeadValues, nil
 }
 
 // getNSegmentHeadersFromFile :
@@ -466,17 +525,21 @@ func getNSegmentHeadersFromFile(mpdList []MPD, mpdListIndex int, currentMPDRepAd
 	segmentNumber int, streamDuration int,
 	isByteRangeMPD bool,
 	maxBuffer int, currentURL string,
-	headerURL string, debugLog bool) map[int][]int {
+	headerURL string, debugLog bool, ctx context.Context) (map[int][]int, error) {
 
 	// file name
 	var fileName string
+	debugFile := TransportFromContext(ctx).DebugFile()
 
 	//Map [rate]listduration
 	var contentLengthDictionary map[int][]int
 	contentLengthDictionary = make(map[int][]int)
 
 	// we need some info from the MPD file, so get these:
-	maxStreamDuration, _, highestMPDrepRateIndex, lowestMPDrepRateIndex, segmentDurationArray, _, _ := GetMPDValues(mpdList, mpdListIndex, maxHeight, streamDuration, maxBuffer, currentMPDRepAdaptSet, isByteRangeMPD, debugLog)
+	maxStreamDuration, _, highestMPDrepRateIndex, lowestMPDrepRateIndex, segmentDurationArray, _, _, err := GetMPDValues(mpdList, mpdListIndex, maxHeight, streamDuration, maxBuffer, currentMPDRepAdaptSet, isByteRangeMPD, debugFile, debugLog)
+	if err != nil {
+		return nil, err
+	}
 
 	// now get the maximum number of segments
 	maxSegments := maxStreamDuration / (segmentDurationArray[mpdListIndex] * glob.Conversion1000)
@@ -494,38 +557,35 @@ func getNSegmentHeadersFromFile(mpdList []MPD, mpdListIndex int, currentMPDRepAd
 
 	// create the output log file name
 	// we need clip name, codec, profile and segment duration
-	fileName = glob.DebugFolder + strconv.Itoa(segmentDuration) + "sec_" + mpdTitle
+	fileName = strconv.Itoa(segmentDuration) + "sec_" + mpdTitle
 	// if byte-range add this
 	if isByteRangeMPD {
 		fileName += glob.ByteRangeString
 	}
 	// add the tail to the file
-	fileName += "_" + profile + ".csv"
+	fileName = TransportFromContext(ctx).run.Path(output.SegmentHeaders, fileName+"_"+profile+".csv")
 
 	// check if the file already exists
-	_, err := os.Stat(fileName)
+	_, err = os.Stat(fileName)
 	if err != nil {
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "The segment header file for this MPD does not exist")
-		fmt.Println("The MPD header file: " + fileName + " does not exist, please change the -" + glob.GetHeaderName + " flag to on")
-		utils.StopApp()
-	} else {
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "The segment header file for this MPD already exists")
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "The segment header file for this MPD does not exist")
+		return nil, errors.New("the MPD header file: " + fileName + " does not exist, please change the -" + glob.GetHeaderName + " flag to on")
 	}
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "The segment header file for this MPD already exists")
 
 	// create the file with the fileName
 	f, err := os.Open(fileName)
 	if err != nil {
-		fmt.Println("Error when opening the file for segment lengths")
-		utils.StopApp()
+		return nil, errors.New("error when opening the file for segment lengths: " + err.Error())
 	}
 	defer f.Close()
 
 	reader := csv.NewReader(bufio.NewReader(f))
 
 	//for {
-	lines, error := reader.ReadAll()
-	if error != nil {
-		log.Fatal(error)
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New("error when reading the file for segment lengths: " + err.Error())
 	}
 
 	for rowIndex, columnIndex := range lines {
@@ -570,19 +630,25 @@ func getNSegmentHeadersFromFile(mpdList []MPD, mpdListIndex int, currentMPDRepAd
 		*/
 	}
 
-	return contentLengthDictionary
+	return contentLengthDictionary, nil
 }
 
 // GetContentLengthHeader :
 // get the header of the next segment to have the informations about it
-func GetContentLengthHeader(currentMPD MPD, currentURL string, currentMPDRepAdaptSet int, repRate int, segmentNumber int, adaptationSetBaseURL string, debugLog bool, client *http.Client) int {
+// return an error if the header cannot be requested
+func GetContentLengthHeader(currentMPD MPD, currentURL string, currentMPDRepAdaptSet int, repRate int, segmentNumber int, adaptationSetBaseURL string, debugLog bool, client *http.Client, ctx context.Context) (int, error) {
+
+	debugFile := TransportFromContext(ctx).DebugFile()
 
 	// get the base url
-	baseURL := GetNextSegment(currentMPD, segmentNumber, repRate, currentMPDRepAdaptSet)
+	baseURL, err := GetNextSegment(currentMPD, segmentNumber, repRate, currentMPDRepAdaptSet, ctx)
+	if err != nil {
+		return 0, err
+	}
 
 	// join the new file location to the base url
 	url := JoinURL(currentURL, adaptationSetBaseURL+baseURL, debugLog)
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "get header file from URL: "+url+"\n")
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "get header file from URL: "+url+"\n")
 
 	if url == "" {
 		fmt.Println("null urlHeader")
@@ -594,24 +660,25 @@ func GetContentLengthHeader(currentMPD MPD, currentURL string, currentMPDRepAdap
 	// or just add a description of the request:
 	// body, header, ...
 	// possibly needs a custom media type as well? or just the media type of the body?
-	abrqlog.MainTracer.Request(abrqlog.MediaTypeOther, url, "")
+	TransportFromContext(ctx).Tracer().Request(abrqlog.MediaTypeOther, url, "")
 
 	//Get the header of the url
 	resp, err := client.Head(url)
 	if err != nil {
-		panic(err)
+		TransportFromContext(ctx).Tracer().AbortRequest(url)
+		return 0, errors.New("the header of " + url + " cannot be requested: " + err.Error())
 	}
 	defer resp.Body.Close()
 
 	//TODO get size of header
-	//abrqlog.MainTracer.RequestUpdate(url, len(resp.Header))
+	//TransportFromContext(ctx).Tracer().RequestUpdate(url, len(resp.Header))
 
 	contentLen, err := strconv.Atoi(resp.Header.Get("Content-Length"))
 	if err != nil {
 		// fmt.Println("can't convert the content-length response to an int")
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "can't convert the content-length response to an int")
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "can't convert the content-length response to an int")
 	}
-	return contentLen
+	return contentLen, nil
 }
 
 // getSegmentHeaders :
@@ -629,9 +696,10 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 	segmentNumber int, streamDuration int,
 	isByteRangeMPD bool,
 	maxBuffer int, currentURL string,
-	headerURL string, debugLog bool, printToFile bool, client *http.Client) map[int][]int {
+	headerURL string, debugLog bool, printToFile bool, client *http.Client, ctx context.Context) (map[int][]int, error) {
 
 	var fileName string
+	debugFile := TransportFromContext(ctx).DebugFile()
 
 	// variable for the file
 	var f *os.File
@@ -641,7 +709,10 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 	contentLengthDictionary = make(map[int][]int)
 
 	// we need some info from the MPD file, so get these:
-	maxStreamDuration, _, highestMPDrepRateIndex, lowestMPDrepRateIndex, segmentDurationArray, _, baseURL := GetMPDValues(mpdList, mpdListIndex, maxHeight, streamDuration, maxBuffer, currentMPDRepAdaptSet, isByteRangeMPD, debugLog)
+	maxStreamDuration, _, highestMPDrepRateIndex, lowestMPDrepRateIndex, segmentDurationArray, _, baseURL, err := GetMPDValues(mpdList, mpdListIndex, maxHeight, streamDuration, maxBuffer, currentMPDRepAdaptSet, isByteRangeMPD, debugFile, debugLog)
+	if err != nil {
+		return nil, err
+	}
 
 	// now get the maximum number of segments
 	maxSegments := maxStreamDuration / (segmentDurationArray[mpdListIndex] * glob.Conversion1000)
@@ -661,26 +732,25 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 
 		// create the output log file name
 		// we need clip name, codec, profile and segment duration
-		fileName = glob.DebugFolder + strconv.Itoa(segmentDuration) + "sec_" + mpdTitle
+		fileName = strconv.Itoa(segmentDuration) + "sec_" + mpdTitle
 		// if byte-range add this
 		if isByteRangeMPD {
 			fileName += glob.ByteRangeString
 		}
 		// add the tail to the file
-		fileName += "_" + profile + ".csv"
+		fileName = TransportFromContext(ctx).run.Path(output.SegmentHeaders, fileName+"_"+profile+".csv")
 
 		// check if the file already exists
 		_, err := os.Stat(fileName)
 		if err == nil && !printToFile {
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "The segment header file for this MPD already exists")
-			fmt.Println("The MPD header file: " + fileName + " already exists, please change the -" + glob.GetHeaderName + " flag")
-			utils.StopApp()
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "The segment header file for this MPD already exists")
+			return nil, errors.New("the MPD header file: " + fileName + " already exists, please change the -" + glob.GetHeaderName + " flag")
 		}
 		// create the file with the fileName
+		os.MkdirAll(filepath.Dir(fileName), os.ModePerm)
 		f, err = os.Create(fileName)
 		if err != nil {
-			fmt.Println("Error when creating the file for segment lengths")
-			utils.StopApp()
+			return nil, errors.New("error when creating the file for segment lengths: " + err.Error())
 		}
 		defer f.Close()
 
@@ -749,7 +819,10 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 			for j := highestMPDrepRateIndex; j <= lowestMPDrepRateIndex; j++ {
 				// get the byte range of the next segment that will be downloaded
 				// we get this from the MPD struct
-				_, startRange, endRange := GetNextByteRangeURL(mpdList[mpdListIndex], i, j, currentMPDRepAdaptSet)
+				_, startRange, endRange, err := GetNextByteRangeURL(mpdList[mpdListIndex], i, j, currentMPDRepAdaptSet, ctx)
+				if err != nil {
+					return nil, err
+				}
 				// now we have the ranges, just substract one from the other
 				contentLength := endRange - startRange
 				// save this value in a dictionary
@@ -782,7 +855,10 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 			*/
 			for j := highestMPDrepRateIndex; j <= lowestMPDrepRateIndex; j++ {
 				// get the content length of the next segment that will be downloaded
-				contentLength := GetContentLengthHeader(mpdList[mpdListIndex], currentURL, currentMPDRepAdaptSet, j, i, baseURL, debugLog, client)
+				contentLength, err := GetContentLengthHeader(mpdList[mpdListIndex], currentURL, currentMPDRepAdaptSet, j, i, baseURL, debugLog, client, ctx)
+				if err != nil {
+					return nil, err
+				}
 				// save this value in a dictionary
 				contentLengthDictionary[j] = append(contentLengthDictionary[j], contentLength)
 				if printToFile {
@@ -797,7 +873,7 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 				} else {
 					for j := highestMPDrepRateIndex; j >= lowestMPDrepRateIndex; j-- {
 						// get the content length of the next segment that will be downloaded
-						contentLength := GetContentLengthHeader(mpdList[mpdListIndex], currentURL, currentMPDRepAdaptSet, j, i, baseURL, debugLog)
+						contentLength := GetContentLengthHeader(mpdList[mpdListIndex], currentURL, currentMPDRepAdaptSet, j, i, baseURL, debugLog, client, ctx)
 						// save this value in a dictionary
 						contentLengthDictionary[j] = append(contentLengthDictionary[j], contentLength)
 						if printToFile {
@@ -815,7 +891,7 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
 			}
 		}
 	}
-	return contentLengthDictionary
+	return contentLengthDictionary, nil
 }
 
 // GetCodec :
@@ -824,7 +900,7 @@ func getSegmentHeaders(mpdList []MPD, mpdListIndex int, currentMPDRepAdaptSet in
  * return an array of the codecs offered in the MPDs
  * return the index for the codec provided, -1 for all codecs
  */
-func GetCodec(mpdList []MPD, codec string, debugLog bool) ([][]string, [][]int, bool) {
+func GetCodec(mpdList []MPD, codec string, debugFile string, debugLog bool) ([][]string, [][]int, bool) {
 
 	var tempCodecList [][]string
 	var tempIndexList [][]int
@@ -877,8 +953,8 @@ func GetCodec(mpdList []MPD, codec string, debugLog bool) ([][]string, [][]int,
 			}
 		}
 
-		logging.DebugPrintfIntArray(glob.DebugFile, debugLog, "DEBUG: ", "Codec Index List : %v for MPD "+strconv.Itoa(i+1), codecIndexList)
-		logging.DebugPrintfStringArray(glob.DebugFile, debugLog, "DEBUG: ", "Codec List : %v for MPD "+strconv.Itoa(i+1), codecList)
+		logging.DebugPrintfIntArray(debugFile, debugLog, "DEBUG: ", "Codec Index List : %v for MPD "+strconv.Itoa(i+1), codecIndexList)
+		logging.DebugPrintfStringArray(debugFile, debugLog, "DEBUG: ", "Codec List : %v for MPD "+strconv.Itoa(i+1), codecList)
 
 		// save an array of array
 		tempCodecList = append(tempCodecList, codecList)
@@ -891,7 +967,8 @@ func GetCodec(mpdList []MPD, codec string, debugLog bool) ([][]string, [][]int,
 
 // GetMPDValues :
 // get important values from the provided MPD
-func GetMPDValues(mpd []MPD, mpdListIndex int, maxHeight int, streamDuration int, maxBuffer int, currentMPDRepAdaptSet int, isByteRangeMPD bool, debugLog bool) (int, int, int, int, []int, []int, string) {
+// return an error if the representation rates of the MPD are not in order
+func GetMPDValues(mpd []MPD, mpdListIndex int, maxHeight int, streamDuration int, maxBuffer int, currentMPDRepAdaptSet int, isByteRangeMPD bool, debugFile string, debugLog bool) (int, int, int, int, []int, []int, string, error) {
 
 	var maxStreamDuration int
 	var segmentDurationArray []int
@@ -921,45 +998,50 @@ func GetMPDValues(mpd []MPD, mpdListIndex int, maxHeight int, streamDuration int
 		maxStreamDuration = segmentDuration*(maxSegments-1) + lastSegmentDuration
 	}
 	maxBufferLevel = maxBuffer
-	minMPDlistIndex = GetMPDheightIndex(mpd[mpdListIndex], maxHeight, currentMPDRepAdaptSet, debugLog)
+	minMPDlistIndex = GetMPDheightIndex(mpd[mpdListIndex], maxHeight, currentMPDRepAdaptSet, debugFile, debugLog)
 	maxMPDlistIndex = GetMaxListIndex(mpd[mpdListIndex], currentMPDRepAdaptSet)
 	bandwithList = GetRepresentationBandwidth(mpd[mpdListIndex], currentMPDRepAdaptSet)
 
 	// determine if the MPD rep_rates are highest to lowest or lowest to highest
 	if bandwithList[minMPDlistIndex] >= bandwithList[maxMPDlistIndex] {
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Rep_Rate "+strconv.Itoa(minMPDlistIndex)+" @ "+strconv.Itoa(bandwithList[minMPDlistIndex])+" is bigger than or equal to Rep_Rate "+strconv.Itoa(maxMPDlistIndex)+" @ "+strconv.Itoa(bandwithList[maxMPDlistIndex]))
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Rep_Rate "+strconv.Itoa(minMPDlistIndex)+" @ "+strconv.Itoa(bandwithList[minMPDlistIndex])+" is bigger than or equal to Rep_Rate "+strconv.Itoa(maxMPDlistIndex)+" @ "+strconv.Itoa(bandwithList[maxMPDlistIndex]))
 	} else {
 		// rep_rates are lowest to highest
 		// max index is reversed
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Rep_Rate "+strconv.Itoa(maxMPDlistIndex)+" @ "+strconv.Itoa(bandwithList[maxMPDlistIndex])+" is smaller than Rep_Rate "+strconv.Itoa(minMPDlistIndex)+" @ "+strconv.Itoa(bandwithList[minMPDlistIndex]))
-		fmt.Println("There is a problem with the indexes set for the representation rates in the MPD file, so stop")
-		os.Exit(3)
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Rep_Rate "+strconv.Itoa(maxMPDlistIndex)+" @ "+strconv.Itoa(bandwithList[maxMPDlistIndex])+" is smaller than Rep_Rate "+strconv.Itoa(minMPDlistIndex)+" @ "+strconv.Itoa(bandwithList[minMPDlistIndex]))
+		return 0, 0, 0, 0, nil, nil, "", errors.New("there is a problem with the indexes set for the representation rates in the MPD file")
 	}
 
-	logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "Collect metrics from selected MPD file")
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Maximum stream duration: "+strconv.Itoa(maxStreamDuration))
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Maximum number of segments: "+strconv.Itoa(maxSegments))
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Maximum buffer: "+strconv.Itoa(maxBufferLevel))
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Minimum MPD index: "+strconv.Itoa(minMPDlistIndex))
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Maximum MPD index: "+strconv.Itoa(maxMPDlistIndex))
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Segment Duration: "+strconv.Itoa(segmentDurationArray[mpdListIndex]))
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Base URL: "+baseURL)
-	logging.DebugPrintfIntArray(glob.DebugFile, debugLog, "\nDEBUG: ", "\nBandwidth List : %v\n\n", bandwithList)
+	logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "Collect metrics from selected MPD file")
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Maximum stream duration: "+strconv.Itoa(maxStreamDuration))
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Maximum number of segments: "+strconv.Itoa(maxSegments))
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Maximum buffer: "+strconv.Itoa(maxBufferLevel))
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Minimum MPD index: "+strconv.Itoa(minMPDlistIndex))
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Maximum MPD index: "+strconv.Itoa(maxMPDlistIndex))
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Segment Duration: "+strconv.Itoa(segmentDurationArray[mpdListIndex]))
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Base URL: "+baseURL)
+	logging.DebugPrintfIntArray(debugFile, debugLog, "\nDEBUG: ", "\nBandwidth List : %v\n\n", bandwithList)
 
 	// return the values
-	return maxStreamDuration, maxBufferLevel, minMPDlistIndex, maxMPDlistIndex, segmentDurationArray, bandwithList, baseURL
+	return maxStreamDuration, maxBufferLevel, minMPDlistIndex, maxMPDlistIndex, segmentDurationArray, bandwithList, baseURL, nil
 }
 
 // GetNextSegment :
 /*
  * select the right segment in the MPD given
- * Return the URL of this segment
+ * Return the URL of this segment, or an error if a HLS segment is not in its playlist
  */
-func GetNextSegment(mpd MPD, SegNumber int, SegQUALITY int, currentMPDRepAdaptSet int) string {
+func GetNextSegment(mpd MPD, SegNumber int, SegQUALITY int, currentMPDRepAdaptSet int, ctx context.Context) (string, error) {
 
 	// the base url for this segment/rep_rate
 	var repRateBaseURL string
 
+	// HLS segments are listed one by one in the media playlist
+	if mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[(SegQUALITY)].PlaylistURL != "" {
+		nextURL, _, err := getHLSSegment(mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[(SegQUALITY)], SegNumber, ctx)
+		return nextURL, err
+	}
+
 	// get the base media url for a given representation rate
 	// remember index's are one less than rep_rate value
 	mimType := mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[(SegQUALITY)].MimeType
@@ -981,12 +1063,12 @@ func GetNextSegment(mpd MPD, SegNumber int, SegQUALITY int, currentMPDRepAdaptSe
 
 	nextURL = strings.Replace(nextURL, "$Bandwidth$", bw, -1)
 
-	return nextURL
+	return nextURL, nil
 }
 
 // GetMPDheightIndex :
 // get the maximum index for a given resolution height in a provided MPD file
-func GetMPDheightIndex(mpd MPD, maxHeight int, currentMPDRepAdaptSet int, debugLog bool) int {
+func GetMPDheightIndex(mpd MPD, maxHeight int, currentMPDRepAdaptSet int, debugFile string, debugLog bool) int {
 
 	// define the maximum height index
 	var maxHeightIndex = 0
@@ -998,8 +1080,8 @@ func GetMPDheightIndex(mpd MPD, maxHeight int, currentMPDRepAdaptSet int, debugL
 
 		// if the representation height is the same as the passed in height
 		if mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[j].Height <= maxHeight {
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "getMPDheightIndex - maxHeight: "+strconv.Itoa(maxHeight))
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "getMPDheightIndex - bitrate: "+strconv.Itoa(mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[j].BandWidth))
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "getMPDheightIndex - maxHeight: "+strconv.Itoa(maxHeight))
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "getMPDheightIndex - bitrate: "+strconv.Itoa(mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[j].BandWidth))
 
 			// determine which rep index has the highest bitrate
 			if mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[j].BandWidth > bitrate {
@@ -1010,7 +1092,7 @@ func GetMPDheightIndex(mpd MPD, maxHeight int, currentMPDRepAdaptSet int, debugL
 				maxHeightIndex = j + 1
 
 				//logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "rep_rate for maxHeight: "+strconv.Itoa(mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[j].ID-1))
-				logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "rep_rate for maxHeight:"+strconv.Itoa(j))
+				logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "rep_rate for maxHeight:"+strconv.Itoa(j))
 			}
 		}
 	}
@@ -1060,6 +1142,11 @@ func GetByteRangeSegmentDetails(mpd []MPD, mpdListIndex int, currentMPDRepAdaptS
 		segmentDurations = append(segmentDurations, duration/timeScale)
 	}
 
+	// a HLS playlist lists its segments, which may be shorter than the rounded segment duration
+	if count, ok := hlsSegmentCount(mpd[mpdListIndex].Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[0]); ok {
+		return count, segmentDurations
+	}
+
 	// return the number of segments and segment duration
 	return streamDuration / segmentDurations[mpdListIndex], segmentDurations
 }
@@ -1106,13 +1193,26 @@ func GetSegmentDetails(mpd []MPD, mpdListIndex int, adaptationSetIndex ...int) (
 		segmentDurations = append(segmentDurations, duration/timeScale)
 	}
 
+	// a HLS playlist lists its segments, which may be shorter than the rounded segment duration
+	if count, ok := hlsSegmentCount(mpd[mpdListIndex].Periods[0].AdaptationSet[adaptIndex].Representation[0]); ok {
+		return count, segmentDurations
+	}
+
 	// return the number of segments and segment duration
 	return streamDuration / segmentDurations[mpdListIndex], segmentDurations
 }
 
 // SplitMPDSegmentDuration :
 // get the per second details from the MPD segments
+// the durations of an MPD are checked by ParseMPD, so a duration that cannot be read is 0
 func SplitMPDSegmentDuration(mpdSegDuration string) int {
+	seconds, _ := parseMPDDuration(mpdSegDuration)
+	return seconds
+}
+
+// parseMPDDuration :
+// * the seconds of an MPD duration, an error if it has no minutes or its fields cannot be converted to int
+func parseMPDDuration(mpdSegDuration string) (int, error) {
 
 	var totalTimeinSeconds int
 	var streamDuration string
@@ -1129,9 +1229,7 @@ func SplitMPDSegmentDuration(mpdSegDuration string) int {
 		// if there are hours, convert to seconds
 		i3, err := strconv.Atoi(streamDurationH)
 		if err != nil {
-			fmt.Println("*** Problem with converting segment hours to int ***")
-			// stop the app
-			utils.StopApp()
+			return 0, errors.New("problem with converting the hours of " + mpdSegDuration + " to int")
 		}
 		if i3 > 0 {
 			totalTimeinSeconds = i3 * 60 * 60
@@ -1145,13 +1243,14 @@ func SplitMPDSegmentDuration(mpdSegDuration string) int {
 
 	// split around the Minutes
 	s := strings.Split(streamDuration, "M")
+	if len(s) < 2 {
+		return 0, errors.New("problem with converting the minutes of " + mpdSegDuration + " to int")
+	}
 
 	// if there are minutes, convert to seconds
 	i1, err := strconv.Atoi(s[0])
 	if err != nil {
-		fmt.Println("*** Problem with converting segment minutes to int ***")
-		// stop the app
-		utils.StopApp()
+		return 0, errors.New("problem with converting the minutes of " + mpdSegDuration + " to int")
 	}
 	if i1 > 0 {
 		totalTimeinSeconds = i1 * 60
@@ -1162,11 +1261,11 @@ func SplitMPDSegmentDuration(mpdSegDuration string) int {
 	s = strings.Split(s[0], ".")
 	i2, err := strconv.Atoi(s[0])
 	if err != nil {
-		fmt.Println("*** Problem with converting segment seconds to int ***")
+		return totalTimeinSeconds, errors.New("problem with converting the seconds of " + mpdSegDuration + " to int")
 	}
 
 	// return the minutes and seconds (in seconds)
-	return totalTimeinSeconds + i2
+	return totalTimeinSeconds + i2, nil
 }
 
 // URLList :
@@ -1187,24 +1286,23 @@ func URLList(urlString string) []string {
 * Read the string of url parameters passed to the app
 * split the urls to have a list
 * call getStructList with the list to have the MPDs
-* return a struct of MPDs
+* return a struct of MPDs, or an error if the MPDs cannot be read
  */
-func ReadURLArray(args string, debugLog bool, useTestbedBool bool, quicbool bool) (structList []MPD) {
+func ReadURLArray(args string, debugLog bool, useTestbedBool bool, quicbool bool, ctx context.Context) (structList []MPD, err error) {
 
 	var requestedURLs []string
+	debugFile := TransportFromContext(ctx).DebugFile()
 
 	//fmt.Println("URL array : ", args)
 	// print to debug log
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "URL array passed : "+args)
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "URL array passed : "+args)
 
 	// lets now split the url(s) around the ","
 	urlInput := URLList(args)
 
 	// if more than one url is passed in, then stop and print error
 	if len(urlInput) > 1 {
-		fmt.Println("*** only one url can be passed to goDASH, please remove any additional URLs. Use -h for more info ***")
-		fmt.Println(args)
-		os.Exit(3)
+		return nil, errors.New("only one url can be passed to goDASH, please remove any additional URLs from " + args + ". Use -h for more info")
 	}
 
 	for i := 0; i < len(urlInput); i++ {
@@ -1216,14 +1314,79 @@ func ReadURLArray(args string, debugLog bool, useTestbedBool bool, quicbool bool
 
 		//fmt.Printf("Parameters: %s\n", urlInput[i])
 		// print to debug log
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Parameters: "+urlInput[i])
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Parameters: "+urlInput[i])
 	}
 	if len(requestedURLs) > 0 {
+		// the MPD download is a startup sample of the fast start
+		probe, done := TransportFromContext(ctx).Accountant().StartupProbe()
 		// get the []struct of MPDs
-		structList = getStructList(requestedURLs, glob.DebugFile, debugLog, useTestbedBool, quicbool)
+		structList, err = getStructList(requestedURLs, debugFile, debugLog, useTestbedBool, quicbool, xlayer.WithAccountant(ctx, probe))
+		done()
+	}
+
+	return structList, err
+}
+
+// ReverseRepresentations :
+/*
+ * if the representations of an adaptation set are in ascending order (index 0 has the lowest rate)
+ * reverse them, so index 0 has the highest rate, and reset the ID numbers
+ * returns true if the representations were reversed
+ */
+func ReverseRepresentations(mpd *MPD, currentMPDRepAdaptSet int) bool {
+
+	representations := mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation
+	mpdLength := len(representations)
+	if mpdLength == 0 || representations[0].BandWidth >= representations[mpdLength-1].BandWidth {
+		return false
 	}
 
-	return structList
+	for i, j := 0, mpdLength-1; i < j; i, j = i+1, j-1 {
+		representations[i], representations[j] = representations[j], representations[i]
+	}
+	for j := range representations {
+		representations[j].ID = strconv.Itoa(j + 1)
+	}
+	return true
+}
+
+// GetMPDStreamDuration :
+/*
+ * get the stream duration in seconds of the first MPD in the list,
+ * from the MediaPresentationDuration, or from the number of segments and the MaxSegmentDuration
+ * returns -1 if the MPD has neither
+ */
+func GetMPDStreamDuration(mpdList []MPD, audioContent bool) int {
+
+	// first work out if we are using a byte-range MPD
+	isByteRangeMPD := GetRepresentationBaseURL(mpdList[0], 0) != glob.RepRateBaseURL
+
+	// variables
+	var segmentDurationArray []int
+	var maxSegments int
+
+	// get max number segments and segment duration from the first URL MPD - index 0
+	if isByteRangeMPD {
+		// if this is a byte-range MPD, get byte range metrics
+		maxSegments, segmentDurationArray = GetByteRangeSegmentDetails(mpdList, 0, 0)
+	} else {
+		// if not, get standard profile metrics
+		maxSegments, segmentDurationArray = GetSegmentDetails(mpdList, 0)
+		// get the audio info as well
+		if audioContent {
+			maxSegments, segmentDurationArray = GetSegmentDetails(mpdList, 0, 0)
+		}
+	}
+
+	if mpdList[0].MediaPresentationDuration != "" {
+		return SplitMPDSegmentDuration(mpdList[0].MediaPresentationDuration)
+	} else if mpdList[0].MaxSegmentDuration != "" {
+		// get the segment duration of the last segment (typically larger than normal)
+		lastSegmentDuration := SplitMPDSegmentDuration(mpdList[0].MaxSegmentDuration)
+		// MPD stream duration from the current segment duration for the first MPD in the url list
+		return segmentDurationArray[0]*(maxSegments-1) + lastSegmentDuration
+	}
+	return -1
 }
 
 // GetFullStreamHeader :
@@ -1244,42 +1407,53 @@ func GetFullStreamHeader(mpd MPD, isByteRangeMPD bool, currentMPDRepAdaptSet int
 
 // GetNextByteRangeURL :
 // Return the base URL, start and end range for the byte range MPD
-func GetNextByteRangeURL(mpd MPD, SegNumber int, SegQUALITY int, currentMPDRepAdaptSet int) (string, int, int) {
+// or an error if the segment or its byte range cannot be read
+func GetNextByteRangeURL(mpd MPD, SegNumber int, SegQUALITY int, currentMPDRepAdaptSet int, ctx context.Context) (string, int, int, error) {
 
 	// get the base media url for a given representation rate
 	// remember index's are one less than rep_rate value
 	baseURL := mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[(SegQUALITY)].BaseURL
-	mediaRange := mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[(SegQUALITY)].SegmentList.SegmentURL[SegNumber-1].MediaRange
+	var mediaRange string
+
+	// HLS segments are listed one by one in the media playlist
+	if mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[(SegQUALITY)].PlaylistURL != "" {
+		var err error
+		baseURL, mediaRange, err = getHLSSegment(mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[(SegQUALITY)], SegNumber, ctx)
+		if err != nil {
+			return "", 0, 0, err
+		}
+	} else {
+		mediaRange = mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[(SegQUALITY)].SegmentList.SegmentURL[SegNumber-1].MediaRange
+	}
 
 	// get the start and end ranges
-	startRange, endRange := splitByteRange(mediaRange)
+	startRange, endRange, err := splitByteRange(mediaRange)
 
-	return baseURL, startRange, endRange
+	return baseURL, startRange, endRange, err
 }
 
 // splitByteRange :
 // split and return the string range into start and end int values
-func splitByteRange(byteRange string) (int, int) {
+func splitByteRange(byteRange string) (int, int, error) {
 
 	// split the input string around the "-"
 	s := strings.Split(byteRange, "-")
+	if len(s) != 2 {
+		return 0, 0, errors.New("problem with converting the byte range " + byteRange + " to int")
+	}
 
 	// get the start range
 	startRange, err := strconv.Atoi(s[0])
 	if err != nil {
-		fmt.Println("*** Problem with converting Byte Range to int ***")
-		// stop the app
-		utils.StopApp()
+		return 0, 0, errors.New("problem with converting the byte range " + byteRange + " to int")
 	}
 
 	// get the endRange
 	endRange, err := strconv.Atoi(s[1])
 	if err != nil {
-		fmt.Println("*** Problem with converting Byte Range to int ***")
-		// stop the app
-		utils.StopApp()
+		return 0, 0, errors.New("problem with converting the byte range " + byteRange + " to int")
 	}
 
 	// return the byte ranges
-	return startRange, endRange
+	return startRange, endRange, nil
 }
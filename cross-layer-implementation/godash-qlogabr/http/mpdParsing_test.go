@@ -0,0 +1,21 @@
+package http
+
+import "testing"
+
+func TestParseMPDSortsTheRepresentations(t *testing.T) {
+	body := []byte(`<MPD mediaPresentationDuration="PT0H0M20.000S"><Period><AdaptationSet>` +
+		`<Representation id="high" bandwidth="2000000"/><Representation id="low" bandwidth="500000"/>` +
+		`</AdaptationSet></Period></MPD>`)
+	mpd, err := ParseMPD(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reps := mpd.Periods[0].AdaptationSet[0].Representation
+	if len(reps) != 2 || reps[0].BandWidth != 500000 || reps[1].BandWidth != 2000000 {
+		t.Errorf("the representations are %+v, want the lowest bandwidth first", reps)
+	}
+
+	if _, err := ParseMPD([]byte(`<MPD mediaPresentationDuration="20 seconds"/>`)); err == nil {
+		t.Error("an MPD with an unreadable duration is not an error")
+	}
+}
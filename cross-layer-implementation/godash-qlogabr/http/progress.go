@@ -0,0 +1,67 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// Progress : the bytes of a response body received so far, read while the request downloads
+type Progress struct {
+	received int64
+}
+
+type progressKey struct{}
+
+// Received : the bytes of the body received so far
+func (p *Progress) Received() int {
+	return int(atomic.LoadInt64(&p.received))
+}
+
+// WithProgress :
+// * return a copy of ctx whose GetFile request counts the bytes of its body in p
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// progressReader : a reader that adds the bytes it reads to a progress
+type progressReader struct {
+	io.Reader
+	progress *Progress
+}
+
+func (r progressReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	atomic.AddInt64(&r.progress.received, int64(n))
+	return n, err
+}
+
+// countProgress :
+// * the body, counted in the progress added by WithProgress, if ctx has one
+func countProgress(body io.Reader, ctx context.Context) io.Reader {
+	if p, ok := ctx.Value(progressKey{}).(*Progress); ok && p != nil {
+		return progressReader{Reader: body, progress: p}
+	}
+	return body
+}
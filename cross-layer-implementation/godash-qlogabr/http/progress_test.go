@@ -0,0 +1,47 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/uccmisl/godash/output"
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+func TestGetFileProgress(t *testing.T) {
+	// the server sends half of the segment and waits before the rest
+	rest := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1000))
+		w.(http.Flusher).Flush()
+		<-rest
+		w.Write(make([]byte, 1000))
+	}))
+	defer server.Close()
+	defer close(rest)
+
+	progress := &Progress{}
+	transport := NewTransport(output.Run{Root: t.TempDir()}, nil, nil)
+	ctx := WithProgress(WithTransport(context.Background(), transport), progress)
+	done := make(chan int, 1)
+	go func() {
+		_, segSize, _, _, _, _, _ := GetFile(server.URL+"/video.mpd", "seg_1.m4s", t.TempDir(), false, 0, 0, 1, 2, false, false, "", false, false, 0, false, false, "", abrqlog.MediaTypeVideo, ctx)
+		done <- segSize
+	}()
+
+	// the bytes of the body are counted while it downloads
+	deadline := time.Now().Add(2 * time.Second)
+	for progress.Received() < 1000 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if received := progress.Received(); received != 1000 {
+		t.Fatalf("%d bytes received while the first half of the body downloads, want 1000", received)
+	}
+	rest <- struct{}{}
+	if segSize := <-done; segSize != 2000 || progress.Received() != 2000 {
+		t.Errorf("%d bytes received of a segment of %d, want 2000", progress.Received(), segSize)
+	}
+}
@@ -0,0 +1,424 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/output"
+)
+
+// segmentSizeProbeWorkers : number of parallel segment size probes
+const segmentSizeProbeWorkers = 8
+
+// segmentSizeFilePrefix : prefix of the cached segment size sidecar files
+const segmentSizeFilePrefix = "segment_sizes_"
+
+// segmentSizeFileKeyPrefix : prefix of the line of a sidecar file with the MPD and representations of its sizes
+const segmentSizeFileKeyPrefix = "#"
+
+// BuildSegmentSizeIndex :
+/*
+ * fill in the per segment sizes (Chunks, in bits) and MaxAvgRatio of every
+ * representation in an adaptation set, so BBA2 and the stall predictor
+ * no longer need an MPD rewritten by Convert_to_BBA2.py
+ * representations that already have <chunks> in the MPD are left as is
+ * sizes come from, in order:
+ * - a cached sidecar file from a previous run
+ * - the SegmentList mediaRange of a byte-range MPD
+ * - the sidx box of a SegmentBase indexRange
+ * - parallel HEAD requests, or "Range: bytes=0-0" when HEAD has no Content-Length
+ * probed sizes are saved in the sidecar file for the next run, unless a probe failed,
+ * the sidecar is only read for the same MPD url, duration and representations
+ */
+func BuildSegmentSizeIndex(mpd *MPD, currentURL string, currentMPDRepAdaptSet int, isByteRangeMPD bool, quicBool bool, debugLog bool, useTestbedBool bool, ctx context.Context) {
+
+	adaptationSet := &mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet]
+	debugFile := TransportFromContext(ctx).DebugFile()
+
+	// check if we have anything to do
+	missing := false
+	for _, rep := range adaptationSet.Representation {
+		if rep.Chunks == "" {
+			missing = true
+		}
+	}
+	if !missing {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment sizes are defined in the MPD")
+		return
+	}
+
+	sidecarFile := segmentSizeFileName(currentURL, currentMPDRepAdaptSet, ctx)
+	key := segmentSizeFileKey(*mpd, currentURL, currentMPDRepAdaptSet)
+	fileKey, cached := readSegmentSizeFile(sidecarFile)
+	if fileKey != key {
+		if len(cached) > 0 {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "the segment size file "+sidecarFile+" is of another MPD, the sizes are read again")
+		}
+		cached = nil
+	}
+	probed := false
+	complete := true
+
+	for repIndex := range adaptationSet.Representation {
+		rep := &adaptationSet.Representation[repIndex]
+		if rep.Chunks != "" {
+			continue
+		}
+
+		sizes, ok := cached[rep.BandWidth]
+		source := "sidecar file " + sidecarFile
+		if !ok {
+			switch {
+			case len(rep.SegmentList.SegmentURL) > 0 && rep.SegmentList.SegmentURL[0].MediaRange != "":
+				sizes = segmentSizesFromMediaRange(rep.SegmentList.SegmentURL)
+				source = "SegmentList mediaRange"
+			case rep.SegmentBase.IndexRange != "":
+				sizes = segmentSizesFromSidx(*rep, currentURL, adaptationSet.BaseURL, quicBool, debugLog, useTestbedBool, ctx)
+				source = "sidx box"
+			default:
+				var ok bool
+				sizes, ok = segmentSizesFromProbes(*mpd, currentURL, currentMPDRepAdaptSet, repIndex, isByteRangeMPD, quicBool, debugLog, useTestbedBool, ctx)
+				source = "segment probes"
+				probed = true
+				complete = complete && ok
+			}
+		}
+
+		rep.Chunks, rep.MaxAvgRatio = chunkListAndRatio(sizes)
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment sizes of rep_rate "+strconv.Itoa(rep.BandWidth)+" from "+source+": "+strconv.Itoa(len(sizes))+" segments")
+	}
+
+	// save the probed sizes, so we only need to probe once per MPD
+	// an index with a failed probe is probed again on the next run
+	if probed && complete {
+		writeSegmentSizeFile(sidecarFile, key, adaptationSet.Representation, debugFile, debugLog)
+	}
+}
+
+// segmentSizeFileName :
+// * the sidecar file name, based on the MPD url and adaptation set
+func segmentSizeFileName(currentURL string, currentMPDRepAdaptSet int, ctx context.Context) string {
+	mpdName := strings.TrimSpace(currentURL)
+	mpdName = strings.TrimPrefix(strings.TrimPrefix(mpdName, "https://"), "http://")
+	mpdName = strings.TrimSuffix(mpdName, path.Ext(mpdName))
+	replacer := strings.NewReplacer("/", "_", ":", "_", "?", "_", "&", "_", "=", "_")
+	return TransportFromContext(ctx).run.Path(output.SegmentHeaders, segmentSizeFilePrefix+replacer.Replace(mpdName)+"_"+strconv.Itoa(currentMPDRepAdaptSet)+".csv")
+}
+
+// segmentSizeFileKey :
+/*
+ * the first line of a sidecar file, the sizes it holds are only valid for
+ * the MPD url, its duration and the "<id>:<bandwidth>" of every representation of the adaptation set
+ */
+func segmentSizeFileKey(mpd MPD, currentURL string, currentMPDRepAdaptSet int) string {
+	var representations []string
+	for _, rep := range mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation {
+		representations = append(representations, rep.ID+":"+strconv.Itoa(rep.BandWidth))
+	}
+	return segmentSizeFileKeyPrefix + strings.TrimSpace(currentURL) + " " + mpd.MediaPresentationDuration + " " + strings.Join(representations, ",")
+}
+
+// ReadSegmentSizeFile :
+/*
+ * read a sidecar file, one representation per line
+ * "<bandwidth>,<size of segment 1 in bits>,<size of segment 2 in bits>,..."
+ * returns an empty map if the file does not exist,
+ * a representation with a size that is not positive is left out
+ */
+func ReadSegmentSizeFile(fileName string) map[int][]int {
+	_, sizes := readSegmentSizeFile(fileName)
+	return sizes
+}
+
+// readSegmentSizeFile :
+// * the key of the MPD the sidecar file was written for, and the sizes of ReadSegmentSizeFile
+func readSegmentSizeFile(fileName string) (string, map[int][]int) {
+
+	sizes := make(map[int][]int)
+	key := ""
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return key, sizes
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// long streams have long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, segmentSizeFileKeyPrefix) {
+			key = line
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			continue
+		}
+		bandwidth, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		var repSizes []int
+		for _, field := range fields[1:] {
+			size, err := strconv.Atoi(field)
+			if err != nil || size <= 0 {
+				repSizes = nil
+				break
+			}
+			repSizes = append(repSizes, size)
+		}
+		if repSizes != nil {
+			sizes[bandwidth] = repSizes
+		}
+	}
+
+	return key, sizes
+}
+
+// writeSegmentSizeFile :
+// * save the key of the MPD and the chunk list of every representation to the sidecar file
+func writeSegmentSizeFile(fileName string, key string, representations []Representation, debugFile string, debugLog bool) {
+
+	os.MkdirAll(filepath.Dir(fileName), os.ModePerm)
+	f, err := os.Create(fileName)
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to create the segment size file "+fileName)
+		return
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, key)
+	for _, rep := range representations {
+		if rep.Chunks != "" {
+			fmt.Fprintln(f, strconv.Itoa(rep.BandWidth)+","+rep.Chunks)
+		}
+	}
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment sizes saved to "+fileName)
+}
+
+// segmentSizesFromMediaRange :
+// * the size in bits of every segment of a byte-range SegmentList, 0 if its media range cannot be read
+func segmentSizesFromMediaRange(segmentURLs []segmentURL) []int {
+	var sizes []int
+	for _, segURL := range segmentURLs {
+		startRange, endRange, err := splitByteRange(segURL.MediaRange)
+		if err != nil {
+			sizes = append(sizes, 0)
+			continue
+		}
+		sizes = append(sizes, (endRange-startRange+1)*8)
+	}
+	return sizes
+}
+
+// segmentSizesFromSidx :
+// * download the sidx box of a SegmentBase representation and return the subsegment sizes in bits
+func segmentSizesFromSidx(rep Representation, currentURL string, adaptationSetBaseURL string, quicBool bool, debugLog bool, useTestbedBool bool, ctx context.Context) []int {
+
+	debugFile := TransportFromContext(ctx).DebugFile()
+	url := JoinURL(currentURL, adaptationSetBaseURL+rep.BaseURL, debugLog)
+
+	startRange, endRange, err := splitByteRange(rep.SegmentBase.IndexRange)
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to read the index range of "+url+": "+err.Error())
+		return nil
+	}
+
+	body, _, _, err := GetURL(url, true, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, ctx)
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to download the sidx box of "+url+": "+err.Error())
+		return nil
+	}
+
+	sizes, err := parseSidx(body)
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to read the sidx box of "+url+": "+err.Error())
+		return nil
+	}
+	return sizes
+}
+
+// segmentSizesFromProbes :
+/*
+ * get the size in bits of every segment of a representation with parallel requests
+ * a failed probe is tried once more, a segment whose size is still unknown gets the size of its bandwidth,
+ * so no algorithm takes it for free, and false is returned so the sizes are not saved
+ */
+func segmentSizesFromProbes(mpd MPD, currentURL string, currentMPDRepAdaptSet int, repIndex int, isByteRangeMPD bool, quicBool bool, debugLog bool, useTestbedBool bool, ctx context.Context) ([]int, bool) {
+
+	// the number of segments in this MPD
+	numSegments, segmentDurations := GetSegmentDetails([]MPD{mpd}, 0, currentMPDRepAdaptSet)
+	if numSegments <= 0 {
+		return nil, false
+	}
+	adaptationSetBaseURL := mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].BaseURL
+
+	// the shared client, over HTTP/3 when quic is on
+	debugFile := TransportFromContext(ctx).DebugFile()
+	_, client, _, err := GetHTTPClient(quicBool, debugFile, debugLog, useTestbedBool, ctx)
+	if err != nil {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to probe the segment sizes: "+err.Error())
+		return nil, false
+	}
+
+	sizes := make([]int, numSegments)
+	segmentNumbers := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < segmentSizeProbeWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for segmentNumber := range segmentNumbers {
+				segURL, err := GetNextSegment(mpd, segmentNumber, repIndex, currentMPDRepAdaptSet, ctx)
+				if err != nil {
+					continue
+				}
+				url := JoinURL(currentURL, adaptationSetBaseURL+segURL, debugLog)
+				sizes[segmentNumber-1] = probeContentLength(client, url, ctx) * 8
+			}
+		}()
+	}
+	// a cancelled stream stops probing, the probes in flight are cancelled with it
+	for segmentNumber := 1; segmentNumber <= numSegments && ctx.Err() == nil; segmentNumber++ {
+		select {
+		case segmentNumbers <- segmentNumber:
+		case <-ctx.Done():
+		}
+	}
+	close(segmentNumbers)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, false
+	}
+
+	// a missing last segment is not an error, remove any trailing unknown sizes
+	for len(sizes) > 0 && sizes[len(sizes)-1] <= 0 {
+		sizes = sizes[:len(sizes)-1]
+	}
+
+	complete := true
+	for i := range sizes {
+		if sizes[i] > 0 {
+			continue
+		}
+		if segURL, err := GetNextSegment(mpd, i+1, repIndex, currentMPDRepAdaptSet, ctx); err == nil {
+			sizes[i] = probeContentLength(client, JoinURL(currentURL, adaptationSetBaseURL+segURL, debugLog), ctx) * 8
+		}
+		if sizes[i] <= 0 {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "unable to probe the size of segment "+strconv.Itoa(i+1)+" of rep_rate "+strconv.Itoa(repIndex))
+			sizes[i] = mpd.Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[repIndex].BandWidth * segmentDurations[0]
+			complete = false
+		}
+	}
+
+	return sizes, complete && len(sizes) > 0
+}
+
+// probeContentLength :
+/*
+ * get the size in bytes of a url without downloading it
+ * first with a HEAD request, then with "Range: bytes=0-0" and the Content-Range total
+ * returns 0 if the size is unknown or ctx is cancelled
+ */
+func probeContentLength(client *http.Client, url string, ctx context.Context) int {
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return 0
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK && resp.ContentLength > 0 {
+			return int(resp.ContentLength)
+		}
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0
+	}
+	req.Header.Add("Range", "bytes=0-0")
+	resp, err = client.Do(req)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// "bytes 0-0/<total>"
+		contentRange := strings.Split(resp.Header.Get("Content-Range"), "/")
+		if len(contentRange) == 2 {
+			total, err := strconv.Atoi(strings.TrimSpace(contentRange[1]))
+			if err == nil {
+				return total
+			}
+		}
+	case http.StatusOK:
+		// the server ignored the range
+		if resp.ContentLength > 0 {
+			return int(resp.ContentLength)
+		}
+	}
+	return 0
+}
+
+// chunkListAndRatio :
+// * the comma separated chunk list and the maximum to average size ratio, as written by Convert_to_BBA2.py
+func chunkListAndRatio(sizes []int) (string, float32) {
+
+	if len(sizes) == 0 {
+		return "", 0
+	}
+
+	var chunks []string
+	sum := 0
+	max := 0
+	for _, size := range sizes {
+		chunks = append(chunks, strconv.Itoa(size))
+		sum += size
+		if size > max {
+			max = size
+		}
+	}
+	if sum == 0 {
+		return strings.Join(chunks, ","), 0
+	}
+	average := float64(sum) / float64(len(sizes))
+
+	return strings.Join(chunks, ","), float32(float64(max) / average)
+}
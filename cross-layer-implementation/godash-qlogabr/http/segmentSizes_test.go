@@ -0,0 +1,137 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/output"
+)
+
+// segmentSizesServer : segment n of every rep_rate is n * 100 bytes, segment 2 fails while failing is set
+func segmentSizesServer(failing *int32, probes *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(probes, 1)
+		name := strings.TrimSuffix(r.URL.Path[strings.LastIndex(r.URL.Path, "_")+1:], ".m4s")
+		segment, err := strconv.Atoi(name)
+		if err != nil || (segment == 2 && atomic.LoadInt32(failing) == 1) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(segment*100))
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			w.Write(make([]byte, segment*100))
+		}
+	}))
+}
+
+// segmentSizesMPD : 3 segments of 2 seconds of 2 rep_rates, without chunks
+func segmentSizesMPD(lowBandwidth int) MPD {
+	template := SegmentTemplate{Media: "seg_$Bandwidth$_$Number$.m4s", Duration: 2, Timescale: 1}
+	return MPD{
+		MediaPresentationDuration: "PT0H0M6.000S",
+		Periods: []Period{{AdaptationSet: []AdaptationSet{{Representation: []Representation{
+			{ID: "1", BandWidth: 1000000, MimeType: glob.RepRateCodecVideo, SegmentTemplate: template},
+			{ID: "2", BandWidth: lowBandwidth, MimeType: glob.RepRateCodecVideo, SegmentTemplate: template},
+		}}}}},
+	}
+}
+
+func TestBuildSegmentSizeIndex(t *testing.T) {
+	var failing, probes int32 = 1, 0
+	server := segmentSizesServer(&failing, &probes)
+	defer server.Close()
+
+	transport := NewTransport(output.Run{Root: t.TempDir()}, nil, nil)
+	ctx := WithTransport(context.Background(), transport)
+	mpdURL := server.URL + "/video.mpd"
+	sidecarFile := segmentSizeFileName(mpdURL, 0, ctx)
+
+	build := func(mpd MPD) MPD {
+		atomic.StoreInt32(&probes, 0)
+		BuildSegmentSizeIndex(&mpd, mpdURL, 0, false, false, false, false, ctx)
+		return mpd
+	}
+
+	// a failed probe gets the size of its bandwidth, and the index is not saved
+	mpd := build(segmentSizesMPD(500000))
+	if chunks := mpd.Periods[0].AdaptationSet[0].Representation[1].Chunks; chunks != "800,1000000,2400" {
+		t.Errorf("the chunks with a failed probe are %s", chunks)
+	}
+	if _, err := os.Stat(sidecarFile); err == nil {
+		t.Fatalf("the index with a failed probe is saved")
+	}
+
+	// once every probe succeeds, the index is saved with the MPD it is of
+	atomic.StoreInt32(&failing, 0)
+	mpd = build(segmentSizesMPD(500000))
+	if chunks := mpd.Periods[0].AdaptationSet[0].Representation[1].Chunks; chunks != "800,1600,2400" {
+		t.Errorf("the probed chunks are %s", chunks)
+	}
+	f, err := os.Open(sidecarFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	f.Close()
+	if key := segmentSizeFileKey(segmentSizesMPD(500000), mpdURL, 0); scanner.Text() != key {
+		t.Errorf("the sidecar file starts with %q and not %q", scanner.Text(), key)
+	}
+
+	// the same MPD reads the sidecar file
+	mpd = build(segmentSizesMPD(500000))
+	if probes != 0 || mpd.Periods[0].AdaptationSet[0].Representation[1].Chunks != "800,1600,2400" {
+		t.Errorf("the saved index took %d probes", probes)
+	}
+	// the sidecar file of other representations is stale
+	build(segmentSizesMPD(600000))
+	if probes == 0 {
+		t.Errorf("the sidecar file of other representations is read")
+	}
+}
+
+func TestReadSegmentSizeFile(t *testing.T) {
+	fileName := t.TempDir() + "/segment_sizes.csv"
+	body := "#http://example.com/video.mpd PT0H0M6.000S 1:1000000\n1000000,800,1600,2400\n500000,400,0,1200\n"
+	if err := os.WriteFile(fileName, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, sizes := readSegmentSizeFile(fileName)
+	if key != "#http://example.com/video.mpd PT0H0M6.000S 1:1000000" {
+		t.Errorf("the key is %q", key)
+	}
+	// the rep_rate with a zero size is left out
+	if len(sizes) != 1 || len(sizes[1000000]) != 3 {
+		t.Errorf("the sizes are %v", sizes)
+	}
+}
+
+func TestBuildSegmentSizeIndexStopsOnCancel(t *testing.T) {
+	var failing, probes int32 = 0, 0
+	server := segmentSizesServer(&failing, &probes)
+	defer server.Close()
+
+	transport := NewTransport(output.Run{Root: t.TempDir()}, nil, nil)
+	ctx, cancel := context.WithCancel(WithTransport(context.Background(), transport))
+	cancel()
+	mpdURL := server.URL + "/video.mpd"
+
+	// a cancelled stream sends no probes and saves no index
+	mpd := segmentSizesMPD(500000)
+	BuildSegmentSizeIndex(&mpd, mpdURL, 0, false, false, false, false, ctx)
+	if probes != 0 {
+		t.Errorf("a cancelled stream sent %d probes", probes)
+	}
+	if _, err := os.Stat(segmentSizeFileName(mpdURL, 0, ctx)); err == nil {
+		t.Error("the index of a cancelled stream is saved")
+	}
+}
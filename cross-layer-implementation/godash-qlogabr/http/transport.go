@@ -0,0 +1,135 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/http3"
+	"github.com/lucas-clemente/quic-go/qlog"
+	"github.com/uccmisl/godash/P2Pconsul"
+	xlayer "github.com/uccmisl/godash/crosslayer"
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/output"
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// Transport : the HTTP client and the download state of one stream
+/*
+ * every request of a stream goes through the transport carried by its context,
+ * so streams of the same process share nothing
+ */
+type Transport struct {
+	// the transport qlogs and segment header files are written beneath the run
+	run        output.Run
+	accountant *xlayer.CrossLayerAccountant
+	tracer     *abrqlog.StreamTracer
+	// consul node of the collaborative clients
+	noden P2Pconsul.NodeUrl
+	// debug log of the stream
+	debugFile string
+
+	// the client is created by the first request
+	mu     sync.Mutex
+	client *http.Client
+	tr     *http.Transport
+	trQuic *http3.RoundTripper
+
+	// the init segment details per media type and the details of the last downloaded segments
+	mp4Mutex     sync.Mutex
+	mp4InitInfos map[abrqlog.MediaType]MP4InitInfo
+	segmentInfos map[string]SegmentInfo
+}
+
+type transportKey struct{}
+
+// NewTransport :
+/*
+ * create the transport of a stream, its files are written beneath run,
+ * the accountant gets the QUIC events and tracer the ABR qlog events of the stream
+ * the debug log is the one of run, until SetDebugFile replaces it
+ * a nil accountant is replaced by one that does not track events, a nil tracer drops its events
+ */
+func NewTransport(run output.Run, accountant *xlayer.CrossLayerAccountant, tracer *abrqlog.StreamTracer) *Transport {
+	if accountant == nil {
+		accountant = &xlayer.CrossLayerAccountant{EventChannel: make(chan qlog.Event)}
+		accountant.Listen(false)
+	}
+	return &Transport{
+		run:          run,
+		accountant:   accountant,
+		tracer:       tracer,
+		debugFile:    run.Path(output.DebugLog, glob.DebugTextFile+glob.FileFormat),
+		mp4InitInfos: make(map[abrqlog.MediaType]MP4InitInfo),
+		segmentInfos: make(map[string]SegmentInfo),
+	}
+}
+
+// Tracer :
+// * the ABR qlog of the stream
+func (t *Transport) Tracer() *abrqlog.StreamTracer {
+	return t.tracer
+}
+
+// Accountant :
+// * the cross-layer accountant that gets the QUIC events of the stream
+func (t *Transport) Accountant() *xlayer.CrossLayerAccountant {
+	return t.accountant
+}
+
+// SetNoden :
+// * set the consul node, the downloaded segments are announced to the collaborative clients
+func (t *Transport) SetNoden(node P2Pconsul.NodeUrl) {
+	t.noden = node
+}
+
+// SetDebugFile :
+// * set the debug log of the stream, the requests of the stream write their debug lines to it
+func (t *Transport) SetDebugFile(debugFile string) {
+	t.debugFile = debugFile
+}
+
+// DebugFile :
+// * the debug log of the stream
+func (t *Transport) DebugFile() string {
+	return t.debugFile
+}
+
+// WithTransport :
+// * return a copy of ctx that carries the transport of a stream
+func WithTransport(ctx context.Context, t *Transport) context.Context {
+	return context.WithValue(ctx, transportKey{}, t)
+}
+
+// TransportFromContext :
+/*
+ * return the transport added by WithTransport,
+ * a request without one gets a transport of its own, with the default output and no qlog
+ */
+func TransportFromContext(ctx context.Context) *Transport {
+	if t, ok := ctx.Value(transportKey{}).(*Transport); ok {
+		return t
+	}
+	return NewTransport(output.Default(), nil, nil)
+}
@@ -27,13 +27,12 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"path/filepath"
 
 	"github.com/francoispqt/gojay"
 	"github.com/uccmisl/godash/logging"
-	"github.com/uccmisl/godash/utils"
 
 	"io"
 	"io/ioutil"
@@ -45,7 +44,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/uccmisl/godash/P2Pconsul"
 	"github.com/uccmisl/godash/P2Pconsul/HelperFunctions"
 	glob "github.com/uccmisl/godash/global"
 
@@ -56,6 +54,7 @@ import (
 	"github.com/lucas-clemente/quic-go/qlog"
 
 	xlayer "github.com/uccmisl/godash/crosslayer"
+	"github.com/uccmisl/godash/output"
 	abrqlog "github.com/uccmisl/godash/qlog"
 )
 
@@ -83,30 +82,23 @@ func (h bufferedWriteCloser) Close() error {
 
 // ***
 
-// Noden consul node
-var Noden P2Pconsul.NodeUrl
+// nopWriteCloser : drops the qlog of a connection whose file cannot be created
+type nopWriteCloser struct{}
 
-// SetNoden set the consul node
-func SetNoden(node P2Pconsul.NodeUrl) {
-	Noden = node
-}
-
-var client *http.Client = nil
-var tr *http.Transport
-var trQuic *http3.RoundTripper
+func (nopWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (nopWriteCloser) Close() error                { return nil }
 
-var globAccountant *xlayer.CrossLayerAccountant = nil
-
-// Sets the globAccountant to the given accountant object
-func SetAccountant(acc *xlayer.CrossLayerAccountant) {
-	globAccountant = acc
-}
+// GetHTTPClient :
+// * return the client of the transport of ctx, the first call creates it
+// * return an error if the certificates of the testbed cannot be loaded
+func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, ctx context.Context) (*http.Transport, *http.Client, *http3.RoundTripper, error) {
 
-// getHTTPClient:
-func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBool bool) (*http.Transport, *http.Client, *http3.RoundTripper) {
+	t := TransportFromContext(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	if client != nil {
-		return tr, client, trQuic
+	if t.client != nil {
+		return t.tr, t.client, t.trQuic, nil
 	}
 
 	var cert tls.Certificate
@@ -122,22 +114,20 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 		dir, err := filepath.Abs(filepath.Dir(os.Args[0]))
 		if err != nil {
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Unable to determine executable location for testbed server certs")
-			log.Fatal(err)
+			return nil, nil, nil, err
 		}
 
 		// Read the key pair to create certificate
 		cert, err = tls.LoadX509KeyPair(dir+"/"+glob.HTTPcertLocation, dir+"/"+glob.HTTPkeyLocation)
 		if err != nil {
-			log.Println("Unable to load X509 key and cert")
-			log.Fatal(err)
+			return nil, nil, nil, errors.New("unable to load X509 key and cert: " + err.Error())
 		}
 		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "loading X509 key and cert: "+dir+"/"+glob.HTTPcertLocation+" "+dir+"/"+glob.HTTPkeyLocation)
 
 		// Create a CA certificate pool and add cert.pem to it
 		caCert, err = ioutil.ReadFile(dir + "/" + glob.HTTPcertLocation)
 		if err != nil {
-			log.Println("Unable to read X509 cert")
-			log.Fatal(err)
+			return nil, nil, nil, errors.New("unable to read X509 cert: " + err.Error())
 		}
 		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "reading X509 cert")
 
@@ -155,17 +145,20 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 		qconf := quic.Config{}
 		//qconf.KeepAlive = true
 		qconf.Tracer = qlog.NewTracer(func(_ quiclogging.Perspective, connID []byte) io.WriteCloser {
-			filename := fmt.Sprintf("logs/client_%x.qlog", connID)
+			// the crosslayer events of the ABR qlog refer to this connection
+			t.accountant.SetConnectionID(fmt.Sprintf("%x", connID))
 			//filename := "logs/client.qlog"
-			f, err := os.Create(filename)
+			f, err := t.run.Create(output.TransportQlog, fmt.Sprintf("client_%x.qlog", connID))
 			//f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+			// the stream goes on without its transport qlog
 			if err != nil {
-				log.Fatal(err)
+				log.Println(err)
+				return nopWriteCloser{}
 			}
-			log.Printf("Creating qlog file %s.\n", filename)
+			log.Printf("Creating qlog file %s.\n", f.Name())
 			return NewBufferedWriteCloser(bufio.NewWriter(f), f)
 		},
-			globAccountant.EventChannel,
+			t.accountant.EventChannel,
 		)
 		//go printQlogEvents(qlogEventChan)
 		//accountant := xlayer.CrossLayerAccountant{EventChannel: qlogEventChan}
@@ -173,16 +166,16 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 
 		// if we are not using the terstbed
 		if !useTestbedBool {
-			trQuic = &http3.RoundTripper{
+			t.trQuic = &http3.RoundTripper{
 				TLSClientConfig: &tls.Config{
 					RootCAs:            caCertPool,
 					InsecureSkipVerify: glob.InsecureSSL,
 				},
 				QuicConfig: &qconf,
 			}
-			defer trQuic.Close()
-			client = &http.Client{
-				Transport: trQuic,
+			defer t.trQuic.Close()
+			t.client = &http.Client{
+				Transport: t.trQuic,
 			}
 		} else {
 
@@ -199,10 +192,10 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 			// set up our http transport
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "creating our http transport using our tls config for quic")
 
-			trQuic = &http3.RoundTripper{TLSClientConfig: quicConfig, QuicConfig: &qconf, DisableCompression: true}
+			t.trQuic = &http3.RoundTripper{TLSClientConfig: quicConfig, QuicConfig: &qconf, DisableCompression: true}
 			// set up the client
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "creating our client using our http transport and our tls config for quic")
-			client = &http.Client{Transport: trQuic}
+			t.client = &http.Client{Transport: t.trQuic}
 		}
 		// otherwise use a normal-ish HTTP client
 	} else {
@@ -219,10 +212,10 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 			}
 			// set up our http transport
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "creating our http transport using our tls config")
-			tr = &http.Transport{TLSClientConfig: config}
+			t.tr = &http.Transport{TLSClientConfig: config}
 			// set up the client
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "creating our client using our http transport and our tls config")
-			client = &http.Client{Transport: tr}
+			t.client = &http.Client{Transport: t.tr}
 
 		} else {
 			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "setup default client but with a defined ssl security check")
@@ -231,12 +224,12 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 				// this is set statically in the globalVar.go file (set to true if needed)
 				InsecureSkipVerify: glob.InsecureSSL,
 			}
-			tr = &http.Transport{TLSClientConfig: config}
-			client = &http.Client{Transport: tr}
+			t.tr = &http.Transport{TLSClientConfig: config}
+			t.client = &http.Client{Transport: t.tr}
 		}
 	}
 
-	return tr, client, trQuic
+	return t.tr, t.client, t.trQuic, nil
 
 }
 
@@ -244,7 +237,8 @@ func GetHTTPClient(quicBool bool, debugFile string, debugLog bool, useTestbedBoo
 // * get the response body of the url
 // * calculate the rtt
 // * return the response body and the rtt
-func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, returnContentLengthOnly bool, ctx context.Context) (io.ReadCloser, time.Duration, string, int, int) {
+// * return an error if the url cannot be requested or does not return status okay
+func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, returnContentLengthOnly bool, ctx context.Context) (io.ReadCloser, time.Duration, string, int, int, error) {
 
 	var client *http.Client
 	var err error
@@ -252,8 +246,15 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 	// var trQuic *http3.RoundTripper
 	var contentLen = 0
 
+	// the ABR qlog of the stream of this request
+	tracer := TransportFromContext(ctx).Tracer()
+
 	// assign the protocols for this client
-	_, client, _ = GetHTTPClient(quicBool, debugFile, debugLog, useTestbedBool)
+	_, client, _, err = GetHTTPClient(quicBool, debugFile, debugLog, useTestbedBool, ctx)
+	if err != nil {
+		tracer.AbortRequest(url)
+		return nil, 0, "", 0, 0, err
+	}
 
 	// request the url
 	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Get the url "+url)
@@ -261,11 +262,8 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 
 	if err != nil {
-		fmt.Println(err)
-		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		return nil, 0, "", 0, 0, errors.New("the URL " + url + " doesn't match with anything: " + err.Error())
 	}
 
 	// logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "get the rtt "+url)
@@ -355,6 +353,10 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 
 	// if we want to use quic
 	// determine the rtt for this segment
+	// the predictions of the accountant of this request are about this url
+	if accountant := xlayer.AccountantFromContext(ctx); accountant != nil {
+		accountant.SetRequestURL(url)
+	}
 	start := time.Now()
 	//request the URL using the client
 	resp, err = client.Do(req)
@@ -362,15 +364,18 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 	end := time.Now()
 	rtt := end.Sub(start)
 
-	abrqlog.MainTracer.RTT.UpdateRTT(rtt, end)
-	abrqlog.MainTracer.UpdatedMetrics(abrqlog.MainTracer.RTT)
+	tracer.RecordRTT(rtt, end)
 
 	if err != nil {
-		fmt.Println(err)
-		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		return nil, rtt, "", 0, 0, errors.New("the URL " + url + " doesn't match with anything: " + err.Error())
+	}
+
+	// hand the QUIC stream of this response to the accountant of the adaptation set that requested it
+	if accountant := xlayer.AccountantFromContext(ctx); accountant != nil {
+		if streamer, ok := resp.Body.(interface{ StreamID() quic.StreamID }); ok {
+			accountant.ClaimStream(int64(streamer.StreamID()))
+		}
 	}
 
 	// get protocol version
@@ -384,42 +389,40 @@ func getURLBody(url string, isByteRangeMPD bool, startRange int, endRange int, q
 	if resp.StatusCode != http.StatusOK && !isByteRangeMPD {
 		// add this to the debug log
 		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "The URL returned a non status okay error code: "+strconv.Itoa(resp.StatusCode))
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		resp.Body.Close()
+		return nil, rtt, protocol, contentLen, status, errors.New("the URL " + url + " returned the status code " + strconv.Itoa(resp.StatusCode))
 	}
 	//fmt.Println("len : ", resp.ContentLength)
 
 	// return the response body
-	return resp.Body, rtt, protocol, contentLen, status
+	return resp.Body, rtt, protocol, contentLen, status, nil
 
 }
 
 // getURLProgressively :
 // * get the response body of the url
 // * calculate the rtt and throughtput for the download per second
-// * return the rtt
-func getURLProgressively(url string, isByteRangeMPD bool, startRange int, endRange int, fileLocation string) time.Duration {
+// * return the rtt, or an error if the url cannot be downloaded
+func getURLProgressively(url string, isByteRangeMPD bool, startRange int, endRange int, fileLocation string, ctx context.Context) (time.Duration, error) {
 
 	var thrPerSecond []int64
+	tracer := TransportFromContext(ctx).Tracer()
 
 	// set up a http client
 	client := grab.NewClient()
 	// request the url and save to a file location
 	req, err := grab.NewRequest(fileLocation, url)
-	// if there is an error, stop the app
 	if err != nil {
-		fmt.Println(err)
-		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		return 0, errors.New("the URL " + url + " doesn't match with anything: " + err.Error())
 	}
 
 	// determine the rtt for this segment
 	start := time.Now()
 	if _, err := http.DefaultTransport.RoundTrip(req.HTTPRequest); err != nil {
-		log.Fatal(err)
+		tracer.AbortRequest(url)
+		return 0, err
 	}
 	// get rtt
 	rtt := time.Since(start)
@@ -465,10 +468,8 @@ func getURLProgressively(url string, isByteRangeMPD bool, startRange int, endRan
 	}
 	// check for errors
 	if err := resp.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Download failed: %v\n", err)
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		return rtt, errors.New("the download of " + url + " failed: " + err.Error())
 	}
 
 	/* We can't use this as progressive has a different status code
@@ -482,31 +483,32 @@ func getURLProgressively(url string, isByteRangeMPD bool, startRange int, endRan
 	*/
 
 	// return the rtt
-	return rtt
+	return rtt, nil
 
 }
 
 // GetURLByteRangeBody :
 // * get the response body of the url and return an io.ReadCloser
 // * based on byte-ranges
-func GetURLByteRangeBody(url string, startRange int, endRange int) (io.ReadCloser, time.Duration) {
+// * return an error if the url cannot be requested or does not return status okay
+func GetURLByteRangeBody(url string, startRange int, endRange int, ctx context.Context) (io.ReadCloser, time.Duration, error) {
+
+	tracer := TransportFromContext(ctx).Tracer()
 
 	// set up a http client
 	client := &http.Client{}
 	// request the url
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		fmt.Println(err)
-		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		return nil, 0, errors.New("the URL " + url + " doesn't match with anything: " + err.Error())
 	}
 
 	//req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
 	start := time.Now()
 	if _, err := http.DefaultTransport.RoundTrip(req); err != nil {
-		log.Fatal(err)
+		tracer.AbortRequest(url)
+		return nil, 0, err
 	}
 	// get rtt
 	rtt := time.Since(start)
@@ -518,61 +520,56 @@ func GetURLByteRangeBody(url string, startRange int, endRange int) (io.ReadClose
 	//request the URL using the client
 	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Println(err)
-		fmt.Println("the URL " + url + " doesn't match with anything")
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		return nil, rtt, errors.New("the URL " + url + " doesn't match with anything: " + err.Error())
 	}
 
 	//Check if the GET method has sent a status code equal to 200
 	if resp.StatusCode != http.StatusOK {
-		// add this to the debug log
-		fmt.Println("The URL returned a non status okay error code: " + strconv.Itoa(resp.StatusCode))
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		resp.Body.Close()
+		return nil, rtt, errors.New("the URL " + url + " returned the status code " + strconv.Itoa(resp.StatusCode))
 	}
 	//fmt.Println("len : ", resp.ContentLength)
 
 	// return the response body
-	return resp.Body, rtt
+	return resp.Body, rtt, nil
 
 }
 
 // GetURL :
 // * return the content of the body of the url
-func GetURL(url string, isByteRangeMPD bool, startRange int, endRange int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool) ([]byte, time.Duration, string) {
+// * return an error if the body cannot be read
+func GetURL(url string, isByteRangeMPD bool, startRange int, endRange int, quicBool bool, debugFile string, debugLog bool, useTestbedBool bool, ctx context.Context) ([]byte, time.Duration, string, error) {
+
+	tracer := TransportFromContext(ctx).Tracer()
 
 	byteRangeString := ""
 	if startRange != endRange {
 		byteRangeString = fmt.Sprint(startRange) + "-" + fmt.Sprint(endRange)
 	}
-	abrqlog.MainTracer.Request(abrqlog.MediaTypeOther, url, byteRangeString)
-
-	ctx2 := context.Background()
+	tracer.Request(abrqlog.MediaTypeOther, url, byteRangeString)
 
 	// get the response body and rtt for this url
-	responseBody, rtt, protocol, _, status := getURLBody(url, isByteRangeMPD, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, false, ctx2)
+	responseBody, rtt, protocol, _, status, err := getURLBody(url, isByteRangeMPD, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, false, ctx)
+	if err != nil {
+		return nil, rtt, protocol, err
+	}
 
 	// Lets read from the http stream and not create a file to store the body
 	body, err := ioutil.ReadAll(responseBody)
+	// close the responseBody
+	responseBody.Close()
 	//bodyString := string(body)
 	if err != nil {
-		fmt.Println("Unable to read from url")
-		fmt.Println("Statuscode:", status)
-		abrqlog.MainTracer.AbortRequest(url)
-		// stop the app
-		utils.StopApp()
+		tracer.AbortRequest(url)
+		return nil, rtt, protocol, errors.New("unable to read from " + url + ", status code " + strconv.Itoa(status) + ": " + err.Error())
 	}
 
-	abrqlog.MainTracer.RequestUpdate(url, int64(len(body)))
-
-	// close the responseBody
-	responseBody.Close()
+	tracer.RequestUpdate(url, int64(len(body)))
 
 	// return the body of the responseBody
-	return body, rtt, protocol
+	return body, rtt, protocol, nil
 }
 
 // GetRepresentationBaseURL :
@@ -613,11 +610,17 @@ func JoinURL(baseURL string, append string, debugLog bool) string {
 /*
  * Function getFile :
  * get the provided file from the online HTTP server and save to folder
+ * the bytes of the body received so far are counted in the Progress of WithProgress, if ctx has one
+ * return an error if the file cannot be downloaded or saved
  */
 func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteRangeMPD bool, startRange int, endRange int,
 	segmentNumber int, segmentDuration int, addSegDuration bool, quicBool bool, debugFile string, debugLog bool,
 	useTestbedBool bool, repRate int, saveFilesBool bool, AudioByteRange bool, profile string, mediaType abrqlog.MediaType,
-	ctx context.Context) (time.Duration, int, string, string, float64, int) {
+	ctx context.Context) (time.Duration, int, string, string, float64, int, error) {
+
+	// the transport of the stream of this request
+	t := TransportFromContext(ctx)
+	tracer := t.Tracer()
 
 	// create the string where we want to save this file
 	var createFile string
@@ -654,46 +657,37 @@ func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteR
 	if startRange != endRange {
 		byteRangeString = fmt.Sprint(startRange) + "-" + fmt.Sprint(endRange)
 	}
-	abrqlog.MainTracer.Request(mediaType, urlHeaderString, byteRangeString)
+	tracer.Request(mediaType, urlHeaderString, byteRangeString)
 
 	//request the URL with GET
-	body, rtt, protocol, _, status := getURLBody(urlHeaderString, isByteRangeMPD, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, false, ctx)
+	body, rtt, protocol, _, status, err := getURLBody(urlHeaderString, isByteRangeMPD, startRange, endRange, quicBool, debugFile, debugLog, useTestbedBool, false, ctx)
+	if err != nil {
+		return rtt, 0, protocol, createFile, 0, status, err
+	}
 
 	// read from the buffer
 	var buf bytes.Buffer
-	// duplicate the buffer incase I need it later
-	tee := io.TeeReader(body, &buf)
+	// duplicate the buffer incase I need it later, the bytes read so far are counted for WithProgress
+	tee := io.TeeReader(countProgress(body, ctx), &buf)
 	myBytes, _ := ioutil.ReadAll(tee)
 	// get the size of this segment
-	size := strconv.FormatInt(int64(len(myBytes)), 10)
-	segSize, err := strconv.Atoi(size)
-	if err != nil {
-		logging.DebugPrint(debugFile, debugLog, "Error : ", "Cannot convert the size to an int when getting a file")
-		abrqlog.MainTracer.AbortRequest(urlHeaderString)
-		utils.StopApp()
-	}
+	segSize := len(myBytes)
 
-	abrqlog.MainTracer.RequestUpdate(urlHeaderString, int64(segSize))
+	tracer.RequestUpdate(urlHeaderString, int64(segSize))
 
 	// get the P.1203 segSize (less the header)
 	withoutHeaderVal := int64(segSize)
 
-	// lets see if we can find this {0x00, 0x00, 0x00, 0x04, 0x68, 0xEF, 0xBC, 0x80}
-	// in our segment
-	src := []byte("0000000468EFBC80")
-	dst := make([]byte, hex.DecodedLen(len(src)))
-	n, err := hex.Decode(dst, src)
-	if err != nil {
-		log.Fatal(err)
-	}
-	// see if this value is in myBytes
-	if bytes.Contains(myBytes, dst[:n]) {
-		// get the index for our dst value
-		mdatValueInt := bytes.Index(myBytes, dst[:n])
-		// add 8 bits for header
-		mdatValueInt += 8
-		// get the file byte size less the header
-		withoutHeaderVal = int64(segSize) - int64(mdatValueInt)
+	// read the boxes of the segment, only the mdat payload is media
+	// MPEG-TS and incomplete (aborted) segments keep the full segment size
+	segInfo, err := t.inspectSegment(myBytes, mediaType, createFile)
+	if err == nil {
+		withoutHeaderVal = segInfo.MediaBytes
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment media bytes: "+strconv.FormatInt(segInfo.MediaBytes, 10)+
+			", samples: "+strconv.Itoa(segInfo.SampleCount())+", keyframes: "+strconv.Itoa(len(segInfo.Keyframes()))+
+			", frame rate: "+fmt.Sprintf("%.3f", segInfo.FrameRate()))
+	} else {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "segment is not a fragmented mp4 segment: "+err.Error())
 	}
 	// determine the bitrate based on segment duration - multiply by 8 and divide by segment duration
 	kbpsInt := ((withoutHeaderVal * 8) / int64(segmentDuration))
@@ -702,7 +696,7 @@ func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteR
 	// convert to sn easier string value
 	kbpsFloatStringVal := fmt.Sprintf("%3f", kbpsFloat)
 	// log this value
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "HTTP body size is "+kbpsFloatStringVal)
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "HTTP body size is "+kbpsFloatStringVal)
 
 	// if we want to save the streamed files
 	// NOTICE (Arno Verstraete): this check was disabled for testing
@@ -716,8 +710,8 @@ func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteR
 		// write if not existing, append if existing
 		out, err := os.OpenFile(createFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			fmt.Println("*** " + createFile + " cannot be downloaded and written/append to file ***")
-			utils.StopApp()
+			body.Close()
+			return rtt, segSize, protocol, createFile, kbpsFloat, status, errors.New(createFile + " cannot be downloaded and written/append to file: " + err.Error())
 		}
 		// save the file to the provided file location
 		// out, err := os.Create(createFile)
@@ -730,40 +724,41 @@ func GetFile(currentURL string, fileBaseURL string, fileLocation string, isByteR
 
 		// Write the body to file
 		_, err = io.Copy(out, body)
+		out.Close()
 		if err != nil {
-			fmt.Println("*** " + createFile + " cannot be saved ***")
-			// stop the app
-			utils.StopApp()
+			body.Close()
+			return rtt, segSize, protocol, createFile, kbpsFloat, status, errors.New(createFile + " cannot be saved: " + err.Error())
 		}
 	}
 
 	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Before consul update")
 	//check if mode is collaborative or standard
-	if Noden.ClientName != "off" && Noden.ClientName != "" {
-		logging.DebugPrint(debugFile, debugLog, "DEBUG: consul client - ", Noden.ClientName)
-		Noden.UpdateConsul(HelperFunctions.HashSha(urlHeaderString))
+	if t.noden.ClientName != "off" && t.noden.ClientName != "" {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: consul client - ", t.noden.ClientName)
+		t.noden.UpdateConsul(HelperFunctions.HashSha(urlHeaderString))
 	}
 	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "After consul update")
 
 	// close the body connection
 	body.Close()
 
-	return rtt, segSize, protocol, createFile, kbpsFloat, status
+	return rtt, segSize, protocol, createFile, kbpsFloat, status, nil
 }
 
 // GetFileProgressively :
 /*
  * get the provided file from the online HTTP server and save to folder
  * get a 1-second piece of each file
+ * return an error if the file cannot be downloaded
  */
-func GetFileProgressively(currentURL string, fileBaseURL string, fileLocation string, isByteRangeMPD bool, startRange int, endRange int, segmentNumber int, segmentDuration int, addSegDuration bool, debugLog bool, AudioByteRange bool, profile string) (time.Duration, int) {
+func GetFileProgressively(currentURL string, fileBaseURL string, fileLocation string, isByteRangeMPD bool, startRange int, endRange int, segmentNumber int, segmentDuration int, addSegDuration bool, debugLog bool, AudioByteRange bool, profile string, ctx context.Context) (time.Duration, int, error) {
 
 	// create the string where we want to save this file
 	var createFile string
 
 	// join the new file location to the base url
 	urlHeaderString := JoinURL(currentURL, fileBaseURL, debugLog)
-	logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "get file from URL: "+urlHeaderString+"\n")
+	logging.DebugPrint(TransportFromContext(ctx).DebugFile(), debugLog, "DEBUG: ", "get file from URL: "+urlHeaderString+"\n")
 
 	if urlHeaderString == "" {
 		fmt.Println("null urlHeader")
@@ -790,28 +785,22 @@ func GetFileProgressively(currentURL string, fileBaseURL string, fileLocation st
 	// save the file to the provided file location
 	out, err := os.Create(createFile)
 	if err != nil {
-		fmt.Println("*** " + createFile + " cannot be downloaded ***")
-		// stop the app
-		utils.StopApp()
+		return 0, 0, errors.New(createFile + " cannot be downloaded: " + err.Error())
 	}
 	defer out.Close()
 
 	//request the URL with GET
-	rtt := getURLProgressively(urlHeaderString, isByteRangeMPD, startRange, endRange, createFile)
-
-	fi, err := os.Stat(createFile)
+	rtt, err := getURLProgressively(urlHeaderString, isByteRangeMPD, startRange, endRange, createFile, ctx)
 	if err != nil {
-		fmt.Println(err)
+		return rtt, 0, err
 	}
 
-	size := strconv.FormatInt(fi.Size(), 10)
-	segSize, err := strconv.Atoi(size)
+	fi, err := os.Stat(createFile)
 	if err != nil {
-		logging.DebugPrint(glob.DebugFile, debugLog, "Error : ", "Cannot convert the size to an int when getting a file")
-		utils.StopApp()
+		return rtt, 0, err
 	}
 
-	return rtt, segSize
+	return rtt, int(fi.Size()), nil
 }
 
 func printQlogEvents(c chan qlog.Event) {
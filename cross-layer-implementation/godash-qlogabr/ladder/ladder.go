@@ -0,0 +1,324 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package ladder : the constraints on the rep_rates the ABR algorithms may select
+package ladder
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/http"
+)
+
+// Constraints :
+/*
+ * the limits of the representations of a video adaptation set, a zero limit is no limit
+ * a representation that does not give its width, height or frame rate passes that limit
+ */
+type Constraints struct {
+	MaxWidth     int
+	MaxHeight    int
+	MaxFrameRate int
+	// MaxBitrate in bps
+	MaxBitrate int
+	// MaxPixels : the largest width times height
+	MaxPixels int
+	// DynamicRange : any, sdr or hdr, an empty DynamicRange is any
+	DynamicRange string
+}
+
+// Rung : the properties of a representation the constraints are checked against
+type Rung struct {
+	Width     int
+	Height    int
+	FrameRate int
+	// Bandwidth in bps
+	Bandwidth int
+	HDR       bool
+}
+
+// Change : the constraints from At on, At is the time since the start of the stream
+type Change struct {
+	At          time.Duration
+	Constraints Constraints
+}
+
+// screens : the largest video of the screen profiles
+var screens = map[string]Constraints{
+	glob.ScreenPhone:  {MaxWidth: 1280, MaxHeight: 720, MaxFrameRate: 30},
+	glob.ScreenTablet: {MaxWidth: 1920, MaxHeight: 1080, MaxFrameRate: 60},
+	glob.ScreenLaptop: {MaxWidth: 1920, MaxHeight: 1080, MaxFrameRate: 60},
+	glob.ScreenTV:     {MaxWidth: 3840, MaxHeight: 2160, MaxFrameRate: 60},
+}
+
+// ScreenNames : the names of the screen profiles
+var ScreenNames = []string{glob.ScreenPhone, glob.ScreenTablet, glob.ScreenLaptop, glob.ScreenTV}
+
+// DynamicRanges : the values of the dynamic range constraint
+var DynamicRanges = []string{glob.DynamicRangeAny, glob.DynamicRangeSDR, glob.DynamicRangeHDR}
+
+// hdrTransferCharacteristics : the cicp transfer characteristics of HDR video, PQ and HLG
+var hdrTransferCharacteristics = []string{"16", "18"}
+
+// hdrCodecs : the codecs of Dolby Vision, which is always HDR
+var hdrCodecs = []string{"dvh1", "dvhe", "dav1"}
+
+// Active : there is at least one limit
+func (c Constraints) Active() bool {
+	return c != Constraints{} && c != Constraints{DynamicRange: glob.DynamicRangeAny}
+}
+
+// Valid : no limit is negative and the dynamic range is one of DynamicRanges or empty
+func (c Constraints) Valid() bool {
+	if c.MaxWidth < 0 || c.MaxHeight < 0 || c.MaxFrameRate < 0 || c.MaxBitrate < 0 || c.MaxPixels < 0 {
+		return false
+	}
+	for _, dynamicRange := range DynamicRanges {
+		if c.DynamicRange == dynamicRange {
+			return true
+		}
+	}
+	return c.DynamicRange == ""
+}
+
+// Merge :
+/*
+ * the tighter limit of c and other for every limit
+ * the dynamic range of other replaces the one of c, unless it is any
+ */
+func (c Constraints) Merge(other Constraints) Constraints {
+	tighter := func(a int, b int) int {
+		if a == 0 || (b != 0 && b < a) {
+			return b
+		}
+		return a
+	}
+	c.MaxWidth = tighter(c.MaxWidth, other.MaxWidth)
+	c.MaxHeight = tighter(c.MaxHeight, other.MaxHeight)
+	c.MaxFrameRate = tighter(c.MaxFrameRate, other.MaxFrameRate)
+	c.MaxBitrate = tighter(c.MaxBitrate, other.MaxBitrate)
+	c.MaxPixels = tighter(c.MaxPixels, other.MaxPixels)
+	if other.DynamicRange != "" && other.DynamicRange != glob.DynamicRangeAny {
+		c.DynamicRange = other.DynamicRange
+	}
+	return c
+}
+
+// Allows : the rung is within every limit
+func (c Constraints) Allows(rung Rung) bool {
+	above := func(value int, limit int) bool {
+		return limit > 0 && value > limit
+	}
+	switch {
+	case above(rung.Width, c.MaxWidth), above(rung.Height, c.MaxHeight), above(rung.FrameRate, c.MaxFrameRate):
+		return false
+	case above(rung.Bandwidth, c.MaxBitrate), above(rung.Width*rung.Height, c.MaxPixels):
+		return false
+	case c.DynamicRange == glob.DynamicRangeSDR && rung.HDR, c.DynamicRange == glob.DynamicRangeHDR && !rung.HDR:
+		return false
+	}
+	return true
+}
+
+// Screen :
+/*
+ * the constraints of a screen, one of ScreenNames or "<width>x<height>" in pixels
+ * returns false if the screen is neither
+ */
+func Screen(screen string) (Constraints, bool) {
+	if c, ok := screens[screen]; ok {
+		return c, true
+	}
+	size := strings.Split(screen, "x")
+	if len(size) != 2 {
+		return Constraints{}, false
+	}
+	width, err := strconv.Atoi(size[0])
+	if err != nil || width <= 0 {
+		return Constraints{}, false
+	}
+	height, err := strconv.Atoi(size[1])
+	if err != nil || height <= 0 {
+		return Constraints{}, false
+	}
+	return Constraints{MaxWidth: width, MaxHeight: height}, true
+}
+
+// Schedule :
+/*
+ * the changes of the screen schedule entries, "<seconds>:<screen>", in the order of their time
+ * every change is base with the limits of its screen, so a schedule can simulate a window resize
+ */
+func Schedule(base Constraints, entries []string) ([]Change, error) {
+	var changes []Change
+	for _, entry := range entries {
+		fields := strings.SplitN(entry, ":", 2)
+		if len(fields) != 2 {
+			return nil, errors.New("the screen schedule entry " + strconv.Quote(entry) + " is not <seconds>:<screen>")
+		}
+		seconds, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || seconds < 0 {
+			return nil, errors.New("the screen schedule entry " + strconv.Quote(entry) + " does not start at a positive number of seconds")
+		}
+		screen, ok := Screen(fields[1])
+		if !ok {
+			return nil, errors.New("the screen schedule entry " + strconv.Quote(entry) + " has no screen " + strconv.Quote(fields[1]))
+		}
+		changes = append(changes, Change{At: time.Duration(seconds * float64(time.Second)), Constraints: base.Merge(screen)})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].At < changes[j].At
+	})
+	return changes, nil
+}
+
+// At : the constraints of the changes at the time since the start of the stream, base before the first change
+func At(base Constraints, changes []Change, at time.Duration) Constraints {
+	c := base
+	for _, change := range changes {
+		if change.At > at {
+			break
+		}
+		c = change.Constraints
+	}
+	return c
+}
+
+// HDR :
+/*
+ * the representation of the adaptation set is HDR video,
+ * its transfer characteristics are PQ or HLG, or it is Dolby Vision
+ */
+func HDR(set http.AdaptationSet, rep http.Representation) bool {
+	for _, codec := range hdrCodecs {
+		if strings.HasPrefix(rep.Codecs, codec) {
+			return true
+		}
+	}
+	var properties []http.Descriptor
+	properties = append(properties, set.EssentialProperty...)
+	properties = append(properties, set.SupplementalProperty...)
+	properties = append(properties, rep.EssentialProperty...)
+	properties = append(properties, rep.SupplementalProperty...)
+	for _, property := range properties {
+		if property.SchemeIDURI != http.TransferCharacteristicsScheme {
+			continue
+		}
+		for _, transfer := range hdrTransferCharacteristics {
+			if property.Value == transfer {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Rungs : the rungs of the representations of an adaptation set, in the order of its rep_rate indexes
+func Rungs(set http.AdaptationSet) []Rung {
+	setHeight, _ := strconv.Atoi(set.Height)
+	rungs := make([]Rung, len(set.Representation))
+	for i, rep := range set.Representation {
+		rungs[i] = Rung{Width: rep.Width, Height: rep.Height, FrameRate: rep.FrameRate, Bandwidth: rep.BandWidth, HDR: HDR(set, rep)}
+		// the representations without a size or frame rate have the one of their adaptation set
+		if rungs[i].Width == 0 {
+			rungs[i].Width = set.Width
+		}
+		if rungs[i].Height == 0 {
+			rungs[i].Height = setHeight
+		}
+		if rungs[i].FrameRate == 0 {
+			rungs[i].FrameRate = set.FrameRate
+		}
+	}
+	return rungs
+}
+
+// Allowed :
+/*
+ * the rep_rates of the rungs the constraints allow, between the highest and lowest rep_rate index of the MPD
+ * if no rep_rate is allowed, the lowest rep_rate is, so there is always a rep_rate to stream
+ */
+func Allowed(rungs []Rung, c Constraints, highest int, lowest int) []bool {
+	allowed := make([]bool, len(rungs))
+	found := false
+	for i, rung := range rungs {
+		allowed[i] = i >= highest && i <= lowest && c.Allows(rung)
+		found = found || allowed[i]
+	}
+	if !found && lowest >= 0 && lowest < len(allowed) {
+		allowed[lowest] = true
+	}
+	return allowed
+}
+
+// Bounds : the highest and the lowest allowed rep_rate index
+func Bounds(allowed []bool) (int, int) {
+	highest, lowest := -1, -1
+	for i, ok := range allowed {
+		if !ok {
+			continue
+		}
+		if highest < 0 {
+			highest = i
+		}
+		lowest = i
+	}
+	return highest, lowest
+}
+
+// Select :
+/*
+ * the allowed rep_rate for the rep_rate of an algorithm,
+ * the highest allowed rep_rate that is not above it, or the lowest allowed rep_rate if there is none
+ */
+func Select(allowed []bool, repRate int) int {
+	for i := repRate; i >= 0 && i < len(allowed); i++ {
+		if allowed[i] {
+			return i
+		}
+	}
+	_, lowest := Bounds(allowed)
+	if lowest < 0 {
+		return repRate
+	}
+	return lowest
+}
+
+// Codec :
+/*
+ * the first codec of the preference that is offered, e.g. the codecs of http.GetCodec for an MPD
+ * returns false if none of them is offered
+ */
+func Codec(offered []string, preference []string) (string, bool) {
+	for _, codec := range preference {
+		for _, offer := range offered {
+			if offer == codec {
+				return codec, true
+			}
+		}
+	}
+	return "", false
+}
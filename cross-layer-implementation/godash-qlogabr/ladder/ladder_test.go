@@ -0,0 +1,130 @@
+package ladder
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/http"
+)
+
+// testRungs : a 4K ladder with the highest rep_rate first, the 4K rung is HDR at 60 fps
+var testRungs = []Rung{
+	{Width: 3840, Height: 2160, FrameRate: 60, Bandwidth: 16000000, HDR: true},
+	{Width: 1920, Height: 1080, FrameRate: 60, Bandwidth: 6000000},
+	{Width: 1920, Height: 1080, FrameRate: 30, Bandwidth: 4000000},
+	{Width: 1280, Height: 720, FrameRate: 30, Bandwidth: 2000000},
+	{Width: 640, Height: 360, FrameRate: 30, Bandwidth: 600000},
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Constraints
+		highest int
+		want    []bool
+	}{
+		{"no limits", Constraints{}, 0, []bool{true, true, true, true, true}},
+		{"phone", screens[glob.ScreenPhone], 0, []bool{false, false, false, true, true}},
+		{"frame rate", Constraints{MaxFrameRate: 30}, 0, []bool{false, false, true, true, true}},
+		{"bitrate", Constraints{MaxBitrate: 5000000}, 0, []bool{false, false, true, true, true}},
+		{"pixels", Constraints{MaxPixels: 1280 * 720}, 0, []bool{false, false, false, true, true}},
+		{"sdr", Constraints{DynamicRange: glob.DynamicRangeSDR}, 0, []bool{false, true, true, true, true}},
+		{"hdr", Constraints{DynamicRange: glob.DynamicRangeHDR}, 0, []bool{true, false, false, false, false}},
+		{"max height of the MPD", Constraints{MaxFrameRate: 30}, 3, []bool{false, false, false, true, true}},
+		// nothing fits, so the lowest rep_rate is streamed
+		{"nothing allowed", Constraints{MaxWidth: 320}, 0, []bool{false, false, false, false, true}},
+	}
+	for _, test := range tests {
+		if got := Allowed(testRungs, test.c, test.highest, len(testRungs)-1); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: allowed %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	// the 1080p30 rung is not allowed
+	allowed := []bool{false, true, false, true, true}
+	for repRate, want := range []int{1, 1, 3, 3, 4} {
+		if got := Select(allowed, repRate); got != want {
+			t.Errorf("rep_rate %d snaps to %d, want %d", repRate, got, want)
+		}
+	}
+	if highest, lowest := Bounds(allowed); highest != 1 || lowest != 4 {
+		t.Errorf("bounds %d and %d, want 1 and 4", highest, lowest)
+	}
+	// a rep_rate below the lowest allowed one snaps up to it
+	if got := Select([]bool{true, true, false}, 2); got != 1 {
+		t.Errorf("rep_rate 2 snaps to %d, want 1", got)
+	}
+}
+
+func TestScreenAndSchedule(t *testing.T) {
+	if c, ok := Screen("1280x800"); !ok || c != (Constraints{MaxWidth: 1280, MaxHeight: 800}) {
+		t.Errorf("screen 1280x800 is %+v, %v", c, ok)
+	}
+	for _, screen := range []string{"watch", "1280x", "x720", "0x720"} {
+		if _, ok := Screen(screen); ok {
+			t.Errorf("%q is a screen", screen)
+		}
+	}
+
+	base := Constraints{MaxBitrate: 5000000, MaxHeight: 1080}
+	changes, err := Schedule(base, []string{"30:phone", "0:tv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].At != 0 || changes[1].At != 30*time.Second {
+		t.Fatalf("changes %+v are not in the order of their time", changes)
+	}
+	// the tv does not lift the limits of base, the phone lowers them
+	if got := At(base, changes, 10*time.Second); got != (Constraints{MaxWidth: 3840, MaxHeight: 1080, MaxFrameRate: 60, MaxBitrate: 5000000}) {
+		t.Errorf("tv constraints %+v", got)
+	}
+	if got := At(base, changes, time.Minute); got != (Constraints{MaxWidth: 1280, MaxHeight: 720, MaxFrameRate: 30, MaxBitrate: 5000000}) {
+		t.Errorf("phone constraints %+v", got)
+	}
+	for _, entries := range [][]string{{"phone"}, {"-1:phone"}, {"10:watch"}} {
+		if _, err := Schedule(base, entries); err == nil {
+			t.Errorf("schedule %v has no error", entries)
+		}
+	}
+}
+
+func TestHDR(t *testing.T) {
+	var set http.AdaptationSet
+	err := xml.Unmarshal([]byte(`<AdaptationSet height="1080">
+		<Representation id="1" codecs="hvc1.2.4.L150" width="3840" height="2160" bandwidth="16000000">
+			<SupplementalProperty schemeIdUri="urn:mpeg:mpegB:cicp:TransferCharacteristics" value="16"/>
+		</Representation>
+		<Representation id="2" codecs="dvh1.05.06" bandwidth="12000000"/>
+		<Representation id="3" codecs="hvc1.1.6.L120" width="1920" bandwidth="6000000">
+			<SupplementalProperty schemeIdUri="urn:mpeg:mpegB:cicp:TransferCharacteristics" value="1"/>
+		</Representation>
+	</AdaptationSet>`), &set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rungs := Rungs(set)
+	for i, want := range []bool{true, true, false} {
+		if rungs[i].HDR != want {
+			t.Errorf("representation %d is HDR %v, want %v", i+1, rungs[i].HDR, want)
+		}
+	}
+	// the representations without a height have the one of the adaptation set
+	if rungs[1].Height != 1080 || rungs[2].Height != 1080 {
+		t.Errorf("heights %d and %d, want the 1080 of the adaptation set", rungs[1].Height, rungs[2].Height)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	offered := []string{glob.RepRateCodecAVC, glob.RepRateCodecHEVC, glob.RepRateCodecAudio}
+	if codec, ok := Codec(offered, []string{glob.RepRateCodecAV1, glob.RepRateCodecHEVC, glob.RepRateCodecAVC}); !ok || codec != glob.RepRateCodecHEVC {
+		t.Errorf("codec %q, want the %s fallback", codec, glob.RepRateCodecHEVC)
+	}
+	if _, ok := Codec(offered, []string{glob.RepRateCodecAV1}); ok {
+		t.Errorf("%s is not offered", glob.RepRateCodecAV1)
+	}
+}
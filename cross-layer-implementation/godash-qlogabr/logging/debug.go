@@ -22,7 +22,6 @@
 package logging
 
 import (
-	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -81,6 +80,20 @@ type SegPrintLogInformation struct {
 	RateChange     []float64
 	MimeType       string
 	Profile        string
+	// read from the segment boxes, empty if the segment is not fragmented mp4
+	MediaFps       float64
+	FrameSizes     []int
+	FrameDurations []float64
+	Keyframes      []int
+	// download cancelled by the stall predictor, and the bytes received before it was cancelled
+	Aborted      bool
+	AbortedBytes int
+	// rep_rate of the cancelled download, the time it ran, and the predicted remaining
+	// download time and buffer level when the stall predictor cancelled it
+	AbortedRepRate   int
+	AbortElapsed     int
+	AbortPredicted   int
+	AbortBufferLevel int
 }
 
 // headers for the print log
@@ -111,6 +124,13 @@ const duanmuHeader = glob.DuanmuHeader
 const yinHeader = glob.YinHeader
 const yuHeader = glob.YuHeader
 
+// cross-layer aborts
+const abortRateHeader = glob.AbortRateHeader
+const abortBytesHeader = glob.AbortBytesHeader
+const abortTimeHeader = glob.AbortTimeHeader
+const abortPredHeader = glob.AbortPredHeader
+const abortBuffHeader = glob.AbortBuffHeader
+
 // DebugPrint :
 // * fileLocation string - pass in fileLocation
 // * printLog bool - pass in boolean to print log
@@ -124,12 +144,9 @@ func DebugPrint(fileLocation string, printLog bool, inputPrefix string, inputStr
 		// open the log file
 		f, err := os.OpenFile(fileLocation, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			// print an error
+			// print an error, the stream goes on without this debug line
 			log.Println(err)
-			// print the flag help output
-			flag.Usage()
-			// exit the application
-			os.Exit(3)
+			return
 		}
 
 		// create a logger, set to Debug
@@ -151,12 +168,9 @@ func DebugPrintfIntArray(fileLocation string, printLog bool, inputPrefix string,
 		// open the log file
 		f, err := os.OpenFile(fileLocation, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			// print an error
+			// print an error, the stream goes on without this debug line
 			log.Println(err)
-			// print the flag help output
-			flag.Usage()
-			// exit the application
-			os.Exit(3)
+			return
 		}
 
 		// create a logger, set to Debug
@@ -178,12 +192,9 @@ func DebugPrintfStringArray(fileLocation string, printLog bool, inputPrefix stri
 		// open the log file
 		f, err := os.OpenFile(fileLocation, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			// print an error
+			// print an error, the stream goes on without this debug line
 			log.Println(err)
-			// print the flag help output
-			flag.Usage()
-			// exit the application
-			os.Exit(3)
+			return
 		}
 
 		// create a logger, set to Debug
@@ -206,12 +217,12 @@ func PrintsegInformationLogMap(debugFile string, debugLog bool, mapSegments map[
 
 	// print map header
 	mainPrintString := "%7s  %10s  %8s  %12s  %8s  %12s  %8s  %8s  %10s"
-	extendPrintString := "  %12s%s%s%s%s%s%s%s%s%s%s%s%s%s%s\n"
-	PrintToFile("seg_Num", "size", "downTime", "thr", "duration", "playbackTime", "repIndex", "MPDIndex", "adaptIndex", "bandwith", "", true, "", "", "", "", "", "", mainPrintString, extendPrintString, debugFile, "", "", "", "", "", "", "")
+	extendPrintString := "  %12s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s\n"
+	PrintToFile("seg_Num", "size", "downTime", "thr", "duration", "playbackTime", "repIndex", "MPDIndex", "adaptIndex", "bandwith", "", true, "", "", "", "", "", "", mainPrintString, extendPrintString, debugFile, "", "", "", "", "", "", "", "", "", "", "", "")
 
 	for k := 1; k <= len(mapSegments); k++ {
 		// print out each segment map
-		PrintToFile(strconv.Itoa(k), strconv.Itoa(mapSegments[k].SegSize), strconv.Itoa(mapSegments[k].DeliveryTime), strconv.Itoa(mapSegments[k].DelRate), strconv.Itoa(mapSegments[k].SegmentDuration*glob.Conversion1000), strconv.Itoa(mapSegments[k].PlaybackTime), strconv.Itoa(mapSegments[k].RepIndex), strconv.Itoa(mapSegments[k].MpdIndex), strconv.Itoa(mapSegments[k].AdaptIndex), strconv.Itoa(mapSegments[k].Bandwidth), "", true, "", "", "", "", "", "", mainPrintString, extendPrintString, debugFile, "", "", "", "", "", "", "")
+		PrintToFile(strconv.Itoa(k), strconv.Itoa(mapSegments[k].SegSize), strconv.Itoa(mapSegments[k].DeliveryTime), strconv.Itoa(mapSegments[k].DelRate), strconv.Itoa(mapSegments[k].SegmentDuration*glob.Conversion1000), strconv.Itoa(mapSegments[k].PlaybackTime), strconv.Itoa(mapSegments[k].RepIndex), strconv.Itoa(mapSegments[k].MpdIndex), strconv.Itoa(mapSegments[k].AdaptIndex), strconv.Itoa(mapSegments[k].Bandwidth), "", true, "", "", "", "", "", "", mainPrintString, extendPrintString, debugFile, "", "", "", "", "", "", "", "", "", "", "", "")
 	}
 	// }
 }
@@ -220,7 +231,7 @@ func PrintsegInformationLogMap(debugFile string, debugLog bool, mapSegments map[
 // * print a line to the file logDownload
 func PrintToFile(segNum string, arrTime string, delTime string, stallDur string,
 	repLevel string, delRate string, actRate string, byteSize string,
-	buffLevel string, algo string, segDuration string, extendPrintLog bool, codec string, width string, height string, fps string, playHeader string, rttHeader string, mainPrintString string, extendPrintString string, fileLocation string, segReplace string, httpProtocol string, p1203 string, clae string, duanmu string, yin string, yu string) {
+	buffLevel string, algo string, segDuration string, extendPrintLog bool, codec string, width string, height string, fps string, playHeader string, rttHeader string, mainPrintString string, extendPrintString string, fileLocation string, segReplace string, httpProtocol string, p1203 string, clae string, duanmu string, yin string, yu string, abortRate string, abortBytes string, abortTime string, abortPred string, abortBuff string) {
 
 	// open the logfile and print to it
 	f, err := os.OpenFile(fileLocation, os.O_APPEND|os.O_WRONLY, 0644)
@@ -236,7 +247,7 @@ func PrintToFile(segNum string, arrTime string, delTime string, stallDur string,
 
 	if extendPrintLog {
 		//fmt.Fprint(f, algo+"\t"+segDuration+"\t"+codec+"\t"+height+"\t"+width+"\t"+fps+"\t"+playHeader+"\t"+rttHeader+"\t\n")
-		fmt.Fprintf(f, extendPrintString, algo, segDuration, codec, width, height, fps, playHeader, rttHeader, segReplace, httpProtocol, p1203, clae, duanmu, yin, yu)
+		fmt.Fprintf(f, extendPrintString, algo, segDuration, codec, width, height, fps, playHeader, rttHeader, segReplace, httpProtocol, p1203, clae, duanmu, yin, yu, abortRate, abortBytes, abortTime, abortPred, abortBuff)
 	} else {
 		fmt.Fprint(f, "\n")
 	}
@@ -258,17 +269,17 @@ func PrintHeaders(extendPrintLog bool, fileLocation string, logDownload string,
 
 	// print a line of the log file to terminal
 	PrintLog(segNum, arrTime, delTime, stallDur, repLevel, delRate, actRate,
-		byteSize, buffLevel, algoHeader, segDurHeader, extendPrintLog, codecHeader, heightHeader, widthHeader, fpsHeader, playHeader, rttHeader, fileLocation, logDownload, printLog, printHeadersData, segReplaceHeader, httpProtocolHeader, p1203Header, claeHeader, duanmuHeader, yinHeader, yuHeader)
+		byteSize, buffLevel, algoHeader, segDurHeader, extendPrintLog, codecHeader, heightHeader, widthHeader, fpsHeader, playHeader, rttHeader, fileLocation, logDownload, printLog, printHeadersData, segReplaceHeader, httpProtocolHeader, p1203Header, claeHeader, duanmuHeader, yinHeader, yuHeader, abortRateHeader, abortBytesHeader, abortTimeHeader, abortPredHeader, abortBuffHeader)
 }
 
 // PrintLog :
 // * print a line to the output log
 func PrintLog(segNum string, arrTime string, delTime string, stallDur string,
 	repLevel string, delRate string, actRate string, byteSize string,
-	buffLevel string, algoIn string, segDurationIn string, extendPrintLog bool, codecIn string, widthIn string, heightIn string, fpsIn string, playIn string, rttIn string, fileLocation string, logDownload string, printLog bool, printHeadersData map[string]string, segReplaceIn string, httpProtocolIn string, p1203In string, claeIn string, duanmuIn string, yinIn string, yuIn string) {
+	buffLevel string, algoIn string, segDurationIn string, extendPrintLog bool, codecIn string, widthIn string, heightIn string, fpsIn string, playIn string, rttIn string, fileLocation string, logDownload string, printLog bool, printHeadersData map[string]string, segReplaceIn string, httpProtocolIn string, p1203In string, claeIn string, duanmuIn string, yinIn string, yuIn string, abortRateIn string, abortBytesIn string, abortTimeIn string, abortPredIn string, abortBuffIn string) {
 
 	const mainPrintString = "%10s   %10s   %10s   %10s   %10s   %10s   %10s   %10s   %10s"
-	const fileExtendPrintString = "   %12s   %7s   %5s   %5s   %6s   %5s   %8s   %8s   %s   %8s   %8s   %8s   %8s   %12s   %12s\n"
+	const fileExtendPrintString = "   %12s   %7s   %5s   %5s   %6s   %5s   %8s   %8s   %s   %8s   %8s   %8s   %8s   %12s   %12s   %10s   %11s   %10s   %10s   %10s\n"
 	var extendPrintString = ""
 	const fiveString = "   %5s"
 	const eightString = "   %8s"
@@ -288,6 +299,11 @@ func PrintLog(segNum string, arrTime string, delTime string, stallDur string,
 	var duanmu = ""
 	var yin = ""
 	var yu = ""
+	var abortRate = ""
+	var abortBytes = ""
+	var abortTime = ""
+	var abortPred = ""
+	var abortBuff = ""
 
 	//"   %12s   %7s   %5s   %5s   %6s   %5s   %8s   %8s\n"
 	//"Algorithm\":\"off\",\"Seg_Dur\":\"on\",\"Codec\":\"on\",\"Width\":\"on\",\"Height\":\"on\",\"FPS\":\"on\",\"Play_Pos\":\"on\",\"RTT\"
@@ -313,10 +329,15 @@ func PrintLog(segNum string, arrTime string, delTime string, stallDur string,
 			checkInputHeader(printHeadersData, duanmuHeader, &extendPrintString, twelveString, &duanmu, duanmuIn)
 			checkInputHeader(printHeadersData, yinHeader, &extendPrintString, twelveString, &yin, yinIn)
 			checkInputHeader(printHeadersData, yuHeader, &extendPrintString, twelveString, &yu, yuIn)
+			checkInputHeader(printHeadersData, abortRateHeader, &extendPrintString, "   %10s", &abortRate, abortRateIn)
+			checkInputHeader(printHeadersData, abortBytesHeader, &extendPrintString, "   %11s", &abortBytes, abortBytesIn)
+			checkInputHeader(printHeadersData, abortTimeHeader, &extendPrintString, "   %10s", &abortTime, abortTimeIn)
+			checkInputHeader(printHeadersData, abortPredHeader, &extendPrintString, "   %10s", &abortPred, abortPredIn)
+			checkInputHeader(printHeadersData, abortBuffHeader, &extendPrintString, "   %10s", &abortBuff, abortBuffIn)
 
 			// one of these has to be true, so print a new line at the end
 			extendPrintString += "\n"
-			fmt.Printf(extendPrintString, algo, segDuration, codec, width, height, fps, play, rtt, segReplace, httpProtocol, p1203, clae, duanmu, yin, yu)
+			fmt.Printf(extendPrintString, algo, segDuration, codec, width, height, fps, play, rtt, segReplace, httpProtocol, p1203, clae, duanmu, yin, yu, abortRate, abortBytes, abortTime, abortPred, abortBuff)
 		} else {
 			fmt.Printf("\n")
 		}
@@ -324,7 +345,7 @@ func PrintLog(segNum string, arrTime string, delTime string, stallDur string,
 
 	printLocal := fileLocation + "/" + logDownload
 
-	PrintToFile(segNum, arrTime, delTime, stallDur, repLevel, delRate, actRate, byteSize, buffLevel, algoIn, segDurationIn, extendPrintLog, codecIn, widthIn, heightIn, fpsIn, playIn, rttIn, mainPrintString, fileExtendPrintString, printLocal, segReplaceIn, httpProtocolIn, p1203In, claeIn, duanmuIn, yinIn, yuIn)
+	PrintToFile(segNum, arrTime, delTime, stallDur, repLevel, delRate, actRate, byteSize, buffLevel, algoIn, segDurationIn, extendPrintLog, codecIn, widthIn, heightIn, fpsIn, playIn, rttIn, mainPrintString, fileExtendPrintString, printLocal, segReplaceIn, httpProtocolIn, p1203In, claeIn, duanmuIn, yinIn, yuIn, abortRateIn, abortBytesIn, abortTimeIn, abortPredIn, abortBuffIn)
 }
 
 //
@@ -394,7 +415,13 @@ func PrintPlayOutLog(currentTime int, initBuffer int, mapSegments []map[int]SegP
 					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Clae),
 					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Duanmu),
 					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Yin),
-					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Yu))
+					fmt.Sprintf("%.3f", mapSegments[logIndex][playoutSegmentNumber].Yu),
+					// add the cross-layer abort of the segment, zero if it was not aborted
+					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].AbortedRepRate/glob.Conversion1000),
+					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].AbortedBytes),
+					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].AbortElapsed),
+					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].AbortPredicted),
+					strconv.Itoa(mapSegments[logIndex][playoutSegmentNumber].AbortBufferLevel))
 
 				// update the played boolean to true
 				localMap := mapSegments[logIndex][playoutSegmentNumber]
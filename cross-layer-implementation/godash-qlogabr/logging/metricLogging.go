@@ -1,92 +1,147 @@
 package logging
 
 import (
-	"os"
-	"strconv"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
-
-	glob "github.com/uccmisl/godash/global"
 )
 
-type MetricLoggingFormat struct {
+// MetricEvent : one value of a registered metric
+type MetricEvent struct {
 	TimeStamp time.Time
-	Tag       string
-	Message   string
+	Metric    *Metric
+	// Values : one value per field of the metric
+	Values []float64
+	// Message : the description of a message metric
+	Message string
+}
+
+// MetricSinkConfig : where the metric logger writes to
+type MetricSinkConfig struct {
+	// Sinks : the names of the enabled sinks, text, csv, ndjson and/or prometheus
+	Sinks []string
+	// Path : the file of the file sinks, without extension
+	Path string
+	// PrometheusAddr : the listen address of the prometheus endpoint
+	PrometheusAddr string
 }
 
 type MetricLogger struct {
 	startTimeUnix         int64
-	WriteChannel          chan MetricLoggingFormat
+	events                chan MetricEvent
+	sinks                 []MetricSink
+	mu                    sync.RWMutex
+	stopped               bool
+	stopPoller            chan struct{}
+	done                  sync.WaitGroup
+	bufferMu              sync.Mutex
 	bufferLevelMilli      int
 	lastBufferLevelUpdate time.Time
 	pollFrequencyMilli    int
 }
 
-func check(e error) {
-	if e != nil {
-		panic(e)
-	}
-}
-
-func (a *MetricLogger) StartLogger(pollFrequencyMilli int, bandwithList []int, bufferSize int) {
+// StartLogger : open the metric sinks of config and start writing the metrics, an error if a sink cannot be opened
+func (a *MetricLogger) StartLogger(pollFrequencyMilli int, bandwithList []int, bufferSize int, config MetricSinkConfig) error {
 	// Initialise logger
 	a.pollFrequencyMilli = pollFrequencyMilli
-	a.WriteChannel = make(chan MetricLoggingFormat)
 	startTime := time.Now()
 	a.startTimeUnix = startTime.UnixMilli()
 
+	sinks, err := NewMetricSinks(config)
+	if err != nil {
+		return errors.New("unable to start the metric logger: " + err.Error())
+	}
+	a.sinks = sinks
+	a.events = make(chan MetricEvent, 256)
+	a.stopPoller = make(chan struct{})
+
 	a.SetBufferLevel(0)
 
 	// Write logs non-blocking
+	a.done.Add(1)
 	go a.WriteLog()
 	go a.MetricsPoller()
 
-	a.WriteChannel <- MetricLoggingFormat{
-		TimeStamp: time.Now(),
-		Tag:       "HIGHESTBANDWIDTH",
-		Message:   strconv.Itoa(bandwithList[0]),
-	}
+	a.Log(MetricHighestBandwidth, float64(bandwithList[0]))
+	a.Log(MetricBufferSize, float64(bufferSize))
+	a.Log(MetricStartTime, float64(a.startTimeUnix))
+	return nil
+}
 
-	a.WriteChannel <- MetricLoggingFormat{
-		TimeStamp: time.Now(),
-		Tag:       "BUFFERSIZE",
-		Message:   strconv.Itoa(bufferSize),
+// Log : log the values of metric, one value per field of the metric
+func (a *MetricLogger) Log(metric *Metric, values ...float64) {
+	if len(values) != len(metric.Fields) {
+		fmt.Printf("*** metric %s has %d fields and not %d values ***\n", metric.Name, len(metric.Fields), len(values))
+		return
 	}
+	a.write(MetricEvent{TimeStamp: time.Now(), Metric: metric, Values: values})
+}
 
-	a.WriteChannel <- MetricLoggingFormat{
-		TimeStamp: time.Now(),
-		Tag:       "STARTTIME",
-		Message:   strconv.Itoa(int(a.startTimeUnix)),
+// LogMessage : log a message of metric
+func (a *MetricLogger) LogMessage(metric *Metric, message string) {
+	a.write(MetricEvent{TimeStamp: time.Now(), Metric: metric, Message: message})
+}
+
+// write : pass event to the sinks, events of a logger that is not running are dropped
+func (a *MetricLogger) write(event MetricEvent) {
+	if a == nil {
+		return
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.events == nil || a.stopped {
+		return
 	}
+	a.events <- event
 }
 
 func (a *MetricLogger) WriteLog() {
-	// Open log file
-	f, err := os.OpenFile(glob.MetricsLogLoctation, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-	check(err)
-
-	// Close the file when the logger ends
-	defer f.Close()
+	defer a.done.Done()
 
 	// Keep popping messages from the channel
-	for log := range a.WriteChannel {
-		f.WriteString(strconv.Itoa(int(log.TimeStamp.UnixMilli()-a.startTimeUnix)) + " " + log.Tag + " " + log.Message + "\n")
+	for event := range a.events {
+		ms := event.TimeStamp.UnixMilli() - a.startTimeUnix
+		for _, sink := range a.sinks {
+			if err := sink.Write(ms, event); err != nil {
+				fmt.Println("*** unable to write metric " + event.Metric.Name + ": " + err.Error() + " ***")
+			}
+		}
+	}
+
+	// Close the sinks when the logger ends
+	for _, sink := range a.sinks {
+		if err := sink.Close(); err != nil {
+			fmt.Println("*** unable to close the metric sink: " + err.Error() + " ***")
+		}
 	}
 }
 
+// Stop : stop the poller, write the logged events and close the sinks
+func (a *MetricLogger) Stop() {
+	a.mu.Lock()
+	if a.events == nil || a.stopped {
+		a.mu.Unlock()
+		return
+	}
+	a.stopped = true
+	close(a.stopPoller)
+	close(a.events)
+	a.mu.Unlock()
+
+	a.done.Wait()
+}
+
 func (a *MetricLogger) SetBufferLevel(buffLevelMilli int) {
+	a.bufferMu.Lock()
+	defer a.bufferMu.Unlock()
 	a.bufferLevelMilli = buffLevelMilli
 	a.lastBufferLevelUpdate = time.Now()
-
-	// Log bufferlevel
-	/*a.WriteChannel <- MetricLoggingFormat{
-		TimeStamp: a.lastBufferLevelUpdate,
-		Tag:       "BUFFERLEVEL",
-		Message:   strconv.Itoa(buffLevelMilli),
-	}*/
 }
 
 func (a *MetricLogger) CalculateCurrentBufferOccupancy() int {
+	a.bufferMu.Lock()
+	defer a.bufferMu.Unlock()
 	diffMilli := time.Since(a.lastBufferLevelUpdate).Milliseconds()
 	bufferLevelMilli := a.bufferLevelMilli - int(diffMilli)
 	if bufferLevelMilli < 0 {
@@ -96,15 +151,17 @@ func (a *MetricLogger) CalculateCurrentBufferOccupancy() int {
 }
 
 func (a *MetricLogger) MetricsPoller() {
-	for true {
+	ticker := time.NewTicker(time.Duration(a.pollFrequencyMilli) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
 		// Log bufferlevel
-		a.WriteChannel <- MetricLoggingFormat{
-			TimeStamp: time.Now(),
-			Tag:       "BUFFERLEVEL",
-			Message:   strconv.Itoa(a.CalculateCurrentBufferOccupancy()),
-		}
+		a.Log(MetricBufferLevel, float64(a.CalculateCurrentBufferOccupancy()))
 
-		// Sleep for the given duration
-		time.Sleep(time.Duration(a.pollFrequencyMilli) * time.Millisecond)
+		select {
+		case <-a.stopPoller:
+			return
+		case <-ticker.C:
+		}
 	}
 }
@@ -0,0 +1,314 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package logging
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// MetricSink : an output of the metric logger
+type MetricSink interface {
+	// Write : save event, ms is the time since the start of the logger
+	Write(ms int64, event MetricEvent) error
+	// Close : flush and close the sink
+	Close() error
+}
+
+// MetricSinkNames : the sinks of the metric logger
+var MetricSinkNames = []string{glob.MetricsSinkText, glob.MetricsSinkCSV, glob.MetricsSinkNDJSON, glob.MetricsSinkPrometheus}
+
+// NewMetricSinks :
+/*
+ * open the sinks of config, the file sinks save to config.Path plus their extension
+ * an unknown sink name is an error
+ */
+func NewMetricSinks(config MetricSinkConfig) ([]MetricSink, error) {
+
+	var sinks []MetricSink
+	closeAll := func() {
+		for _, sink := range sinks {
+			sink.Close()
+		}
+	}
+
+	for _, name := range config.Sinks {
+		var sink MetricSink
+		var err error
+		switch strings.TrimSpace(name) {
+		case glob.MetricsSinkText:
+			sink, err = newTextSink(config.Path + glob.FileFormat)
+		case glob.MetricsSinkCSV:
+			sink, err = newCSVSink(config.Path + ".csv")
+		case glob.MetricsSinkNDJSON:
+			sink, err = newNDJSONSink(config.Path + ".ndjson")
+		case glob.MetricsSinkPrometheus:
+			sink, err = NewPrometheusSink(config.PrometheusAddr)
+		default:
+			err = errors.New("unknown metric sink " + name)
+		}
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// formatMetricValue : values as the text log always printed them, integers without decimals
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// fileSink : a buffered file of a file sink
+type fileSink struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func openFileSink(fileName string) (fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return fileSink{}, err
+	}
+	f, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return fileSink{}, err
+	}
+	return fileSink{file: f, writer: bufio.NewWriter(f)}, nil
+}
+
+func (s fileSink) Close() error {
+	err := s.writer.Flush()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// textSink : the "<ms> <TAG> <values>" lines the paper scripts read
+type textSink struct {
+	fileSink
+}
+
+func newTextSink(fileName string) (MetricSink, error) {
+	f, err := openFileSink(fileName)
+	return textSink{f}, err
+}
+
+func (s textSink) Write(ms int64, event MetricEvent) error {
+	line := strconv.FormatInt(ms, 10) + " " + event.Metric.Name
+	switch {
+	case len(event.Values) > 0:
+		for _, value := range event.Values {
+			line += " " + formatMetricValue(value)
+		}
+	case event.Message != "":
+		line += " " + event.Message
+	default:
+		// the scripts read the third column, an event without values repeats its name
+		line += " " + event.Metric.Name
+	}
+	_, err := s.writer.WriteString(line + "\n")
+	return err
+}
+
+// csvSink : one row per value, time_ms,metric,kind,unit,field,value,message
+type csvSink struct {
+	fileSink
+	csv *csv.Writer
+}
+
+func newCSVSink(fileName string) (MetricSink, error) {
+	f, err := openFileSink(fileName)
+	if err != nil {
+		return nil, err
+	}
+	s := csvSink{fileSink: f, csv: csv.NewWriter(f.writer)}
+	return s, s.csv.Write([]string{"time_ms", "metric", "kind", "unit", "field", "value", "message"})
+}
+
+func (s csvSink) Write(ms int64, event MetricEvent) error {
+	timeMs := strconv.FormatInt(ms, 10)
+	metric := event.Metric
+	if len(event.Values) == 0 {
+		return s.csv.Write([]string{timeMs, metric.Name, string(metric.Kind), metric.Unit, "", "", event.Message})
+	}
+	for i, value := range event.Values {
+		if err := s.csv.Write([]string{timeMs, metric.Name, string(metric.Kind), metric.Unit, metric.Fields[i], formatMetricValue(value), event.Message}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s csvSink) Close() error {
+	s.csv.Flush()
+	if err := s.csv.Error(); err != nil {
+		s.fileSink.Close()
+		return err
+	}
+	return s.fileSink.Close()
+}
+
+// ndjsonSink : one json object per event
+type ndjsonSink struct {
+	fileSink
+	encoder *json.Encoder
+}
+
+// ndjsonEvent : the json object of an event
+type ndjsonEvent struct {
+	TimeMs  int64              `json:"time_ms"`
+	Metric  string             `json:"metric"`
+	Kind    MetricKind         `json:"kind"`
+	Unit    string             `json:"unit,omitempty"`
+	Values  map[string]float64 `json:"values,omitempty"`
+	Message string             `json:"message,omitempty"`
+}
+
+func newNDJSONSink(fileName string) (MetricSink, error) {
+	f, err := openFileSink(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return ndjsonSink{fileSink: f, encoder: json.NewEncoder(f.writer)}, nil
+}
+
+func (s ndjsonSink) Write(ms int64, event MetricEvent) error {
+	e := ndjsonEvent{
+		TimeMs:  ms,
+		Metric:  event.Metric.Name,
+		Kind:    event.Metric.Kind,
+		Unit:    event.Metric.Unit,
+		Message: event.Message,
+	}
+	if len(event.Values) > 0 {
+		e.Values = make(map[string]float64, len(event.Values))
+		for i, value := range event.Values {
+			e.Values[event.Metric.Fields[i]] = value
+		}
+	}
+	return s.encoder.Encode(e)
+}
+
+// PrometheusSink :
+/*
+ * serve the metrics in the prometheus text format on /metrics
+ * gauges and events export their last values, every metric also counts its events
+ */
+type PrometheusSink struct {
+	mu       sync.Mutex
+	values   map[*Metric][]float64
+	counts   map[*Metric]int
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewPrometheusSink : listen on addr, ":0" picks a free port
+func NewPrometheusSink(addr string) (*PrometheusSink, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &PrometheusSink{
+		values:   make(map[*Metric][]float64),
+		counts:   make(map[*Metric]int),
+		listener: listener,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		s.WriteExposition(w)
+	})
+	s.server = &http.Server{Handler: mux}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// Addr : the address the sink listens on
+func (s *PrometheusSink) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *PrometheusSink) Write(ms int64, event MetricEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[event.Metric]++
+	if len(event.Values) > 0 {
+		s.values[event.Metric] = append([]float64(nil), event.Values...)
+	}
+	return nil
+}
+
+// WriteExposition : the registered metrics in the prometheus text format
+func (s *PrometheusSink) WriteExposition(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, metric := range RegisteredMetrics() {
+		name := prometheusName(metric)
+		help := metric.Help
+		if metric.Unit != "" {
+			help += " (" + metric.Unit + ")"
+		}
+		if values, ok := s.values[metric]; ok {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+			for i, value := range values {
+				fmt.Fprintf(w, "%s{field=%q} %s\n", name, metric.Fields[i], formatMetricValue(value))
+			}
+		}
+		fmt.Fprintf(w, "# HELP %s_total events of %s\n# TYPE %s_total counter\n%s_total %d\n", name, metric.Name, name, name, s.counts[metric])
+	}
+}
+
+func (s *PrometheusSink) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// prometheusName : godash_ and the lower case metric name, with only letters, digits and underscores
+func prometheusName(metric *Metric) string {
+	name := []byte("godash_" + strings.ToLower(metric.Name))
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMetricLoggerFileSinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics_log")
+
+	var logger MetricLogger
+	if err := logger.StartLogger(1000, []int{4000000, 1000000}, 10, MetricSinkConfig{Sinks: []string{"text", "csv", "ndjson"}, Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	logger.Log(MetricSegmentReplacement, 7, 2500000)
+	logger.Log(MetricStallPredictor)
+	logger.LogMessage(MetricLogicSwitch, "RATE_TO_BBA")
+	// a value for a metric without fields is dropped
+	logger.Log(MetricStallPredictor, 1)
+	logger.Stop()
+	// events after Stop are dropped
+	logger.Log(MetricBufferLevel, 1)
+
+	text, err := os.ReadFile(path + ".txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(text)), "\n") {
+		// drop the time column, and the buffer level the poller logs
+		fields := strings.SplitN(line, " ", 2)
+		if !strings.HasPrefix(fields[1], "BUFFERLEVEL") {
+			got = append(got, fields[1])
+		}
+	}
+	want := []string{"HIGHESTBANDWIDTH 4000000", "BUFFERSIZE 10", "STARTTIME " + strings.Fields(got[2])[1],
+		"SegmentReplacement 7 2500000", "STALLPREDICTOR STALLPREDICTOR", "LOGICSWITCH RATE_TO_BBA"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("text log\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	csv, err := os.ReadFile(path + ".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(csv), "time_ms,metric,kind,unit,field,value,message\n") || !strings.Contains(string(csv), ",SegmentReplacement,event,bps,segment,7,\n") {
+		t.Errorf("csv log\n%s", csv)
+	}
+
+	ndjson, err := os.ReadFile(path + ".ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ndjson), `"metric":"SegmentReplacement","kind":"event","unit":"bps","values":{"bitrate":2500000,"segment":7}}`) {
+		t.Errorf("ndjson log\n%s", ndjson)
+	}
+}
+
+func TestPrometheusSink(t *testing.T) {
+	sink, err := NewPrometheusSink("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	sink.Write(0, MetricEvent{Metric: MetricBufferLevel, Values: []float64{1500}})
+	sink.Write(100, MetricEvent{Metric: MetricBufferLevel, Values: []float64{1400}})
+	sink.Write(100, MetricEvent{Metric: MetricStallPredictor})
+
+	var out bytes.Buffer
+	sink.WriteExposition(&out)
+	for _, want := range []string{
+		"# TYPE godash_bufferlevel gauge\n",
+		"godash_bufferlevel{field=\"level\"} 1400\n",
+		"godash_bufferlevel_total 2\n",
+		"godash_stallpredictor_total 1\n",
+		"godash_segmentarrived_total 0\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("exposition has no %q\n%s", want, out.String())
+		}
+	}
+}
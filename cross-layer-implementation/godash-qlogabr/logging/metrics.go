@@ -0,0 +1,130 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package logging
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MetricKind : how the values of a metric are to be read
+type MetricKind string
+
+const (
+	// GaugeMetric : a level, every event replaces the previous values
+	GaugeMetric MetricKind = "gauge"
+	// EventMetric : something that happened, with the values at that time
+	EventMetric MetricKind = "event"
+	// MessageMetric : something that happened, described by a message
+	MessageMetric MetricKind = "message"
+)
+
+// Metric : the schema of a metric
+type Metric struct {
+	// Name : the tag of the metric in the text log
+	Name string
+	Kind MetricKind
+	// Unit : the unit of the values
+	Unit string
+	Help string
+	// Fields : the names of the values of an event, in order
+	Fields []string
+}
+
+// registered metrics, in registration order
+var (
+	metricsMu      sync.Mutex
+	metricRegistry = make(map[string]*Metric)
+	metricOrder    []*Metric
+)
+
+// RegisterMetric :
+/*
+ * add a metric to the registry, the metric logger only logs registered metrics
+ * registering a name twice returns the metric registered first
+ */
+func RegisterMetric(metric Metric) *Metric {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if registered, ok := metricRegistry[metric.Name]; ok {
+		fmt.Println("*** metric " + metric.Name + " is already registered ***")
+		return registered
+	}
+	m := metric
+	metricRegistry[m.Name] = &m
+	metricOrder = append(metricOrder, &m)
+	return &m
+}
+
+// LookupMetric : the registered metric called name
+func LookupMetric(name string) (*Metric, bool) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metric, ok := metricRegistry[name]
+	return metric, ok
+}
+
+// RegisteredMetrics : the registered metrics
+func RegisteredMetrics() []*Metric {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	return append([]*Metric(nil), metricOrder...)
+}
+
+// the metrics of goDASH, the names are the tags of the text log
+var (
+	// stream
+	MetricHighestBandwidth = RegisterMetric(Metric{Name: "HIGHESTBANDWIDTH", Kind: GaugeMetric, Unit: "bps", Help: "highest rep_rate of the stream", Fields: []string{"bitrate"}})
+	MetricBufferSize       = RegisterMetric(Metric{Name: "BUFFERSIZE", Kind: GaugeMetric, Unit: "s", Help: "maximum buffer of the stream", Fields: []string{"size"}})
+	MetricStartTime        = RegisterMetric(Metric{Name: "STARTTIME", Kind: GaugeMetric, Unit: "ms", Help: "unix time the metric logger started", Fields: []string{"time"}})
+	MetricBufferLevel      = RegisterMetric(Metric{Name: "BUFFERLEVEL", Kind: GaugeMetric, Unit: "ms", Help: "buffer level, polled by the metric logger", Fields: []string{"level"}})
+
+	// player
+	MetricSegmentDownloadStart = RegisterMetric(Metric{Name: "SegmentDownloadStart", Kind: EventMetric, Unit: "bps", Help: "a segment download started at this rep_rate", Fields: []string{"bitrate"}})
+	MetricSegmentArrived       = RegisterMetric(Metric{Name: "SegmentArrived", Kind: EventMetric, Unit: "bps", Help: "a segment download ended at this rep_rate", Fields: []string{"bitrate"}})
+	MetricSegmentReplacement   = RegisterMetric(Metric{Name: "SegmentReplacement", Kind: EventMetric, Unit: "bps", Help: "HLS replaces a segment at this rep_rate", Fields: []string{"segment", "bitrate"}})
+	MetricPlaybackRate         = RegisterMetric(Metric{Name: "PLAYBACKRATE", Kind: GaugeMetric, Help: "speed of the playback, set by LoL+", Fields: []string{"rate"}})
+	MetricThroughputPrediction = RegisterMetric(Metric{Name: "THROUGHPUTPREDICTION", Kind: GaugeMetric, Unit: "bps", Help: "throughput of the next segment predicted by -predictor, and the confidence in it", Fields: []string{"throughput", "confidence"}})
+	MetricVBRBitrate           = RegisterMetric(Metric{Name: "VBRBITRATE", Kind: GaugeMetric, Unit: "bps", Help: "actual bitrate of the next segments of the current rep_rate the throughput algorithms compare against, and its bandwidth in the MPD", Fields: []string{"actual", "nominal"}})
+
+	// cross-layer stall predictor
+	MetricWindowThroughput      = RegisterMetric(Metric{Name: "WINDOWTHROUGHPUT", Kind: GaugeMetric, Unit: "bits/ms", Help: "throughput of the current segment download", Fields: []string{"throughput"}})
+	MetricSumBits               = RegisterMetric(Metric{Name: "SUMBITS", Kind: GaugeMetric, Unit: "bits", Help: "bits received of the current segment", Fields: []string{"bits"}})
+	MetricWindowThreshold       = RegisterMetric(Metric{Name: "WINDOWTHRESHOLD", Kind: GaugeMetric, Unit: "bits", Help: "bits to receive before the stall predictor starts", Fields: []string{"bits"}})
+	MetricSegmentChunkSize      = RegisterMetric(Metric{Name: "SEGMENTCHUNKSIZE", Kind: GaugeMetric, Unit: "bits", Help: "size of the current segment", Fields: []string{"bits"}})
+	MetricAbortRequiredTime     = RegisterMetric(Metric{Name: "ABORTLOGIC_REQUIREDTIME", Kind: GaugeMetric, Unit: "ms", Help: "predicted remaining download time of the current segment", Fields: []string{"time"}})
+	MetricAbortLevel            = RegisterMetric(Metric{Name: "ABORTLOGIC_LEVEL", Kind: GaugeMetric, Unit: "ms", Help: "buffer level seen by the stall predictor", Fields: []string{"level"}})
+	MetricStallPredictor        = RegisterMetric(Metric{Name: "STALLPREDICTOR", Kind: EventMetric, Help: "the stall predictor aborted the current segment"})
+	MetricStallPredictorDetails = RegisterMetric(Metric{Name: "DEBUGINFO", Kind: MessageMetric, Help: "values of the stall predictor"})
+
+	// BBA-2
+	MetricLowerReservoir = RegisterMetric(Metric{Name: "LOWERRESERVOIR", Kind: GaugeMetric, Unit: "ms", Help: "BBA-2 lower reservoir", Fields: []string{"reservoir"}})
+	MetricUpperReservoir = RegisterMetric(Metric{Name: "UPPERRESERVOIR", Kind: GaugeMetric, Unit: "ms", Help: "BBA-2 upper reservoir", Fields: []string{"reservoir"}})
+	MetricChunkSum       = RegisterMetric(Metric{Name: "CHUNKSUM", Kind: GaugeMetric, Unit: "ms", Help: "BBA-2 reservoir before clamping", Fields: []string{"sum"}})
+	MetricPercentage     = RegisterMetric(Metric{Name: "PERCENTAGE", Kind: GaugeMetric, Help: "BBA-2 position in the buffer cushion", Fields: []string{"percentage"}})
+	MetricDesiredBitrate = RegisterMetric(Metric{Name: "DESIREDBITRATE", Kind: GaugeMetric, Unit: "bps", Help: "BBA-2 bitrate mapped from the buffer cushion", Fields: []string{"bitrate"}})
+	MetricLogicSwitch    = RegisterMetric(Metric{Name: "LOGICSWITCH", Kind: MessageMetric, Help: "BBA-2 switched from the rate based start up"})
+	// the network state the dynamic BBA-2 reservoirs are sized on
+	MetricThroughputVariation = RegisterMetric(Metric{Name: "THROUGHPUTVARIATION", Kind: GaugeMetric, Help: "BBA-2 coefficient of variation of the throughput of the last segments", Fields: []string{"variation"}})
+	MetricReservoirRTT        = RegisterMetric(Metric{Name: "RESERVOIRRTT", Kind: GaugeMetric, Unit: "ms", Help: "BBA-2 smoothed RTT every chunk of the lower reservoir waits", Fields: []string{"rtt"}})
+)
@@ -0,0 +1,154 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package output
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Artefact : a kind of file a run writes
+type Artefact string
+
+const (
+	// TransportQlog : the qlog of a QUIC connection
+	TransportQlog Artefact = "transport_qlog"
+	// ABRQlog : the qlog of the player
+	ABRQlog Artefact = "abr_qlog"
+	// Metrics : the metric logs
+	Metrics Artefact = "metrics"
+	// DebugLog : the debug log
+	DebugLog Artefact = "debug"
+	// Summary : the QoE report of the stream
+	Summary Artefact = "summary"
+	// SegmentHeaders : the segment header and segment size files of an MPD
+	SegmentHeaders Artefact = "headers"
+	// Segments : the folder of the stored DASH segments
+	Segments Artefact = "segments"
+	// Config : the effective config of the run
+	Config Artefact = "config"
+)
+
+// DefaultTemplate : the logs in <root>/<run>/logs and the segments in <root>/<run>/files
+const DefaultTemplate = "{root}/{run}/{dir}/{name}"
+
+// the placeholders of a naming template
+var placeholders = []string{"{root}", "{run}", "{dir}", "{kind}", "{name}"}
+
+// Run :
+/*
+ * the output of one streaming session, every file of the session is written beneath Root
+ * a run does not touch the filesystem until a file is created
+ */
+type Run struct {
+	// Root : the folder the files of the run are written beneath
+	Root string
+	// ID : the id of the run, empty if the run has no own folder
+	ID string
+	// Template : the naming template of the files, see Path
+	Template string
+}
+
+// New :
+/*
+ * a run with output root, run id and naming template, an empty template is DefaultTemplate
+ * the template must hold {name} and may hold {root}, {run}, {dir} and {kind}
+ */
+func New(root string, id string, template string) (Run, error) {
+	if root == "" {
+		root = "."
+	}
+	if template == "" {
+		template = DefaultTemplate
+	}
+	if !strings.Contains(template, "{name}") {
+		return Run{}, errors.New("output template " + template + " has no {name}")
+	}
+	rest := template
+	for _, placeholder := range placeholders {
+		rest = strings.ReplaceAll(rest, placeholder, "")
+	}
+	if strings.ContainsAny(rest, "{}") {
+		return Run{}, errors.New("output template " + template + " has an unknown placeholder, use " + strings.Join(placeholders, " "))
+	}
+	if strings.ContainsAny(id, `/\`) {
+		return Run{}, errors.New("run id " + id + " is not a folder name")
+	}
+	return Run{Root: root, ID: id, Template: template}, nil
+}
+
+// Default : the run of the current folder, logs in ./logs and segments in ./files
+func Default() Run {
+	return Run{Root: ".", Template: DefaultTemplate}
+}
+
+// Sub :
+/*
+ * the run of one part of this run, like a client of a multi-client run,
+ * its files are written in folder id of the run folder, or of the root if the template has no {run}
+ */
+func (r Run) Sub(id string) Run {
+	if strings.Contains(r.Template, "{run}") || r.Template == "" {
+		if r.ID != "" {
+			id = r.ID + "/" + id
+		}
+		return Run{Root: r.Root, ID: id, Template: r.Template}
+	}
+	return Run{Root: filepath.Join(r.Root, id), ID: r.ID, Template: r.Template}
+}
+
+// dir : the folder an artefact is saved in by default
+func (a Artefact) dir() string {
+	if a == Segments {
+		return "files"
+	}
+	return "logs"
+}
+
+// Path :
+/*
+ * the location of file name of artefact
+ * {root} is the output root, {run} the run id, {dir} "logs" or "files", {kind} the artefact
+ */
+func (r Run) Path(artefact Artefact, name string) string {
+	template := r.Template
+	if template == "" {
+		template = DefaultTemplate
+	}
+	root := r.Root
+	if root == "" {
+		root = "."
+	}
+	p := strings.NewReplacer("{root}", root, "{run}", r.ID, "{dir}", artefact.dir(), "{kind}", string(artefact), "{name}", name).Replace(template)
+	return filepath.Clean(p)
+}
+
+// Create : create file name of artefact, and the folders it is in
+func (r Run) Create(artefact Artefact, name string) (*os.File, error) {
+	fileName := r.Path(artefact, name)
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return nil, err
+	}
+	return os.Create(fileName)
+}
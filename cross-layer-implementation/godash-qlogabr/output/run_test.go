@@ -0,0 +1,62 @@
+package output
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRunPath(t *testing.T) {
+	tests := []struct {
+		root, id, template string
+		artefact           Artefact
+		name               string
+		want               string
+	}{
+		// the default run keeps the logs in ./logs and the segments in ./files
+		{"", "", "", ABRQlog, "Client_abr_x.qlog", "logs/Client_abr_x.qlog"},
+		{"", "", "", Segments, "", "files"},
+		{"/tmp/out", "r1", "", Metrics, "metrics_log", "/tmp/out/r1/logs/metrics_log"},
+		{"/tmp/out", "r1", "", Segments, "clip", "/tmp/out/r1/files/clip"},
+		{"out", "r2", "{root}/{kind}/{run}_{name}", TransportQlog, "client_ab.qlog", "out/transport_qlog/r2_client_ab.qlog"},
+	}
+	for _, test := range tests {
+		run, err := New(test.root, test.id, test.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := run.Path(test.artefact, test.name); got != filepath.FromSlash(test.want) {
+			t.Errorf("Path(%s, %q) of %+v = %s, want %s", test.artefact, test.name, run, got, test.want)
+		}
+	}
+}
+
+func TestNewRejectsTemplates(t *testing.T) {
+	for _, template := range []string{"{root}/{run}", "{root}/{date}/{name}"} {
+		if _, err := New(".", "", template); err == nil {
+			t.Errorf("template %s is accepted", template)
+		}
+	}
+	if _, err := New(".", "a/b", ""); err == nil {
+		t.Error("run id a/b is accepted")
+	}
+}
+
+func TestSub(t *testing.T) {
+	tests := []struct {
+		root, id, template string
+		want               string
+	}{
+		{".", "", "", "client_1/logs/summary.json"},
+		{"out", "r1", "", "out/r1/client_1/logs/summary.json"},
+		{"out", "r1", "{root}/{dir}/{name}", "out/client_1/logs/summary.json"},
+	}
+	for _, test := range tests {
+		run, err := New(test.root, test.id, test.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := run.Sub("client_1").Path(Summary, "summary.json"); got != filepath.FromSlash(test.want) {
+			t.Errorf("Sub(client_1) of %+v = %s, want %s", run, got, test.want)
+		}
+	}
+}
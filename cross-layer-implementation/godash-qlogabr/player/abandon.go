@@ -0,0 +1,98 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package player
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	algo "github.com/uccmisl/godash/algorithms"
+)
+
+// abandonCheckInterval : time between two checks of the abandonment rule of a download
+const abandonCheckInterval = 50 * time.Millisecond
+
+// abandonment :
+/*
+ * the abandonment rule of BOLA-E, checked while a segment downloads
+ * the fields are set when the rule cancels the download, read them after stop
+ */
+type abandonment struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	aborted       bool
+	repRate       int
+	receivedBytes int
+	predicted     int
+	bufferLevel   int
+}
+
+// watchAbandonment :
+/*
+ * check the abandonment rule of BOLA-E for the download of segment segmentNumber at rep_rate repRate until stop
+ * rate returns the rate of the download in bps after elapsed milliseconds, cancel aborts the download
+ */
+func (p *pipeline) watchAbandonment(repRate int, segmentNumber int, bufferLevel int, rate func(elapsed int) float64, cancel context.CancelFunc) *abandonment {
+	a := &abandonment{done: make(chan struct{})}
+	start := time.Now()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(abandonCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.done:
+				return
+			case <-ticker.C:
+				elapsed := int(time.Since(start).Milliseconds())
+				downloadRate := rate(elapsed)
+				newRepRate, predicted, abandon := algo.BOLAAbandon(repRate, segmentNumber, downloadRate, elapsed, bufferLevel, &p.abr.BOLA)
+				if abandon {
+					a.aborted = true
+					a.repRate = newRepRate
+					a.receivedBytes = int(downloadRate * float64(elapsed) / 1000 / 8)
+					a.predicted = predicted
+					a.bufferLevel = bufferLevel - elapsed
+					if a.bufferLevel < 0 {
+						a.bufferLevel = 0
+					}
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return a
+}
+
+// stop : stop checking the abandonment rule, a nil abandonment is not checked
+func (a *abandonment) stop() {
+	if a == nil {
+		return
+	}
+	close(a.done)
+	a.wg.Wait()
+}
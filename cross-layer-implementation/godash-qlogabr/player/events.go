@@ -0,0 +1,174 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package player
+
+import (
+	"time"
+
+	abrqlog "github.com/uccmisl/godash/qlog"
+	"github.com/uccmisl/godash/qoe"
+)
+
+// SegmentEvent : a segment that was downloaded and added to the buffer
+type SegmentEvent struct {
+	MediaType abrqlog.MediaType
+	Number    int
+	// rep_rate index and its bandwidth in bits per second
+	RepRate   int
+	Bandwidth int
+	Bytes     int
+	// DeliveryTime of the segment and the Throughput in bits per second
+	DeliveryTime time.Duration
+	Throughput   int
+	// BufferLevel of the adaptation set after the segment was added
+	BufferLevel time.Duration
+	// Replaced is true if the segment replaced a buffered segment (HLS)
+	Replaced bool
+}
+
+// SwitchEvent : the rep_rate of an adaptation set changed
+type SwitchEvent struct {
+	MediaType abrqlog.MediaType
+	// rep_rate indexes and their bandwidth in bits per second
+	From          int
+	To            int
+	FromBandwidth int
+	ToBandwidth   int
+}
+
+// StallEvent : the playback stalled before a segment arrived
+type StallEvent struct {
+	MediaType abrqlog.MediaType
+	Segment   int
+	Duration  time.Duration
+}
+
+// AbortEvent : the stall predictor cancelled the download of a segment
+type AbortEvent struct {
+	MediaType abrqlog.MediaType
+	Segment   int
+	// RepRate index of the cancelled download and the Bytes it received
+	RepRate int
+	Bytes   int
+	Elapsed time.Duration
+	// Predicted download time and the BufferLevel when the download was cancelled
+	Predicted   time.Duration
+	BufferLevel time.Duration
+}
+
+// Events :
+/*
+ * the callbacks of a stream, nil callbacks are not called
+ * every adaptation set streams in its own pipeline, so callbacks can be called concurrently
+ * and should return quickly, the pipeline waits for them
+ */
+type Events struct {
+	OnSegment func(SegmentEvent)
+	OnSwitch  func(SwitchEvent)
+	OnStall   func(StallEvent)
+	OnAbort   func(AbortEvent)
+}
+
+// Stats : the totals of a stream so far, over all adaptation sets
+type Stats struct {
+	Segments  int
+	Bytes     int
+	Switches  int
+	Stalls    int
+	StallTime time.Duration
+	Aborts    int
+	// BufferLevel and Bitrate (in bits per second) after the last segment
+	BufferLevel time.Duration
+	Bitrate     int
+}
+
+// Stats :
+// * the totals of the stream so far, safe to call while the player streams
+func (pl *Player) Stats() Stats {
+	pl.statsMutex.Lock()
+	defer pl.statsMutex.Unlock()
+	return pl.stats
+}
+
+// Summary :
+// * the QoE report of the stream, false until the stream has ended
+func (pl *Player) Summary() (qoe.SessionSummary, bool) {
+	pl.statsMutex.Lock()
+	defer pl.statsMutex.Unlock()
+	if pl.summary == nil {
+		return qoe.SessionSummary{}, false
+	}
+	return *pl.summary, true
+}
+
+// segment :
+// * account a downloaded segment and call OnSegment
+func (pl *Player) segment(e SegmentEvent) {
+	pl.statsMutex.Lock()
+	pl.stats.Segments++
+	pl.stats.Bytes += e.Bytes
+	pl.stats.BufferLevel = e.BufferLevel
+	pl.stats.Bitrate = e.Bandwidth
+	pl.statsMutex.Unlock()
+
+	if pl.cfg.Events.OnSegment != nil {
+		pl.cfg.Events.OnSegment(e)
+	}
+}
+
+// switched :
+// * account a rep_rate switch and call OnSwitch
+func (pl *Player) switched(e SwitchEvent) {
+	pl.statsMutex.Lock()
+	pl.stats.Switches++
+	pl.statsMutex.Unlock()
+
+	if pl.cfg.Events.OnSwitch != nil {
+		pl.cfg.Events.OnSwitch(e)
+	}
+}
+
+// stalled :
+// * account a stall and call OnStall
+func (pl *Player) stalled(e StallEvent) {
+	pl.statsMutex.Lock()
+	pl.stats.Stalls++
+	pl.stats.StallTime += e.Duration
+	pl.statsMutex.Unlock()
+
+	if pl.cfg.Events.OnStall != nil {
+		pl.cfg.Events.OnStall(e)
+	}
+}
+
+// aborted :
+// * account a cancelled download and call OnAbort
+func (pl *Player) aborted(e AbortEvent) {
+	pl.statsMutex.Lock()
+	pl.stats.Aborts++
+	pl.stats.Bytes += e.Bytes
+	pl.statsMutex.Unlock()
+
+	if pl.cfg.Events.OnAbort != nil {
+		pl.cfg.Events.OnAbort(e)
+	}
+}
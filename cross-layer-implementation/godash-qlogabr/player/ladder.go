@@ -0,0 +1,82 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package player
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/uccmisl/godash/http"
+	"github.com/uccmisl/godash/ladder"
+	"github.com/uccmisl/godash/logging"
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// SetConstraints :
+// * replace the ladder constraints, the next video segment is selected within them, safe to call while the player streams
+func (pl *Player) SetConstraints(constraints ladder.Constraints) {
+	pl.ladderMutex.Lock()
+	pl.constraints = constraints
+	pl.ladderMutex.Unlock()
+	logging.DebugPrint(pl.cfg.DebugFile, pl.cfg.DebugLog, "DEBUG: ", "ladder constraints set to "+constraintsString(constraints))
+}
+
+// startSchedule :
+// * the screens of the schedule are changed from now on
+func (pl *Player) startSchedule() {
+	pl.ladderMutex.Lock()
+	pl.scheduleStart = time.Now()
+	pl.ladderMutex.Unlock()
+}
+
+// currentConstraints :
+// * the ladder constraints now, a screen of the schedule replaces them once its time has come
+func (pl *Player) currentConstraints() ladder.Constraints {
+	pl.ladderMutex.Lock()
+	defer pl.ladderMutex.Unlock()
+	schedule := pl.cfg.ScreenSchedule
+	for pl.scheduleNext < len(schedule) && time.Since(pl.scheduleStart) >= schedule[pl.scheduleNext].At {
+		pl.constraints = schedule[pl.scheduleNext].Constraints
+		logging.DebugPrint(pl.cfg.DebugFile, pl.cfg.DebugLog, "DEBUG: ", "screen schedule sets the ladder constraints to "+constraintsString(pl.constraints))
+		pl.scheduleNext++
+	}
+	return pl.constraints
+}
+
+// allowedRepRates :
+/*
+ * the rep_rates of a video adaptation set the ladder constraints allow, between the highest and lowest rep_rate of the MPD
+ * nil for audio, and while there are no constraints
+ */
+func (pl *Player) allowedRepRates(set http.AdaptationSet, mediaType abrqlog.MediaType, highest int, lowest int) []bool {
+	constraints := pl.currentConstraints()
+	if mediaType != abrqlog.MediaTypeVideo || !constraints.Active() {
+		return nil
+	}
+	return ladder.Allowed(ladder.Rungs(set), constraints, highest, lowest)
+}
+
+// constraintsString : the limits of the constraints for the debug log
+func constraintsString(c ladder.Constraints) string {
+	return "width " + strconv.Itoa(c.MaxWidth) + ", height " + strconv.Itoa(c.MaxHeight) + ", frame rate " + strconv.Itoa(c.MaxFrameRate) +
+		", bitrate " + strconv.Itoa(c.MaxBitrate) + ", pixels " + strconv.Itoa(c.MaxPixels) + ", dynamic range " + c.DynamicRange
+}
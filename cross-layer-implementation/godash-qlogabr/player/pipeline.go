@@ -0,0 +1,415 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package player
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/uccmisl/godash/P2Pconsul"
+	algo "github.com/uccmisl/godash/algorithms"
+	xlayer "github.com/uccmisl/godash/crosslayer"
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/http"
+	"github.com/uccmisl/godash/logging"
+	abrqlog "github.com/uccmisl/godash/qlog"
+)
+
+// pipeline : the download and ABR state of one adaptation set
+type pipeline struct {
+	// the client of the stream, index in its mimeTypes
+	player  *Player
+	index   int
+	session *session
+
+	// ABR state
+	abr algo.State
+
+	// MPD values of this adaptation set
+	mpdListIndex         int
+	segmentDuration      int
+	segmentDurationArray []int
+	maxBufferLevel       int
+
+	// playback values
+	waitToPlayCounter int
+	playPosition      int
+
+	// the error that ended the stream of this adaptation set
+	err error
+}
+
+// publishedBuffer : the buffer level of a pipeline after its last segment, in milliseconds
+type publishedBuffer struct {
+	level int
+	at    time.Time
+}
+
+// session : the playback shared by the pipelines of a stream
+type session struct {
+	mu          sync.Mutex
+	streamSpeed float64
+	tracer      *abrqlog.StreamTracer
+	// buffers and startup state of the pipelines that are still downloading
+	buffers map[int]publishedBuffer
+	ready   map[int]bool
+	playing bool
+	started time.Time
+	// content played at earlier speeds, in milliseconds, and the time the current speed was set
+	played    int
+	speedFrom time.Time
+	// stalls after the playback started, in milliseconds of content
+	stalled int
+	// ready state of the player in qlog
+	readyState abrqlog.ReadyState
+	// audio values used by the QoE models
+	audioRate  int
+	audioCodec string
+}
+
+// newSession :
+// * create the shared playback state, logged to the ABR qlog of tracer
+func newSession(streamSpeed float64, tracer *abrqlog.StreamTracer) *session {
+	return &session{
+		streamSpeed: streamSpeed,
+		tracer:      tracer,
+		buffers:     make(map[int]publishedBuffer),
+		ready:       make(map[int]bool),
+		readyState:  abrqlog.ReadyStateHaveMetadata,
+	}
+}
+
+// join :
+// * add a pipeline to the playback, it has an empty buffer until it publishes one
+func (s *session) join(index int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buffers[index] = publishedBuffer{at: time.Now()}
+	s.ready[index] = false
+}
+
+// publish :
+// * save the buffer level in milliseconds of a pipeline after it added a segment
+func (s *session) publish(index int, level int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.buffers[index]; ok {
+		s.buffers[index] = publishedBuffer{level: level, at: time.Now()}
+	}
+}
+
+// minimumBuffer :
+/*
+ * the buffer the player has left, which is the lowest buffer of all pipelines
+ * ownBuffer is the current buffer of the calling pipeline,
+ * the buffers of the others are drained from the time they were published
+ */
+func (s *session) minimumBuffer(index int, ownBuffer int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	minimum := ownBuffer
+	for other, buffer := range s.buffers {
+		if other == index {
+			continue
+		}
+		// buffers do not drain before the playback starts
+		from := buffer.at
+		if !s.playing {
+			from = time.Now()
+		} else if from.Before(s.started) {
+			from = s.started
+		}
+		level := buffer.level - int(float64(time.Since(from).Milliseconds())*s.streamSpeed)
+		if level < minimum {
+			minimum = level
+		}
+	}
+	return minimum
+}
+
+// play :
+/*
+ * mark a pipeline as having its initial buffer
+ * returns true once all pipelines have their initial buffer,
+ * the first call that starts the playback logs it to qlog
+ */
+func (s *session) play(index int, ready bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.playing {
+		return true
+	}
+	if ready {
+		s.ready[index] = true
+	}
+	for _, pipelineReady := range s.ready {
+		if !pipelineReady {
+			return false
+		}
+	}
+
+	s.playing = true
+	s.started = time.Now()
+	s.speedFrom = s.started
+	playhead := abrqlog.NewPlayheadStatus()
+	playhead.PlayheadTime = 0
+	playhead.PlayheadFrame = 0
+	s.tracer.PlayerInteraction(abrqlog.InteractionStatePlay, playhead, s.streamSpeed)
+	s.changeReadyState(abrqlog.ReadyStateHaveEnoughData)
+
+	return true
+}
+
+// isPlaying :
+// * true once all pipelines had their initial buffer and the playback started
+func (s *session) isPlaying() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.playing
+}
+
+// changeReadyState :
+// * log the ready state of the player to qlog when it changes, the caller holds s.mu
+func (s *session) changeReadyState(state abrqlog.ReadyState) {
+	if s.readyState == state {
+		return
+	}
+	s.readyState = state
+	s.tracer.ChangeReadyState(state)
+}
+
+// arrived :
+// * a segment arrived, the player has data to play once the first segment arrives
+func (s *session) arrived() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readyState < abrqlog.ReadyStateHaveCurrentData {
+		s.changeReadyState(abrqlog.ReadyStateHaveCurrentData)
+	}
+}
+
+// rebuffer :
+/*
+ * account the stall in milliseconds a pipeline found after its segment arrived, 0 if there was none
+ * the player has only the current data while it stalls, and enough data again after a segment arrives in time
+ */
+func (s *session) rebuffer(stall int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.playing {
+		return
+	}
+	if stall > 0 {
+		s.stalled += stall
+		s.changeReadyState(abrqlog.ReadyStateHaveCurrentData)
+	} else {
+		s.changeReadyState(abrqlog.ReadyStateHaveEnoughData)
+	}
+}
+
+// playhead :
+// * the position of the playback, the content played since it started less the stalls
+func (s *session) playhead() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.playing {
+		return 0
+	}
+	return s.position()
+}
+
+// position :
+// * the playhead, the caller holds s.mu
+func (s *session) position() time.Duration {
+	position := s.played + int(float64(time.Since(s.speedFrom).Milliseconds())*s.streamSpeed) - s.stalled
+	if position < 0 {
+		return 0
+	}
+	return time.Duration(position) * time.Millisecond
+}
+
+// speed :
+// * the current speed of the playback
+func (s *session) speed() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.streamSpeed
+}
+
+// setSpeed :
+/*
+ * change the speed of the playback and log it to qlog, the content played so far is kept at the old speed
+ * returns false before the playback started, when the speed is not changed
+ */
+func (s *session) setSpeed(streamSpeed float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.playing {
+		return false
+	}
+	now := time.Now()
+	s.played += int(float64(now.Sub(s.speedFrom).Milliseconds()) * s.streamSpeed)
+	s.speedFrom = now
+	s.streamSpeed = streamSpeed
+
+	playhead := abrqlog.NewPlayheadStatus()
+	playhead.PlayheadTime = s.position()
+	s.tracer.PlayerInteraction(abrqlog.InteractionStateSpeed, playhead, streamSpeed)
+	return true
+}
+
+// leave :
+// * remove a pipeline that downloaded all its segments, returns true if it was the last one
+func (s *session) leave(index int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.buffers, index)
+	delete(s.ready, index)
+	return len(s.buffers) == 0
+}
+
+// setAudio :
+// * save the rate in kbps and codec of the audio adaptation set
+func (s *session) setAudio(rate int, codec string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audioRate = rate
+	s.audioCodec = codec
+}
+
+// audio :
+// * the rate in kbps and codec of the audio adaptation set
+func (s *session) audio() (int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.audioRate, s.audioCodec
+}
+
+// logSwitch :
+/*
+ * log a rep_rate switch of this adaptation set to qlog, with the bitrate in kbps and the resolution of video
+ * a from of -1 logs the first rep_rate of the stream
+ */
+func (p *pipeline) logSwitch(representations []http.Representation, bandwithList []int, from int, to int) {
+	fromRep := abrqlog.NewRepresentation()
+	toRep := abrqlog.NewRepresentation()
+	if from >= 0 {
+		fromRep.ID = strconv.Itoa(from)
+		fromRep.Bitrate = int64(bandwithList[from] / glob.Conversion1000)
+		if representations[from].Width > 0 && representations[from].Height > 0 {
+			fromRep.Width = int32(representations[from].Width)
+			fromRep.Height = int32(representations[from].Height)
+		}
+	}
+	toRep.ID = strconv.Itoa(to)
+	toRep.Bitrate = int64(bandwithList[to] / glob.Conversion1000)
+	if representations[to].Width > 0 && representations[to].Height > 0 {
+		toRep.Width = int32(representations[to].Width)
+		toRep.Height = int32(representations[to].Height)
+	}
+	p.player.tracer.Switch(p.player.mimeTypesMediaType[p.index], fromRep, toRep)
+
+	if from >= 0 {
+		p.player.switched(SwitchEvent{
+			MediaType:     p.player.mimeTypesMediaType[p.index],
+			From:          from,
+			To:            to,
+			FromBandwidth: bandwithList[from],
+			ToBandwidth:   bandwithList[to],
+		})
+	}
+}
+
+// finish :
+// * leave the playback, the last pipeline ends the stream in qlog
+func (p *pipeline) finish() {
+	if p.session.leave(p.index) {
+		playhead := abrqlog.NewPlayheadStatus()
+		playhead.PlayheadTime = p.session.playhead()
+		p.player.tracer.EndStream(playhead)
+	}
+}
+
+// fail :
+/*
+ * end the stream of the pipeline with err, and return the values of streamLoop
+ * a replacement call leaves the playback to the call it replaces
+ */
+func (p *pipeline) fail(err error, hlsUsed bool, segmentNumber int, mapSegmentLogPrintout map[int]logging.SegPrintLogInformation) (int, []map[int]logging.SegPrintLogInformation) {
+	p.err = err
+	if !hlsUsed {
+		p.finish()
+	}
+	return segmentNumber, []map[int]logging.SegPrintLogInformation{mapSegmentLogPrintout}
+}
+
+// streamPipelines :
+/*
+ * stream every adaptation set in its own pipeline
+ * a single adaptation set uses the accountant as is, otherwise every pipeline
+ * gets its own accountant, which only counts the QUIC streams of its requests
+ * a pipeline that fails stops the others
+ * returns the last segment number of the first pipeline, the logs of all pipelines and the first error
+ */
+func streamPipelines(pipelines []*pipeline, streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant, metricsLogger *logging.MetricLogger, ctx context.Context) (int, []map[int]logging.SegPrintLogInformation, error) {
+
+	segmentNumbers := make([]int, len(pipelines))
+	mapSegmentLogPrintouts := make([]map[int]logging.SegPrintLogInformation, len(pipelines))
+
+	for _, p := range pipelines {
+		p.session.join(p.index)
+	}
+
+	if len(pipelines) == 1 {
+		segmentNumber, logs := pipelines[0].streamLoop(streamStructs[:1], Noden, accountant, metricsLogger, ctx)
+		return segmentNumber, logs, pipelines[0].err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i, p := range pipelines {
+		wg.Add(1)
+		go func(i int, p *pipeline) {
+			defer wg.Done()
+			var logs []map[int]logging.SegPrintLogInformation
+			segmentNumbers[i], logs = p.streamLoop(streamStructs[i:i+1], Noden, accountant.NewStreamAccountant(), metricsLogger, ctx)
+			mapSegmentLogPrintouts[i] = logs[0]
+			if p.err != nil {
+				cancel()
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	for _, p := range pipelines {
+		if p.err != nil {
+			return segmentNumbers[0], mapSegmentLogPrintouts, p.err
+		}
+	}
+	return segmentNumbers[0], mapSegmentLogPrintouts, nil
+}
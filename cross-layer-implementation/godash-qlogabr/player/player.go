@@ -25,11 +25,12 @@ package player
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
-	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/uccmisl/godash/P2Pconsul"
@@ -39,7 +40,10 @@ import (
 	glob "github.com/uccmisl/godash/global"
 	"github.com/uccmisl/godash/hlsfunc"
 	"github.com/uccmisl/godash/http"
+	"github.com/uccmisl/godash/ladder"
 	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/output"
+	"github.com/uccmisl/godash/predictor"
 	"github.com/uccmisl/godash/qoe"
 	"github.com/uccmisl/godash/utils"
 
@@ -47,170 +51,203 @@ import (
 	abrqlog "github.com/uccmisl/godash/qlog"
 )
 
-// play position
-var playPosition = 0
-
-// current segment number
-var segmentNumber = 1
-var segmentDuration int
-var nextSegmentNumber int
-
-// current buffer level
-var bufferLevel = 0
-var maxBufferLevel int
-var waitToPlayCounter = 0
-var stallTime = 0
-
-// current mpd file
-var mpdListIndex = 0
-var lowestMPDrepRateIndex []int
-var highestMPDrepRateIndex []int
-
-// save the previous mpdIndex
-var oldMPDIndex = 0
-
-// determine if an MPD is byte-range or not
-var isByteRangeMPD bool
-var startRange = 0
-var endRange = 0
-
-// current representation rate
-var repRate = 0
-
-//var repRatesReversed bool
-
-// current adaptationSet
-var currentMPDRepAdaptSet int
-
-// Segment size (in bits)
-var segSize int
-
-// baseURL for this MPD file
-var baseURL string
-var headerURL string
-var currentURL string
-
-// we need to keep a tab on the different size segments - use this for now
-// we will use an array in the future
-var segmentDurationTotal = 0
-var segmentDurationArray []int
-
-// the list of bandwith values (rep_rates) from the current MPD file
-var bandwithList []int
-
-// list of throughtputs - noted from downloading the segments
-var thrList []int
-
-// time values
-var startTime time.Time
-var nextRunTime time.Time
-var arrivalTime int
-
-// additional output logs values
-var repCodec string
-var repHeight int
-var repWidth int
-var repFps int
-var mimeType string
-
-// used to calculate targetRate - float64
-var kP = 0.01
-var kI = 0.001
-var staticAlgParameter = 0.0
+// ErrHeadersSaved is returned by Stream when GetHeaderBool is set, the run ends once the segment headers are saved
+var ErrHeadersSaved = errors.New("the segment headers have been saved")
+
+// Config : the settings of a stream
+type Config struct {
+	// MPDs of the urls in URL, in the same order
+	MPDs      []http.MPD
+	DebugFile string
+	DebugLog  bool
+	Codec     string
+	CodecName string
+	MaxHeight int
+	// StreamDuration in milliseconds
+	StreamDuration int
+	StreamSpeed    float64
+	// MaxBuffer in seconds and InitBuffer in segments
+	MaxBuffer            int
+	InitBuffer           int
+	Adapt                string
+	URL                  string
+	FileDownloadLocation string
+	ExtendPrintLog       bool
+	HLS                  string
+	HLSBool              bool
+	Quic                 string
+	QuicBool             bool
+	// GetHeaderBool saves the segment headers and ends the stream,
+	// GetHeaderReadFromFile reads them online or from the saved file
+	GetHeaderBool         bool
+	GetHeaderReadFromFile string
+	ExponentialRatio      float64
+	PrintHeadersData      map[string]string
+	PrintLog              bool
+	UseTestbed            bool
+	GetQoE                bool
+	SaveFiles             bool
+	// Noden is the consul node of the collaborative clients
+	Noden   P2Pconsul.NodeUrl
+	Metrics logging.MetricSinkConfig
+	// MetricsPollInterval of the buffer level, in milliseconds
+	MetricsPollInterval int
+	// BBA2 reservoirs, and the stall predictor of the cross-layer algorithms
+	// an empty AbortLogic uses the abort logic of Adapt
+	BBA2             algo.BBA2Params
+	PredictionWindow float64
+	AbortLogic       string
+	// lookahead and QoE weights of the MPC algorithms
+	MPC algo.MPCParams
+	// latency target and playback rate controller of LoL+
+	LoLP algo.LoLPParams
+	// Predictor : the name of the throughput predictor of Adapt, its own estimator if empty
+	Predictor string
+	// learned policy of the Pensieve algorithms
+	Pensieve *algo.PensieveModel
+	// first rep_rate and initial buffer from the capacity of the startup downloads
+	FastStart algo.FastStartParams
+	// the throughput algorithms compare against the actual bitrate of the next segments
+	VBR algo.VBRParams
+	// Constraints on the rep_rates of the video, replaced by SetConstraints and by the screens of ScreenSchedule
+	Constraints    ladder.Constraints
+	ScreenSchedule []ladder.Change
+	// the session summary and the segment headers are written beneath Run
+	Run    output.Run
+	Events Events
+}
 
-// first step is to check the first MPD for the codec (I had problem passing a
-// 2-dimensional array, so I moved the check to here)
-var codecList [][]string
-var codecIndexList [][]int
-var usedVideoCodec bool
-var codecIndex int
-var audioContent bool
-var onlyAudio bool
-var audioRate int
-var audioCodec string
+// Player :
+/*
+ * one client, the values of its stream are only shared by the pipelines of the stream,
+ * so several players can stream in the same process
+ */
+type Player struct {
+	cfg Config
 
-var urlInput []string
+	// the ABR qlog of the stream, from the transport of the stream
+	tracer *abrqlog.StreamTracer
 
-// For the mapSegments of segments :
-// Map with the segment number and a structure of informations
-// one map contains all content
-var mapSegmentLogPrintouts []map[int]logging.SegPrintLogInformation
+	// index values for the types of MPD types
+	mimeTypes          []int
+	mimeTypesMediaType []abrqlog.MediaType
 
-// a map of maps containing segment header information
-var segHeadValues map[int]map[int][]int
+	// rep_rate boundaries per mimeType
+	lowestMPDrepRateIndex  []int
+	highestMPDrepRateIndex []int
 
-// default value for the exponential ratio
-var exponentialRatio float64
+	// the codecs of each MPD
+	codecList      [][]string
+	codecIndexList [][]int
 
-// file download location
-var fileDownloadLocation string
+	// a map of maps containing segment header information
+	segHeadValues map[int]map[int][]int
 
-// printHeadersData local
-var printHeadersData map[string]string
-
-// print the log to terminal
-var printLog bool
-
-// variable to determine if we are using the goDASHbed testbed
-var useTestbedBool bool
-
-// variable to determine if we should generate QoE values
-var getQoEBool bool
-
-// variable to determine if we should save our streaming files
-var saveFilesBool bool
+	statsMutex sync.Mutex
+	stats      Stats
+	// the QoE report, set at the end of the stream
+	summary *qoe.SessionSummary
 
-// other QoE variables
-var segRates []float64
-var sumSegRate float64
-var totalStallDur float64
-var nStalls int
-var nSwitches int
-var rateChange []float64
-var sumRateChange float64
-var rateDifference float64
-
-// index values for the types of MPD types
-var mimeTypes []int
-var mimeTypesMediaType []abrqlog.MediaType
+	// the ladder constraints now, and the next screen of the schedule
+	ladderMutex   sync.Mutex
+	constraints   ladder.Constraints
+	scheduleStart time.Time
+	scheduleNext  int
+}
 
-var streamStructs []http.StreamStruct
-var BBA2DataStruct algo.BBA2Data
+// New :
+// * create a player for the stream of cfg, a player streams once
+func New(cfg Config) *Player {
+	return &Player{cfg: cfg, constraints: cfg.Constraints}
+}
 
 // Stream :
 /*
  * get the header file for the current video clip
  * check the different arguments in order to stream
  * call streamLoop to begin to stream
+ * the requests use the http transport of ctx, a stream without one gets a transport of its own
+ * cancelling ctx ends the stream after the current segments, and Stream returns ctx.Err()
  */
-func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, codecName string, maxHeight int, streamDuration int, streamSpeed float64, maxBuffer int, initBuffer int, adapt string, urlString string, fileDownloadLocationIn string, extendPrintLog bool, hls string, hlsBool bool, quic string, quicBool bool, getHeaderBool bool, getHeaderReadFromFile string, exponentialRatioIn float64, printHeadersDataIn map[string]string, printLogIn bool,
-	useTestbedBoolIn bool, getQoEBoolIn bool, saveFilesBoolIn bool, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant) {
+func (pl *Player) Stream(ctx context.Context) error {
+
+	cfg := pl.cfg
+	mpdList := cfg.MPDs
+	debugFile, debugLog := cfg.DebugFile, cfg.DebugLog
+	codec, codecName := cfg.Codec, cfg.CodecName
+	maxHeight, streamDuration, streamSpeed := cfg.MaxHeight, cfg.StreamDuration, cfg.StreamSpeed
+	maxBuffer, initBuffer := cfg.MaxBuffer, cfg.InitBuffer
+	adapt, urlString := cfg.Adapt, cfg.URL
+	fileDownloadLocation, extendPrintLog := cfg.FileDownloadLocation, cfg.ExtendPrintLog
+	hls, hlsBool, quic, quicBool := cfg.HLS, cfg.HLSBool, cfg.Quic, cfg.QuicBool
+	getHeaderBool, getHeaderReadFromFile := cfg.GetHeaderBool, cfg.GetHeaderReadFromFile
+	printHeadersData, printLog := cfg.PrintHeadersData, cfg.PrintLog
+	useTestbedBool, getQoEBool, saveFilesBool := cfg.UseTestbed, cfg.GetQoE, cfg.SaveFiles
+	Noden, metricsConfig, run := cfg.Noden, cfg.Metrics, cfg.Run
+
+	// every request of the stream goes through the same transport, and logs to the debug log of the stream
+	transport := http.TransportFromContext(ctx)
+	if debugFile != "" {
+		transport.SetDebugFile(debugFile)
+	}
+	debugFile = transport.DebugFile()
+	ctx = http.WithTransport(ctx, transport)
+	accountant := transport.Accountant()
+	pl.tracer = transport.Tracer()
+
+	// current segment number and the first mpd file
+	segmentNumber := 1
+	mpdListIndex := 0
+	bufferLevel := 0
+	segmentDurationTotal := 0
+	arrivalTime := 0
+
+	// determine if an MPD is byte-range or not
+	var isByteRangeMPD bool
+	startRange, endRange := 0, 0
+
+	// current representation rate and adaptationSet
+	repRate := 0
+	var currentMPDRepAdaptSet int
+
+	// baseURL for this MPD file
+	var baseURL string
+	var headerURL string
+	var currentURL string
+	var urlInput []string
+
+	// MPD values of the current adaptation set
+	var segmentDuration int
+	var segmentDurationArray []int
+	var maxBufferLevel int
+	var bandwithList []int
+
+	// time values
+	var startTime time.Time
+	var nextRunTime time.Time
+
+	var streamStructs []http.StreamStruct
 
 	// set debug logs for the collab clients
 	if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
 		Noden.SetDebug(debugFile, debugLog)
 	}
 
+	// the times of the screen schedule are from the start of the stream
+	pl.startSchedule()
+
 	// check if the codec is in the MPD urls passed in
-	codecList, codecIndexList, audioContent = http.GetCodec(mpdList, codec, debugLog)
+	codecList, codecIndexList, audioContent := http.GetCodec(mpdList, codec, debugFile, debugLog)
+	pl.codecList, pl.codecIndexList = codecList, codecIndexList
 	// determine if the passed in codec is one of the codecs we use (checking the first MPD only)
 	// fmt.Println(codecList)
 	// fmt.Println(codecIndexList)
 	// fmt.Println(audioContent)
-	usedVideoCodec, codecIndex = utils.FindInStringArray(codecList[0], codec)
+	usedVideoCodec, _ := utils.FindInStringArray(codecList[0], codec)
 
 	// logs
 	var mapSegmentLogPrintouts []map[int]logging.SegPrintLogInformation
 
-	// set local val
-	exponentialRatio = exponentialRatioIn
-	fileDownloadLocation = fileDownloadLocationIn
-	printHeadersData = printHeadersDataIn
-	printLog = printLogIn
-	useTestbedBool = useTestbedBoolIn
-	getQoEBool = getQoEBoolIn
-	saveFilesBool = saveFilesBoolIn
-
 	// check the codec and print error is false
 	// if !usedVideoCodec {
 	// 	// print error message
@@ -219,21 +256,23 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 	// 	utils.StopApp()
 	// }
 	if codecList[0][0] == glob.RepRateCodecAudio && len(codecList[0]) == 1 {
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "*** This is an audio only file, ignoring Video Codec - "+codec+" ***\n")
-		onlyAudio = true
-		// reset the codeIndex to suit Audio only
-		codecIndex = 0
-		//codecIndexList[0][codecIndex] = 0
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "*** This is an audio only file, ignoring Video Codec - "+codec+" ***\n")
 	} else if !usedVideoCodec {
 		// print error message
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "*** -"+glob.CodecName+" "+codec+" is not in the provided MPD, please check "+urlString+" ***\n")
-		// stop the app
-		utils.StopApp()
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "*** -"+glob.CodecName+" "+codec+" is not in the provided MPD, please check "+urlString+" ***\n")
+		return errors.New("-" + glob.CodecName + " " + codec + " is not in the provided MPD, please check " + urlString)
 	}
 
 	// Start the metrics logger
 	var metricsLogger logging.MetricLogger
 
+	// one pipeline per adaptation set, they share the playback
+	playback := newSession(streamSpeed, pl.tracer)
+	var pipelines []*pipeline
+
+	// the largest initial buffer the fast start selected for an adaptation set
+	fastStartBuffer := 0
+
 	// the input must be a defined value - loops over the adaptationSets
 	// currently one adaptation set per video and audio
 	for currentMPDRepAdaptSetIndex := range codecIndexList[mpdListIndex] {
@@ -244,7 +283,7 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			currentMPDRepAdaptSet = currentMPDRepAdaptSetIndex
 
 			// lets work out how many mimeTypes we have
-			mimeTypes = append(mimeTypes, currentMPDRepAdaptSetIndex)
+			pl.mimeTypes = append(pl.mimeTypes, currentMPDRepAdaptSetIndex)
 
 			//TODO better mimetypeparsing
 			mimeTypeString := http.GetRepresentationMimeType(mpdList[mpdListIndex], currentMPDRepAdaptSetIndex)
@@ -256,7 +295,7 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			} else if strings.Contains(mimeTypeString, "text") {
 				currentMediaType = abrqlog.MediaTypeSubtitles
 			}
-			mimeTypesMediaType = append(mimeTypesMediaType, currentMediaType)
+			pl.mimeTypesMediaType = append(pl.mimeTypesMediaType, currentMediaType)
 
 			// currentMPDRepAdaptSet = 1
 			// determine if we are using a byte-range or standard MPD profile
@@ -277,13 +316,18 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			// segmentDuration - segment duration
 			// bandwithList - get all the range of representation bandwiths of the MPD
 
-			// maxSegments was the first value
+			// the first value is the stream duration in milliseconds
 			l_highestMPDrepRateIndex := 0
 			l_lowestMPDrepRateIndex := 0
-			_, maxBufferLevel, l_highestMPDrepRateIndex, l_lowestMPDrepRateIndex, segmentDurationArray, bandwithList, baseURL = http.GetMPDValues(mpdList, mpdListIndex, maxHeight, streamDuration, maxBuffer, currentMPDRepAdaptSet, isByteRangeMPD, debugLog)
+			mpdStreamDuration := 0
+			var err error
+			mpdStreamDuration, maxBufferLevel, l_highestMPDrepRateIndex, l_lowestMPDrepRateIndex, segmentDurationArray, bandwithList, baseURL, err = http.GetMPDValues(mpdList, mpdListIndex, maxHeight, streamDuration, maxBuffer, currentMPDRepAdaptSet, isByteRangeMPD, debugFile, debugLog)
+			if err != nil {
+				return err
+			}
 
-			highestMPDrepRateIndex = append(highestMPDrepRateIndex, l_highestMPDrepRateIndex)
-			lowestMPDrepRateIndex = append(lowestMPDrepRateIndex, l_lowestMPDrepRateIndex)
+			pl.highestMPDrepRateIndex = append(pl.highestMPDrepRateIndex, l_highestMPDrepRateIndex)
+			pl.lowestMPDrepRateIndex = append(pl.lowestMPDrepRateIndex, l_lowestMPDrepRateIndex)
 
 			// get the profile for this file
 			profiles := strings.Split(mpdList[mpdListIndex].Profiles, ":")
@@ -295,7 +339,7 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 				profile += glob.ByteRangeString
 			}
 
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "DASH profile for the header is: "+profile)
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "DASH profile for the header is: "+profile)
 
 			// reset repRate
 			repRate = l_lowestMPDrepRateIndex
@@ -316,8 +360,8 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			// update audio rate and codec
 			AudioByteRange := false
 			if audioContent && codecList[0][currentMPDRepAdaptSetIndex] == glob.RepRateCodecAudio {
-				audioRate = mpdList[mpdListIndex].Periods[0].AdaptationSet[len(mimeTypes)-1].Representation[repRate].BandWidth / 1000
-				audioCodec = mpdList[mpdListIndex].Periods[0].AdaptationSet[len(mimeTypes)-1].Representation[repRate].Codecs
+				playback.setAudio(mpdList[mpdListIndex].Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[repRate].BandWidth/1000,
+					mpdList[mpdListIndex].Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation[repRate].Codecs)
 				if isByteRangeMPD {
 					AudioByteRange = true
 					logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Audio Byte-Range Header")
@@ -357,129 +401,132 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			}
 			// Collaborative Code - End
 
-			ctx2 := context.Background()
-
 			bba2Based := false
+			bolaBased := false
+			mpcBased := false
+			pensieveBased := false
+
+			// the init segment download is a startup sample of the fast start
+			initCtx := ctx
+			var initDone func()
+			if pl.cfg.FastStart.Enabled {
+				var probe *xlayer.CrossLayerAccountant
+				probe, initDone = accountant.StartupProbe()
+				initCtx = xlayer.WithAccountant(ctx, probe)
+			}
 
 			// determine the inital variables to set, based on the algorithm choice
 			switch adapt {
-			case glob.ConventionalAlg:
-				// there is no byte range in this file, so we set byte-range bool to false
-				// we don't want to add the seg duration to this file, so 'addSegDuration' is false
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-				// set the inital rep_rate to the lowest value index
-				repRate = l_lowestMPDrepRateIndex
-			case glob.ElasticAlg:
-				//fmt.Println("Elastic / in player.go")
-				//fmt.Println("currentURL: ", currentURL)
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-				repRate = l_lowestMPDrepRateIndex
-				///fmt.Println("MPD file repRate index: ", repRate)
-				//fmt.Println("MPD file bandwithList[repRate]", bandwithList[repRate])
-			case glob.ProgressiveAlg:
-				// get the header file
-				// there is no byte range in this file, so we set byte-range bool to false
-				http.GetFileProgressively(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber, segmentDuration, false, debugLog, AudioByteRange, profile)
 			case glob.TestAlg:
 				fmt.Println("testAlg / in player.go")
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, AudioByteRange, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-
-				//fmt.Println("lowestmpd: ", lowestMPDrepRateIndex)
-				repRate = l_lowestMPDrepRateIndex
-
-			case glob.BBAAlg:
-				//fmt.Println("BBAAlg / in player.go")
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-
-				repRate = l_lowestMPDrepRateIndex
-
-			case glob.ArbiterAlg:
-				//fmt.Println("ArbiterAlg / in player.go")
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-
-				repRate = l_lowestMPDrepRateIndex
-
-			case glob.LogisticAlg:
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-				repRate = l_lowestMPDrepRateIndex
-			case glob.MeanAverageAlg:
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-			case glob.GeomAverageAlg:
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-			case glob.EMWAAverageAlg:
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-			case glob.MeanAverageXLAlg:
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-			case glob.MeanAverageRecentXLAlg:
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-			case glob.BBA1Alg_AV:
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-
-				repRate = l_lowestMPDrepRateIndex
-			case glob.BBA1Alg_AVXL:
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-
-				repRate = l_lowestMPDrepRateIndex
-			case glob.BBA2Alg_AV:
-				bba2Based = true
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
-
-				repRate = l_lowestMPDrepRateIndex
-			case glob.BBA2Alg_AVXL_base:
+			case glob.BBA2Alg_AV, glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_rate, glob.BBA2Alg_AVXL_double:
 				bba2Based = true
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+			case glob.BOLABasicAlg, glob.BOLAEAlg, glob.BOLAEXLAlg:
+				bolaBased = true
+			case glob.MPCAlg, glob.MPCXLAlg:
+				mpcBased = true
+			case glob.PensieveAlg, glob.PensieveXLAlg:
+				pensieveBased = true
+			}
 
-				repRate = l_lowestMPDrepRateIndex
-			case glob.BBA2Alg_AVXL_rate:
-				bba2Based = true
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+			// get the header file
+			// there is no byte range in this file, so we set byte-range bool to false, the test algorithm uses the audio byte-range
+			// we don't want to add the seg duration to this file, so 'addSegDuration' is false
+			if adapt == glob.ProgressiveAlg {
+				_, _, err = http.GetFileProgressively(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber, segmentDuration, false, debugLog, AudioByteRange, profile, ctx)
+			} else {
+				_, _, _, _, _, _, err = http.GetFile(currentURL, baseJoined, fileDownloadLocation, adapt == glob.TestAlg && AudioByteRange, startRange, endRange, segmentNumber,
+					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, initCtx)
+			}
+			if err != nil {
+				if initDone != nil {
+					initDone()
+				}
+				return err
+			}
 
-				repRate = l_lowestMPDrepRateIndex
-			case glob.BBA2Alg_AVXL_double:
-				bba2Based = true
-				http.GetFile(currentURL, baseJoined, fileDownloadLocation, false, startRange, endRange, segmentNumber,
-					segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, currentMediaType, ctx2)
+			// the capacity of the MPD and init segment downloads selects the first rep_rate and the initial buffer
+			if initDone != nil {
+				initDone()
+				capacity := algo.StartupCapacity(accountant.StartupSamples(), accountant.HandshakeRTT(), accountant.ZeroRTT())
+				var startBuffer int
+				repRate, startBuffer = algo.FastStart(capacity, bandwithList, l_highestMPDrepRateIndex, l_lowestMPDrepRateIndex, segmentDuration*glob.Conversion1000, initBuffer, pl.cfg.FastStart)
+				fastStartBuffer = utils.Max(fastStartBuffer, startBuffer)
+				logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "fast start capacity: "+strconv.Itoa(capacity)+" bps, first rep_rate: "+strconv.Itoa(repRate)+", initial buffer: "+strconv.Itoa(startBuffer))
+			}
 
-				repRate = l_lowestMPDrepRateIndex
+			// BBA2, BOLA, MPC, Pensieve, the stall predictor and the VBR layer need the size of every segment
+			vbr := pl.cfg.VBR.Enabled
+			if bba2Based || bolaBased || mpcBased || pensieveBased || vbr || adapt == glob.BBA1Alg_AVXL {
+				http.BuildSegmentSizeIndex(&mpdList[mpdListIndex], OriginalURL, currentMPDRepAdaptSet, isByteRangeMPD, quicBool, debugLog, useTestbedBool, ctx)
 			}
 
+			var bba2Data algo.BBA2Data
 			if bba2Based {
 				var chunksLowest string = ""
 				var chunksLowestBandwidth int
 				var maxAvgRatioList []float32
-				for i := 0; i < len(mpdList[mpdListIndex].Periods); i++ {
-					for j := 0; j < len(mpdList[mpdListIndex].Periods[i].AdaptationSet); j++ {
-						for k := 0; k < len(mpdList[mpdListIndex].Periods[i].AdaptationSet[j].Representation); k++ {
-							if mpdList[mpdListIndex].Periods[i].AdaptationSet[j].Representation[k].Chunks != "" {
-								if chunksLowest == "" {
-									chunksLowest = mpdList[mpdListIndex].Periods[i].AdaptationSet[j].Representation[k].Chunks
-									chunksLowestBandwidth = mpdList[mpdListIndex].Periods[i].AdaptationSet[j].Representation[k].BandWidth
-								} else if chunksLowestBandwidth > mpdList[mpdListIndex].Periods[i].AdaptationSet[j].Representation[k].BandWidth {
-									chunksLowest = mpdList[mpdListIndex].Periods[i].AdaptationSet[j].Representation[k].Chunks
-									chunksLowestBandwidth = mpdList[mpdListIndex].Periods[i].AdaptationSet[j].Representation[k].BandWidth
-								}
-							}
-							maxAvgRatioList = append(maxAvgRatioList, mpdList[mpdListIndex].Periods[i].AdaptationSet[j].Representation[k].MaxAvgRatio)
+				// the ratio list is indexed on the rep_rate, so only use the current adaptation set
+				representations := mpdList[mpdListIndex].Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation
+				for k := 0; k < len(representations); k++ {
+					if representations[k].Chunks != "" {
+						if chunksLowest == "" || chunksLowestBandwidth > representations[k].BandWidth {
+							chunksLowest = representations[k].Chunks
+							chunksLowestBandwidth = representations[k].BandWidth
 						}
 					}
+					maxAvgRatioList = append(maxAvgRatioList, representations[k].MaxAvgRatio)
+				}
+				chunkList, err := utils.GetChunkList(chunksLowest)
+				if err != nil || len(chunkList) == 0 {
+					return errors.New("unable to get the segment sizes of the lowest rep_rate, which are needed for " + adapt)
+				}
+				bba2Data = algo.NewBBA2Data(chunkList, maxAvgRatioList, &metricsLogger, pl.cfg.BBA2)
+				if repRate != l_lowestMPDrepRateIndex {
+					algo.SetBBA2FastStart(&bba2Data)
+				}
+			}
+
+			// BOLA, MPC, Pensieve and the VBR layer plan with the size of the next segments of every rep_rate
+			var chunkLists [][]int
+			if bolaBased || mpcBased || pensieveBased || vbr {
+				for _, representation := range mpdList[mpdListIndex].Periods[0].AdaptationSet[currentMPDRepAdaptSet].Representation {
+					chunkList, _ := utils.GetChunkList(representation.Chunks)
+					chunkLists = append(chunkLists, chunkList)
+				}
+			}
+			var segmentSizes *algo.SegmentSizes
+			if vbr {
+				segmentSizes = algo.NewSegmentSizes(chunkLists, segmentDuration*glob.Conversion1000)
+				if bba2Based {
+					algo.SetBBA2SegmentSizes(&bba2Data, segmentSizes)
+				}
+			}
+			var bolaData algo.BOLAData
+			if bolaBased {
+				bolaData = algo.NewBOLAData(bandwithList, chunkLists, segmentDuration*glob.Conversion1000, maxBufferLevel, adapt != glob.BOLABasicAlg, pl.cfg.PredictionWindow)
+			}
+			var mpcData algo.MPCData
+			if mpcBased {
+				mpcData = algo.NewMPCData(bandwithList, chunkLists, segmentDuration*glob.Conversion1000, maxBufferLevel, pl.cfg.MPC)
+			}
+			var pensieveData algo.PensieveData
+			if pensieveBased {
+				if pl.cfg.Pensieve == nil {
+					return errors.New(adapt + " needs the learned policy of -" + glob.PensieveModelName)
 				}
-				BBA2DataStruct = algo.NewBBA2Data(chunksLowest, maxAvgRatioList, &metricsLogger)
+				pensieveData, err = algo.NewPensieveData(bandwithList, chunkLists, mpdStreamDuration/(segmentDuration*glob.Conversion1000), pl.cfg.Pensieve)
+				if err != nil {
+					return errors.New("the learned policy of " + adapt + " cannot stream this adaptation set: " + err.Error())
+				}
+			}
+			var l2aData algo.L2AData
+			if adapt == glob.L2AAlg {
+				l2aData = algo.NewL2AData(bandwithList, segmentDuration*glob.Conversion1000)
+			}
+			var lolpData algo.LoLPData
+			if adapt == glob.LoLPAlg {
+				lolpData = algo.NewLoLPData(bandwithList, segmentDuration*glob.Conversion1000, streamSpeed, pl.cfg.LoLP)
 			}
 
 			// debug logs
@@ -495,28 +542,36 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			nextRunTime = time.Now()
 			//fmt.Println("STARTTIME_GODASH ", startTime.UnixMilli())
 
-			_, client, _ := http.GetHTTPClient(quicBool, glob.DebugFile, debugLog, useTestbedBool)
+			_, client, _, err := http.GetHTTPClient(quicBool, debugFile, debugLog, useTestbedBool, ctx)
+			if err != nil {
+				return err
+			}
 
 			// get the segment headers and stop this run
 			if getHeaderBool {
 				// get the segment headers for all MPD url passed as arguments - print to file
-				http.GetAllSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true, client)
+				if _, err := http.GetAllSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true, client, ctx); err != nil {
+					return err
+				}
 
 				// print error message
-				fmt.Printf("*** - All segment header have been downloaded to " + glob.DebugFolder + " - ***\n")
-				// exit the application
-				os.Exit(3)
+				fmt.Printf("*** - All segment header have been downloaded to " + run.Path(output.SegmentHeaders, "") + " - ***\n")
+				// this run only gets the headers
+				return ErrHeadersSaved
 			} else {
 				if getHeaderReadFromFile == glob.GetHeaderOnline {
 					// get the segment headers for all MPD url passed as arguments - not from file
-					segHeadValues = http.GetAllSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, false, client)
+					pl.segHeadValues, err = http.GetAllSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, false, client, ctx)
 				} else if getHeaderReadFromFile == glob.GetHeaderOffline {
 					// get the segment headers for all MPD url passed as arguments - yes from file
 					// get headers from file for a given number of seconds of stream time
 					// let's assume every n seconds
-					segHeadValues = http.GetNSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true, client)
+					pl.segHeadValues, err = http.GetNSegmentHeaders(mpdList, codecIndexList, maxHeight, 1, streamDuration, isByteRangeMPD, maxBuffer, headerURL, codec, urlInput, debugLog, true, client, ctx)
 
 				}
+				if err != nil {
+					return err
+				}
 			}
 
 			// I need to have two of more sets of lists for the following content
@@ -558,6 +613,35 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 			}
 			streamStructs = append(streamStructs, streaminfo)
 			mapSegmentLogPrintouts = append(mapSegmentLogPrintouts, mapSegmentLogPrintout)
+
+			// the ABR state of this adaptation set
+			pipelines = append(pipelines, &pipeline{
+				player:  pl,
+				index:   len(pl.mimeTypes) - 1,
+				session: playback,
+				abr: algo.State{
+					BBA2:         bba2Data,
+					BOLA:         bolaData,
+					MPC:          mpcData,
+					Pensieve:     pensieveData,
+					L2A:          l2aData,
+					LoLP:         lolpData,
+					SegmentSizes: segmentSizes,
+					Predictor:    predictor.New(pl.cfg.Predictor),
+				},
+				mpdListIndex:         mpdListIndex,
+				segmentDuration:      segmentDuration,
+				segmentDurationArray: segmentDurationArray,
+				maxBufferLevel:       maxBufferLevel,
+			})
+		}
+	}
+
+	// every adaptation set waits for the initial buffer of the fast start
+	if fastStartBuffer > 0 {
+		initBuffer = fastStartBuffer
+		for i := range streamStructs {
+			streamStructs[i].InitBuffer = initBuffer
 		}
 	}
 
@@ -567,18 +651,38 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 	// print the output log headers
 	logging.PrintHeaders(extendPrintLog, fileDownloadLocation, glob.LogDownload, debugFile, debugLog, printLog, printHeadersData)
 
-	metricsLogger.StartLogger(100, bandwithList, maxBuffer)
+	if err := metricsLogger.StartLogger(pl.cfg.MetricsPollInterval, bandwithList, maxBuffer, metricsConfig); err != nil {
+		return err
+	}
 
-	if adapt == glob.BBA1Alg_AVXL || adapt == glob.BBA2Alg_AVXL_base {
-		accountant.InitialisePredictor(&metricsLogger, crosslayer.Base)
-	} else if adapt == glob.BBA2Alg_AVXL_rate {
-		accountant.InitialisePredictor(&metricsLogger, crosslayer.Rate)
-	} else if adapt == glob.BBA2Alg_AVXL_double {
-		accountant.InitialisePredictor(&metricsLogger, crosslayer.Double)
+	// the cross-layer algorithms predict stalls, with the abort logic of the algorithm unless one is set
+	var abortLogic crosslayer.AbortLogic
+	predictStall := true
+	switch adapt {
+	case glob.BBA1Alg_AVXL, glob.BBA2Alg_AVXL_base:
+		abortLogic = crosslayer.Base
+	case glob.BBA2Alg_AVXL_rate:
+		abortLogic = crosslayer.Rate
+	case glob.BBA2Alg_AVXL_double:
+		abortLogic = crosslayer.Double
+	default:
+		predictStall = false
+	}
+	if logic, ok := crosslayer.ParseAbortLogic(pl.cfg.AbortLogic); ok {
+		abortLogic = logic
+	}
+	if predictStall {
+		accountant.InitialisePredictor(&metricsLogger, abortLogic, float32(pl.cfg.PredictionWindow))
 	}
 
 	// Streaming loop function - using the first MPD index - 0, and hlsUsed false
-	segmentNumber, mapSegmentLogPrintouts = streamLoop(streamStructs, Noden, accountant, &metricsLogger)
+	segmentNumber, mapSegmentLogPrintouts, err := streamPipelines(pipelines, streamStructs, Noden, accountant, &metricsLogger, ctx)
+
+	// write the remaining metrics and close the metric sinks
+	metricsLogger.Stop()
+	if err != nil {
+		return err
+	}
 
 	// print sections of the map to the debug log - if debug is true
 	if debugLog {
@@ -589,916 +693,1072 @@ func Stream(mpdList []http.MPD, debugFile string, debugLog bool, codec string, c
 	// and an end time that includes for the original initial buffer size in seconds
 	logging.PrintPlayOutLog(mapSegmentLogPrintouts[0][segmentNumber-1].PlayStartPosition+mapSegmentLogPrintouts[0][initBuffer].PlayStartPosition, initBuffer, mapSegmentLogPrintouts, glob.LogDownload, printLog, printHeadersData)
 
+	// save the QoE report of the stream next to the metrics log
+	// the QoE models score the first video adaptation set
+	maxRepRate := 0
+	for i, logs := range mapSegmentLogPrintouts {
+		if len(logs) > 0 && logs[1].MimeType != glob.RepRateCodecAudio {
+			maxRepRate = streamStructs[i].BandwithList[pl.highestMPDrepRateIndex[i]]
+			break
+		}
+	}
+	audioRate, audioCodec := playback.audio()
+	summary := qoe.NewSessionSummary(mapSegmentLogPrintouts, qoe.Session{
+		InitBuffer: initBuffer,
+		MaxRepRate: maxRepRate,
+		AudioRate:  audioRate,
+		AudioCodec: audioCodec,
+		DebugFile:  debugFile,
+		DebugLog:   debugLog,
+	}, getQoEBool)
+	qoe.WriteSessionSummary(summary, run.Path(output.Summary, glob.SessionSummaryFile))
+	pl.statsMutex.Lock()
+	pl.summary = &summary
+	pl.statsMutex.Unlock()
+
+	// let the last QUIC events reach the qlogs
 	time.Sleep(1 * time.Second)
-	abrqlog.MainTracer.Close()
-}
 
-var currently_playing = false
+	return ctx.Err()
+}
 
 // streamLoop :
 /*
  * take the first segment number, download it with a low quality
  * call itself with the next segment number
+ * every pipeline runs its own streamLoop for its adaptation set
  */
-func streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant, metricsLogger *logging.MetricLogger) (int, []map[int]logging.SegPrintLogInformation) {
+func (p *pipeline) streamLoop(streamStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant, metricsLogger *logging.MetricLogger, ctx context.Context) (int, []map[int]logging.SegPrintLogInformation) {
 
 	// variable for rtt for this segment
 	var rtt time.Duration
 	// has this chunk been replaced by hls
-	var hlsReplaced = "no"
+	var hlsReplaced = glob.SegReplaceNo
 	// if we undertake HLS, we need to revise the buffer values
 	var bufferDifference int
 	// if we set this chunk to HLS used
 	if streamStructs[0].HlsUsed {
-		hlsReplaced = "yes"
+		hlsReplaced = glob.SegReplaceYes
 	}
 	var segURL string
+	var startRange, endRange int
 
 	// save point for the HTTP protocol used
 	var protocol string
 
 	//
 	var segmentFileName string
+	var segSize int
 
 	//
 	var P1203Header float64
 
-	// logging info
-	// var mapSegmentLogPrintouts []map[int]logging.SegPrintLogInformation
-
-	// lets loop over our mimeTypes
-	for mimeTypeIndex := range mimeTypes {
-
-		// get the values from the stream struct
-		segmentNumber := streamStructs[mimeTypeIndex].SegmentNumber
-		currentURL := streamStructs[mimeTypeIndex].CurrentURL
-		initBuffer := streamStructs[mimeTypeIndex].InitBuffer
-		maxBuffer := streamStructs[mimeTypeIndex].MaxBuffer
-		codecName := streamStructs[mimeTypeIndex].CodecName
-		codec := streamStructs[mimeTypeIndex].Codec
-		urlString := streamStructs[mimeTypeIndex].UrlString
-		urlInput := streamStructs[mimeTypeIndex].UrlInput
-		mpdList := streamStructs[mimeTypeIndex].MpdList
-		adapt := streamStructs[mimeTypeIndex].Adapt
-		maxHeight := streamStructs[mimeTypeIndex].MaxHeight
-		isByteRangeMPD := streamStructs[mimeTypeIndex].IsByteRangeMPD
-		startTime := streamStructs[mimeTypeIndex].StartTime
-		nextRunTime := streamStructs[mimeTypeIndex].NextRunTime
-		arrivalTime := streamStructs[mimeTypeIndex].ArrivalTime
-		oldMPDIndex := streamStructs[mimeTypeIndex].OldMPDIndex
-		nextSegmentNumber := streamStructs[mimeTypeIndex].NextSegmentNumber
-		hls := streamStructs[mimeTypeIndex].Hls
-		hlsBool := streamStructs[mimeTypeIndex].HlsBool
-		mapSegmentLogPrintout := streamStructs[mimeTypeIndex].MapSegmentLogPrintout
-		streamDuration := streamStructs[mimeTypeIndex].StreamDuration
-		streamSpeed := streamStructs[mimeTypeIndex].StreamSpeed
-		extendPrintLog := streamStructs[mimeTypeIndex].ExtendPrintLog
-		hlsUsed := streamStructs[mimeTypeIndex].HlsUsed
-		bufferLevel := streamStructs[mimeTypeIndex].BufferLevel
-		segmentDurationTotal := streamStructs[mimeTypeIndex].SegmentDurationTotal
-		quic := streamStructs[mimeTypeIndex].Quic
-		quicBool := streamStructs[mimeTypeIndex].QuicBool
-		baseURL := streamStructs[mimeTypeIndex].BaseURL
-		debugLog := streamStructs[mimeTypeIndex].DebugLog
-		audioContent := streamStructs[mimeTypeIndex].AudioContent
-		repRate := streamStructs[mimeTypeIndex].RepRate
-		bandwithList := streamStructs[mimeTypeIndex].BandwithList
-		profile := streamStructs[mimeTypeIndex].Profile
-
-		// determine the MimeType and mimeTypeIndex - set video by default
-		// get the mimeType of this adaptationSet
-		mimeType = mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypeIndex].Representation[repRate].MimeType
-
-		// update audio rate and codec
-		AudioByteRange := false
-		if audioContent && mimeType == glob.RepRateCodecAudio {
-			audioRate = mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].BandWidth / 1000
-			audioCodec = mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Codecs
-			if isByteRangeMPD {
-				AudioByteRange = true
-				logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Audio Byte-Range Segment")
-			}
+	// stall and QoE values of this segment
+	var stallTime int
+	var segRates []float64
+	var sumSegRate float64
+	var totalStallDur float64
+	var nStalls int
+	var nSwitches int
+	var rateChange []float64
+	var sumRateChange float64
+	var rateDifference float64
+
+	// additional output logs values
+	var repCodec string
+	var repHeight int
+	var repWidth int
+	var repFps int
+
+	// the adaptation set of this pipeline
+	mimeTypeIndex := p.index
+
+	// the values of the client, shared by its pipelines
+	pl := p.player
+	mimeTypes, mimeTypesMediaType := pl.mimeTypes, pl.mimeTypesMediaType
+	highestMPDrepRateIndex, lowestMPDrepRateIndex := pl.highestMPDrepRateIndex, pl.lowestMPDrepRateIndex
+	codecList, codecIndexList := pl.codecList, pl.codecIndexList
+	fileDownloadLocation, exponentialRatio := pl.cfg.FileDownloadLocation, pl.cfg.ExponentialRatio
+	printHeadersData, printLog := pl.cfg.PrintHeadersData, pl.cfg.PrintLog
+	debugFile := http.TransportFromContext(ctx).DebugFile()
+	useTestbedBool, getQoEBool, saveFilesBool := pl.cfg.UseTestbed, pl.cfg.GetQoE, pl.cfg.SaveFiles
+
+	// get the values from the stream struct
+	segmentNumber := streamStructs[0].SegmentNumber
+	currentURL := streamStructs[0].CurrentURL
+	initBuffer := streamStructs[0].InitBuffer
+	maxBuffer := streamStructs[0].MaxBuffer
+	codecName := streamStructs[0].CodecName
+	codec := streamStructs[0].Codec
+	urlString := streamStructs[0].UrlString
+	urlInput := streamStructs[0].UrlInput
+	mpdList := streamStructs[0].MpdList
+	adapt := streamStructs[0].Adapt
+	maxHeight := streamStructs[0].MaxHeight
+	isByteRangeMPD := streamStructs[0].IsByteRangeMPD
+	startTime := streamStructs[0].StartTime
+	nextRunTime := streamStructs[0].NextRunTime
+	arrivalTime := streamStructs[0].ArrivalTime
+	oldMPDIndex := streamStructs[0].OldMPDIndex
+	nextSegmentNumber := streamStructs[0].NextSegmentNumber
+	hls := streamStructs[0].Hls
+	hlsBool := streamStructs[0].HlsBool
+	mapSegmentLogPrintout := streamStructs[0].MapSegmentLogPrintout
+	streamDuration := streamStructs[0].StreamDuration
+	// LoL+ changes the speed of the playback during the session
+	streamSpeed := p.session.speed()
+	extendPrintLog := streamStructs[0].ExtendPrintLog
+	hlsUsed := streamStructs[0].HlsUsed
+	bufferLevel := streamStructs[0].BufferLevel
+	segmentDurationTotal := streamStructs[0].SegmentDurationTotal
+	quic := streamStructs[0].Quic
+	quicBool := streamStructs[0].QuicBool
+	baseURL := streamStructs[0].BaseURL
+	debugLog := streamStructs[0].DebugLog
+	audioContent := streamStructs[0].AudioContent
+	repRate := streamStructs[0].RepRate
+	bandwithList := streamStructs[0].BandwithList
+	profile := streamStructs[0].Profile
+
+	// determine the MimeType and mimeTypeIndex - set video by default
+	// get the mimeType of this adaptationSet
+	mimeType := mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].MimeType
+
+	// update audio rate and codec
+	AudioByteRange := false
+	if audioContent && mimeType == glob.RepRateCodecAudio {
+		p.session.setAudio(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].BandWidth/1000,
+			mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Codecs)
+		if isByteRangeMPD {
+			AudioByteRange = true
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Audio Byte-Range Segment")
 		}
+	}
 
-		logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current MimeType header: "+mimeType)
-		/*
-		 * Function  :
-		 * let's think about HLS - chunk replacement
-		 * before we decide what chunks to change, lets create a file for HLS
-		 * then add functions to switch out an old chunk
-		 */
-		// only use HLS if we have at least one segment to replacement
-		if hlsBool && segmentNumber > 1 &&
-			mimeType == glob.RepRateCodecVideo {
-			switch hls {
-			// passive - least amount of replacement
-			case glob.HlsOn:
-				if segmentNumber == 6 {
-					// hlsUsed is set to true
-					chunkReplace := 5
-					var thisRunTimeVal int
-					// replace a previously downloaded segment with this call
-					nextSegmentNumber, mapSegmentLogPrintouts, bufferDifference, thisRunTimeVal, nextRunTime =
-						hlsfunc.GetHlsSegment(
-							streamLoop,
-							chunkReplace,
-							mapSegmentLogPrintouts,
-							maxHeight,
-							urlInput,
-							initBuffer,
-							maxBuffer,
-							codecName,
-							codec,
-							urlString,
-							mpdList,
-							nextSegmentNumber,
-							extendPrintLog,
-							startTime,
-							nextRunTime,
-							arrivalTime,
-							true,
-							quic,
-							quicBool,
-							baseURL,
-							glob.DebugFile,
-							debugLog,
-							glob.RepRateBaseURL,
-							audioContent,
-							repRate,
-							mimeTypeIndex,
-							Noden,
-							accountant,
-							metricsLogger,
-						)
-
-					// change the current buffer to reflect the time taken to get this HLS segment
-					bufferLevel -= (int(float64(thisRunTimeVal)*streamSpeed) + bufferDifference)
-
-					// change the buffer levels of the previous chunks, so the printout reflects this value
-					mapSegmentLogPrintouts[mimeTypeIndex] = hlsfunc.ChangeBufferLevels(mapSegmentLogPrintouts[mimeTypeIndex], segmentNumber, chunkReplace, bufferDifference)
-				}
+	logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current MimeType header: "+mimeType)
+	/*
+	 * Function  :
+	 * let's think about HLS - chunk replacement
+	 * before we decide what chunks to change, lets create a file for HLS
+	 * then add functions to switch out an old chunk
+	 */
+	// only use HLS if we have at least one segment to replacement,
+	// and only once the playback started, a replacement is not part of the initial buffer
+	if hlsBool && segmentNumber > 1 && mimeType == glob.RepRateCodecVideo && p.session.isPlaying() {
+
+		// find the buffered segments we can upgrade before they are played, based on the last throughput
+		policy := hlsfunc.GetReplacementPolicy(hls, bufferLevel, maxBuffer)
+		candidates := hlsfunc.SelectReplacementSegments(hls, mapSegmentLogPrintout, segmentNumber, bufferLevel, maxBuffer, p.segmentDuration,
+			mapSegmentLogPrintout[segmentNumber-1].DelRate, bandwithList)
+
+		for _, candidate := range candidates {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "HLS "+policy+" replaces segment "+strconv.Itoa(candidate.SegmentNumber)+" with rep_rate "+strconv.Itoa(candidate.RepRate))
+
+			from := abrqlog.NewRepresentation()
+			from.ID = strconv.Itoa(candidate.OldRepRate)
+			from.Bitrate = int64(mapSegmentLogPrintout[candidate.SegmentNumber].Bandwidth / glob.Conversion1000)
+			to := abrqlog.NewRepresentation()
+			to.ID = strconv.Itoa(candidate.RepRate)
+			to.Bitrate = int64(bandwithList[candidate.RepRate] / glob.Conversion1000)
+			pl.tracer.SegmentReplacement(mimeTypesMediaType[mimeTypeIndex], candidate.SegmentNumber, policy, from, to,
+				time.Duration(candidate.Deadline)*time.Millisecond)
+
+			metricsLogger.Log(logging.MetricSegmentReplacement, float64(candidate.SegmentNumber), float64(bandwithList[candidate.RepRate]))
+
+			// the replacement call streams one segment of this pipeline,
+			// add the values hlsfunc does not know about
+			replaceSegment := func(hlsStructs []http.StreamStruct, Noden P2Pconsul.NodeUrl, accountant *xlayer.CrossLayerAccountant, metricsLogger *logging.MetricLogger, ctx context.Context) (int, []map[int]logging.SegPrintLogInformation) {
+				hlsStructs[0].StreamSpeed = streamSpeed
+				hlsStructs[0].BandwithList = bandwithList
+				hlsStructs[0].Profile = profile
+				return p.streamLoop(hlsStructs, Noden, accountant, metricsLogger, ctx)
 			}
+
+			var thisRunTimeVal int
+			// replace a previously downloaded segment with this call
+			nextSegmentNumber, _, bufferDifference, thisRunTimeVal, nextRunTime =
+				hlsfunc.GetHlsSegment(
+					replaceSegment,
+					candidate.SegmentNumber,
+					[]map[int]logging.SegPrintLogInformation{mapSegmentLogPrintout},
+					maxHeight,
+					urlInput,
+					initBuffer,
+					maxBuffer,
+					codecName,
+					codec,
+					urlString,
+					mpdList,
+					nextSegmentNumber,
+					extendPrintLog,
+					startTime,
+					nextRunTime,
+					arrivalTime,
+					true,
+					quic,
+					quicBool,
+					baseURL,
+					debugFile,
+					debugLog,
+					glob.RepRateBaseURL,
+					audioContent,
+					candidate.RepRate,
+					0,
+					Noden,
+					accountant,
+					metricsLogger,
+					ctx,
+				)
+			if p.err != nil {
+				return p.fail(p.err, hlsUsed, segmentNumber, mapSegmentLogPrintout)
+			}
+
+			// change the current buffer to reflect the time taken to get this HLS segment
+			bufferLevel -= (int(float64(thisRunTimeVal)*streamSpeed) + bufferDifference)
+
+			// change the buffer levels of the previous chunks, so the printout reflects this value
+			mapSegmentLogPrintout = hlsfunc.ChangeBufferLevels(mapSegmentLogPrintout, segmentNumber, candidate.SegmentNumber, bufferDifference)
 		}
+	}
 
-		// if we have changed the MPD, we need to update some variables
-		if oldMPDIndex != mpdListIndex {
+	// if we have changed the MPD, we need to update some variables
+	if oldMPDIndex != p.mpdListIndex {
 
-			// set the new mpdListIndex
-			mpdListIndex = oldMPDIndex
+		// set the new mpdListIndex
+		p.mpdListIndex = oldMPDIndex
 
-			// get the current url - trim any white space
-			currentURL = strings.TrimSpace(urlInput[mpdListIndex])
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL header: "+currentURL)
+		// get the current url - trim any white space
+		currentURL = strings.TrimSpace(urlInput[p.mpdListIndex])
+		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current URL header: "+currentURL)
 
-			// get the relavent values from this MPD
-			l_highestMPDrepRateIndex := 0
-			l_lowestMPDrepRateIndex := 0
-			streamDuration, maxBufferLevel, l_highestMPDrepRateIndex, l_lowestMPDrepRateIndex, segmentDurationArray, bandwithList, baseURL = http.GetMPDValues(mpdList, mpdListIndex, maxHeight, streamDuration, maxBuffer, mimeTypes[mimeTypeIndex], isByteRangeMPD, debugLog)
-			highestMPDrepRateIndex[mimeTypeIndex] = l_highestMPDrepRateIndex
-			lowestMPDrepRateIndex[mimeTypeIndex] = l_lowestMPDrepRateIndex
-
-			// current segment duration
-			segmentDuration = segmentDurationArray[mpdListIndex]
-
-			// ONLY CHANGE THE NUMBER OF SEGMENTS HERE
-			//	numSegments := streamDuration / segmentDuration
-
-			//	fmt.Println(segmentNumber)
-			//	fmt.Println(segmentDuration)
-			//	fmt.Println(numSegments)
-
-			// determine if the passed in codec is one of the codecs we use (checking the current MPD)
-			usedVideoCodec, codecIndex = utils.FindInStringArray(codecList[mpdListIndex], codec)
-			// check the codec and print error is false
-			// if !usedVideoCodec {
-			// 	// print error message
-			// 	fmt.Printf("*** -" + codecName + " " + codec + " is not in the provided MPD, please check " + urlString + " ***\n")
-			// 	// stop the app
-			// 	utils.StopApp()
-			// }
-			if codecList[0][0] == glob.RepRateCodecAudio && len(codecList[0]) == 1 {
-				logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "*** This is an audio only file, ignoring Video Codec - "+codec+" ***\n")
-				onlyAudio = true
-				// reset the codeIndex to suit Audio only
-				codecIndex = 0
-				//codecIndexList[0][codecIndex] = 0
-			} else if !usedVideoCodec {
-				// print error message
-				logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "*** -"+glob.CodecName+" "+codec+" is not in the provided MPD, please check "+urlString+" ***\n")
-				// stop the app
-				utils.StopApp()
-			}
+		// get the relavent values from this MPD
+		l_highestMPDrepRateIndex := 0
+		l_lowestMPDrepRateIndex := 0
+		var err error
+		streamDuration, p.maxBufferLevel, l_highestMPDrepRateIndex, l_lowestMPDrepRateIndex, p.segmentDurationArray, bandwithList, baseURL, err = http.GetMPDValues(mpdList, p.mpdListIndex, maxHeight, streamDuration, maxBuffer, mimeTypes[mimeTypeIndex], isByteRangeMPD, debugFile, debugLog)
+		if err != nil {
+			return p.fail(err, hlsUsed, segmentNumber, mapSegmentLogPrintout)
+		}
+		highestMPDrepRateIndex[mimeTypeIndex] = l_highestMPDrepRateIndex
+		lowestMPDrepRateIndex[mimeTypeIndex] = l_lowestMPDrepRateIndex
+
+		// current segment duration
+		p.segmentDuration = p.segmentDurationArray[p.mpdListIndex]
+
+		// ONLY CHANGE THE NUMBER OF SEGMENTS HERE
+		//	numSegments := streamDuration / segmentDuration
+
+		//	fmt.Println(segmentNumber)
+		//	fmt.Println(segmentDuration)
+		//	fmt.Println(numSegments)
+
+		// determine if the passed in codec is one of the codecs we use (checking the current MPD)
+		usedVideoCodec, codecIndex := utils.FindInStringArray(codecList[p.mpdListIndex], codec)
+		// check the codec and print error is false
+		// if !usedVideoCodec {
+		// 	// print error message
+		// 	fmt.Printf("*** -" + codecName + " " + codec + " is not in the provided MPD, please check " + urlString + " ***\n")
+		// 	// stop the app
+		// 	utils.StopApp()
+		// }
+		if codecList[0][0] == glob.RepRateCodecAudio && len(codecList[0]) == 1 {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "*** This is an audio only file, ignoring Video Codec - "+codec+" ***\n")
+			// reset the codeIndex to suit Audio only
+			codecIndex = 0
+			//codecIndexList[0][codecIndex] = 0
+		} else if !usedVideoCodec {
+			// print error message
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "*** -"+glob.CodecName+" "+codec+" is not in the provided MPD, please check "+urlString+" ***\n")
+			return p.fail(errors.New("-"+glob.CodecName+" "+codec+" is not in the provided MPD, please check "+urlString), hlsUsed, segmentNumber, mapSegmentLogPrintout)
+		}
 
-			// save the current MPD Rep_rate Adaptation Set
-			mimeTypes[mimeTypeIndex] = codecIndexList[mpdListIndex][codecIndex]
+		// save the current MPD Rep_rate Adaptation Set
+		mimeTypes[mimeTypeIndex] = codecIndexList[p.mpdListIndex][codecIndex]
 
-			// get the profile for this file
-			profiles := strings.Split(mpdList[mpdListIndex].Profiles, ":")
-			numProfile := len(profiles) - 2
-			profile = profiles[numProfile]
+		// get the profile for this file
+		profiles := strings.Split(mpdList[p.mpdListIndex].Profiles, ":")
+		numProfile := len(profiles) - 2
+		profile = profiles[numProfile]
 
-			// if byte-range add this to the file name
-			if isByteRangeMPD {
-				profile += glob.ByteRangeString
-			}
+		// if byte-range add this to the file name
+		if isByteRangeMPD {
+			profile += glob.ByteRangeString
 		}
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "DASH profile for this segment is: "+profile)
-
-		// break out if we have downloaded all of our segments
-		// which is current segment duration total plus the next segment to be downloaded
-		if segmentDurationTotal+(segmentDuration*glob.Conversion1000) > streamDuration &&
-			mimeTypeIndex == len(mimeTypes)-1 {
-			// save the current log
-			streamStructs[mimeTypeIndex].MapSegmentLogPrintout = mapSegmentLogPrintout
-			// get the logs for all adaptationSets
-			for mimeTypeIndex := range mimeTypes {
-				mapSegmentLogPrintouts = append(mapSegmentLogPrintouts, streamStructs[mimeTypeIndex].MapSegmentLogPrintout)
-			}
+	}
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "DASH profile for this segment is: "+profile)
+
+	// break out if we have downloaded all of our segments
+	// which is current segment duration total plus the next segment to be downloaded
+	// or if the stream was cancelled
+	if segmentDurationTotal+(p.segmentDuration*glob.Conversion1000) > streamDuration || ctx.Err() != nil {
+		// a replacement call only streams one segment
+		if !hlsUsed {
+			p.finish()
+		}
+		return segmentNumber, []map[int]logging.SegPrintLogInformation{mapSegmentLogPrintout}
+	}
 
-			playhead := abrqlog.NewPlayheadStatus()
-			playhead.PlayheadTime = time.Duration(playPosition) * time.Millisecond
-			abrqlog.MainTracer.EndStream(playhead)
+	// keep rep_rate within the index boundaries
+	// MISL - might cause problems
+	if repRate < highestMPDrepRateIndex[mimeTypeIndex] {
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Changing rep_rate index: from "+strconv.Itoa(repRate)+" to "+strconv.Itoa(highestMPDrepRateIndex[mimeTypeIndex]))
+		repRate = highestMPDrepRateIndex[mimeTypeIndex]
+	}
+
+	// the ladder constraints narrow the rep_rates of the video the algorithms see
+	// the bounds of the MPD are kept by the player for the next segment
+	adaptationSet := mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]]
+	if allowed := pl.allowedRepRates(adaptationSet, mimeTypesMediaType[mimeTypeIndex], highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex]); allowed != nil {
+		highestMPDrepRateIndex = append([]int(nil), highestMPDrepRateIndex...)
+		lowestMPDrepRateIndex = append([]int(nil), lowestMPDrepRateIndex...)
+		highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex] = ladder.Bounds(allowed)
+		if constrained := ladder.Select(allowed, repRate); constrained != repRate {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Ladder constraints change rep_rate index: from "+strconv.Itoa(repRate)+" to "+strconv.Itoa(constrained))
+			repRate = constrained
+		}
+	}
+
+	// the first rep_rate of the stream
+	if segmentNumber == 1 && !hlsUsed {
+		p.logSwitch(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation, bandwithList, -1, repRate)
+	}
+
+	// get the segment
+	var err error
+	if isByteRangeMPD {
+		segURL, startRange, endRange, err = http.GetNextByteRangeURL(mpdList[p.mpdListIndex], segmentNumber, repRate, mimeTypes[mimeTypeIndex], ctx)
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "byte start range: "+strconv.Itoa(startRange))
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "byte end range: "+strconv.Itoa(endRange))
+	} else {
+		segURL, err = http.GetNextSegment(mpdList[p.mpdListIndex], segmentNumber, repRate, mimeTypes[mimeTypeIndex], ctx)
+	}
+	if err != nil {
+		return p.fail(err, hlsUsed, segmentNumber, mapSegmentLogPrintout)
+	}
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "current segment URL: "+segURL)
+
+	// Collaborative Code - Start
+	OriginalURL := currentURL
+	OriginalBaseURL := baseURL
+	baseJoined := baseURL + segURL
+	urlHeaderString := http.JoinURL(currentURL, baseURL+segURL, debugLog)
+	if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
+		currentURL = Noden.Search(urlHeaderString, p.segmentDuration, true, profile)
+
+		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
+		currentURL = strings.Split(currentURL, "::")[0]
+		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
+		urlSplit := strings.Split(currentURL, "/")
+		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current URL joined: "+urlSplit[len(urlSplit)-1])
+		baseJoined = urlSplit[len(urlSplit)-1]
+	}
+	// Collaborative Code - End
+
+	// the accountant of this pipeline counts the QUIC stream of the request
+	segmentCtx, cancel := context.WithCancel(xlayer.WithAccountant(ctx, accountant))
+	aborted := false
+	// the record of a download cancelled by the stall predictor
+	abortedBytes := 0
+	abortedRepRate := 0
+	abortElapsed := 0
+	abortPredicted := 0
+	abortBufferLevel := 0
+
+	// Start Time of this segment
+	currentTime := time.Now()
+	// The abort logic needs the chunk size of the next segment of one representation lower for predictions
+	nextSegmentLowerReprateChunkSize := utils.GetChunk(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber+1)
+	if repRate > 0 {
+		nextSegmentLowerReprateChunkSize = utils.GetChunk(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate-1].Chunks, segmentNumber+1)
+	}
+	// a predictor and the dynamic reservoir of BBA-2 also sample the packet-level throughput of the segment
+	if p.abr.Predictor != nil || (pl.cfg.BBA2.Dynamic && algo.BBA2Based(adapt)) {
+		accountant.SegmentStart()
+	}
+	// the buffer does not drain before playback starts, so the initial buffer of the fast start is not aborted,
+	// and a replacement is not aborted, the segment it replaces is already in the buffer
+	predictStall := (!pl.cfg.FastStart.Enabled || p.waitToPlayCounter >= initBuffer) && !hlsUsed
+	switch adapt {
+	case glob.MeanAverageXLAlg:
+		accountant.StartTiming()
+	case glob.MeanAverageRecentXLAlg:
+		accountant.StartTiming()
+	case glob.BBA1Alg_AV:
+		accountant.StartTiming()
+	case glob.BBA1Alg_AVXL:
+		if repRate != lowestMPDrepRateIndex[mimeTypeIndex] && predictStall {
+			accountant.SegmentStart_predictStall(p.segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[utils.GetLowestRepRateIndex(bandwithList)], utils.GetChunk(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, maxBuffer, bandwithList, p.segmentDuration*1000, segmentNumber, &p.abr.BBA2))
+		}
+	case glob.BBA2Alg_AV:
+		accountant.StartTiming()
+	case glob.BBA2Alg_AVXL_base:
+		if repRate != lowestMPDrepRateIndex[mimeTypeIndex] && predictStall {
+			accountant.SegmentStart_predictStall(p.segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[utils.GetLowestRepRateIndex(bandwithList)], utils.GetChunk(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, maxBuffer, bandwithList, p.segmentDuration*1000, segmentNumber, &p.abr.BBA2))
+		}
+	case glob.BBA2Alg_AVXL_rate:
+		if repRate != lowestMPDrepRateIndex[mimeTypeIndex] && predictStall {
+			accountant.SegmentStart_predictStall(p.segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[utils.GetLowestRepRateIndex(bandwithList)], utils.GetChunk(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, maxBuffer, bandwithList, p.segmentDuration*1000, segmentNumber, &p.abr.BBA2))
+		}
+	case glob.BBA2Alg_AVXL_double:
+		if repRate != lowestMPDrepRateIndex[mimeTypeIndex] && predictStall {
+			accountant.SegmentStart_predictStall(p.segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[utils.GetLowestRepRateIndex(bandwithList)], utils.GetChunk(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, maxBuffer, bandwithList, p.segmentDuration*1000, segmentNumber, &p.abr.BBA2))
+		}
+	}
+
+	// BOLA-E abandons a download on its rate so far, from the bytes of the body received or measured by the accountant
+	var abandon *abandonment
+	if repRate != lowestMPDrepRateIndex[mimeTypeIndex] && !hlsUsed {
+		switch adapt {
+		case glob.BOLAEAlg:
+			progress := &http.Progress{}
+			segmentCtx = http.WithProgress(segmentCtx, progress)
+			abandon = p.watchAbandonment(repRate, segmentNumber, bufferLevel, func(elapsed int) float64 {
+				if elapsed <= 0 {
+					return 0
+				}
+				return float64(progress.Received()*8) / (float64(elapsed) / 1000)
+			}, cancel)
+		case glob.BOLAEXLAlg:
+			accountant.SegmentStart()
+			abandon = p.watchAbandonment(repRate, segmentNumber, bufferLevel, func(elapsed int) float64 {
+				if elapsed <= 0 {
+					return 0
+				}
+				return float64(accountant.ReceivedBytes()*8) / (float64(elapsed) / 1000)
+			}, cancel)
+		}
+	}
+
+	// MPC-CL plans with the throughput of the packets of the segment, and it is an input of pensieveXL
+	if adapt == glob.MPCXLAlg || adapt == glob.PensieveXLAlg {
+		accountant.SegmentStart()
+	}
+
+	metricsLogger.SetBufferLevel(p.session.minimumBuffer(p.index, bufferLevel))
+	metricsLogger.Log(logging.MetricSegmentDownloadStart, float64(bandwithList[repRate]))
+
+	var status int
+	//fmt.Println("CURRSEGMENTNUMBER", segmentNumber)
+
+	//fmt.Println("GETTINGSEGMENT", time.Now().UnixMilli())
+
+	// Download the segment - add the segment duration to the file name
+	if adapt == glob.ProgressiveAlg {
+		rtt, segSize, err = http.GetFileProgressively(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, p.segmentDuration, true, debugLog, AudioByteRange, profile, segmentCtx)
+	} else {
+		rtt, segSize, protocol, segmentFileName, P1203Header, status, err = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, p.segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], segmentCtx)
+	}
+
+	//fmt.Println("segSize: ", segSize)
+
+	// arrival and delivery times for this segment
+	arrivalTime = int(time.Since(startTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000))
+	deliveryTime := int(time.Since(currentTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000)) //Time in milliseconds
+	thisRunTimeVal := int(time.Since(nextRunTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000))
+	prevNextRunTime := nextRunTime
+	nextRunTime = time.Now()
+
+	//fmt.Println("deliveryTime: ", deliveryTime)
+	accountant.StopTiming()
+	// the download is over, release the context of the segment
+	abandon.stop()
+	cancel()
+	if abandon != nil && abandon.aborted {
+		aborted = true
+	}
+	// an aborted download is downloaded again below, and a cancelled stream ends at the next segment
+	if err != nil && !aborted && ctx.Err() == nil {
+		return p.fail(err, hlsUsed, segmentNumber, mapSegmentLogPrintout)
+	}
 
-			return segmentNumber, mapSegmentLogPrintouts
+	//fmt.Println(status, aborted)
+	logging.DebugPrint(debugFile, debugLog, "DEBUG: ", strconv.Itoa(status))
+	metricsLogger.Log(logging.MetricSegmentArrived, float64(bandwithList[repRate]))
+
+	if aborted {
+		// the bytes and time of the cancelled download are wasted
+		abortedBytes = accountant.ReceivedBytes()
+		abortedRepRate = bandwithList[repRate]
+		abortElapsed = deliveryTime
+		abortPredicted, abortBufferLevel = accountant.AbortPrediction()
+		if abandon != nil && abandon.aborted {
+			abortedBytes = abandon.receivedBytes
+			abortPredicted, abortBufferLevel = abandon.predicted, abandon.bufferLevel
+			pl.tracer.AbortDecision(urlHeaderString, accountant.ConnectionID(), "bola", time.Duration(abortPredicted)*time.Millisecond, time.Duration(abortBufferLevel)*time.Millisecond)
+		}
+
+		abortedRep := abrqlog.NewRepresentation()
+		abortedRep.ID = strconv.Itoa(repRate)
+		abortedRep.Bitrate = int64(bandwithList[repRate] / glob.Conversion1000)
+		pl.tracer.RequestAborted(mimeTypesMediaType[mimeTypeIndex], segmentNumber, abortedRep, int64(abortedBytes),
+			time.Duration(abortElapsed)*time.Millisecond, time.Duration(abortPredicted)*time.Millisecond, time.Duration(abortBufferLevel)*time.Millisecond)
+		pl.aborted(AbortEvent{
+			MediaType:   mimeTypesMediaType[mimeTypeIndex],
+			Segment:     segmentNumber,
+			RepRate:     repRate,
+			Bytes:       abortedBytes,
+			Elapsed:     time.Duration(abortElapsed) * time.Millisecond,
+			Predicted:   time.Duration(abortPredicted) * time.Millisecond,
+			BufferLevel: time.Duration(abortBufferLevel) * time.Millisecond,
+		})
+
+		// Reset BBA2 to startup parameters
+		if adapt == glob.BBA2Alg_AV || adapt == glob.BBA2Alg_AVXL_base || adapt == glob.BBA2Alg_AVXL_rate || adapt == glob.BBA2Alg_AVXL_double {
+			algorithms.ResetBBAData_afterAbort(&p.abr.BBA2, bufferLevel)
+		}
+		//fmt.Println("After sleep")
+		//time.Sleep(8 * time.Second)
+		///fmt.Println("After sleep")
+		// We will not restart abort detection because we do not want to abort again
+		repRate = utils.GetLowestRepRateIndex(bandwithList)
+		// BOLA-E downloads the segment at the rep_rate its abandonment rule selected
+		if abandon != nil && abandon.aborted {
+			repRate = abandon.repRate
 		}
 
 		// keep rep_rate within the index boundaries
 		// MISL - might cause problems
-		if repRate < highestMPDrepRateIndex[mimeTypeIndex] {
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Changing rep_rate index: from "+strconv.Itoa(repRate)+" to "+strconv.Itoa(highestMPDrepRateIndex[mimeTypeIndex]))
+		/*if repRate < highestMPDrepRateIndex[mimeTypeIndex] {
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "Changing rep_rate index: from "+strconv.Itoa(repRate)+" to "+strconv.Itoa(highestMPDrepRateIndex[mimeTypeIndex]))
 			repRate = highestMPDrepRateIndex[mimeTypeIndex]
-		}
+		}*/
+
+		metricsLogger.Log(logging.MetricSegmentDownloadStart, float64(bandwithList[repRate]))
 
 		// get the segment
 		if isByteRangeMPD {
-			segURL, startRange, endRange = http.GetNextByteRangeURL(mpdList[mpdListIndex], segmentNumber, repRate, mimeTypes[mimeTypeIndex])
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "byte start range: "+strconv.Itoa(startRange))
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "byte end range: "+strconv.Itoa(endRange))
+			segURL, startRange, endRange, err = http.GetNextByteRangeURL(mpdList[p.mpdListIndex], segmentNumber, repRate, mimeTypes[mimeTypeIndex], ctx)
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "byte start range: "+strconv.Itoa(startRange))
+			logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "byte end range: "+strconv.Itoa(endRange))
 		} else {
-			segURL = http.GetNextSegment(mpdList[mpdListIndex], segmentNumber, repRate, mimeTypes[mimeTypeIndex])
+			segURL, err = http.GetNextSegment(mpdList[p.mpdListIndex], segmentNumber, repRate, mimeTypes[mimeTypeIndex], ctx)
+		}
+		if err != nil {
+			return p.fail(err, hlsUsed, segmentNumber, mapSegmentLogPrintout)
 		}
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "current segment URL: "+segURL)
+		logging.DebugPrint(debugFile, debugLog, "DEBUG: ", "current segment URL: "+segURL)
 
 		// Collaborative Code - Start
-		OriginalURL := currentURL
-		OriginalBaseURL := baseURL
+		OriginalURL = currentURL
+		OriginalBaseURL = baseURL
 		baseJoined := baseURL + segURL
 		urlHeaderString := http.JoinURL(currentURL, baseURL+segURL, debugLog)
 		if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
-			currentURL = Noden.Search(urlHeaderString, segmentDuration, true, profile)
+			currentURL = Noden.Search(urlHeaderString, p.segmentDuration, true, profile)
 
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
+			logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
 			currentURL = strings.Split(currentURL, "::")[0]
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
+			logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
 			urlSplit := strings.Split(currentURL, "/")
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL joined: "+urlSplit[len(urlSplit)-1])
+			logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "current URL joined: "+urlSplit[len(urlSplit)-1])
 			baseJoined = urlSplit[len(urlSplit)-1]
 		}
-		// Collaborative Code - End
 
-		ctx, cancel := context.WithCancel(context.Background())
-		aborted := false
+		ctxaborted := xlayer.WithAccountant(ctx, accountant)
 
 		// Start Time of this segment
-		currentTime := time.Now()
-		// The abort logic needs the chunk size of the next segment of one representation lower for predictions
-		nextSegmentLowerReprateChunkSize := utils.GetChunk(mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber+1)
-		if repRate > 0 {
-			nextSegmentLowerReprateChunkSize = utils.GetChunk(mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate-1].Chunks, segmentNumber+1)
-		}
-		switch adapt {
-		case glob.MeanAverageXLAlg:
-			accountant.StartTiming()
-		case glob.MeanAverageRecentXLAlg:
-			accountant.StartTiming()
-		case glob.BBA1Alg_AV:
-			accountant.StartTiming()
-		case glob.BBA1Alg_AVXL:
-			if repRate != lowestMPDrepRateIndex[mimeTypeIndex] {
-				accountant.SegmentStart_predictStall(segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[utils.GetLowestRepRateIndex(bandwithList)], utils.GetChunk(mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, maxBuffer, bandwithList, segmentDuration*1000, segmentNumber, &BBA2DataStruct))
-			}
-		case glob.BBA2Alg_AV:
-			accountant.StartTiming()
-		case glob.BBA2Alg_AVXL_base:
-			if repRate != lowestMPDrepRateIndex[mimeTypeIndex] {
-				accountant.SegmentStart_predictStall(segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[utils.GetLowestRepRateIndex(bandwithList)], utils.GetChunk(mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, maxBuffer, bandwithList, segmentDuration*1000, segmentNumber, &BBA2DataStruct))
-			}
-		case glob.BBA2Alg_AVXL_rate:
-			if repRate != lowestMPDrepRateIndex[mimeTypeIndex] {
-				accountant.SegmentStart_predictStall(segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[utils.GetLowestRepRateIndex(bandwithList)], utils.GetChunk(mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, maxBuffer, bandwithList, segmentDuration*1000, segmentNumber, &BBA2DataStruct))
-			}
-		case glob.BBA2Alg_AVXL_double:
-			if repRate != lowestMPDrepRateIndex[mimeTypeIndex] {
-				accountant.SegmentStart_predictStall(segmentDuration, bandwithList[repRate], bufferLevel, cancel, &aborted, maxBuffer*glob.Conversion1000, bandwithList[utils.GetLowestRepRateIndex(bandwithList)], utils.GetChunk(mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Chunks, segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, maxBuffer, bandwithList, segmentDuration*1000, segmentNumber, &BBA2DataStruct))
-			}
-		}
-
-		metricsLogger.SetBufferLevel(bufferLevel)
-		metricsLogger.WriteChannel <- logging.MetricLoggingFormat{
-			TimeStamp: time.Now(),
-			Tag:       "SegmentDownloadStart",
-			Message:   strconv.Itoa(bandwithList[repRate]),
-		}
-
-		var status int
-		//fmt.Println("CURRSEGMENTNUMBER", segmentNumber)
-
 		//fmt.Println("GETTINGSEGMENT", time.Now().UnixMilli())
-
-		// Download the segment - add the segment duration to the file name
-		switch adapt {
-		case glob.ConventionalAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.ElasticAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.ProgressiveAlg:
-			rtt, segSize = http.GetFileProgressively(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, debugLog, AudioByteRange, profile)
-		case glob.LogisticAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.MeanAverageAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.GeomAverageAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.EMWAAverageAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.TestAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.ArbiterAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.BBAAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.MeanAverageXLAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.MeanAverageRecentXLAlg:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.BBA1Alg_AV:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.BBA1Alg_AVXL:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.BBA2Alg_AV:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.BBA2Alg_AVXL_base:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.BBA2Alg_AVXL_rate:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
-		case glob.BBA2Alg_AVXL_double:
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctx)
+		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "ABORT has choosen rep_Rate "+strconv.Itoa(repRate)+" @ a rate of "+strconv.Itoa(bandwithList[repRate]/glob.Conversion1000))
+		currentTime = time.Now()
+		rtt, segSize, protocol, segmentFileName, P1203Header, status, err = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, p.segmentDuration, true, quicBool, debugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctxaborted)
+		if err != nil && ctx.Err() == nil {
+			return p.fail(err, hlsUsed, segmentNumber, mapSegmentLogPrintout)
 		}
-
-		//fmt.Println("segSize: ", segSize)
-
+		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "Abort segment arrived")
+		//intln("SEGMENTARRIVED", bandwithList[repRate], time.Now().UnixMilli())
 		// arrival and delivery times for this segment
 		arrivalTime = int(time.Since(startTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000))
-		deliveryTime := int(time.Since(currentTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000)) //Time in milliseconds
-		thisRunTimeVal := int(time.Since(nextRunTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000))
-		prevNextRunTime := nextRunTime
-		nextRunTime = time.Now()
-
-		//fmt.Println("deliveryTime: ", deliveryTime)
-		accountant.StopTiming()
-
-		//fmt.Println(status, aborted)
-		logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", strconv.Itoa(status))
-		metricsLogger.WriteChannel <- logging.MetricLoggingFormat{
-			TimeStamp: time.Now(),
-			Tag:       "SegmentArrived",
-			Message:   strconv.Itoa(bandwithList[repRate]),
-		}
+		deliveryTime = int(time.Since(currentTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000)) //Time in milliseconds
+		thisRunTimeVal = int(time.Since(prevNextRunTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000))
 
-		if aborted {
-			// Reset BBA2 to startup parameters
-			if adapt == glob.BBA2Alg_AV || adapt == glob.BBA2Alg_AVXL_base || adapt == glob.BBA2Alg_AVXL_rate || adapt == glob.BBA2Alg_AVXL_double {
-				algorithms.ResetBBAData_afterAbort(&BBA2DataStruct, bufferLevel)
-			}
-			//fmt.Println("After sleep")
-			//time.Sleep(8 * time.Second)
-			///fmt.Println("After sleep")
-			// We will not restart abort detection because we do not want to abort again
-			repRate = utils.GetLowestRepRateIndex(bandwithList)
-
-			// keep rep_rate within the index boundaries
-			// MISL - might cause problems
-			/*if repRate < highestMPDrepRateIndex[mimeTypeIndex] {
-				logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "Changing rep_rate index: from "+strconv.Itoa(repRate)+" to "+strconv.Itoa(highestMPDrepRateIndex[mimeTypeIndex]))
-				repRate = highestMPDrepRateIndex[mimeTypeIndex]
-			}*/
-
-			metricsLogger.WriteChannel <- logging.MetricLoggingFormat{
-				TimeStamp: time.Now(),
-				Tag:       "SegmentDownloadStart",
-				Message:   strconv.Itoa(bandwithList[repRate]),
-			}
-
-			// get the segment
-			if isByteRangeMPD {
-				segURL, startRange, endRange = http.GetNextByteRangeURL(mpdList[mpdListIndex], segmentNumber, repRate, mimeTypes[mimeTypeIndex])
-				logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "byte start range: "+strconv.Itoa(startRange))
-				logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "byte end range: "+strconv.Itoa(endRange))
-			} else {
-				segURL = http.GetNextSegment(mpdList[mpdListIndex], segmentNumber, repRate, mimeTypes[mimeTypeIndex])
-			}
-			logging.DebugPrint(glob.DebugFile, debugLog, "DEBUG: ", "current segment URL: "+segURL)
+		nextRunTime = time.Now()
 
-			// Collaborative Code - Start
-			OriginalURL = currentURL
-			OriginalBaseURL = baseURL
-			baseJoined := baseURL + segURL
-			urlHeaderString := http.JoinURL(currentURL, baseURL+segURL, debugLog)
-			if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
-				currentURL = Noden.Search(urlHeaderString, segmentDuration, true, profile)
+		metricsLogger.Log(logging.MetricSegmentArrived, float64(bandwithList[repRate]))
+	} else {
+		//fmt.Println("SEGMENTARRIVED", bandwithList[repRate], time.Now().UnixMilli())
+	}
 
-				logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
-				currentURL = strings.Split(currentURL, "::")[0]
-				logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL joined: "+currentURL)
-				urlSplit := strings.Split(currentURL, "/")
-				logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "current URL joined: "+urlSplit[len(urlSplit)-1])
-				baseJoined = urlSplit[len(urlSplit)-1]
-			}
+	// a replacement is only selected once the playback started, it does not change the startup of the session
+	if !hlsUsed {
+		p.session.arrived()
+	}
 
-			ctxaborted := context.Background()
+	// some times we want to wait for an initial number of segments before stream begins
+	// no need to do asny printouts when we are replacing this chunk
+	// && !hlsReplaced
+	// playback starts once every adaptation set has its initial buffer
+	if hlsUsed || p.session.play(p.index, initBuffer <= p.waitToPlayCounter) {
+
+		// get the segment less the initial buffer
+		// this needs to be based on running time and not based on number segments
+		// I'll need a function for this
+		//playoutSegmentNumber := segmentNumber - initBuffer
+
+		// only print this out if we are not hls replaced
+		if !hlsUsed {
+			// print out the content of the segment that is currently passed to the player
+			var printLogs []map[int]logging.SegPrintLogInformation
+			printLogs = append(printLogs, mapSegmentLogPrintout)
+			logging.PrintPlayOutLog(arrivalTime, initBuffer, printLogs, glob.LogDownload, printLog, printHeadersData)
+		}
 
-			// Start Time of this segment
-			//fmt.Println("GETTINGSEGMENT", time.Now().UnixMilli())
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "ABORT has choosen rep_Rate "+strconv.Itoa(repRate)+" @ a rate of "+strconv.Itoa(bandwithList[repRate]/glob.Conversion1000))
-			currentTime = time.Now()
-			rtt, segSize, protocol, segmentFileName, P1203Header, status = http.GetFile(currentURL, baseJoined, fileDownloadLocation, isByteRangeMPD, startRange, endRange, segmentNumber, segmentDuration, true, quicBool, glob.DebugFile, debugLog, useTestbedBool, repRate, saveFilesBool, AudioByteRange, profile, mimeTypesMediaType[mimeTypeIndex], ctxaborted)
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "Abort segment arrived")
-			//intln("SEGMENTARRIVED", bandwithList[repRate], time.Now().UnixMilli())
-			// arrival and delivery times for this segment
-			arrivalTime = int(time.Since(startTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000))
-			deliveryTime = int(time.Since(currentTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000)) //Time in milliseconds
-			thisRunTimeVal = int(time.Since(prevNextRunTime).Nanoseconds() / (glob.Conversion1000 * glob.Conversion1000))
+		// get the current buffer (excluding the current segment)
+		ownBuffer := (bufferLevel - int(float64(thisRunTimeVal)*streamSpeed))
+		// playback stalls when any of the adaptation sets runs out
+		currentBuffer := p.session.minimumBuffer(p.index, ownBuffer)
 
-			nextRunTime = time.Now()
+		// if we have a buffer level then we have no stalls
+		if currentBuffer >= 0 {
+			stallTime = 0
 
-			metricsLogger.WriteChannel <- logging.MetricLoggingFormat{
-				TimeStamp: time.Now(),
-				Tag:       "SegmentArrived",
-				Message:   strconv.Itoa(bandwithList[repRate]),
-			}
+			// if the buffer is empty, then we need to calculate
 		} else {
-			//fmt.Println("SEGMENTARRIVED", bandwithList[repRate], time.Now().UnixMilli())
-		}
+			stallTime = currentBuffer
 
-		// some times we want to wait for an initial number of segments before stream begins
-		// no need to do asny printouts when we are replacing this chunk
-		// && !hlsReplaced
-		if initBuffer <= waitToPlayCounter || currently_playing {
-
-			if !currently_playing {
-				currently_playing = true
-				playhead := abrqlog.NewPlayheadStatus()
-				playhead.PlayheadTime = 0
-				playhead.PlayheadFrame = 0
-				abrqlog.MainTracer.PlayerInteraction(abrqlog.InteractionStatePlay, playhead, streamSpeed)
-			}
+			playhead := abrqlog.NewPlayheadStatus()
+			playhead.PlayheadTime = p.session.playhead()
+			pl.tracer.Rebuffer(playhead)
+
+			bufferStats := abrqlog.NewBufferStats()
+			bufferStats.PlayoutTime = time.Duration(0)
+			bufferStats.MaxTime = time.Duration(streamStructs[0].MaxBuffer) * time.Second
+			pl.tracer.UpdateBufferOccupancy(mimeTypesMediaType[mimeTypeIndex],
+				bufferStats)
+		}
 
-			// get the segment less the initial buffer
-			// this needs to be based on running time and not based on number segments
-			// I'll need a function for this
-			//playoutSegmentNumber := segmentNumber - initBuffer
-
-			// only print this out if we are not hls replaced
-			if !hlsUsed {
-				// print out the content of the segment that is currently passed to the player
-				var printLogs []map[int]logging.SegPrintLogInformation
-				printLogs = append(printLogs, mapSegmentLogPrintout)
-				logging.PrintPlayOutLog(arrivalTime, initBuffer, printLogs, glob.LogDownload, printLog, printHeadersData)
+		if !hlsUsed {
+			p.session.rebuffer(utils.Abs(stallTime))
+			if stallTime < 0 {
+				pl.stalled(StallEvent{
+					MediaType: mimeTypesMediaType[mimeTypeIndex],
+					Segment:   segmentNumber,
+					Duration:  time.Duration(utils.Abs(stallTime)) * time.Millisecond,
+				})
 			}
+		}
 
-			// get the current buffer (excluding the current segment)
-			currentBuffer := (bufferLevel - int(float64(thisRunTimeVal)*streamSpeed))
-
-			// if we have a buffer level then we have no stalls
-			if currentBuffer >= 0 {
-				stallTime = 0
-
-				// if the buffer is empty, then we need to calculate
-			} else {
-				stallTime = currentBuffer
-
-				playhead := abrqlog.NewPlayheadStatus()
-				playhead.PlayheadTime = time.Duration(playPosition) * time.Millisecond
-				abrqlog.MainTracer.Rebuffer(playhead)
-
-				bufferStats := abrqlog.NewBufferStats()
-				bufferStats.PlayoutTime = time.Duration(0)
-				bufferStats.MaxTime = time.Duration(streamStructs[mimeTypeIndex].MaxBuffer) * time.Second
-				abrqlog.MainTracer.UpdateBufferOccupancy(mimeTypesMediaType[mimeTypeIndex],
-					bufferStats)
-			}
+		// To have the bufferLevel we take the max between the remaining buffer and 0, we add the duration of the segment we downloaded
+		// our buffer does not drain while another adaptation set stalls the playback
+		bufferLevel = utils.Max(ownBuffer-utils.Min(currentBuffer, 0), 0) + (p.segmentDuration * glob.Conversion1000)
 
-			// To have the bufferLevel we take the max between the remaining buffer and 0, we add the duration of the segment we downloaded
-			bufferLevel = utils.Max(bufferLevel-int(float64(thisRunTimeVal)*streamSpeed), 0) + (segmentDuration * glob.Conversion1000)
+	} else {
+		// If we reach this it means that the buffer has once reached the initial desired level, after this we never want to wait for it to fill up again before we start playing
+		//inStartupPhase = false
+		// add to the current buffer before we start to play
+		bufferLevel += (p.segmentDuration * glob.Conversion1000)
+	}
 
-			// increment the waitToPlayCounter
-			waitToPlayCounter++
+	// increment the waitToPlayCounter, a replacement is not a new segment of the stream
+	if !hlsUsed {
+		p.waitToPlayCounter++
+	}
 
-		} else {
-			// If we reach this it means that the buffer has once reached the initial desired level, after this we never want to wait for it to fill up again before we start playing
-			//inStartupPhase = false
-			// add to the current buffer before we start to play
-			bufferLevel += (segmentDuration * glob.Conversion1000)
-			// increment the waitToPlayCounter
-			waitToPlayCounter++
+	// check if the buffer level is higher than the max buffer
+	if bufferLevel > maxBuffer*glob.Conversion1000 {
+		// retrieve the time it is going to sleep from the buffer level
+		// sleep until the max buffer level is reached
+		sleepTime := int(float64(bufferLevel-(maxBuffer*glob.Conversion1000)) / streamSpeed)
+		// sleep, a cancelled stream stops at the next segment
+		select {
+		case <-time.After(time.Duration(sleepTime) * time.Millisecond):
+		case <-ctx.Done():
 		}
 
-		// check if the buffer level is higher than the max buffer
-		if bufferLevel > maxBuffer*glob.Conversion1000 {
-			// retrieve the time it is going to sleep from the buffer level
-			// sleep until the max buffer level is reached
-			sleepTime := int(float64(bufferLevel-(maxBuffer*glob.Conversion1000)) / streamSpeed)
-			// sleep
-			time.Sleep(time.Duration(sleepTime) * time.Millisecond)
+		// reset the buffer to the new value less sleep time - should equal maxBuffer
+		bufferLevel -= int(float64(sleepTime) * streamSpeed)
+	}
 
-			// reset the buffer to the new value less sleep time - should equal maxBuffer
-			bufferLevel -= int(float64(sleepTime) * streamSpeed)
-		}
+	// some times we want to wait for an initial number of segments before stream begins
+	// if we are going to print out some additonal log headers, then get these values
+	if extendPrintLog && initBuffer < p.waitToPlayCounter {
+		// base the play out position on the buffer level
+		p.playPosition = segmentDurationTotal + (p.segmentDuration * glob.Conversion1000) - bufferLevel
+		// we need to keep a tab on the different size segments - use this for now
+		segmentDurationTotal += (p.segmentDuration * glob.Conversion1000)
+	} else {
+		segmentDurationTotal += (p.segmentDuration * glob.Conversion1000)
+	}
 
-		// some times we want to wait for an initial number of segments before stream begins
-		// if we are going to print out some additonal log headers, then get these values
-		if extendPrintLog && initBuffer < waitToPlayCounter {
-			// base the play out position on the buffer level
-			playPosition = segmentDurationTotal + (segmentDuration * glob.Conversion1000) - bufferLevel
-			// we need to keep a tab on the different size segments - use this for now
-			segmentDurationTotal += (segmentDuration * glob.Conversion1000)
-		} else {
-			segmentDurationTotal += (segmentDuration * glob.Conversion1000)
+	// if we are going to print out some additonal log headers, then get these values
+	if extendPrintLog {
+
+		// get the current codec
+		repCodec = mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Codecs
+
+		// change the codec into something we can understand
+		// switch {
+		// case strings.Contains(repCodec, "avc"):
+		// 	// set the inital rep_rate to the lowest value
+		// 	repCodec = glob.RepRateCodecAVC
+		// case strings.Contains(repCodec, "hev"):
+		// 	repCodec = glob.RepRateCodecHEVC
+		// case strings.Contains(repCodec, "vp"):
+		// 	repCodec = glob.RepRateCodecVP9
+		// case strings.Contains(repCodec, "av1"):
+		// 	repCodec = glob.RepRateCodecAV1
+		// }
+
+		switch {
+		case strings.Contains(repCodec, "avc"):
+			repCodec = glob.RepRateCodecAVC
+		case strings.Contains(repCodec, "hev"):
+			repCodec = glob.RepRateCodecHEVC
+		case strings.Contains(repCodec, "hvc1"):
+			repCodec = glob.RepRateCodecHEVC
+		case strings.Contains(repCodec, "vp"):
+			repCodec = glob.RepRateCodecVP9
+		case strings.Contains(repCodec, "av1"):
+			repCodec = glob.RepRateCodecAV1
+		case strings.Contains(repCodec, "mp4a"):
+			repCodec = glob.RepRateCodecAudio
+		case strings.Contains(repCodec, "ac-3"):
+			repCodec = glob.RepRateCodecAudio
 		}
 
-		// if we are going to print out some additonal log headers, then get these values
-		if extendPrintLog {
-
-			// get the current codec
-			repCodec = mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Codecs
-
-			// change the codec into something we can understand
-			// switch {
-			// case strings.Contains(repCodec, "avc"):
-			// 	// set the inital rep_rate to the lowest value
-			// 	repCodec = glob.RepRateCodecAVC
-			// case strings.Contains(repCodec, "hev"):
-			// 	repCodec = glob.RepRateCodecHEVC
-			// case strings.Contains(repCodec, "vp"):
-			// 	repCodec = glob.RepRateCodecVP9
-			// case strings.Contains(repCodec, "av1"):
-			// 	repCodec = glob.RepRateCodecAV1
-			// }
-
-			switch {
-			case strings.Contains(repCodec, "avc"):
-				repCodec = glob.RepRateCodecAVC
-			case strings.Contains(repCodec, "hev"):
-				repCodec = glob.RepRateCodecHEVC
-			case strings.Contains(repCodec, "hvc1"):
-				repCodec = glob.RepRateCodecHEVC
-			case strings.Contains(repCodec, "vp"):
-				repCodec = glob.RepRateCodecVP9
-			case strings.Contains(repCodec, "av1"):
-				repCodec = glob.RepRateCodecAV1
-			case strings.Contains(repCodec, "mp4a"):
-				repCodec = glob.RepRateCodecAudio
-			case strings.Contains(repCodec, "ac-3"):
-				repCodec = glob.RepRateCodecAudio
-			}
+		// get rep_rate height, width and frames per second
+		repHeight = mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Height
+		repWidth = mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Width
+		repFps = mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].FrameRate
+	}
 
-			// get rep_rate height, width and frames per second
-			repHeight = mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Height
-			repWidth = mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].Width
-			repFps = mpdList[mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation[repRate].FrameRate
+	// calculate the throughtput (we get the segSize while downloading the file)
+	// multiple segSize by 8 to get bits and not bytes
+	thr := algo.CalculateThroughtput(segSize*8, deliveryTime)
+	//fmt.Println("THROUGHPUT: ", strconv.Itoa(thr))
+
+	// the segment bitrate, from the mdat payload if we could read the segment boxes
+	actRate := (segSize * 8) / (p.segmentDuration * glob.Conversion1000)
+	var mediaFps float64
+	var frameSizes []int
+	var frameDurations []float64
+	var keyframes []int
+	if segInfo, ok := http.TransportFromContext(ctx).TakeSegmentInfo(segmentFileName); ok {
+		actRate = int(segInfo.MediaBytes*8) / (p.segmentDuration * glob.Conversion1000)
+		mediaFps = segInfo.FrameRate()
+		keyframes = segInfo.Keyframes()
+		for _, sample := range segInfo.Samples {
+			frameSizes = append(frameSizes, sample.Size)
+			if segInfo.Timescale > 0 {
+				frameDurations = append(frameDurations, float64(sample.Duration)/float64(segInfo.Timescale))
+			}
 		}
+	}
 
-		// calculate the throughtput (we get the segSize while downloading the file)
-		// multiple segSize by 8 to get bits and not bytes
-		thr := algo.CalculateThroughtput(segSize*8, deliveryTime)
-		//fmt.Println("THROUGHPUT: ", strconv.Itoa(thr))
-
-		// save the bitrate from the input segment (less the header info)
-		var kbps float64
-		if getQoEBool {
-			if val, ok := printHeadersData[glob.P1203Header]; ok {
-				if val == "on" || val == "On" {
+	// save the bitrate from the input segment (less the header info)
+	var kbps float64
+	if getQoEBool {
+		if val, ok := printHeadersData[glob.P1203Header]; ok {
+			if val == "on" || val == "On" {
 
-					// we use this to read from a file
-					// kbps = qoe.GetKBPS(segmentFileName, int64(segmentDuration), debugLog, isByteRangeMPD, segSize)
+				// we use this to read from a file
+				// kbps = qoe.GetKBPS(segmentFileName, int64(segmentDuration), debugFile, debugLog, isByteRangeMPD, segSize)
 
-					// we do this to read from our buffer values
-					kbps = P1203Header
-				}
+				// we do this to read from our buffer values
+				kbps = P1203Header
 			}
-			// lets move the logic setup for the QoE values from the algorithms to player
-			// we don't need to save the segRate as this is also called 'Bandwidth'
-			// segRate := float64(log[j].Bandwidth)
-
-			// add this to the seg rate slice
-			if segmentNumber > 1 {
-				// append to the segRates list
-				segRates = append(mapSegmentLogPrintout[segmentNumber-1].SegmentRates, float64(bandwithList[repRate]))
-				// sum the seg rates
-				sumSegRate = mapSegmentLogPrintout[segmentNumber-1].SumSegRate + float64(bandwithList[repRate])
-				// sum the total stall duration
-				totalStallDur = float64(mapSegmentLogPrintout[segmentNumber-1].StallTime) + float64(stallTime)
-				// get the number of stalls
-				if stallTime > 0 {
-					// increment the number of stalls
-					nStalls = mapSegmentLogPrintout[segmentNumber-1].NumStalls + 1
-				} else {
-					// otherwise save the number of stalls from the previous log
-					nStalls = mapSegmentLogPrintout[segmentNumber-1].NumStalls
-				}
-				// get the number of switches
-				if bandwithList[repRate] == mapSegmentLogPrintout[segmentNumber-1].Bandwidth {
-					// store the previous value of switches
-					nSwitches = mapSegmentLogPrintout[segmentNumber-1].NumSwitches
-				} else {
-					// increment the number of switches
-					nSwitches = mapSegmentLogPrintout[segmentNumber-1].NumSwitches + 1
-				}
-				rateDifference = math.Abs(float64(bandwithList[repRate]) - float64(mapSegmentLogPrintout[segmentNumber-1].Bandwidth))
-				sumRateChange = mapSegmentLogPrintout[segmentNumber-1].SumRateChange + rateDifference
-				rateChange = append(mapSegmentLogPrintout[segmentNumber-1].RateChange, rateDifference)
-
+		}
+		// lets move the logic setup for the QoE values from the algorithms to player
+		// we don't need to save the segRate as this is also called 'Bandwidth'
+		// segRate := float64(log[j].Bandwidth)
+
+		// add this to the seg rate slice
+		if segmentNumber > 1 {
+			// append to the segRates list
+			segRates = append(mapSegmentLogPrintout[segmentNumber-1].SegmentRates, float64(bandwithList[repRate]))
+			// sum the seg rates
+			sumSegRate = mapSegmentLogPrintout[segmentNumber-1].SumSegRate + float64(bandwithList[repRate])
+			// sum the total stall duration
+			totalStallDur = float64(mapSegmentLogPrintout[segmentNumber-1].StallTime) + float64(stallTime)
+			// get the number of stalls
+			if stallTime > 0 {
+				// increment the number of stalls
+				nStalls = mapSegmentLogPrintout[segmentNumber-1].NumStalls + 1
+			} else {
+				// otherwise save the number of stalls from the previous log
+				nStalls = mapSegmentLogPrintout[segmentNumber-1].NumStalls
+			}
+			// get the number of switches
+			if bandwithList[repRate] == mapSegmentLogPrintout[segmentNumber-1].Bandwidth {
+				// store the previous value of switches
+				nSwitches = mapSegmentLogPrintout[segmentNumber-1].NumSwitches
 			} else {
+				// increment the number of switches
+				nSwitches = mapSegmentLogPrintout[segmentNumber-1].NumSwitches + 1
+			}
+			rateDifference = math.Abs(float64(bandwithList[repRate]) - float64(mapSegmentLogPrintout[segmentNumber-1].Bandwidth))
+			sumRateChange = mapSegmentLogPrintout[segmentNumber-1].SumRateChange + rateDifference
+			rateChange = append(mapSegmentLogPrintout[segmentNumber-1].RateChange, rateDifference)
 
-				// otherwise create the list
-				segRates = append(segRates, float64(bandwithList[repRate]))
-				// sum the seg rates
-				sumSegRate = float64(bandwithList[repRate])
-				// sum the total stall duration
-				totalStallDur = float64(stallTime)
-				// get the number of stalls
-				if stallTime > 0 {
-					// increment the number of stalls
-					nStalls = 1
-				} else {
-					// otherwise set to zero (may not be needed, go might default to zero)
-					nStalls = 0
-				}
-				// get the number of switches
-				nSwitches = 0
+		} else {
+
+			// otherwise create the list
+			segRates = append(segRates, float64(bandwithList[repRate]))
+			// sum the seg rates
+			sumSegRate = float64(bandwithList[repRate])
+			// sum the total stall duration
+			totalStallDur = float64(stallTime)
+			// get the number of stalls
+			if stallTime > 0 {
+				// increment the number of stalls
+				nStalls = 1
+			} else {
+				// otherwise set to zero (may not be needed, go might default to zero)
+				nStalls = 0
 			}
+			// get the number of switches
+			nSwitches = 0
 		}
+	}
 
-		// Print to output log
-		//printLog(strconv.Itoa(segmentNumber), strconv.Itoa(arrivalTime), strconv.Itoa(deliveryTime), strconv.Itoa(Abs(stallTime)), strconv.Itoa(bandwithList[repRate]/1000), strconv.Itoa((segSize*8)/deliveryTime), strconv.Itoa((segSize*8)/(segmentDuration*1000)), strconv.Itoa(segSize), strconv.Itoa(bufferLevel), adapt, strconv.Itoa(segmentDuration*1000), extendPrintLog, repCodec, strconv.Itoa(repWidth), strconv.Itoa(repHeight), strconv.Itoa(repFps), strconv.Itoa(playPosition), strconv.FormatFloat(float64(rtt.Nanoseconds())/1000000, 'f', 3, 64), fileDownloadLocation)
-
-		// store the current segment log output information in a map
-		printInformation := logging.SegPrintLogInformation{
-			ArrivalTime:          arrivalTime,
-			DeliveryTime:         deliveryTime,
-			StallTime:            stallTime,
-			Bandwidth:            bandwithList[repRate],
-			DelRate:              thr,
-			ActRate:              (segSize * 8) / (segmentDuration * glob.Conversion1000),
-			SegSize:              segSize,
-			P1203HeaderSize:      P1203Header,
-			BufferLevel:          bufferLevel,
-			Adapt:                adapt,
-			SegmentDuration:      segmentDuration,
-			ExtendPrintLog:       extendPrintLog,
-			RepCodec:             repCodec,
-			RepWidth:             repWidth,
-			RepHeight:            repHeight,
-			RepFps:               repFps,
-			PlayStartPosition:    segmentDurationTotal,
-			PlaybackTime:         playPosition,
-			Rtt:                  float64(rtt.Nanoseconds()) / (glob.Conversion1000 * glob.Conversion1000),
-			FileDownloadLocation: fileDownloadLocation,
-			RepIndex:             repRate,
-			MpdIndex:             mpdListIndex,
-			AdaptIndex:           mimeTypes[mimeTypeIndex],
-			SegmentIndex:         nextSegmentNumber,
-			SegReplace:           hlsReplaced,
-			Played:               false,
-			HTTPprotocol:         protocol,
-			P1203Kbps:            kbps,
-			SegmentFileName:      segmentFileName,
-			SegmentRates:         segRates,
-			SumSegRate:           sumSegRate,
-			TotalStallDur:        totalStallDur,
-			NumStalls:            nStalls,
-			NumSwitches:          nSwitches,
-			RateDifference:       rateDifference,
-			SumRateChange:        sumRateChange,
-			RateChange:           rateChange,
-			MimeType:             mimeType,
-			Profile:              profile,
-		}
+	// Print to output log
+	//printLog(strconv.Itoa(segmentNumber), strconv.Itoa(arrivalTime), strconv.Itoa(deliveryTime), strconv.Itoa(Abs(stallTime)), strconv.Itoa(bandwithList[repRate]/1000), strconv.Itoa((segSize*8)/deliveryTime), strconv.Itoa((segSize*8)/(segmentDuration*1000)), strconv.Itoa(segSize), strconv.Itoa(bufferLevel), adapt, strconv.Itoa(segmentDuration*1000), extendPrintLog, repCodec, strconv.Itoa(repWidth), strconv.Itoa(repHeight), strconv.Itoa(repFps), strconv.Itoa(playPosition), strconv.FormatFloat(float64(rtt.Nanoseconds())/1000000, 'f', 3, 64), fileDownloadLocation)
+
+	// store the current segment log output information in a map
+	printInformation := logging.SegPrintLogInformation{
+		ArrivalTime:          arrivalTime,
+		DeliveryTime:         deliveryTime,
+		StallTime:            stallTime,
+		Bandwidth:            bandwithList[repRate],
+		DelRate:              thr,
+		ActRate:              actRate,
+		SegSize:              segSize,
+		P1203HeaderSize:      P1203Header,
+		BufferLevel:          bufferLevel,
+		Adapt:                adapt,
+		SegmentDuration:      p.segmentDuration,
+		ExtendPrintLog:       extendPrintLog,
+		RepCodec:             repCodec,
+		RepWidth:             repWidth,
+		RepHeight:            repHeight,
+		RepFps:               repFps,
+		MediaFps:             mediaFps,
+		FrameSizes:           frameSizes,
+		FrameDurations:       frameDurations,
+		Keyframes:            keyframes,
+		PlayStartPosition:    segmentDurationTotal,
+		PlaybackTime:         p.playPosition,
+		Rtt:                  float64(rtt.Nanoseconds()) / (glob.Conversion1000 * glob.Conversion1000),
+		FileDownloadLocation: fileDownloadLocation,
+		RepIndex:             repRate,
+		MpdIndex:             p.mpdListIndex,
+		AdaptIndex:           mimeTypes[mimeTypeIndex],
+		SegmentIndex:         nextSegmentNumber,
+		SegReplace:           hlsReplaced,
+		Played:               false,
+		HTTPprotocol:         protocol,
+		P1203Kbps:            kbps,
+		SegmentFileName:      segmentFileName,
+		SegmentRates:         segRates,
+		SumSegRate:           sumSegRate,
+		TotalStallDur:        totalStallDur,
+		NumStalls:            nStalls,
+		NumSwitches:          nSwitches,
+		RateDifference:       rateDifference,
+		SumRateChange:        sumRateChange,
+		RateChange:           rateChange,
+		MimeType:             mimeType,
+		Profile:              profile,
+		Aborted:              aborted,
+		AbortedBytes:         abortedBytes,
+		AbortedRepRate:       abortedRepRate,
+		AbortElapsed:         abortElapsed,
+		AbortPredicted:       abortPredicted,
+		AbortBufferLevel:     abortBufferLevel,
+	}
 
-		// this saves per segment number so from 1 on, and not 0 on
-		// remember this :)
-		mapSegmentLogPrintout[segmentNumber] = printInformation
+	// this saves per segment number so from 1 on, and not 0 on
+	// remember this :)
+	mapSegmentLogPrintout[segmentNumber] = printInformation
+
+	pl.segment(SegmentEvent{
+		MediaType:    mimeTypesMediaType[mimeTypeIndex],
+		Number:       segmentNumber,
+		RepRate:      repRate,
+		Bandwidth:    bandwithList[repRate],
+		Bytes:        segSize,
+		DeliveryTime: time.Duration(deliveryTime) * time.Millisecond,
+		Throughput:   thr,
+		BufferLevel:  time.Duration(bufferLevel) * time.Millisecond,
+		Replaced:     hlsUsed,
+	})
+
+	// if we want to create QoE, then pass in the printInformation and save the QoE values to log
+	// don't save json when using collaborative
+	var saveCollabFilesBool bool
+	if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
+		saveCollabFilesBool = false
+	} else {
+		saveCollabFilesBool = saveFilesBool
+	}
+	if getQoEBool {
+		audioRate, audioCodec := p.session.audio()
+		qoe.CreateQoE(&mapSegmentLogPrintout, printHeadersData, qoe.Session{
+			InitBuffer: initBuffer,
+			MaxRepRate: bandwithList[highestMPDrepRateIndex[mimeTypeIndex]],
+			AudioRate:  audioRate,
+			AudioCodec: audioCodec,
+			SaveFiles:  saveCollabFilesBool,
+			DebugFile:  debugFile,
+			DebugLog:   debugLog,
+		})
+	}
 
-		// if we want to create QoE, then pass in the printInformation and save the QoE values to log
-		// don't save json when using collaborative
-		var saveCollabFilesBool bool
-		if Noden.ClientName != glob.CollabPrintOff && Noden.ClientName != "" {
-			saveCollabFilesBool = false
-		} else {
-			saveCollabFilesBool = saveFilesBool
-		}
-		if getQoEBool {
-			qoe.CreateQoE(&mapSegmentLogPrintout, debugLog, initBuffer, bandwithList[highestMPDrepRateIndex[mimeTypeIndex]], printHeadersData, saveCollabFilesBool, audioRate, audioCodec)
-		}
+	// a replacement only downloads its segment again,
+	// the decision of the next rep_rate and the buffer of the stream are left to the stream loop that selected it
+	if hlsUsed {
+		return segmentNumber + 1, []map[int]logging.SegPrintLogInformation{mapSegmentLogPrintout}
+	}
 
-		preRepRate := repRate
+	preRepRate := repRate
 
-		//fmt.Println("BUFFERLEVEL: ", bufferLevel)
+	//fmt.Println("BUFFERLEVEL: ", bufferLevel)
 
-		// to calculate throughtput and select the repRate from it (in algorithm.go)
+	// Arbiter+ and BBA request the segment sizes of the MPD over HTTP
+	other := func(thr int, bitrates []int) int {
 		switch adapt {
-		//Conventional Algo
-		case glob.ConventionalAlg:
-			//fmt.Println("old: ", repRate)
-			algo.Conventional(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
-			//fmt.Println("new: ", repRate)
-			//Harmonic Mean Algo
-		case glob.ElasticAlg:
-			//fmt.Println("old repRate index: ", repRate)
-			//fmt.Println("old bandwithList[repRate]", bandwithList[repRate])
-			algo.ElasticAlgo(&thrList, thr, deliveryTime, maxBuffer, &repRate, bandwithList, &staticAlgParameter, bufferLevel, kP, kI, lowestMPDrepRateIndex[mimeTypeIndex])
-			//fmt.Println("new repRate index: ", repRate)
-			//fmt.Println("new bandwithList[repRate]", bandwithList[repRate])
-			//fmt.Println("elastic segmentNumber: ", segmentNumber)
-			//fmt.Println("segURL: ", segURL)
-		//Progressive Algo
-		case glob.ProgressiveAlg:
-			// fmt.Println("old: ", repRate)
-			algo.Conventional(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
-			// fmt.Println("new: ", repRate)
-		//Logistic Algo
-		case glob.LogisticAlg:
-			// fmt.Println("old: ", repRate)
-			algo.Logistic(&thrList, thr, &repRate, bandwithList, bufferLevel,
-				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], glob.DebugFile, debugLog,
-				maxBufferLevel)
-			// fmt.Println("new: ", repRate)
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "reprate returned: "+strconv.Itoa(repRate))
-		//Mean Average Algo
-		case glob.MeanAverageAlg:
-			//fmt.Println("old: ", repRate)
-			algo.MeanAverageAlgo(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
-			//fmt.Println("new: ", repRate)
-		//Geometric Average Algo
-		case glob.GeomAverageAlg:
-			//fmt.Println("old: ", repRate)
-			algo.GeomAverageAlgo(&thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
-			//fmt.Println("new: ", repRate)
-		//Exponential Average Algo
-		case glob.EMWAAverageAlg:
-			//fmt.Println("old: ", repRate)
-			algo.EMWAAverageAlgo(&thrList, &repRate, exponentialRatio, 3, thr, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
-
 		case glob.ArbiterAlg:
-
-			repRate = algo.CalculateSelectedIndexArbiter(thr, segmentDuration*1000, segmentNumber, maxBufferLevel,
-				repRate, &thrList, streamDuration, mpdList[mpdListIndex], currentURL,
+			return algo.CalculateSelectedIndexArbiter(thr, p.segmentDuration*1000, segmentNumber, p.maxBufferLevel,
+				repRate, &p.abr.ThrList, streamDuration, mpdList[p.mpdListIndex], currentURL,
 				mimeTypes[mimeTypeIndex], segmentNumber, baseURL, debugLog, deliveryTime, bufferLevel,
-				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList,
-				segSize, quicBool, useTestbedBool)
-			//fmt.Println("new: ", repRate)
+				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bitrates,
+				segSize, quicBool, useTestbedBool, pl.segHeadValues, ctx)
 		case glob.BBAAlg:
-			//fmt.Println("segDur: ", segmentDuration*1000)
-			//fmt.Println("index rate: ", repRate)
-			//fmt.Println("baseURL: ", baseURL)
-			//fmt.Println("downloadDurationLastSegment: ", deliveryTime)
-			//fmt.Println("maxStreamDuration: ", streamDuration)
-			//fmt.Println("bufferLevel: ", bufferLevel)
-			//fmt.Println("")
-
-			repRate = algo.CalculateSelectedIndexBba(thr, segmentDuration*1000, segmentNumber, maxBufferLevel,
-				repRate, &thrList, streamDuration, mpdList[mpdListIndex], currentURL,
+			return algo.CalculateSelectedIndexBba(thr, p.segmentDuration*1000, segmentNumber, p.maxBufferLevel,
+				repRate, &p.abr.ThrList, streamDuration, mpdList[p.mpdListIndex], currentURL,
 				mimeTypes[mimeTypeIndex], segmentNumber, baseURL, debugLog, deliveryTime, bufferLevel,
-				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, quicBool, useTestbedBool)
-
-		case glob.TestAlg:
-			//fmt.Println("")
-
-		case glob.MeanAverageXLAlg:
-			//fmt.Println("old: ", repRate)
-			algo.MeanAverageXLAlgo(accountant, &thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
-		case glob.MeanAverageRecentXLAlg:
-			//fmt.Println("old: ", repRate)
-			algo.MeanAverageRecentXLAlgo(accountant, &thrList, thr, &repRate, bandwithList, lowestMPDrepRateIndex[mimeTypeIndex])
-		case glob.BBA1Alg_AV:
-			repRate = algo.BBA(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr, preRepRate)
-		case glob.BBA1Alg_AVXL:
-			repRate = algo.BBA(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr, preRepRate)
-		case glob.BBA2Alg_AV:
-			repRate = algo.BBA2(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr, preRepRate, segmentNumber, &BBA2DataStruct)
-		case glob.BBA2Alg_AVXL_base:
-			repRate = algo.BBA2(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr, preRepRate, segmentNumber, &BBA2DataStruct)
-		case glob.BBA2Alg_AVXL_rate:
-			repRate = algo.BBA2(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr, preRepRate, segmentNumber, &BBA2DataStruct)
-		case glob.BBA2Alg_AVXL_double:
-			repRate = algo.BBA2(bufferLevel, maxBufferLevel, highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, segmentDuration*1000, debugLog, glob.DebugFile, &thrList, thr, preRepRate, segmentNumber, &BBA2DataStruct)
+				highestMPDrepRateIndex[mimeTypeIndex], lowestMPDrepRateIndex[mimeTypeIndex], bandwithList, quicBool, useTestbedBool, ctx)
 		}
-		logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", adapt+" has choosen rep_Rate "+strconv.Itoa(repRate)+" @ a rate of "+strconv.Itoa(bandwithList[repRate]/glob.Conversion1000))
+		return repRate
+	}
 
-		postRepRate := repRate
-		if preRepRate != postRepRate {
-			from := abrqlog.NewRepresentation()
-			from.ID = strconv.Itoa(preRepRate)
-			from.Bitrate = int64(bandwithList[preRepRate] / glob.Conversion1000)
-			to := abrqlog.NewRepresentation()
-			to.ID = strconv.Itoa(postRepRate)
-			to.Bitrate = int64(bandwithList[postRepRate] / glob.Conversion1000)
-			abrqlog.MainTracer.Switch(mimeTypesMediaType[mimeTypeIndex], from, to)
+	// to calculate throughtput and select the repRate from it (in algorithm.go)
+	decision, err := algo.Decide(algo.Decision{
+		Adapt:                        adapt,
+		SegmentNumber:                segmentNumber,
+		RepRate:                      repRate,
+		BufferLevel:                  bufferLevel,
+		Throughput:                   thr,
+		DeliveryTime:                 deliveryTime,
+		StallTime:                    stallTime,
+		RTT:                          rtt,
+		SmoothedRTT:                  accountant.SmoothedRTT(),
+		StreamSpeed:                  streamSpeed,
+		MaxBuffer:                    maxBuffer,
+		MaxBufferLevel:               p.maxBufferLevel,
+		SegmentDuration_Milliseconds: p.segmentDuration * glob.Conversion1000,
+		Bandwidths:                   bandwithList,
+		Highest:                      highestMPDrepRateIndex[mimeTypeIndex],
+		Lowest:                       lowestMPDrepRateIndex[mimeTypeIndex],
+		Allowed:                      pl.allowedRepRates(adaptationSet, mimeTypesMediaType[mimeTypeIndex], pl.highestMPDrepRateIndex[mimeTypeIndex], pl.lowestMPDrepRateIndex[mimeTypeIndex]),
+		ExponentialRatio:             exponentialRatio,
+		VBR:                          pl.cfg.VBR,
+		BBA2Dynamic:                  pl.cfg.BBA2.Dynamic,
+		Accountant:                   accountant,
+		Other:                        other,
+		DebugFile:                    debugFile,
+		DebugLog:                     debugLog,
+	}, &p.abr)
+	if err != nil {
+		return p.fail(err, hlsUsed, segmentNumber, mapSegmentLogPrintout)
+	}
+	if p.abr.Predictor != nil {
+		metricsLogger.Log(logging.MetricThroughputPrediction, decision.Prediction, decision.Confidence)
+	}
+	if pl.cfg.VBR.Enabled && algo.ThroughputBased(adapt) {
+		metricsLogger.Log(logging.MetricVBRBitrate, float64(decision.Bitrates[repRate]), float64(bandwithList[repRate]))
+	}
+	repRate = decision.RepRate
+	// the first adaptation set sets the speed of the playback
+	if adapt == glob.LoLPAlg && p.index == 0 {
+		if rate := decision.PlaybackRate; rate != streamSpeed && p.session.setSpeed(rate) {
+			streamSpeed = rate
+			metricsLogger.Log(logging.MetricPlaybackRate, streamSpeed)
 		}
+	}
+	logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", adapt+" has choosen rep_Rate "+strconv.Itoa(repRate)+" @ a rate of "+strconv.Itoa(bandwithList[repRate]/glob.Conversion1000))
 
-		//Increase the segment number
-		segmentNumber++
+	postRepRate := repRate
+	if preRepRate != postRepRate {
+		p.logSwitch(mpdList[p.mpdListIndex].Periods[0].AdaptationSet[mimeTypes[mimeTypeIndex]].Representation, bandwithList, preRepRate, postRepRate)
+	}
 
-		// break out if we have downloaded all of our segments
-		if segmentDurationTotal+(segmentDuration*glob.Conversion1000) > streamDuration {
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "We have downloaded all segments at the end of the streamLoop - segment total: "+strconv.Itoa(segmentDurationTotal)+"  current segment duration: "+strconv.Itoa(segmentDuration*glob.Conversion1000)+" gives a total of:  "+strconv.Itoa(segmentDurationTotal+(segmentDuration*glob.Conversion1000)))
+	// the buffer of this adaptation set and the playhead after this segment
+	bufferStats := abrqlog.NewBufferStats()
+	bufferStats.PlayoutTime = time.Duration(bufferLevel) * time.Millisecond
+	bufferStats.MaxTime = time.Duration(maxBuffer) * time.Second
+	pl.tracer.UpdateBufferOccupancy(mimeTypesMediaType[mimeTypeIndex],
+		bufferStats)
 
-			if mimeTypeIndex == len(mimeTypes)-1 {
-				// save the current log
-				streamStructs[mimeTypeIndex].MapSegmentLogPrintout = mapSegmentLogPrintout
-				// get the logs for all adaptationSets
-				for thisMimeTypeIndex := range mimeTypes {
-					mapSegmentLogPrintouts = append(mapSegmentLogPrintouts, streamStructs[thisMimeTypeIndex].MapSegmentLogPrintout)
-				}
+	playhead := abrqlog.NewPlayheadStatus()
+	playhead.PlayheadTime = p.session.playhead()
+	pl.tracer.PlayheadProgress(playhead)
 
-				playhead := abrqlog.NewPlayheadStatus()
-				playhead.PlayheadTime = time.Duration(playPosition) * time.Millisecond
-				abrqlog.MainTracer.EndStream(playhead)
+	//Increase the segment number
+	segmentNumber++
 
-				return segmentNumber, mapSegmentLogPrintouts
-			}
-		}
-
-		// save info for the next segment
-		streaminfo := http.StreamStruct{
-			SegmentNumber:         segmentNumber,
-			CurrentURL:            OriginalURL,
-			InitBuffer:            initBuffer,
-			MaxBuffer:             maxBuffer,
-			CodecName:             codecName,
-			Codec:                 codec,
-			UrlString:             urlString,
-			UrlInput:              urlInput,
-			MpdList:               mpdList,
-			Adapt:                 adapt,
-			MaxHeight:             maxHeight,
-			IsByteRangeMPD:        isByteRangeMPD,
-			StartTime:             startTime,
-			NextRunTime:           nextRunTime,
-			ArrivalTime:           arrivalTime,
-			OldMPDIndex:           oldMPDIndex,
-			NextSegmentNumber:     nextSegmentNumber,
-			Hls:                   hls,
-			HlsBool:               hlsBool,
-			MapSegmentLogPrintout: mapSegmentLogPrintout,
-			StreamDuration:        streamDuration,
-			StreamSpeed:           streamSpeed,
-			ExtendPrintLog:        extendPrintLog,
-			HlsUsed:               hlsUsed,
-			BufferLevel:           bufferLevel,
-			SegmentDurationTotal:  segmentDurationTotal,
-			Quic:                  quic,
-			QuicBool:              quicBool,
-			BaseURL:               OriginalBaseURL,
-			DebugLog:              debugLog,
-			AudioContent:          audioContent,
-			RepRate:               repRate,
-			BandwithList:          bandwithList,
-			Profile:               profile,
-		}
-		streamStructs[mimeTypeIndex] = streaminfo
+	// break out if we have downloaded all of our segments
+	if segmentDurationTotal+(p.segmentDuration*glob.Conversion1000) > streamDuration {
+		logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "We have downloaded all segments at the end of the streamLoop - segment total: "+strconv.Itoa(segmentDurationTotal)+"  current segment duration: "+strconv.Itoa(p.segmentDuration*glob.Conversion1000)+" gives a total of:  "+strconv.Itoa(segmentDurationTotal+(p.segmentDuration*glob.Conversion1000)))
 
-		bufferStats := abrqlog.NewBufferStats()
-		bufferStats.PlayoutTime = time.Duration(bufferLevel) * time.Millisecond
-		bufferStats.MaxTime = time.Duration(streamStructs[mimeTypeIndex].MaxBuffer) * time.Second
-		abrqlog.MainTracer.UpdateBufferOccupancy(mimeTypesMediaType[mimeTypeIndex],
-			bufferStats)
+		p.finish()
+		return segmentNumber, []map[int]logging.SegPrintLogInformation{mapSegmentLogPrintout}
+	}
 
-		playhead := abrqlog.NewPlayheadStatus()
-		playhead.PlayheadTime = time.Duration(playPosition) * time.Millisecond
-		abrqlog.MainTracer.PlayheadProgress(playhead)
+	// save info for the next segment
+	streaminfo := http.StreamStruct{
+		SegmentNumber:         segmentNumber,
+		CurrentURL:            OriginalURL,
+		InitBuffer:            initBuffer,
+		MaxBuffer:             maxBuffer,
+		CodecName:             codecName,
+		Codec:                 codec,
+		UrlString:             urlString,
+		UrlInput:              urlInput,
+		MpdList:               mpdList,
+		Adapt:                 adapt,
+		MaxHeight:             maxHeight,
+		IsByteRangeMPD:        isByteRangeMPD,
+		StartTime:             startTime,
+		NextRunTime:           nextRunTime,
+		ArrivalTime:           arrivalTime,
+		OldMPDIndex:           oldMPDIndex,
+		NextSegmentNumber:     nextSegmentNumber,
+		Hls:                   hls,
+		HlsBool:               hlsBool,
+		MapSegmentLogPrintout: mapSegmentLogPrintout,
+		StreamDuration:        streamDuration,
+		StreamSpeed:           streamSpeed,
+		ExtendPrintLog:        extendPrintLog,
+		HlsUsed:               hlsUsed,
+		BufferLevel:           bufferLevel,
+		SegmentDurationTotal:  segmentDurationTotal,
+		Quic:                  quic,
+		QuicBool:              quicBool,
+		BaseURL:               OriginalBaseURL,
+		DebugLog:              debugLog,
+		AudioContent:          audioContent,
+		RepRate:               repRate,
+		BandwithList:          bandwithList,
+		Profile:               profile,
 	}
-	//}
+	streamStructs[0] = streaminfo
+
+	// let the other adaptation sets know our buffer level
+	p.session.publish(p.index, bufferLevel)
 
 	// this gets the index for the next MPD and the segment number for the next chunk
-	stopPlayer := false
-
-	// get some new info
-	for mimeTypeIndex := range mimeTypes {
-		stopPlayer, oldMPDIndex, nextSegmentNumber = http.GetNextSegmentDuration(segmentDurationArray, segmentDuration*glob.Conversion1000, segmentDurationTotal, glob.DebugFile, streamStructs[mimeTypeIndex].DebugLog, segmentDurationArray[mpdListIndex], streamStructs[mimeTypeIndex].StreamDuration)
-		streamStructs[mimeTypeIndex].OldMPDIndex = oldMPDIndex
-		streamStructs[mimeTypeIndex].NextSegmentNumber = nextSegmentNumber
-		// mapSegmentLogPrintouts[mimeTypeIndex] = streamStructs[mimeTypeIndex].MapSegmentLogPrintout
-		mapSegmentLogPrintouts = append(mapSegmentLogPrintouts, streamStructs[mimeTypeIndex].MapSegmentLogPrintout)
-	}
+	stopPlayer, oldMPDIndex, nextSegmentNumber := http.GetNextSegmentDuration(p.segmentDurationArray, p.segmentDuration*glob.Conversion1000, segmentDurationTotal, debugFile, debugLog, p.segmentDurationArray[p.mpdListIndex], streamDuration)
+	streamStructs[0].OldMPDIndex = oldMPDIndex
+	streamStructs[0].NextSegmentNumber = nextSegmentNumber
 
 	//fmt.Println("streamLoop oldMPDIndex: ", stopPlayer)
 
 	// stream the next chunk
 	if !stopPlayer {
-		segmentNumber, mapSegmentLogPrintouts = streamLoop(streamStructs, Noden, accountant, metricsLogger)
+		return p.streamLoop(streamStructs, Noden, accountant, metricsLogger, ctx)
 	}
 
-	return segmentNumber, mapSegmentLogPrintouts
+	p.finish()
+	return segmentNumber, []map[int]logging.SegPrintLogInformation{mapSegmentLogPrintout}
 
 }
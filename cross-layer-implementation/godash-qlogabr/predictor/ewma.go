@@ -0,0 +1,69 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package predictor
+
+import (
+	"math"
+	"time"
+)
+
+// DualEWMA :
+/*
+ * the throughput estimator of dash.js, a fast and a slow exponential average weighted on the download time of the segments
+ * the estimate is the lower of the two, so a drop is followed fast and a rise slowly
+ */
+type DualEWMA struct {
+	FastHalfLife time.Duration
+	SlowHalfLife time.Duration
+}
+
+// Predict : the lower average, the confidence is the ratio of the lower to the higher average
+func (e DualEWMA) Predict(samples []Sample) (float64, float64) {
+	fastAlpha := math.Pow(0.5, 1/e.FastHalfLife.Seconds())
+	slowAlpha := math.Pow(0.5, 1/e.SlowHalfLife.Seconds())
+
+	var fast, slow, fastWeight, slowWeight float64
+	for _, sample := range samples {
+		if sample.CrossLayer || sample.Throughput <= 0 {
+			continue
+		}
+		// a sample without a download time weighs one second
+		weight := sample.Duration.Seconds()
+		if weight <= 0 {
+			weight = 1
+		}
+		fastDecay := math.Pow(fastAlpha, weight)
+		slowDecay := math.Pow(slowAlpha, weight)
+		fast = fastDecay*fast + (1-fastDecay)*sample.Throughput
+		slow = slowDecay*slow + (1-slowDecay)*sample.Throughput
+		fastWeight = fastDecay*fastWeight + (1 - fastDecay)
+		slowWeight = slowDecay*slowWeight + (1 - slowDecay)
+	}
+	if fastWeight == 0 || slowWeight == 0 {
+		return 0, 0
+	}
+
+	// the averages start at 0, so they are scaled up by the weight of the samples
+	fast /= fastWeight
+	slow /= slowWeight
+	return math.Min(fast, slow), math.Min(fast, slow) / math.Max(fast, slow)
+}
@@ -0,0 +1,44 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package predictor
+
+// Harmonic :
+/*
+ * the harmonic mean of the throughput of the last Window segments,
+ * which is the throughput of downloading these segments one after the other if they have the same size
+ */
+type Harmonic struct {
+	Window int
+}
+
+// Predict : the harmonic mean, the confidence is the stability of the window
+func (h Harmonic) Predict(samples []Sample) (float64, float64) {
+	throughputs := segmentThroughputs(samples, h.Window)
+	if len(throughputs) == 0 {
+		return 0, 0
+	}
+	inverse := 0.0
+	for _, throughput := range throughputs {
+		inverse += 1 / throughput
+	}
+	return float64(len(throughputs)) / inverse, stability(throughputs)
+}
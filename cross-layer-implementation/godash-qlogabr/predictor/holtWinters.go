@@ -0,0 +1,64 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package predictor
+
+import "math"
+
+// HoltWinters :
+/*
+ * exponential smoothing of the level and the trend of the segment throughput
+ * Alpha smooths the level and Beta the trend, the estimate is the level of the next segment
+ */
+type HoltWinters struct {
+	Alpha float64
+	Beta  float64
+}
+
+// Predict : the next level, the confidence is 1 less the mean relative error of the one step predictions
+func (hw HoltWinters) Predict(samples []Sample) (float64, float64) {
+	throughputs := segmentThroughputs(samples, 0)
+	if len(throughputs) == 0 {
+		return 0, 0
+	}
+
+	level := throughputs[0]
+	trend := 0.0
+	errorSum := 0.0
+	for _, throughput := range throughputs[1:] {
+		errorSum += math.Abs(level+trend-throughput) / throughput
+		previous := level
+		level = hw.Alpha*throughput + (1-hw.Alpha)*(level+trend)
+		trend = hw.Beta*(level-previous) + (1-hw.Beta)*trend
+	}
+
+	// a falling trend does not predict a throughput below half the lowest sample
+	lowest := throughputs[0]
+	for _, throughput := range throughputs {
+		lowest = math.Min(lowest, throughput)
+	}
+	estimate := math.Max(level+trend, lowest/2)
+
+	if len(throughputs) == 1 {
+		return estimate, 0
+	}
+	return estimate, clamp(1 - errorSum/float64(len(throughputs)-1))
+}
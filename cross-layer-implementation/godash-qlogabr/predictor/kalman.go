@@ -0,0 +1,67 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package predictor
+
+import "math"
+
+// Kalman :
+/*
+ * a Kalman filter of the throughput as a random walk, which fuses segment and cross-layer packet-level samples
+ * the noises are relative standard deviations: ProcessNoise of the throughput from one sample to the next,
+ * SegmentNoise and CrossLayerNoise of the measurements
+ */
+type Kalman struct {
+	ProcessNoise    float64
+	SegmentNoise    float64
+	CrossLayerNoise float64
+}
+
+// Predict : the filtered throughput, the confidence is 1 less its relative standard deviation
+func (k Kalman) Predict(samples []Sample) (float64, float64) {
+	var estimate, variance float64
+	started := false
+	for _, sample := range samples {
+		if sample.Throughput <= 0 {
+			continue
+		}
+		noise := k.SegmentNoise
+		if sample.CrossLayer {
+			noise = k.CrossLayerNoise
+		}
+		measurementVariance := math.Pow(noise*sample.Throughput, 2)
+
+		if !started {
+			estimate, variance = sample.Throughput, measurementVariance
+			started = true
+			continue
+		}
+		// predict, the throughput wanders, then update with the measurement
+		variance += math.Pow(k.ProcessNoise*estimate, 2)
+		gain := variance / (variance + measurementVariance)
+		estimate += gain * (sample.Throughput - estimate)
+		variance *= 1 - gain
+	}
+	if !started {
+		return 0, 0
+	}
+	return estimate, clamp(1 - math.Sqrt(variance)/estimate)
+}
@@ -0,0 +1,123 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package predictor : the throughput predictors the ABR algorithms can select by name
+package predictor
+
+import (
+	"math"
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// Sample : a throughput measurement of the stream
+type Sample struct {
+	// Throughput in bps
+	Throughput float64
+	// Duration the throughput was measured over, the download time of a segment
+	Duration time.Duration
+	// CrossLayer is a packet-level sample of the cross-layer accountant, and not of a whole segment
+	CrossLayer bool
+}
+
+// Predictor :
+/*
+ * predicts the throughput of the next segment from the samples of the stream, oldest first
+ * returns the estimate in bps, 0 without samples, and the confidence in the estimate between 0 and 1
+ * a predictor does not keep state between calls, so one predictor can be shared by streams
+ */
+type Predictor interface {
+	Predict(samples []Sample) (float64, float64)
+}
+
+// MaxSamples :
+/*
+ * the number of the latest samples of a stream the predictors are given, twice the window of the quantile
+ * predictor for its segment and cross-layer samples, the recursive predictors have forgotten older samples
+ */
+const MaxSamples = 40
+
+// Append : append sample to samples, and drop the samples before the last MaxSamples so a stream keeps a bounded history
+func Append(samples []Sample, sample ...Sample) []Sample {
+	samples = append(samples, sample...)
+	if len(samples) > MaxSamples {
+		samples = append(samples[:0], samples[len(samples)-MaxSamples:]...)
+	}
+	return samples
+}
+
+// Names : the names of the predictors
+var Names = []string{glob.PredictorHarmonic, glob.PredictorEWMA, glob.PredictorHoltWinters, glob.PredictorQuantile, glob.PredictorKalman}
+
+// New : the predictor called name with its default parameters, nil if there is no such predictor
+func New(name string) Predictor {
+	switch name {
+	case glob.PredictorHarmonic:
+		return Harmonic{Window: 5}
+	case glob.PredictorEWMA:
+		return DualEWMA{FastHalfLife: 3 * time.Second, SlowHalfLife: 8 * time.Second}
+	case glob.PredictorHoltWinters:
+		return HoltWinters{Alpha: 0.5, Beta: 0.2}
+	case glob.PredictorQuantile:
+		return Quantile{Window: 20, Quantile: 0.1}
+	case glob.PredictorKalman:
+		return Kalman{ProcessNoise: 0.1, SegmentNoise: 0.2, CrossLayerNoise: 0.1}
+	}
+	return nil
+}
+
+// segmentThroughputs : the throughputs of the segment samples, the last window of them if window is above 0
+func segmentThroughputs(samples []Sample, window int) []float64 {
+	var throughputs []float64
+	for _, sample := range samples {
+		if !sample.CrossLayer && sample.Throughput > 0 {
+			throughputs = append(throughputs, sample.Throughput)
+		}
+	}
+	if window > 0 && len(throughputs) > window {
+		throughputs = throughputs[len(throughputs)-window:]
+	}
+	return throughputs
+}
+
+// stability : the confidence in throughputs, 1 less their coefficient of variation, between 0 and 1
+func stability(throughputs []float64) float64 {
+	if len(throughputs) == 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, throughput := range throughputs {
+		mean += throughput
+	}
+	mean /= float64(len(throughputs))
+	variance := 0.0
+	for _, throughput := range throughputs {
+		variance += (throughput - mean) * (throughput - mean)
+	}
+	variance /= float64(len(throughputs))
+	return clamp(1 - math.Sqrt(variance)/mean)
+}
+
+// clamp : x within 0 and 1
+func clamp(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
@@ -0,0 +1,113 @@
+package predictor
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// segments : a segment sample of a second for every throughput
+func segments(throughputs ...float64) []Sample {
+	var samples []Sample
+	for _, throughput := range throughputs {
+		samples = append(samples, Sample{Throughput: throughput, Duration: time.Second})
+	}
+	return samples
+}
+
+func TestEveryPredictorHasAName(t *testing.T) {
+	for _, name := range Names {
+		p := New(name)
+		if p == nil {
+			t.Fatalf("no predictor called %s", name)
+		}
+		if estimate, _ := p.Predict(nil); estimate != 0 {
+			t.Errorf("%s predicts %g without samples, want 0", name, estimate)
+		}
+		// a constant throughput is predicted by every predictor
+		estimate, confidence := p.Predict(segments(4e6, 4e6, 4e6, 4e6, 4e6, 4e6))
+		if math.Abs(estimate-4e6) > 1 {
+			t.Errorf("%s predicts %g for a constant 4e6", name, estimate)
+		}
+		if confidence <= 0 || confidence > 1 {
+			t.Errorf("%s has a confidence of %g in a constant throughput", name, confidence)
+		}
+	}
+	if New("unknown") != nil {
+		t.Error("an unknown name has a predictor")
+	}
+}
+
+func TestHarmonic(t *testing.T) {
+	estimate, _ := Harmonic{Window: 2}.Predict(segments(100e6, 1e6, 3e6))
+	if math.Abs(estimate-1.5e6) > 1 {
+		t.Errorf("harmonic mean %g of the last 2 segments, want 1.5e6", estimate)
+	}
+}
+
+func TestDualEWMAFollowsADropFast(t *testing.T) {
+	samples := segments(8e6, 8e6, 8e6, 8e6, 2e6)
+	estimate, confidence := New("ewma").Predict(samples)
+	if estimate >= 8e6 || estimate <= 2e6 {
+		t.Errorf("estimate %g after a drop from 8e6 to 2e6", estimate)
+	}
+	slow, _ := DualEWMA{FastHalfLife: 8 * time.Second, SlowHalfLife: 8 * time.Second}.Predict(samples)
+	if estimate >= slow {
+		t.Errorf("estimate %g is not below the slow average %g", estimate, slow)
+	}
+	if confidence >= 1 {
+		t.Errorf("confidence %g after a drop, want less than 1", confidence)
+	}
+}
+
+func TestHoltWintersFollowsATrend(t *testing.T) {
+	estimate, _ := HoltWinters{Alpha: 0.5, Beta: 0.5}.Predict(segments(1e6, 2e6, 3e6, 4e6, 5e6))
+	if estimate <= 4e6 {
+		t.Errorf("estimate %g of a rising throughput, want above 4e6", estimate)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	estimate, confidence := Quantile{Window: 5, Quantile: 0.25}.Predict(segments(5e6, 1e6, 4e6, 2e6, 3e6))
+	if estimate != 2e6 {
+		t.Errorf("25th percentile %g, want 2e6", estimate)
+	}
+	if confidence != 0.75 {
+		t.Errorf("confidence %g of a full window, want 0.75", confidence)
+	}
+}
+
+func TestKalmanTrustsCrossLayerSamples(t *testing.T) {
+	k := Kalman{ProcessNoise: 0.1, SegmentNoise: 0.4, CrossLayerNoise: 0.1}
+	samples := append(segments(4e6), Sample{Throughput: 2e6}, Sample{Throughput: 2e6, CrossLayer: true})
+	segmentOnly := append(segments(4e6), Sample{Throughput: 2e6}, Sample{Throughput: 2e6})
+
+	fused, _ := k.Predict(samples)
+	unfused, _ := k.Predict(segmentOnly)
+	if fused >= unfused {
+		t.Errorf("a cross-layer sample moves the estimate to %g, a segment sample to %g, want it closer to 2e6", fused, unfused)
+	}
+}
+
+func TestAppendKeepsTheLastSamples(t *testing.T) {
+	var samples, all []Sample
+	for i := 1; i <= 3*MaxSamples; i++ {
+		sample := Sample{Throughput: float64(i) * 1e6, Duration: time.Second}
+		samples = Append(samples, sample)
+		all = append(all, sample)
+	}
+	if len(samples) != MaxSamples || samples[0].Throughput != float64(2*MaxSamples+1)*1e6 {
+		t.Fatalf("%d samples from %g, want the last %d", len(samples), samples[0].Throughput, MaxSamples)
+	}
+	if cap(samples) > 2*MaxSamples {
+		t.Errorf("the samples grow to a capacity of %d", cap(samples))
+	}
+	// the windows of the predictors are within the samples kept
+	for _, name := range []string{"harmonic", "quantile"} {
+		kept, _ := New(name).Predict(samples)
+		every, _ := New(name).Predict(all)
+		if kept != every {
+			t.Errorf("%s predicts %g from the last samples and %g from every sample", name, kept, every)
+		}
+	}
+}
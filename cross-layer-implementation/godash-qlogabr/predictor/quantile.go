@@ -0,0 +1,55 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package predictor
+
+import (
+	"math"
+	"sort"
+)
+
+// Quantile :
+/*
+ * a safe estimate, the Quantile of the throughput of the last Window segments
+ * with Quantile 0.1 the throughput was above the estimate for nine segments out of ten
+ */
+type Quantile struct {
+	Window   int
+	Quantile float64
+}
+
+// Predict : the quantile, the confidence is the part of the window above it, less for a window that is not full
+func (q Quantile) Predict(samples []Sample) (float64, float64) {
+	throughputs := segmentThroughputs(samples, q.Window)
+	if len(throughputs) == 0 {
+		return 0, 0
+	}
+	sorted := append([]float64(nil), throughputs...)
+	sort.Float64s(sorted)
+
+	// linear interpolation between the closest ranks
+	rank := q.Quantile * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	estimate := sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+
+	return estimate, (1 - q.Quantile) * math.Min(1, float64(len(throughputs))/float64(q.Window))
+}
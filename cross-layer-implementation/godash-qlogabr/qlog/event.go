@@ -168,10 +168,73 @@ func (e eventABRSwitch) MarshalJSONObject(enc *gojay.Encoder) {
 	if e.from.Bitrate >= 0 {
 		enc.Int64Key("from_bitrate", e.from.Bitrate)
 	}
+	if e.from.Width >= 0 && e.from.Height >= 0 {
+		enc.Int64Key("from_width", int64(e.from.Width))
+		enc.Int64Key("from_height", int64(e.from.Height))
+	}
 	enc.StringKey("to_id", e.to.ID)
 	if e.to.Bitrate >= 0 {
 		enc.Int64Key("to_bitrate", e.to.Bitrate)
 	}
+	if e.to.Width >= 0 && e.to.Height >= 0 {
+		enc.Int64Key("to_width", int64(e.to.Width))
+		enc.Int64Key("to_height", int64(e.to.Height))
+	}
+}
+
+type eventABRSegmentReplacement struct {
+	mediaType     MediaType
+	segmentNumber int
+	policy        string
+	from          representation
+	to            representation
+	deadline      time.Duration
+}
+
+func (e eventABRSegmentReplacement) Category() category { return categoryABR }
+func (e eventABRSegmentReplacement) Name() string       { return "segment_replacement" }
+func (e eventABRSegmentReplacement) IsNil() bool        { return false }
+
+func (e eventABRSegmentReplacement) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("media_type", e.mediaType.String())
+	enc.IntKey("segment_number", e.segmentNumber)
+	enc.StringKeyOmitEmpty("policy", e.policy)
+	enc.StringKeyOmitEmpty("from_id", e.from.ID)
+	if e.from.Bitrate >= 0 {
+		enc.Int64Key("from_bitrate", e.from.Bitrate)
+	}
+	enc.StringKey("to_id", e.to.ID)
+	if e.to.Bitrate >= 0 {
+		enc.Int64Key("to_bitrate", e.to.Bitrate)
+	}
+	enc.FloatKey("playout_deadline", milliseconds(e.deadline))
+}
+
+type eventABRRequestAborted struct {
+	mediaType          MediaType
+	segmentNumber      int
+	aborted            representation
+	bytesReceived      int64
+	elapsed            time.Duration
+	predictedRemaining time.Duration
+	bufferLevel        time.Duration
+}
+
+func (e eventABRRequestAborted) Category() category { return categoryABR }
+func (e eventABRRequestAborted) Name() string       { return "request_aborted" }
+func (e eventABRRequestAborted) IsNil() bool        { return false }
+
+func (e eventABRRequestAborted) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("media_type", e.mediaType.String())
+	enc.IntKey("segment_number", e.segmentNumber)
+	enc.StringKey("id", e.aborted.ID)
+	if e.aborted.Bitrate >= 0 {
+		enc.Int64Key("bitrate", e.aborted.Bitrate)
+	}
+	enc.Int64Key("bytes_received", e.bytesReceived)
+	enc.FloatKey("elapsed", milliseconds(e.elapsed))
+	enc.FloatKey("predicted_remaining", milliseconds(e.predictedRemaining))
+	enc.FloatKey("buffer_level", milliseconds(e.bufferLevel))
 }
 
 type eventABRReadyStateChange struct {
@@ -259,3 +322,88 @@ func (e eventNetworkAbort) IsNil() bool        { return false }
 func (e eventNetworkAbort) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("resource_url", e.resource_url)
 }
+
+// Cross-layer
+
+// crossLayerRequest is the segment request and QUIC connection a cross-layer event is about
+type crossLayerRequest struct {
+	resourceURL  string
+	connectionID string
+}
+
+func (r crossLayerRequest) marshal(enc *gojay.Encoder) {
+	enc.StringKeyOmitEmpty("resource_url", r.resourceURL)
+	enc.StringKeyOmitEmpty("connection_id", r.connectionID)
+}
+
+type eventCrossLayerPredictionWindow struct {
+	request       crossLayerRequest
+	bitsReceived  int64
+	thresholdBits int64
+	segmentBits   int64
+}
+
+func (e eventCrossLayerPredictionWindow) Category() category { return categoryCrossLayer }
+func (e eventCrossLayerPredictionWindow) Name() string       { return "prediction_window_entered" }
+func (e eventCrossLayerPredictionWindow) IsNil() bool        { return false }
+
+func (e eventCrossLayerPredictionWindow) MarshalJSONObject(enc *gojay.Encoder) {
+	e.request.marshal(enc)
+	enc.Int64Key("bits_received", e.bitsReceived)
+	enc.Int64Key("threshold_bits", e.thresholdBits)
+	enc.Int64Key("segment_bits", e.segmentBits)
+}
+
+type eventCrossLayerThroughputEstimate struct {
+	request      crossLayerRequest
+	throughput   int64
+	bitsReceived int64
+	elapsed      time.Duration
+}
+
+func (e eventCrossLayerThroughputEstimate) Category() category { return categoryCrossLayer }
+func (e eventCrossLayerThroughputEstimate) Name() string       { return "throughput_estimate" }
+func (e eventCrossLayerThroughputEstimate) IsNil() bool        { return false }
+
+func (e eventCrossLayerThroughputEstimate) MarshalJSONObject(enc *gojay.Encoder) {
+	e.request.marshal(enc)
+	enc.Int64Key("throughput_bps", e.throughput)
+	enc.Int64Key("bits_received", e.bitsReceived)
+	enc.Float64Key("elapsed", milliseconds(e.elapsed))
+}
+
+type eventCrossLayerCompletionPredicted struct {
+	request            crossLayerRequest
+	predictedRemaining time.Duration
+	lowestRateTime     time.Duration
+	bufferLevel        time.Duration
+}
+
+func (e eventCrossLayerCompletionPredicted) Category() category { return categoryCrossLayer }
+func (e eventCrossLayerCompletionPredicted) Name() string       { return "completion_predicted" }
+func (e eventCrossLayerCompletionPredicted) IsNil() bool        { return false }
+
+func (e eventCrossLayerCompletionPredicted) MarshalJSONObject(enc *gojay.Encoder) {
+	e.request.marshal(enc)
+	enc.Float64Key("predicted_remaining", milliseconds(e.predictedRemaining))
+	enc.Float64Key("lowest_rate_time", milliseconds(e.lowestRateTime))
+	enc.Float64Key("buffer_level", milliseconds(e.bufferLevel))
+}
+
+type eventCrossLayerAbortDecision struct {
+	request            crossLayerRequest
+	logic              string
+	predictedRemaining time.Duration
+	bufferLevel        time.Duration
+}
+
+func (e eventCrossLayerAbortDecision) Category() category { return categoryCrossLayer }
+func (e eventCrossLayerAbortDecision) Name() string       { return "abort_decision" }
+func (e eventCrossLayerAbortDecision) IsNil() bool        { return false }
+
+func (e eventCrossLayerAbortDecision) MarshalJSONObject(enc *gojay.Encoder) {
+	e.request.marshal(enc)
+	enc.StringKey("logic", e.logic)
+	enc.Float64Key("predicted_remaining", milliseconds(e.predictedRemaining))
+	enc.Float64Key("buffer_level", milliseconds(e.bufferLevel))
+}
@@ -74,6 +74,7 @@ type streamTracer interface {
 
 	// ABR
 	Switch(mediaType MediaType, from, to representation)
+	RequestAborted(mediaType MediaType, segmentNumber int, aborted representation, bytesReceived int64, elapsed, predictedRemaining, bufferLevel time.Duration)
 	ChangeReadyState(state ReadyState)
 
 	// Buffer
@@ -83,6 +84,12 @@ type streamTracer interface {
 	Request(mediaType MediaType, resourceURL string, byteRange string)
 	RequestUpdate(resourceURL string, bytesReceived int64)
 	AbortRequest(resourceURL string)
+
+	// Cross-layer
+	PredictionWindowEntered(resourceURL, connectionID string, bitsReceived, thresholdBits, segmentBits int64)
+	ThroughputEstimate(resourceURL, connectionID string, throughput, bitsReceived int64, elapsed time.Duration)
+	CompletionPredicted(resourceURL, connectionID string, predictedRemaining, lowestRateTime, bufferLevel time.Duration)
+	AbortDecision(resourceURL, connectionID string, logic string, predictedRemaining, bufferLevel time.Duration)
 }
 
 type StreamTracer struct {
@@ -124,10 +131,11 @@ func (t *StreamTracer) run() {
 	tl := &topLevel{
 		traces: []trace{
 			{
-				Title:        "MPEG-DASH goDash",
+				Title:        "MPEG-DASH goDash " + t.sid.String(),
 				Description:  "MPEG-DASH goDash [" + time.Now().String() + "]",
 				VantagePoint: vantagePoint{Type: t.perspective, Name: "goDash application layer"},
 				CommonFields: commonFields{
+					GroupID:       string(t.sid),
 					ProtocolType:  "QLOG_ABR",
 					ReferenceTime: t.referenceTime,
 				},
@@ -177,6 +185,9 @@ func (t *StreamTracer) run() {
 }
 
 func (t *StreamTracer) Close() {
+	if t == nil {
+		return
+	}
 	if err := t.export(); err != nil {
 		log.Printf("exporting qlog failed: %s\n", err)
 	}
@@ -199,16 +210,36 @@ func (t *StreamTracer) recordEvent(eventTime time.Time, details eventDetails) {
 	}
 }
 
-func (t *StreamTracer) Debug(name, msg string) {
+// record records an event of the stream now, a nil tracer drops its events
+func (t *StreamTracer) record(details eventDetails) {
+	if t == nil {
+		return
+	}
 	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventGeneric{
+	t.recordEvent(time.Now(), details)
+	t.mutex.Unlock()
+}
+
+// RecordRTT updates the RTT statistics of the stream with the RTT of a request and logs them
+func (t *StreamTracer) RecordRTT(rtt time.Duration, now time.Time) {
+	if t == nil {
+		return
+	}
+	t.RTT.UpdateRTT(rtt, now)
+	t.UpdatedMetrics(t.RTT)
+}
+
+func (t *StreamTracer) Debug(name, msg string) {
+	t.record(&eventGeneric{
 		name: name,
 		msg:  msg,
 	})
-	t.mutex.Unlock()
 }
 
 func (t *StreamTracer) UpdatedMetrics(rttStats *RTTStats) {
+	if t == nil {
+		return
+	}
 	m := &metrics{
 		MinRTT:      rttStats.MinRTT(),
 		SmoothedRTT: rttStats.SmoothedRTT(),
@@ -227,73 +258,77 @@ func (t *StreamTracer) UpdatedMetrics(rttStats *RTTStats) {
 // Playback
 
 func (t *StreamTracer) InitialiseStream(autoplay bool) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPlaybackStreamInitialised{autoplay: autoplay})
-	t.mutex.Unlock()
+	t.record(&eventPlaybackStreamInitialised{autoplay: autoplay})
 }
 
 func (t *StreamTracer) PlayerInteraction(state InteractionState, playhead playheadStatus, speed float64) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPlaybackInteraction{state: state, playhead: playhead, speed: speed})
-	t.mutex.Unlock()
+	t.record(&eventPlaybackInteraction{state: state, playhead: playhead, speed: speed})
 }
 
 func (t *StreamTracer) Rebuffer(playhead playheadStatus) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPlaybackRebuffer{playhead: playhead})
-	t.mutex.Unlock()
+	t.record(&eventPlaybackRebuffer{playhead: playhead})
 }
 
 func (t *StreamTracer) EndStream(playhead playheadStatus) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPlaybackStreamEnd{playhead: playhead})
-	t.mutex.Unlock()
+	t.record(&eventPlaybackStreamEnd{playhead: playhead})
 }
 
 func (t *StreamTracer) PlayheadProgress(playhead playheadStatus) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventPlaybackPlayheadProgress{playhead: playhead})
-	t.mutex.Unlock()
+	t.record(&eventPlaybackPlayheadProgress{playhead: playhead})
 }
 
 // ABR
 
 func (t *StreamTracer) Switch(mediaType MediaType, from, to representation) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventABRSwitch{mediaType: mediaType, from: from, to: to})
-	t.mutex.Unlock()
+	t.record(&eventABRSwitch{mediaType: mediaType, from: from, to: to})
+}
+
+func (t *StreamTracer) SegmentReplacement(mediaType MediaType, segmentNumber int, policy string, from, to representation, deadline time.Duration) {
+	t.record(&eventABRSegmentReplacement{mediaType: mediaType, segmentNumber: segmentNumber, policy: policy, from: from, to: to, deadline: deadline})
+}
+
+func (t *StreamTracer) RequestAborted(mediaType MediaType, segmentNumber int, aborted representation, bytesReceived int64, elapsed, predictedRemaining, bufferLevel time.Duration) {
+	t.record(&eventABRRequestAborted{mediaType: mediaType, segmentNumber: segmentNumber, aborted: aborted, bytesReceived: bytesReceived, elapsed: elapsed, predictedRemaining: predictedRemaining, bufferLevel: bufferLevel})
 }
 
 func (t *StreamTracer) ChangeReadyState(state ReadyState) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventABRReadyStateChange{state: state})
-	t.mutex.Unlock()
+	t.record(&eventABRReadyStateChange{state: state})
 }
 
 // Buffer
 
 func (t *StreamTracer) UpdateBufferOccupancy(mediaType MediaType, bufferStats bufferStats) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventBufferOccupancyUpdated{media_type: mediaType, buffer_stats: bufferStats})
-	t.mutex.Unlock()
+	t.record(&eventBufferOccupancyUpdated{media_type: mediaType, buffer_stats: bufferStats})
 }
 
 // Network
 
 func (t *StreamTracer) Request(mediaType MediaType, resourceURL string, byteRange string) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventNetworkRequest{media_type: mediaType, resource_url: resourceURL, byte_range: byteRange})
-	t.mutex.Unlock()
+	t.record(&eventNetworkRequest{media_type: mediaType, resource_url: resourceURL, byte_range: byteRange})
 }
 
 func (t *StreamTracer) RequestUpdate(resourceURL string, bytesReceived int64) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventNetworkRequestUpdate{resource_url: resourceURL, bytesReceived: bytesReceived})
-	t.mutex.Unlock()
+	t.record(&eventNetworkRequestUpdate{resource_url: resourceURL, bytesReceived: bytesReceived})
 }
 
 func (t *StreamTracer) AbortRequest(resourceURL string) {
-	t.mutex.Lock()
-	t.recordEvent(time.Now(), &eventNetworkAbort{resource_url: resourceURL})
-	t.mutex.Unlock()
+	t.record(&eventNetworkAbort{resource_url: resourceURL})
+}
+
+// Cross-layer
+
+func (t *StreamTracer) PredictionWindowEntered(resourceURL, connectionID string, bitsReceived, thresholdBits, segmentBits int64) {
+	t.record(&eventCrossLayerPredictionWindow{request: crossLayerRequest{resourceURL, connectionID}, bitsReceived: bitsReceived, thresholdBits: thresholdBits, segmentBits: segmentBits})
+}
+
+func (t *StreamTracer) ThroughputEstimate(resourceURL, connectionID string, throughput, bitsReceived int64, elapsed time.Duration) {
+	t.record(&eventCrossLayerThroughputEstimate{request: crossLayerRequest{resourceURL, connectionID}, throughput: throughput, bitsReceived: bitsReceived, elapsed: elapsed})
+}
+
+func (t *StreamTracer) CompletionPredicted(resourceURL, connectionID string, predictedRemaining, lowestRateTime, bufferLevel time.Duration) {
+	t.record(&eventCrossLayerCompletionPredicted{request: crossLayerRequest{resourceURL, connectionID}, predictedRemaining: predictedRemaining, lowestRateTime: lowestRateTime, bufferLevel: bufferLevel})
+}
+
+func (t *StreamTracer) AbortDecision(resourceURL, connectionID string, logic string, predictedRemaining, bufferLevel time.Duration) {
+	t.record(&eventCrossLayerAbortDecision{request: crossLayerRequest{resourceURL, connectionID}, logic: logic, predictedRemaining: predictedRemaining, bufferLevel: bufferLevel})
 }
@@ -0,0 +1,49 @@
+package qlog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/uccmisl/godash/output"
+)
+
+// NewRunTracer creates a tracer that saves the trace of every stream as <perspective>_abr_<sid>.qlog of run
+func NewRunTracer(run output.Run) *Tracer {
+	return NewTracer(func(p Perspective, streamID string) io.WriteCloser {
+		f, err := run.Create(output.ABRQlog, fmt.Sprintf(p.String()+"_abr_%s.qlog", streamID))
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Creating ABR qlog file %s.\n", f.Name())
+		return NewBufferedWriteCloser(bufio.NewWriter(f), f)
+	})
+}
+
+// StartRunTracer creates the client trace of stream sid, saved in the ABR qlog of run.
+// Every stream has a tracer of its own, a nil *StreamTracer drops its events.
+func StartRunTracer(run output.Run, sid StreamID) *StreamTracer {
+	return NewRunTracer(run).TracerForStream(context.Background(), PerspectiveClient, sid)
+}
+
+type bufferedWriteCloser struct {
+	*bufio.Writer
+	io.Closer
+}
+
+// NewBufferedWriteCloser creates an io.WriteCloser from a bufio.Writer and an io.Closer
+func NewBufferedWriteCloser(writer *bufio.Writer, closer io.Closer) io.WriteCloser {
+	return &bufferedWriteCloser{
+		Writer: writer,
+		Closer: closer,
+	}
+}
+
+func (h bufferedWriteCloser) Close() error {
+	if err := h.Writer.Flush(); err != nil {
+		return err
+	}
+	return h.Closer.Close()
+}
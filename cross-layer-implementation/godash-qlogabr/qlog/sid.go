@@ -2,6 +2,10 @@ package qlog
 
 import (
 	"crypto/rand"
+	"net/url"
+	"path"
+	"strings"
+	"time"
 )
 
 type StreamID string
@@ -15,6 +19,26 @@ func GenerateStreamID(len int) (StreamID, error) {
 	return StreamID(b), nil
 }
 
+// NewStreamID names a stream after the first MPD of urls, the algorithm and the start time,
+// "<mpd name>_<adapt>_<yyyymmdd-hhmmss>", which is also used in the name of its qlog file
+func NewStreamID(urls string, adapt string, start time.Time) StreamID {
+	mpd := strings.Trim(strings.Split(urls, ",")[0], "[] ")
+	if u, err := url.Parse(mpd); err == nil {
+		mpd = u.Path
+	}
+	mpd = strings.TrimSuffix(path.Base(mpd), path.Ext(mpd))
+
+	clean := func(part string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+				return r
+			}
+			return '-'
+		}, part)
+	}
+	return StreamID(clean(mpd) + "_" + clean(adapt) + "_" + start.Format("20060102-150405"))
+}
+
 func (c StreamID) String() string {
 	if len(c) == 0 {
 		return "(empty)"
@@ -39,11 +39,16 @@ func NewPlayheadStatus() playheadStatus {
 type representation struct {
 	ID      string
 	Bitrate int64
+	// resolution of video representations
+	Width  int32
+	Height int32
 }
 
 func NewRepresentation() representation {
 	return representation{
 		ID:      "",
 		Bitrate: -1,
+		Width:   -1,
+		Height:  -1,
 	}
 }
@@ -8,6 +8,7 @@ const (
 	categoryABR
 	categoryBuffer
 	categoryNetwork
+	categoryCrossLayer
 	categoryGeneric
 )
 
@@ -21,6 +22,8 @@ func (c category) String() string {
 		return "buffer"
 	case categoryNetwork:
 		return "network"
+	case categoryCrossLayer:
+		return "crosslayer"
 	case categoryGeneric:
 		return "generic"
 	default:
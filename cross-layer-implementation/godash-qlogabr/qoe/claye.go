@@ -34,7 +34,7 @@ import (
 )
 
 // getClaye : claye qoe estimation
-func getClaye(log map[int]logging.SegPrintLogInformation, c chan float64, maxRepRate int, printOutput bool) {
+func getClaye(log map[int]logging.SegPrintLogInformation, maxRepRate int, printOutput bool) float64 {
 
 	// I don't think we need *totVar* and *stallFree*, so I've removed these
 	// I also moved *stallDurElem* to inside the *if nStalls > 0* as it is not used in the *else*
@@ -137,9 +137,8 @@ func getClaye(log map[int]logging.SegPrintLogInformation, c chan float64, maxRep
 		fmt.Printf("The Claye QoE is %.4f\n", totQoE)
 	}
 
-	// calculate the claye value and return to the channel
-	c <- totQoE
-
+	// return the claye value
+	return totQoE
 }
 
 /*
@@ -27,7 +27,7 @@ import (
 	"github.com/uccmisl/godash/logging"
 )
 
-func getDuanmu(log map[int]logging.SegPrintLogInformation, c chan float64, initBuffer int, printOutput bool) {
+func getDuanmu(log map[int]logging.SegPrintLogInformation, initBuffer int, printOutput bool) float64 {
 
 	var sessionDuration int
 	var totalStall float64
@@ -130,7 +130,6 @@ func getDuanmu(log map[int]logging.SegPrintLogInformation, c chan float64, initB
 	// returned Duanmu value
 	returnedQoE := qoe
 
-	// calculate the Duanmu value and return to the channel
-	c <- returnedQoE
-
+	// return the Duanmu value
+	return returnedQoE
 }
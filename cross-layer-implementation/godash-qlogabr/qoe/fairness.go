@@ -0,0 +1,171 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"time"
+
+	glob "github.com/uccmisl/godash/global"
+)
+
+// ClientSession : the QoE report of one client of a multi-client run
+type ClientSession struct {
+	Name string
+	// Start of the client, relative to the start of the run, and how long it streamed
+	Start    time.Duration
+	Duration time.Duration
+	Summary  SessionSummary
+	// Err is the error the client stopped on, nil if the client streamed to the end
+	Err error
+}
+
+// FairnessReport : the combined report of the clients of a multi-client run
+type FairnessReport struct {
+	Clients []ClientFairness `json:"clients"`
+	// Jain's fairness index over the clients, 1 if every client got the same
+	JainBitrate    float64 `json:"jainBitrate"`
+	JainStallRatio float64 `json:"jainStallRatio"`
+	// Jain's fairness index per QoE model scored for every client, empty without -QoE on
+	JainQoE map[string]float64 `json:"jainQoE"`
+}
+
+// ClientFairness : the values of one client the fairness indexes are computed over
+type ClientFairness struct {
+	Name            string `json:"name"`
+	StartOffsetMs   int    `json:"startOffsetMs"`
+	DurationMs      int    `json:"durationMs"`
+	Algorithm       string `json:"algorithm"`
+	Segments        int    `json:"segments"`
+	StallCount      int    `json:"stallCount"`
+	StallDurationMs int    `json:"stallDurationMs"`
+	// time-weighted bitrate of the first video adaptation set
+	BitrateKbps float64 `json:"bitrateKbps"`
+	// stall time over the time the client streamed
+	StallRatio      float64            `json:"stallRatio"`
+	AbortedSegments int                `json:"abortedSegments"`
+	Models          map[string]float64 `json:"models"`
+	Error           string             `json:"error,omitempty"`
+}
+
+// JainIndex :
+/*
+ * Jain's fairness index of values, (sum x)^2 / (n * sum x^2)
+ * 1 when every value is the same, 1/n when one value takes everything, 0 without values
+ */
+func JainIndex(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum, sumSquares float64
+	for _, value := range values {
+		sum += value
+		sumSquares += value * value
+	}
+	// every value is zero, so every client got the same
+	if sumSquares == 0 {
+		return 1
+	}
+	return sum * sum / (float64(len(values)) * sumSquares)
+}
+
+// NewFairnessReport :
+/*
+ * the fairness of the clients of a run over bitrate, stall ratio and QoE
+ * the stalls of a client are those of its adaptation set with the longest stall time,
+ * as the adaptation sets of a stream stall together
+ */
+func NewFairnessReport(clients []ClientSession) FairnessReport {
+
+	report := FairnessReport{
+		Clients: make([]ClientFairness, 0, len(clients)),
+		JainQoE: make(map[string]float64),
+	}
+
+	var bitrates, stallRatios []float64
+	for _, client := range clients {
+		fairness := ClientFairness{
+			Name:          client.Name,
+			StartOffsetMs: int(client.Start.Milliseconds()),
+			DurationMs:    int(client.Duration.Milliseconds()),
+			Algorithm:     client.Summary.Algorithm,
+			Models:        client.Summary.Models,
+		}
+		if fairness.Models == nil {
+			fairness.Models = make(map[string]float64)
+		}
+		if client.Err != nil {
+			fairness.Error = client.Err.Error()
+		}
+
+		videoFound := false
+		for _, set := range client.Summary.AdaptationSets {
+			if !videoFound && set.MimeType != glob.RepRateCodecAudio {
+				videoFound = true
+				fairness.Segments = set.Segments
+				fairness.BitrateKbps = set.TimeWeightedBitrateKbps
+			}
+			if set.StallDurationMs > fairness.StallDurationMs {
+				fairness.StallCount = set.StallCount
+				fairness.StallDurationMs = set.StallDurationMs
+			}
+			fairness.AbortedSegments += set.AbortedSegments
+		}
+		// an audio only stream
+		if !videoFound && len(client.Summary.AdaptationSets) > 0 {
+			fairness.Segments = client.Summary.AdaptationSets[0].Segments
+			fairness.BitrateKbps = client.Summary.AdaptationSets[0].TimeWeightedBitrateKbps
+		}
+		if fairness.DurationMs > 0 {
+			fairness.StallRatio = float64(fairness.StallDurationMs) / float64(fairness.DurationMs)
+		}
+
+		bitrates = append(bitrates, fairness.BitrateKbps)
+		stallRatios = append(stallRatios, fairness.StallRatio)
+		report.Clients = append(report.Clients, fairness)
+	}
+
+	report.JainBitrate = JainIndex(bitrates)
+	report.JainStallRatio = JainIndex(stallRatios)
+
+	// the models every client has a score of
+	if len(report.Clients) == 0 {
+		return report
+	}
+	for name := range report.Clients[0].Models {
+		var scores []float64
+		for _, client := range report.Clients {
+			if score, ok := client.Models[name]; ok {
+				scores = append(scores, score)
+			}
+		}
+		if len(scores) == len(report.Clients) {
+			report.JainQoE[name] = JainIndex(scores)
+		}
+	}
+
+	return report
+}
+
+// WriteFairnessReport : save the fairness report as json to fileName
+func WriteFairnessReport(report FairnessReport, fileName string) {
+	writeReport(report, "fairness report", fileName)
+}
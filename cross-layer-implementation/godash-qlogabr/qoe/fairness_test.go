@@ -0,0 +1,55 @@
+package qoe
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestJainIndex(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{[]float64{3000, 3000, 3000}, 1},
+		{[]float64{4000, 0, 0, 0}, 0.25},
+		{[]float64{1000, 3000}, 0.8},
+		{[]float64{0, 0}, 1},
+		{nil, 0},
+	}
+	for _, test := range tests {
+		if got := JainIndex(test.values); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("JainIndex(%v) = %f, want %f", test.values, got, test.want)
+		}
+	}
+}
+
+func TestNewFairnessReport(t *testing.T) {
+	clients := []ClientSession{
+		{Name: "client_1", Duration: 10 * time.Second, Summary: SessionSummary{
+			Models: map[string]float64{"P.1203": 4, "Clae": 1},
+			AdaptationSets: []AdaptationSetSummary{
+				{MimeType: "video/mp4", TimeWeightedBitrateKbps: 1000, StallDurationMs: 1000},
+				{MimeType: "audio", TimeWeightedBitrateKbps: 128},
+			},
+		}},
+		{Name: "client_2", Start: 5 * time.Second, Duration: 10 * time.Second, Summary: SessionSummary{
+			Models: map[string]float64{"P.1203": 4},
+			AdaptationSets: []AdaptationSetSummary{
+				{MimeType: "video/mp4", TimeWeightedBitrateKbps: 3000, StallDurationMs: 3000},
+			},
+		}},
+	}
+
+	report := NewFairnessReport(clients)
+	if report.Clients[0].BitrateKbps != 1000 || report.Clients[1].StallRatio != 0.3 || report.Clients[1].StartOffsetMs != 5000 {
+		t.Errorf("unexpected client values %+v", report.Clients)
+	}
+	if math.Abs(report.JainBitrate-0.8) > 1e-9 || math.Abs(report.JainStallRatio-0.8) > 1e-9 {
+		t.Errorf("bitrate and stall ratio indexes are %f and %f, want 0.8", report.JainBitrate, report.JainStallRatio)
+	}
+	// only the models of every client are compared
+	if len(report.JainQoE) != 1 || report.JainQoE["P.1203"] != 1 {
+		t.Errorf("QoE indexes are %v, want P.1203 only", report.JainQoE)
+	}
+}
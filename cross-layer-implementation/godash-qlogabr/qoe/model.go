@@ -0,0 +1,132 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"sync"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+)
+
+// Session : the stream values a QoE model can use
+type Session struct {
+	InitBuffer int
+	// highest rep_rate of the adaptation set in bps
+	MaxRepRate int
+	// audio rate in kbps and codec, empty if there is no audio adaptation set
+	AudioRate  int
+	AudioCodec string
+	// save the model input files next to the segments
+	SaveFiles bool
+	// debug log of the stream
+	DebugFile string
+	DebugLog  bool
+}
+
+// Model : a QoE model that scores the segments streamed so far
+type Model interface {
+	// Name : the print header of the model, also its name in the session summary
+	Name() string
+	// Score : the score of the segments in log, false if the model cannot score these segments
+	Score(log map[int]logging.SegPrintLogInformation, session Session) (float64, bool)
+}
+
+// registered models, in print order
+var (
+	modelsMu sync.Mutex
+	models   []Model
+)
+
+// RegisterModel :
+/*
+ * add a QoE model, every registered model is in the session summary
+ * a model is also scored per segment when its name is an enabled print header
+ */
+func RegisterModel(model Model) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	models = append(models, model)
+}
+
+// Models : the registered QoE models
+func Models() []Model {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	return append([]Model(nil), models...)
+}
+
+// scoreFunc : a model that cannot fail to score the segments
+type scoreFunc struct {
+	name  string
+	score func(log map[int]logging.SegPrintLogInformation, session Session) float64
+}
+
+// Name : the print header of the model
+func (m scoreFunc) Name() string {
+	return m.name
+}
+
+// Score : the score of the segments in log
+func (m scoreFunc) Score(log map[int]logging.SegPrintLogInformation, session Session) (float64, bool) {
+	return m.score(log, session), true
+}
+
+// p1203Model : P.1203 mode 0, only for H264 segments up to 1920x1080
+type p1203Model struct{}
+
+// Name : the print header of the model
+func (p1203Model) Name() string {
+	return glob.P1203Header
+}
+
+// Score : the P.1203 O.35 of the segments in log, the stalls are not part of it
+func (p1203Model) Score(log map[int]logging.SegPrintLogInformation, session Session) (float64, bool) {
+
+	// the P1203 standard only works for H264 (encoder) and up to resolutions of 1920x1080
+	// so make sure the received segments are compliant
+	logging.DebugPrint(session.DebugFile, session.DebugLog, "\nDEBUG: ", "checking for P1203 compatibility")
+	for a := 1; a <= len(log); a++ {
+		if log[a].RepCodec != glob.RepRateCodecAVC || log[a].RepWidth > glob.P1203maxWidth || log[a].RepHeight > glob.P1203maxHeight {
+			logging.DebugPrint(session.DebugFile, session.DebugLog, "\nDEBUG: ", "Downloaded segments are not P1203 compliant")
+			return 0, false
+		}
+	}
+	return createP1203(&log, session.SaveFiles, session.AudioRate, session.AudioCodec), true
+}
+
+// the QoE models of goDASH
+func init() {
+	RegisterModel(p1203Model{})
+	RegisterModel(scoreFunc{glob.ClaeHeader, func(log map[int]logging.SegPrintLogInformation, session Session) float64 {
+		return getClaye(log, session.MaxRepRate, false)
+	}})
+	RegisterModel(scoreFunc{glob.DuanmuHeader, func(log map[int]logging.SegPrintLogInformation, session Session) float64 {
+		return getDuanmu(log, session.InitBuffer, false)
+	}})
+	RegisterModel(scoreFunc{glob.YinHeader, func(log map[int]logging.SegPrintLogInformation, session Session) float64 {
+		return getYin(log, session.InitBuffer, false)
+	}})
+	RegisterModel(scoreFunc{glob.YuHeader, func(log map[int]logging.SegPrintLogInformation, session Session) float64 {
+		return getYu(log, false)
+	}})
+}
@@ -22,25 +22,20 @@
 package qoe
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io/ioutil"
 	"os"
-	"os/exec"
 	"runtime"
 	"strconv"
-	"strings"
 
 	glob "github.com/uccmisl/godash/global"
 
+	"github.com/uccmisl/godash/http"
 	"github.com/uccmisl/godash/logging"
 	"github.com/uccmisl/godash/utils"
 )
 
-// if we want to save the json file content, we can use these commands
-// var p1203 P1203
-// json.Unmarshal([]byte(jsonString), &p1203)
-
 // P1203 structure
 type P1203 struct {
 	I11  I11  `json:"I11"`
@@ -71,12 +66,22 @@ type I13 struct {
 
 // VideoSegment in P1203 json
 type VideoSegment struct {
-	Bitrate    int     `json:"bitrate"`
+	Bitrate    float64 `json:"bitrate"`
 	Codec      string  `json:"codec"`
 	Duration   float64 `json:"duration"`
 	FPS        float64 `json:"fps"`
 	Resolution string  `json:"resolution"`
 	Start      float64 `json:"start"`
+	// the frames of the segment, only written when every segment has them (P.1203 mode 1)
+	Frames []Frame `json:"frames,omitempty"`
+}
+
+// Frame in P1203 json
+type Frame struct {
+	FrameType string  `json:"frameType"`
+	FrameSize int     `json:"frameSize"`
+	Duration  float64 `json:"duration"`
+	DTS       float64 `json:"dts"`
 }
 
 // I23 in P1203 json
@@ -96,17 +101,11 @@ const test = "sda"
 const linux = "linux"
 const darwin = "darwin"
 
-// output strings for the main body of the P.1203 json file
-const bodyPrintStringHeader = "{\n    \"I11\": {\n        \"segments\": [\n            { \"bitrate\": %d, \"codec\": \"%s\", \"duration\": %s, \"start\": 0 }\n        ],\n        \"streamId\": 42\n    },\n    \"I13\": {\n        \"segments\": [\n"
-const bodyPrintString = "            {\n                \"bitrate\": %s,\n                \"codec\": \"%s\",\n                \"duration\": %s,\n                \"fps\": %s,\n                \"resolution\": \"%s\",\n                \"start\": %s\n            }"
-const bodyPrintStringTail = "\n        ],\n        \"streamId\": 42\n    },\n"
-
-// head and tail parts of the stall section of the P.1203 json file
-const stallHead = "    \"I23\": {\n        \"stalling\": ["
-const stallTail = "],\n        \"streamId\": 42\n    },\n"
+// the P.1203 stream id of the audio and video segments and the stalls
+const p1203StreamID = 42
 
-// device details of the P.1203 json file
-const deviceString = "    \"IGen\": {\n        \"device\": \"pc\",\n        \"displaySize\": \"1920x1080\",\n        \"viewingDistance\": \"150cm\"\n    }\n}"
+// the device of the P.1203 json file
+var p1203Device = IGen{Device: "pc", DisplaySize: "1920x1080", ViewingDistance: "150cm"}
 
 // GetOS : return a string equating to the current runtime operating system
 func GetOS() string {
@@ -115,87 +114,37 @@ func GetOS() string {
 }
 
 // createP1203 : create the P1203 value
-func createP1203(logMap *map[int]logging.SegPrintLogInformation, c chan float64, saveFilesBool bool, audioRate int, audioCodec string) {
+func createP1203(logMap *map[int]logging.SegPrintLogInformation, saveFilesBool bool, audioRate int, audioCodec string) float64 {
 
-	// for each of the logs, lets create a P.1203 compliant Json file
-	// get the body
-	bodyString := createP1203body(*logMap, audioRate, audioCodec)
-	// get the stalls
-	stallString := createP1203stalls(*logMap)
-	// add all the output together
-	jsonString := strings.Join([]string{bodyString, stallString, deviceString}, "")
+	// for each of the logs, lets create the P.1203 input
+	p1203 := createP1203input(*logMap, audioRate, audioCodec)
 
-	// save to file or just get the value
+	// save to file
 	if saveFilesBool {
-		// write the output to a json file (file for the last map in the log)
-		createP1203file(*logMap, jsonString)
-
-		// calculate the P1203 value and return to the channel
-		// c <- getP1203Val(*logMap)
-		// just get the value
-	}
-	// lets just get the overall P.1203 value that we want
-	/*out, err := exec.Command("bash", "-c", "python3 -c 'from itu_p1203 import P1203Standalone; print(P1203Standalone("+jsonString+").calculate_complete(True)[\"O46\"])'").Output()
-
-	if err != nil {
-		log.Fatal(err)
+		// write the input to a json file (file for the last map in the log)
+		createP1203file(*logMap, p1203)
 	}
 
-	// get the P1203 value and remove any return carrige
-	stringVal := strings.TrimSuffix(string(out), "\n")
-
-	// convert to float
-	p1203Value, err := strconv.ParseFloat(stringVal, 64)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// calculate the P1203 value and return to the channel
-	c <- p1203Value*/
-	c <- 0.0
-}
-
-// getP1203Val : return the P1203 value for this segment
-func getP1203Val(logMap map[int]logging.SegPrintLogInformation) (p1203Val float64) {
-
-	// needed for the read from json file
-	logSize := len(logMap)
-	fileInput := logMap[logSize].SegmentFileName + ".json"
-
-	// check if file exists
-	if _, err := os.Stat(fileInput); err != nil {
-		// input segment file does not exist, stop the app
-		fmt.Println("*** The file " + fileInput + " does not exist or cannot be found.  please check if correct path is used ***")
-		// stop the app
-		utils.StopApp()
-	}
-
-	// calculate the P1203 value - P1203 knows from looking at the generated json file what mode to use
-	out, err := exec.Command("bash", "-c", "python3 -m itu_p1203 --print-intermediate "+fileInput+" 2> /dev/null | tail -n 6 | head -n1 | cut -f 1 -d ',' | cut -f 4 -d ' '").Output()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// get the P1203 value and remove any return carrige
-	stringVal := strings.TrimSuffix(string(out), "\n")
-
-	// save this string as a float64
-	p1203Val, err = strconv.ParseFloat(stringVal, 64)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	return
+	// return the P1203 value of the session so far, the O.46 of the standard needs the random forest of P.1203.3,
+	// which is not implemented, so the score is the audiovisual quality O.35 the reference implementation agrees with
+	return CalculateP1203(p1203).O35
 }
 
 // createP1203file : create a P1203 json file for the last downloaded segment
-func createP1203file(log map[int]logging.SegPrintLogInformation, jsonString string) {
+func createP1203file(log map[int]logging.SegPrintLogInformation, p1203 P1203) {
 
 	// write the output to a json file (file for the last map in the log)
 	// needed for the write to file
 	logSize := len(log)
 	fileLocation := log[logSize].SegmentFileName + ".json"
 
+	jsonBytes, err := json.MarshalIndent(p1203, "", "    ")
+	if err != nil {
+		fmt.Println("*** unable to create the P.1203 input: " + err.Error() + " ***")
+		// stop the app
+		utils.StopApp()
+	}
+
 	// create the file to the provided file location
 	out, err := os.Create(fileLocation)
 	if err != nil {
@@ -205,8 +154,8 @@ func createP1203file(log map[int]logging.SegPrintLogInformation, jsonString stri
 	}
 	defer out.Close()
 
-	// Write the jsonString to file
-	_, err = out.Write([]byte(jsonString))
+	// Write the json to file
+	_, err = out.Write(jsonBytes)
 	if err != nil {
 		fmt.Println("*** " + fileLocation + " cannot be saved ***")
 		// stop the app
@@ -214,95 +163,95 @@ func createP1203file(log map[int]logging.SegPrintLogInformation, jsonString stri
 	}
 }
 
-// createP1203body : create the body of the json file
-func createP1203body(log map[int]logging.SegPrintLogInformation, audioRate int, audioCodec string) (bodyValues string) {
-
-	var bodyVal string
+// createP1203input : create the P.1203 input of the segments in log
+func createP1203input(log map[int]logging.SegPrintLogInformation, audioRate int, audioCodec string) P1203 {
 
-	// for each of the logs, lets create a P.1203 compliant Json file
+	// mode 1 needs the frames of every segment
+	modeOne := len(log) > 0
 	for a := 1; a <= len(log); a++ {
+		if len(log[a].FrameSizes) == 0 || len(log[a].FrameDurations) != len(log[a].FrameSizes) {
+			modeOne = false
+		}
+	}
 
-		// needed for main body
-		kbps := fmt.Sprintf("%.2f", log[a].P1203Kbps)
-		codec := log[a].RepCodec
-		segmentDuration := fmt.Sprintf("%.1f", float64(log[a].SegmentDuration))
-		fps := fmt.Sprintf("%.1f", float64(log[a].RepFps))
-		resolution := strconv.Itoa(log[a].RepWidth) + "x" + strconv.Itoa(log[a].RepHeight)
-		start := fmt.Sprintf("%.1f", float64(log[a].PlayStartPosition/glob.Conversion1000)-float64(log[a].SegmentDuration))
-
-		// local val
-		var bodyLoop string
-
-		// if we have been in this loop before, we need to add a comma to the end of the string
-		if len(log) > 1 && len(log) != a {
-			bodyLoop = bodyPrintString + "%s\n"
-			// get the body values
-			bodyVal = fmt.Sprintf(bodyLoop, kbps, codec, segmentDuration, fps, resolution, start, ",")
-		} else {
-			bodyVal = fmt.Sprintf(bodyPrintString, kbps, codec, segmentDuration, fps, resolution, start)
+	// the video segments
+	video := I13{StreamID: p1203StreamID}
+	for a := 1; a <= len(log); a++ {
+		// use the frame rate of the segment itself if we know it
+		fps := float64(log[a].RepFps)
+		if log[a].MediaFps > 0 {
+			fps = log[a].MediaFps
 		}
+		segment := VideoSegment{
+			Bitrate:    log[a].P1203Kbps,
+			Codec:      log[a].RepCodec,
+			Duration:   float64(log[a].SegmentDuration),
+			FPS:        fps,
+			Resolution: strconv.Itoa(log[a].RepWidth) + "x" + strconv.Itoa(log[a].RepHeight),
+			Start:      float64(log[a].PlayStartPosition/glob.Conversion1000) - float64(log[a].SegmentDuration),
+		}
+		if modeOne {
+			segment.Frames = createP1203frames(log[a], segment.Start)
+		}
+		video.VideoSegment = append(video.VideoSegment, segment)
+	}
 
-		// save them to our string
-		bodyValues = strings.Join([]string{bodyValues, bodyVal}, "")
+	// a single audio segment of the whole clip
+	// audio codec - ac-3 crashes P.1203, so leave as aac
+	audio := AudioSegment{Bitrate: 192, Codec: "aac", Duration: log[len(log)].PlayStartPosition / glob.Conversion1000}
+	if audioCodec != "" {
+		audio.Bitrate = audioRate
 	}
 
-	// needed for audio header
-	clipDuration := strconv.Itoa(log[len(log)].PlayStartPosition / glob.Conversion1000)
+	return P1203{
+		I11:  I11{AudioSegment: []AudioSegment{audio}, StreamID: p1203StreamID},
+		I13:  video,
+		I23:  I23{Stalling: createP1203stalls(log), StreamID: p1203StreamID},
+		IGen: p1203Device,
+	}
+}
 
-	//audioPrintHeader
-	var AudioHeaderVal string
-	if audioCodec == "" {
-		AudioHeaderVal = fmt.Sprintf(bodyPrintStringHeader, 192, "aac", clipDuration)
-	} else {
-		// audio codec - ac-3 crashes P.1203, so leave as aac
-		AudioHeaderVal = fmt.Sprintf(bodyPrintStringHeader, audioRate, "aac", clipDuration)
+// createP1203frames : create the frames of a segment from its sample sizes, durations and keyframes
+func createP1203frames(segment logging.SegPrintLogInformation, start float64) []Frame {
+
+	keyframes := make(map[int]bool)
+	for _, k := range segment.Keyframes {
+		keyframes[k] = true
 	}
 
-	// add all the stall values to the json head and tail
-	return strings.Join([]string{AudioHeaderVal, bodyValues, bodyPrintStringTail}, "")
+	frames := make([]Frame, len(segment.FrameSizes))
+	dts := start
+	for i, size := range segment.FrameSizes {
+		frameType := "Non-I"
+		if keyframes[i] {
+			frameType = "I"
+		}
+		frames[i] = Frame{FrameType: frameType, FrameSize: size, Duration: segment.FrameDurations[i], DTS: dts}
+		dts += segment.FrameDurations[i]
+	}
+	return frames
 }
 
-// createP1203stalls : create the stall string
-func createP1203stalls(log map[int]logging.SegPrintLogInformation) (stallValues string) {
+// createP1203stalls : create the stalls as [position, duration] in seconds
+func createP1203stalls(log map[int]logging.SegPrintLogInformation) [][]float64 {
 
-	// for each of the logs, lets create a P.1203 compliant Json file
+	var stalls [][]float64
 	for a := 1; a <= len(log); a++ {
 
-		// needed for stalls
-		stallTime := fmt.Sprintf("%.3f", float64(log[a].PlaybackTime/glob.Conversion1000))
-		// output is an int
-		// stallDuration := fmt.Sprintf("%.3f", float64(utils.Abs(log[a].StallTime)/glob.Conversion1000))
-		// output is a float
-		stallDuration := fmt.Sprintf("%.3f", (float64(utils.Abs(log[a].StallTime)) / float64(glob.Conversion1000)))
-
-		// local val
-		var stallLoop string
-
-		// different choices for stall outputs
-		switch a {
-		// a == 1
-		case 1:
-			stallLoop = "[%s,%s]"
-			// get the stall values
-			stallVal := fmt.Sprintf(stallLoop, stallTime, stallDuration)
-			// save them to our string
-			stallValues = strings.Join([]string{stallValues, stallVal}, "")
-		// default for all other cases
-		default:
-			// we only want stalls if there is a stall time
-			if utils.Abs(log[a].StallTime) > 0 {
-				stallLoop = ",[%s,%s]"
-				stallVal := fmt.Sprintf(stallLoop, stallTime, stallDuration)
-				stallValues = strings.Join([]string{stallValues, stallVal}, "")
-			}
+		stallTime := float64(log[a].PlaybackTime / glob.Conversion1000)
+		stallDuration := float64(utils.Abs(log[a].StallTime)) / float64(glob.Conversion1000)
+
+		// the first segment is always there, the initial loading
+		// for all other segments we only want stalls if there is a stall time
+		if a == 1 || utils.Abs(log[a].StallTime) > 0 {
+			stalls = append(stalls, []float64{stallTime, stallDuration})
 		}
 	}
-	// add all the stall values to the json head and tail
-	return strings.Join([]string{stallHead, stallValues, stallTail}, "")
+	return stalls
 }
 
 // GetKBPS : return the kbps value for this segment
-func GetKBPS(fileInput string, segDuration int64, debugLog bool, isByteRangeMPD bool, segSize int) (kbpsFloat float64) {
+func GetKBPS(fileInput string, segDuration int64, debugFile string, debugLog bool, isByteRangeMPD bool, segSize int) (kbpsFloat float64) {
 
 	// if this is a byte-range semgent, save the segment duration to withoutHeaderVal
 	withoutHeaderVal := int64(segSize)
@@ -310,57 +259,21 @@ func GetKBPS(fileInput string, segDuration int64, debugLog bool, isByteRangeMPD
 	// if this is not a byte-range semgent, calcualte the withoutHeaderVal
 	if !isByteRangeMPD {
 
-		// get the correct version of grep based on O/S
-		var grep string
-		var fi os.FileInfo
-		var err error
-
 		// check if file exists
-		if fi, err = os.Stat(fileInput); err != nil {
+		data, err := ioutil.ReadFile(fileInput)
+		if err != nil {
 			// input segment file does not exist, stop the app
 			fmt.Println("*** The segment locationed at " + fileInput + " does not exist or cannot be found.  please check if correct path is used ***")
 			// stop the app
 			utils.StopApp()
 		}
 
-		// set the version of grep we will use
-		switch GetOS() {
-		// linux use grep
-		case linux:
-			// size in bytes using grep
-			grep = "grep"
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "grep being used on Linux")
-		// mac use grep
-		case darwin:
-			// size in bytes using ggrep (from brew or port)
-			grep = "ggrep"
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "ggrep being used on OSX")
-		}
-
-		// get the location of this hex value in the input file - return 2 positions
-		out, err := exec.Command("bash", "-c", "LANG=C "+grep+" -obUaP \"\\x00\\x00\\x00\\x04\\x68\\xEF\\xBC\\x80\" "+fileInput+" | awk 'BEGIN{FS=\":\"}{print $1}'").Output()
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		// sometimes we can't read the hex value from the input segment
-		// in this instance we just use the entire segment size as input to P.1203
-		if !(len(out) == 0) {
-			logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "P1203 has the correct hex value ")
-
-			// get the index of the first return carrige
-			returnIndex := bytes.IndexByte(out, 10)
-			// save this value as a string
-			mdatValue := string(out[:returnIndex])
-			// convert this value to an int64
-			mdatValueInt, err := strconv.ParseInt(mdatValue, 10, 64)
-			if err != nil {
-				log.Fatal(err)
-			}
-			// add 8 bits for header
-			mdatValueInt += 8
-			// get the file byte size less the header
-			withoutHeaderVal = fi.Size() - mdatValueInt
+		// only the mdat payload is media, read it from the segment boxes
+		// if this is not a fragmented mp4 segment we use the entire segment size as input to P.1203
+		segInfo, err := http.ParseMP4Segment(data, nil)
+		if err == nil {
+			logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "P1203 has the mdat size of the segment")
+			withoutHeaderVal = segInfo.MediaBytes
 		}
 	}
 
@@ -371,7 +284,7 @@ func GetKBPS(fileInput string, segDuration int64, debugLog bool, isByteRangeMPD
 
 	kbpsFloatStringVal := fmt.Sprintf("%3f", kbpsFloat)
 
-	logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "P1203 bitrate is "+kbpsFloatStringVal)
+	logging.DebugPrint(debugFile, debugLog, "\nDEBUG: ", "P1203 bitrate is "+kbpsFloatStringVal)
 
 	return
 }
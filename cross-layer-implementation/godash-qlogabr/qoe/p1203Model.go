@@ -0,0 +1,419 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// P1203Scores : the outputs of the P.1203 model for a session
+type P1203Scores struct {
+	// per second audio quality (P.1203.2)
+	O21 []float64
+	// per second video quality (P.1203.1)
+	O22 []float64
+	// stall quality of the session, 1 + 4 times the stall impact
+	O23 float64
+	// per second audiovisual quality (P.1203.3)
+	O34 []float64
+	// audiovisual quality of the session, without stalls
+	O35 float64
+}
+
+// P.1203 output MOS range
+const (
+	p1203MOSmin = 1.0
+	p1203MOSmax = 5.0
+)
+
+// P.1203.2 audio coefficients, per codec
+var p1203AudioCoeffs = map[string][3]float64{
+	"mp2":   {100, -0.02, 15.48},
+	"ac3":   {100, -0.03, 15.70},
+	"aaclc": {100, -0.05, 14.60},
+	"heaac": {100, -0.11, 20.06},
+}
+
+// P.1203.1 mode 0 coefficients
+const (
+	pvA1 = 11.9983519
+	pvA2 = -2.99991847
+	pvA3 = 41.2475074001
+	pvA4 = 0.13183165961
+	pvQ1 = 4.66
+	pvQ2 = -0.07
+	pvQ3 = 4.06
+	pvU1 = 72.61
+	pvU2 = 0.32
+	pvT1 = 30.98
+	pvT2 = 1.29
+	pvT3 = 64.65
+	// handheld adjustment
+	pvHtv1 = -0.60293
+	pvHtv2 = 2.12382
+	pvHtv3 = -0.36936
+	pvHtv4 = 0.03409
+)
+
+// P.1203.3 coefficients
+const (
+	pqAv1   = -0.00069084
+	pqAv2   = 0.15374283
+	pqAv3   = 0.97153861
+	pqAv4   = 0.02461776
+	pqT1    = 0.00666620027943848
+	pqT2    = 0.0000404018840273729
+	pqT3    = 0.156497800436237
+	pqT4    = 0.143179744942738
+	pqT5    = 0.0238641564518876
+	pqCref7 = 0.48412879
+	pqCref8 = 10.0
+	pqC1    = 1.87403625
+	pqC23   = 0.01853820
+	pqS1    = 9.35158684
+	pqS2    = 0.91890815
+	pqS3    = 11.0567558
+)
+
+// CalculateP1203 :
+/*
+ * calculate the ITU-T P.1203 mode 0 scores of a session in process
+ * the input is the same as the P.1203 json file, I11 audio segments, I13 video segments,
+ * I23 stalls as [position, duration] in seconds and IGen the device
+ * Pv (P.1203.1 mode 0) and Pa (P.1203.2) give the per second O.22 and O.21 scores,
+ * Pq (P.1203.3) integrates them with the stalls into O.34, O.23 and O.35
+ * the random forest of P.1203.3 is not implemented, so there is no O.46
+ */
+func CalculateP1203(input P1203) P1203Scores {
+
+	// per segment scores
+	var videoScores []float64
+	for _, segment := range input.I13.VideoSegment {
+		videoScores = append(videoScores, P1203Pv(float64(segment.Bitrate), segment.Resolution, segment.FPS, input.IGen.DisplaySize, input.IGen.Device))
+	}
+	var audioScores []float64
+	for _, segment := range input.I11.AudioSegment {
+		audioScores = append(audioScores, P1203Pa(float64(segment.Bitrate), segment.Codec))
+	}
+
+	// sample the segment scores once per second of media
+	o22 := perSecondFrameScores(videoScores, input.I13.VideoSegment)
+
+	var starts, durations []float64
+	for _, segment := range input.I11.AudioSegment {
+		starts = append(starts, float64(segment.Start))
+		durations = append(durations, float64(segment.Duration))
+	}
+	o21 := perSecondScores(audioScores, starts, durations)
+
+	return P1203Pq(o21, o22, input.I23.Stalling)
+}
+
+// P1203Pv :
+/*
+ * P.1203.1 mode 0 video quality of a segment
+ * bitrate in kbps, resolution and displaySize as "<width>x<height>"
+ */
+func P1203Pv(bitrate float64, resolution string, fps float64, displaySize string, device string) float64 {
+
+	codingRes := pixels(resolution)
+	displayRes := pixels(displaySize)
+	if displayRes <= 0 {
+		displayRes = 1920 * 1080
+	}
+	if codingRes <= 0 {
+		codingRes = displayRes
+	}
+	if bitrate <= 0 || fps <= 0 {
+		return p1203MOSmin
+	}
+
+	// coding degradation
+	quant := pvA1 + pvA2*math.Log(pvA3+math.Log(bitrate)+math.Log(bitrate*bitrate/(codingRes*fps)+pvA4))
+	mosCod := constrain(pvQ1+pvQ2*math.Exp(pvQ3*quant), 1, 5)
+	degCod := constrain(100-rFromMOS(mosCod), 0, 100)
+
+	// upscaling degradation
+	scaleFactor := math.Max(displayRes/codingRes, 1)
+	degScal := constrain(pvU1*math.Log10(pvU2*(scaleFactor-1)+1), 0, 100)
+
+	// frame rate degradation
+	degFrameRate := 0.0
+	if fps < 24 {
+		degFrameRate = (100 - degCod - degScal) * (pvT1 - pvT2*fps) / (pvT3 + fps)
+	}
+	degFrameRate = constrain(degFrameRate, 0, 100)
+
+	// integration
+	degAll := constrain(degCod+degScal+degFrameRate, 0, 100)
+	score := mosFromR(100 - degAll)
+
+	if device == "mobile" || device == "handheld" {
+		score = constrain(pvHtv1+pvHtv2*score+pvHtv3*score*score+pvHtv4*score*score*score, 1, 5)
+	}
+	return score
+}
+
+// P1203Pa :
+// * P.1203.2 audio quality of a segment, bitrate in kbps, unknown codecs are scored as AAC-LC
+func P1203Pa(bitrate float64, codec string) float64 {
+
+	coeffs, ok := p1203AudioCoeffs[strings.ToLower(codec)]
+	if !ok {
+		coeffs = p1203AudioCoeffs["aaclc"]
+	}
+	qCod := coeffs[0]*math.Exp(coeffs[1]*bitrate) + coeffs[2]
+	return mosFromR(100 - qCod)
+}
+
+// P1203Pq :
+/*
+ * P.1203.3 integration of the per second audio (O.21) and video (O.22) scores and the stalls
+ * stalls are [position, duration] in seconds of media, a stall at position 0 is the initial loading
+ * returns the per second audiovisual scores (O.34), the stall quality (O.23), the session
+ * audiovisual score (O.35)
+ */
+func P1203Pq(o21 []float64, o22 []float64, stalls [][]float64) P1203Scores {
+
+	scores := P1203Scores{O21: o21, O22: o22, O23: p1203MOSmin, O35: p1203MOSmin}
+	duration := len(o22)
+	if duration == 0 {
+		return scores
+	}
+
+	// audiovisual quality per second
+	scores.O34 = make([]float64, duration)
+	for t := range o22 {
+		// without audio the audio quality has no impact
+		audio := p1203MOSmax
+		if len(o21) > 0 {
+			audio = o21[t*len(o21)/duration]
+		}
+		scores.O34[t] = constrain(pqAv1+pqAv2*audio+pqAv3*o22[t]+pqAv4*audio*o22[t], 1, 5)
+	}
+
+	scores.O35 = integrateO34(scores.O34)
+	scores.O23 = 1 + 4*stallImpact(stalls, float64(duration))
+
+	return scores
+}
+
+// integrateO34 :
+/*
+ * the session audiovisual quality, a recency weighted average of the per second scores
+ * less the impact of the lowest 10% of the scores
+ * the oscillation and adaptation compensations of P.1203.3 are not implemented,
+ * the reference implementation applies neither to the sessions of the reference tests
+ */
+func integrateO34(o34 []float64) float64 {
+
+	duration := float64(len(o34))
+
+	// recency and quality weighted average
+	var weightedSum, weightSum float64
+	for t, score := range o34 {
+		w1 := pqT1 + pqT2*math.Exp(float64(t)/duration/pqT3)
+		w2 := pqT4 - pqT5*score
+		weightedSum += w1 * w2 * score
+		weightSum += w1 * w2
+	}
+	baseline := weightedSum / weightSum
+
+	// negative bias, from the lowest 10% of the scores
+	diff := make([]float64, len(o34))
+	for t, score := range o34 {
+		diff[t] = score - baseline
+	}
+	negBias := math.Max(0, -percentile(diff, 10)) * pqC1 * pqC23
+
+	return constrain(baseline-negBias, 1, 5)
+}
+
+// stallImpact :
+/*
+ * the factor between 0 and 1 the audiovisual quality is scaled by, based on
+ * the number of stalls, their recency weighted length and the interval between them
+ * the weight of a stall halves every pqCref8 seconds before the end of the session,
+ * the initial loading only counts for a third of its length
+ */
+func stallImpact(stalls [][]float64, duration float64) float64 {
+
+	numStalls := 0
+	totalStallLen := 0.0
+	var positions []float64
+
+	for _, stall := range stalls {
+		if len(stall) < 2 || stall[1] <= 0 {
+			continue
+		}
+		position, length := stall[0], stall[1]
+		if position <= 0 {
+			totalStallLen += length / 3
+			continue
+		}
+		numStalls++
+		positions = append(positions, position)
+		// stalls at the end of the session weigh more
+		weight := pqCref7 + (1-pqCref7)*math.Exp(-(duration-position)*math.Ln2/pqCref8)
+		totalStallLen += length * weight
+	}
+
+	avgStallInterval := 0.0
+	if len(positions) > 1 {
+		sort.Float64s(positions)
+		avgStallInterval = (positions[len(positions)-1] - positions[0]) / float64(len(positions)-1)
+	}
+
+	return math.Exp(-float64(numStalls)/pqS1) *
+		math.Exp(-totalStallLen/duration/pqS2) *
+		math.Exp(-avgStallInterval/duration/pqS3)
+}
+
+// perSecondFrameScores :
+/*
+ * the score of the last video frame before the end of every second of media
+ * the frames of a segment are 1/fps apart from the end of the previous segment on,
+ * like the measurement window of the reference implementation
+ */
+func perSecondFrameScores(scores []float64, segments []VideoSegment) []float64 {
+
+	end := 0.0
+	for _, segment := range segments {
+		end = math.Max(end, segment.Start+segment.Duration)
+	}
+	seconds := int(math.Round(end))
+
+	var perSecond []float64
+	last := -1
+	dts := 0.0
+	for i, segment := range segments {
+		for frame := 0; frame < int(segment.Duration*segment.FPS); frame++ {
+			// every second before this frame ends with the previous frame
+			for last >= 0 && dts >= float64(len(perSecond)+1) && len(perSecond) < seconds {
+				perSecond = append(perSecond, scores[last])
+			}
+			last = i
+			dts += 1 / segment.FPS
+		}
+	}
+	for last >= 0 && len(perSecond) < seconds {
+		perSecond = append(perSecond, scores[last])
+	}
+	return perSecond
+}
+
+// perSecondScores :
+// * the score of the segment playing at every second of media, segments are sorted by start time
+func perSecondScores(scores []float64, starts []float64, durations []float64) []float64 {
+
+	if len(scores) == 0 {
+		return nil
+	}
+	end := 0.0
+	for i := range scores {
+		end = math.Max(end, starts[i]+durations[i])
+	}
+
+	var perSecond []float64
+	segment := 0
+	for t := 0; float64(t) < math.Round(end); t++ {
+		for segment+1 < len(scores) && starts[segment+1] <= float64(t) {
+			segment++
+		}
+		perSecond = append(perSecond, scores[segment])
+	}
+	return perSecond
+}
+
+// mosFromR :
+// * convert a quality rating in the range [0, 100] to a MOS, as in P.1203.1 annex
+func mosFromR(q float64) float64 {
+	const mosMax = 4.9
+	const mosMin = 1.05
+	if q <= 0 {
+		return mosMin
+	}
+	if q >= 100 {
+		return mosMax
+	}
+	return mosMin + (mosMax-mosMin)/100*q + q*(q-60)*(100-q)*7.0e-6
+}
+
+// rFromMOS :
+/*
+ * convert a MOS to a quality rating in the range [0, 100], the inverse of mosFromR
+ * mosFromR is the cubic q^3 - 160 q^2 + 500 q + (mos - mosMin) / 7.0e-6 = 0 in q,
+ * its middle root is the rating, the others are below 0 and above 100
+ */
+func rFromMOS(mos float64) float64 {
+	const mosMax = 4.9
+	const mosMin = 1.05
+	if mos <= mosMin {
+		return 0
+	}
+	mos = math.Min(mos, mosMax)
+
+	// the depressed cubic x^3 + p x + r = 0 of q = x + 160 / 3
+	const b, c = -160.0, 500.0
+	d := (mos - mosMin) / 7.0e-6
+	p := c - b*b/3
+	r := 2*b*b*b/27 - b*c/3 + d
+	return 2*math.Sqrt(-p/3)*math.Cos(math.Acos(3*r/(2*p)*math.Sqrt(-3/p))/3-2*math.Pi/3) - b/3
+}
+
+// pixels :
+// * the number of pixels of a "<width>x<height>" resolution, 0 if unknown
+func pixels(resolution string) float64 {
+	dimensions := strings.Split(strings.ToLower(resolution), "x")
+	if len(dimensions) != 2 {
+		return 0
+	}
+	width, err := strconv.Atoi(strings.TrimSpace(dimensions[0]))
+	if err != nil {
+		return 0
+	}
+	height, err := strconv.Atoi(strings.TrimSpace(dimensions[1]))
+	if err != nil {
+		return 0
+	}
+	return float64(width * height)
+}
+
+// percentile :
+// * the p-th percentile of values, with linear interpolation between the closest ranks
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// constrain :
+// * limit value to [min, max]
+func constrain(value float64, min float64, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
@@ -0,0 +1,111 @@
+package qoe
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+)
+
+// p1203Reference : the scores of the itu-p1203 reference implementation for a P.1203 json file
+type p1203Reference struct {
+	Mode int
+	O23  float64
+	O34  []float64
+	O35  float64
+}
+
+// readP1203Reference : the P.1203 json file of a session and the output of the reference for it
+func readP1203Reference(t *testing.T, name string) (P1203, p1203Reference) {
+	var input P1203
+	data, err := os.ReadFile("testdata/p1203/" + name + ".json")
+	if err == nil {
+		err = json.Unmarshal(data, &input)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the output is keyed on the json file the reference was run on
+	var output map[string]p1203Reference
+	data, err = os.ReadFile("testdata/p1203/" + name + ".itu-p1203.json")
+	if err == nil {
+		err = json.Unmarshal(data, &output)
+	}
+	if err != nil || len(output) != 1 {
+		t.Fatalf("%s: %v", name, err)
+	}
+	for _, reference := range output {
+		return input, reference
+	}
+	return input, p1203Reference{}
+}
+
+func TestCalculateP1203(t *testing.T) {
+	// goDASH sessions scored by itu-p1203 in mode 0, there are no mode 1 sessions as goDASH only implements mode 0
+	// the reference rounds the per second video scores to about 1e-5
+	tests := []struct {
+		name   string
+		stalls int
+	}{
+		{"bba2-ed-2s", 8},
+		{"bba2-bbb-4s", 1},
+		{"bba2cl-ofm-6s", 0},
+	}
+	for _, test := range tests {
+		input, reference := readP1203Reference(t, test.name)
+		if reference.Mode != 0 {
+			t.Fatalf("%s: the reference is of mode %d", test.name, reference.Mode)
+		}
+		scores := CalculateP1203(input)
+
+		if len(scores.O34) != len(reference.O34) {
+			t.Fatalf("%s: %d seconds of O.34, want %d", test.name, len(scores.O34), len(reference.O34))
+		}
+		for second := range reference.O34 {
+			if math.Abs(scores.O34[second]-reference.O34[second]) > 1e-4 {
+				t.Errorf("%s: O.34 of second %d is %f, want %f", test.name, second, scores.O34[second], reference.O34[second])
+			}
+		}
+		if math.Abs(scores.O23-reference.O23) > 1e-9 {
+			t.Errorf("%s: O.23 is %f, want %f", test.name, scores.O23, reference.O23)
+		}
+		if math.Abs(scores.O35-reference.O35) > 1e-4 {
+			t.Errorf("%s: O.35 is %f, want %f", test.name, scores.O35, reference.O35)
+		}
+		if (test.stalls == 0) != (scores.O23 == p1203MOSmax) {
+			t.Errorf("%s: %d stalls have an O.23 of %f", test.name, test.stalls, scores.O23)
+		}
+	}
+}
+
+func TestRFromMOS(t *testing.T) {
+	for _, q := range []float64{3.5, 10, 35, 49.1, 60, 72.2, 83.7, 99} {
+		if got := rFromMOS(mosFromR(q)); math.Abs(got-q) > 1e-9 {
+			t.Errorf("the rating of the MOS of %g is %g", q, got)
+		}
+	}
+	if rFromMOS(1) != 0 || math.Abs(rFromMOS(5)-100) > 1e-9 {
+		t.Errorf("the MOS out of range are rated %g and %g", rFromMOS(1), rFromMOS(5))
+	}
+}
+
+func TestPerSecondFrameScores(t *testing.T) {
+	// the frames of 1/25 second add up to a little more than 4 and 8 seconds, but to a little less than 12 seconds,
+	// so second 12 already ends with the first frame of the last segment
+	segments := []VideoSegment{
+		{Start: 0, Duration: 4, FPS: 25},
+		{Start: 4, Duration: 4, FPS: 25},
+		{Start: 8, Duration: 4, FPS: 25},
+		{Start: 12, Duration: 4, FPS: 25},
+	}
+	got := perSecondFrameScores([]float64{1, 2, 3, 4}, segments)
+	want := []float64{1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 4, 4, 4, 4, 4}
+	if len(got) != len(want) {
+		t.Fatalf("%d seconds, want %d", len(got), len(want))
+	}
+	for second := range want {
+		if got[second] != want[second] {
+			t.Errorf("second %d ends with segment %g, want %g", second+1, got[second], want[second])
+		}
+	}
+}
@@ -26,130 +26,47 @@ import (
 	"github.com/uccmisl/godash/logging"
 )
 
-// CreateQoE : get the P1203 and clae QoE values
-func CreateQoE(log *map[int]logging.SegPrintLogInformation, debugLog bool, initBuffer int, maxRepRate int, printHeadersData map[string]string, saveFilesBool bool, audioRate int, audioCodec string) {
+// CreateQoE :
+/*
+ * score the segments streamed so far with the registered QoE models
+ * and save the scores to the last log, a model is only used when its print header is on
+ * audio segments are not scored
+ */
+func CreateQoE(log *map[int]logging.SegPrintLogInformation, printHeadersData map[string]string, session Session) {
 
 	// *log does not support indexing :(
 	logMap := *log
+	locallog := logMap[len(logMap)]
 
-	// the P1203 standard only works for H264 (encoder) and up to resolutions of 1920x1080
-	// so make sure the received segments are compliant
-	P1023Print := checkInputHeader(printHeadersData, glob.P1203Header)
-	ClaePrint := checkInputHeader(printHeadersData, glob.ClaeHeader)
-	DuanmuPrint := checkInputHeader(printHeadersData, glob.DuanmuHeader)
-	YinPrint := checkInputHeader(printHeadersData, glob.YinHeader)
-	YuPrint := checkInputHeader(printHeadersData, glob.YuHeader)
-	AudioCheck := logMap[len(logMap)].MimeType == glob.RepRateCodecAudio
-
-	// create channels, so the output is in the right order
-	var P1023Results chan float64
-	P1023Results = make(chan float64)
-	var stopP1203 = true
-	if P1023Print && !AudioCheck {
-		logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "checking for P1203 compatibility")
-		for a := 1; a <= len(*log); a++ {
+	AudioCheck := locallog.MimeType == glob.RepRateCodecAudio
 
-			// get the encoder and resolution
-			width := logMap[a].RepWidth
-			height := logMap[a].RepHeight
-			codec := logMap[a].RepCodec
-
-			if codec != glob.RepRateCodecAVC || width > glob.P1203maxWidth || height > glob.P1203maxHeight {
-				logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "Downloaded segments are not P1203 compliant")
-				stopP1203 = false
-				// return
-			}
+	for _, model := range Models() {
+		if AudioCheck || !checkInputHeader(printHeadersData, model.Name()) {
+			continue
 		}
-		// create the P1203 value
-		if stopP1203 {
-			go createP1203(log, P1023Results, saveFilesBool, audioRate, audioCodec)
+		logging.DebugPrint(session.DebugFile, session.DebugLog, "\nDEBUG: ", "Getting "+model.Name()+" Value")
+		score, ok := model.Score(logMap, session)
+		if !ok {
+			continue
 		}
-	}
-
-	var claeResults chan float64
-	claeResults = make(chan float64)
-	if ClaePrint && !AudioCheck {
-		// create the Claye value
-		logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "Getting Claye Value")
-		go getClaye(*log, claeResults, maxRepRate, false)
-	}
-
-	var duanmuResults chan float64
-	duanmuResults = make(chan float64)
-	if DuanmuPrint && !AudioCheck {
-		// create the Duanmu value
-		logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "Getting Duanmu Value")
-		go getDuanmu(*log, duanmuResults, initBuffer, false)
-	}
 
-	var yinResults chan float64
-	yinResults = make(chan float64)
-	if YinPrint && !AudioCheck {
-		// create the Yin value
-		logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "Getting Yin Value")
-		go getYin(*log, yinResults, initBuffer, false)
-	}
-
-	var yuResults chan float64
-	yuResults = make(chan float64)
-	if YuPrint && !AudioCheck {
-		// create the Yu value
-		logging.DebugPrint(glob.DebugFile, debugLog, "\nDEBUG: ", "Getting Yu Value")
-		go getYu(*log, yuResults, false)
-	}
-
-	// // create the P1203 value
-	// go createP1203(log, P1023Results)
-	//
-	// // create the Claye value
-	// go getClaye(*log, claeResults, maxRepRate, false)
-	//
-	// // create the Duanmu value
-	// go getDuanmu(*log, duanmuResults, initBuffer, false)
-	//
-	// // create the Yin value
-	// go getYin(*log, yinResults, initBuffer, false)
-	//
-	// // create the Yu value
-	// go getYu(*log, yuResults, false)
-
-	// create a local copy of the log and allocate the QoE values
-	locallogMap := *log
-	locallog := locallogMap[len(locallogMap)]
-	// calculate the P1203, Claye, Duanmu, Yin and Yu values and
-	// save to the last log as 3 decimal floats
-	if YinPrint && !AudioCheck {
-		locallog.Yin = <-yinResults
-	} else {
-		locallog.Yin = 0.0
-	}
-
-	if YuPrint && !AudioCheck {
-		locallog.Yu = <-yuResults
-	} else {
-		locallog.Yu = 0.0
-	}
-
-	if DuanmuPrint && !AudioCheck {
-		locallog.Duanmu = <-duanmuResults
-	} else {
-		locallog.Duanmu = 0.0
-	}
-
-	if ClaePrint && !AudioCheck {
-		locallog.Clae = <-claeResults
-	} else {
-		locallog.Clae = 0.0
-	}
-
-	if P1023Print && stopP1203 && !AudioCheck {
-		locallog.P1203 = <-P1023Results
-	} else {
-		locallog.P1203 = 0.0
+		// save the score of the goDASH models to the last log
+		switch model.Name() {
+		case glob.P1203Header:
+			locallog.P1203 = score
+		case glob.ClaeHeader:
+			locallog.Clae = score
+		case glob.DuanmuHeader:
+			locallog.Duanmu = score
+		case glob.YinHeader:
+			locallog.Yin = score
+		case glob.YuHeader:
+			locallog.Yu = score
+		}
 	}
 
-	locallogMap[len(locallogMap)] = locallog
-	*log = locallogMap
+	logMap[len(logMap)] = locallog
+	*log = logMap
 }
 
 func checkInputHeader(printHeadersData map[string]string, key string) bool {
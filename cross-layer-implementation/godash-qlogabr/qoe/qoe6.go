@@ -23,12 +23,11 @@ package qoe
 
 import "github.com/uccmisl/godash/logging"
 
-func getQoE6(log map[int]logging.SegPrintLogInformation, c chan float64) {
+func getQoE6(log map[int]logging.SegPrintLogInformation) float64 {
 
 	// returned QoE value
 	returnedQoE := 6.000
 
-	// calculate the claye value and return to the channel
-	c <- returnedQoE
-
+	// return the QoE value
+	return returnedQoE
 }
@@ -0,0 +1,200 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package qoe
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/utils"
+)
+
+// SessionSummary : the QoE report of a stream
+type SessionSummary struct {
+	Algorithm string `json:"algorithm"`
+	// time from the start of the stream until the playback started
+	StartupDelayMs int `json:"startupDelayMs"`
+	// score of every registered model that could score the video segments
+	Models         map[string]float64     `json:"models"`
+	AdaptationSets []AdaptationSetSummary `json:"adaptationSets"`
+}
+
+// AdaptationSetSummary : the QoE report of one adaptation set of a stream
+type AdaptationSetSummary struct {
+	MimeType string `json:"mimeType"`
+	Segments int    `json:"segments"`
+	// stalls after the playback started
+	StallCount      int `json:"stallCount"`
+	StallDurationMs int `json:"stallDurationMs"`
+	// average rep_rate per segment and weighted by the segment durations
+	AverageBitrateKbps      float64 `json:"averageBitrateKbps"`
+	TimeWeightedBitrateKbps float64 `json:"timeWeightedBitrateKbps"`
+	// rep_rate changes between consecutive segments
+	SwitchCount                int     `json:"switchCount"`
+	AverageSwitchMagnitudeKbps float64 `json:"averageSwitchMagnitudeKbps"`
+	// downloads cancelled by the cross-layer stall predictor
+	AbortedSegments int           `json:"abortedSegments"`
+	WastedBytes     int           `json:"wastedBytes"`
+	WastedTimeMs    int           `json:"wastedTimeMs"`
+	Aborts          []AbortRecord `json:"aborts"`
+}
+
+// AbortRecord : a download cancelled by the cross-layer stall predictor
+type AbortRecord struct {
+	SegmentNumber int `json:"segmentNumber"`
+	// rep_rate of the cancelled download
+	RepRateKbps   int `json:"repRateKbps"`
+	BytesReceived int `json:"bytesReceived"`
+	ElapsedMs     int `json:"elapsedMs"`
+	// prediction the stall predictor cancelled the download on
+	PredictedRemainingMs int `json:"predictedRemainingMs"`
+	BufferLevelMs        int `json:"bufferLevelMs"`
+}
+
+// NewSessionSummary :
+/*
+ * summarise the logs of every adaptation set at the end of the stream
+ * the registered models score the first video adaptation set,
+ * only when scoreModels is set, as the models need the per segment QoE values of -QoE on
+ */
+func NewSessionSummary(logs []map[int]logging.SegPrintLogInformation, session Session, scoreModels bool) SessionSummary {
+
+	summary := SessionSummary{
+		Models: make(map[string]float64),
+	}
+
+	videoScored := false
+	for _, log := range logs {
+		if len(log) == 0 {
+			continue
+		}
+		summary.Algorithm = log[1].Adapt
+		summary.AdaptationSets = append(summary.AdaptationSets, summariseAdaptationSet(log, session.InitBuffer))
+
+		// playback starts when every adaptation set has its initial buffer
+		startSegment := session.InitBuffer
+		if startSegment > len(log) {
+			startSegment = len(log)
+		}
+		if startSegment < 1 {
+			startSegment = 1
+		}
+		if log[startSegment].ArrivalTime > summary.StartupDelayMs {
+			summary.StartupDelayMs = log[startSegment].ArrivalTime
+		}
+
+		if scoreModels && !videoScored && log[1].MimeType != glob.RepRateCodecAudio {
+			videoScored = true
+			for _, model := range Models() {
+				score, ok := model.Score(log, session)
+				// json has no NaN, a model without a value is left out
+				if ok && !math.IsNaN(score) && !math.IsInf(score, 0) {
+					summary.Models[model.Name()] = score
+				}
+			}
+		}
+	}
+
+	return summary
+}
+
+// summariseAdaptationSet :
+// * the stall, bitrate, switch and abort values of the log of one adaptation set
+func summariseAdaptationSet(log map[int]logging.SegPrintLogInformation, initBuffer int) AdaptationSetSummary {
+
+	summary := AdaptationSetSummary{
+		MimeType: log[1].MimeType,
+		Segments: len(log),
+		Aborts:   []AbortRecord{},
+	}
+
+	var sumRate, sumWeightedRate, sumDuration, sumSwitch float64
+	for a := 1; a <= len(log); a++ {
+		segment := log[a]
+
+		// the initial buffer is not a stall
+		if a > initBuffer && segment.StallTime != 0 {
+			summary.StallCount++
+			summary.StallDurationMs += utils.Abs(segment.StallTime)
+		}
+
+		rate := float64(segment.Bandwidth) / glob.Conversion1000
+		sumRate += rate
+		sumWeightedRate += rate * float64(segment.SegmentDuration)
+		sumDuration += float64(segment.SegmentDuration)
+
+		if a > 1 && segment.Bandwidth != log[a-1].Bandwidth {
+			summary.SwitchCount++
+			sumSwitch += math.Abs(float64(segment.Bandwidth-log[a-1].Bandwidth)) / glob.Conversion1000
+		}
+
+		if segment.Aborted {
+			summary.AbortedSegments++
+			summary.WastedBytes += segment.AbortedBytes
+			summary.WastedTimeMs += segment.AbortElapsed
+			summary.Aborts = append(summary.Aborts, AbortRecord{
+				SegmentNumber:        a,
+				RepRateKbps:          segment.AbortedRepRate / glob.Conversion1000,
+				BytesReceived:        segment.AbortedBytes,
+				ElapsedMs:            segment.AbortElapsed,
+				PredictedRemainingMs: segment.AbortPredicted,
+				BufferLevelMs:        segment.AbortBufferLevel,
+			})
+		}
+	}
+
+	summary.AverageBitrateKbps = sumRate / float64(len(log))
+	if sumDuration > 0 {
+		summary.TimeWeightedBitrateKbps = sumWeightedRate / sumDuration
+	}
+	if summary.SwitchCount > 0 {
+		summary.AverageSwitchMagnitudeKbps = sumSwitch / float64(summary.SwitchCount)
+	}
+
+	return summary
+}
+
+// WriteSessionSummary : save the session summary as json to fileName
+func WriteSessionSummary(summary SessionSummary, fileName string) {
+	writeReport(summary, "session summary", fileName)
+}
+
+// writeReport : save report as indented json to fileName
+func writeReport(report interface{}, name string, fileName string) {
+
+	data, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		fmt.Println("*** unable to create the " + name + ": " + err.Error() + " ***")
+		return
+	}
+
+	os.MkdirAll(filepath.Dir(fileName), os.ModePerm)
+	err = os.WriteFile(fileName, append(data, '\n'), 0644)
+	if err != nil {
+		fmt.Println("*** " + fileName + " cannot be saved ***")
+	}
+}
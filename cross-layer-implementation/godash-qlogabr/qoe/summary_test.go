@@ -0,0 +1,106 @@
+package qoe
+
+import (
+	"math"
+	"testing"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/logging"
+)
+
+// summaryTestLogs : the logs of a video and an audio adaptation set with an initial buffer of 2 segments
+func summaryTestLogs() []map[int]logging.SegPrintLogInformation {
+	video := map[int]logging.SegPrintLogInformation{
+		// the stall of the first segment is the initial buffer
+		1: {Adapt: "conventional", MimeType: "video/mp4", ArrivalTime: 900, StallTime: -500, Bandwidth: 1000000, SegmentDuration: 2},
+		2: {Adapt: "conventional", MimeType: "video/mp4", ArrivalTime: 1800, Bandwidth: 1000000, SegmentDuration: 2},
+		3: {Adapt: "conventional", MimeType: "video/mp4", ArrivalTime: 4200, StallTime: -1200, Bandwidth: 3000000, SegmentDuration: 2},
+		4: {Adapt: "conventional", MimeType: "video/mp4", ArrivalTime: 6000, Bandwidth: 2000000, SegmentDuration: 4},
+		5: {Adapt: "conventional", MimeType: "video/mp4", ArrivalTime: 9800, StallTime: 300, Bandwidth: 2000000, SegmentDuration: 4},
+	}
+	audio := map[int]logging.SegPrintLogInformation{
+		1: {Adapt: "conventional", MimeType: glob.RepRateCodecAudio, ArrivalTime: 1000, Bandwidth: 128000, SegmentDuration: 4},
+		2: {Adapt: "conventional", MimeType: glob.RepRateCodecAudio, ArrivalTime: 2100, Bandwidth: 128000, SegmentDuration: 4},
+		3: {Adapt: "conventional", MimeType: glob.RepRateCodecAudio, ArrivalTime: 5900, Bandwidth: 128000, SegmentDuration: 4},
+	}
+	return []map[int]logging.SegPrintLogInformation{video, audio, {}}
+}
+
+func TestNewSessionSummary(t *testing.T) {
+	summary := NewSessionSummary(summaryTestLogs(), Session{InitBuffer: 2}, false)
+
+	// playback starts when the audio adaptation set also has its initial buffer
+	if summary.Algorithm != "conventional" || summary.StartupDelayMs != 2100 {
+		t.Errorf("algorithm %s and startup delay %d ms", summary.Algorithm, summary.StartupDelayMs)
+	}
+	// the models are only scored with scoreModels, the empty log has no summary
+	if len(summary.Models) != 0 || len(summary.AdaptationSets) != 2 {
+		t.Fatalf("%d models and %d adaptation sets", len(summary.Models), len(summary.AdaptationSets))
+	}
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"segments", float64(summary.AdaptationSets[0].Segments), 5},
+		{"stall count", float64(summary.AdaptationSets[0].StallCount), 2},
+		{"stall duration", float64(summary.AdaptationSets[0].StallDurationMs), 1500},
+		{"average bitrate", summary.AdaptationSets[0].AverageBitrateKbps, 1800},
+		{"time weighted bitrate", summary.AdaptationSets[0].TimeWeightedBitrateKbps, (1000*2 + 1000*2 + 3000*2 + 2000*4 + 2000*4) / 14.0},
+		{"switch count", float64(summary.AdaptationSets[0].SwitchCount), 2},
+		{"switch magnitude", summary.AdaptationSets[0].AverageSwitchMagnitudeKbps, 1500},
+		{"audio stall count", float64(summary.AdaptationSets[1].StallCount), 0},
+		{"audio time weighted bitrate", summary.AdaptationSets[1].TimeWeightedBitrateKbps, 128},
+		{"audio switch count", float64(summary.AdaptationSets[1].SwitchCount), 0},
+	}
+	for _, test := range tests {
+		if math.Abs(test.got-test.want) > 1e-9 {
+			t.Errorf("%s is %f, want %f", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestSummariseAdaptationSetStartup(t *testing.T) {
+	// an initial buffer longer than the stream starts the playback at the last segment
+	log := summaryTestLogs()[1]
+	summary := NewSessionSummary([]map[int]logging.SegPrintLogInformation{log}, Session{InitBuffer: 10}, false)
+	if summary.StartupDelayMs != 5900 {
+		t.Errorf("the startup delay is %d ms, want 5900", summary.StartupDelayMs)
+	}
+	// every stall is then part of the initial buffer
+	if set := summariseAdaptationSet(summaryTestLogs()[0], 10); set.StallCount != 0 || set.StallDurationMs != 0 {
+		t.Errorf("%d stalls of %d ms in the initial buffer", set.StallCount, set.StallDurationMs)
+	}
+}
+
+func TestSummariseAdaptationSetAborts(t *testing.T) {
+	// the download of segment 4 at 3000 kbps was cancelled and replaced by the 2000 kbps segment
+	log := summaryTestLogs()[0]
+	segment := log[4]
+	segment.Aborted = true
+	segment.AbortedRepRate = 3000000
+	segment.AbortedBytes = 250000
+	segment.AbortElapsed = 800
+	segment.AbortPredicted = 4000
+	segment.AbortBufferLevel = 1500
+	log[4] = segment
+
+	summary := summariseAdaptationSet(log, 2)
+	if summary.AbortedSegments != 1 || summary.WastedBytes != 250000 || summary.WastedTimeMs != 800 {
+		t.Errorf("%d aborted segments wasted %d bytes and %d ms", summary.AbortedSegments, summary.WastedBytes, summary.WastedTimeMs)
+	}
+	want := AbortRecord{SegmentNumber: 4, RepRateKbps: 3000, BytesReceived: 250000, ElapsedMs: 800, PredictedRemainingMs: 4000, BufferLevelMs: 1500}
+	if len(summary.Aborts) != 1 || summary.Aborts[0] != want {
+		t.Errorf("the abort records are %+v, want %+v", summary.Aborts, want)
+	}
+	// the bitrate and the switches are of the segment that replaced the cancelled download
+	if summary.SwitchCount != 2 || summary.AverageBitrateKbps != 1800 {
+		t.Errorf("%d switches and an average bitrate of %f kbps", summary.SwitchCount, summary.AverageBitrateKbps)
+	}
+
+	// without aborts the records are an empty json list
+	if summary := summariseAdaptationSet(summaryTestLogs()[0], 2); summary.Aborts == nil || len(summary.Aborts) != 0 || summary.WastedBytes != 0 {
+		t.Errorf("the abort records without aborts are %v", summary.Aborts)
+	}
+}
@@ -27,7 +27,7 @@ import (
 	"github.com/uccmisl/godash/logging"
 )
 
-func getYin(log map[int]logging.SegPrintLogInformation, c chan float64, initBuffer int, printOutput bool) {
+func getYin(log map[int]logging.SegPrintLogInformation, initBuffer int, printOutput bool) float64 {
 
 	var totalStall float64
 	var nStalls int
@@ -115,7 +115,6 @@ func getYin(log map[int]logging.SegPrintLogInformation, c chan float64, initBuff
 		fmt.Println("Yin value: ", returnedQoE)
 	}
 
-	// calculate the Yin value and return to the channel
-	c <- float64(returnedQoE)
-
+	// return the Yin value
+	return float64(returnedQoE)
 }
@@ -27,7 +27,7 @@ import (
 	"github.com/uccmisl/godash/logging"
 )
 
-func getYu(log map[int]logging.SegPrintLogInformation, c chan float64, printOutput bool) {
+func getYu(log map[int]logging.SegPrintLogInformation, printOutput bool) float64 {
 
 	var totalStall float64
 	var segRates []float64
@@ -131,7 +131,6 @@ func getYu(log map[int]logging.SegPrintLogInformation, c chan float64, printOutp
 		fmt.Println("Yu value: ", returnedQoE)
 	}
 
-	// calculate the Yu value and return to the channel
-	c <- returnedQoE
-
+	// return the Yu value
+	return returnedQoE
 }
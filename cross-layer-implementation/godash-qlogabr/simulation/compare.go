@@ -0,0 +1,86 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package simulation
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/uccmisl/godash/qoe"
+)
+
+// Compare :
+/*
+ * run every algorithm of adapts over every trace, with the video and the settings of cfg
+ * the results are in the order of the traces, then of the algorithms
+ */
+func Compare(traces []Trace, adapts []string, video Video, cfg Config) ([]Result, error) {
+	var results []Result
+	for _, trace := range traces {
+		for _, adapt := range adapts {
+			cfg.Adapt = adapt
+			result, err := Run(trace, video, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("%s over %s: %w", adapt, trace.Name, err)
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// WriteTable : a row per result with the stalls, bitrate, switches and aborts of its video and the score of every QoE model
+func WriteTable(w io.Writer, results []Result) error {
+	var models []string
+	for _, model := range qoe.Models() {
+		models = append(models, model.Name())
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := "trace\talgorithm\tsegments\tavg kbps\tstalls\tstall ms\tswitches\taborts\tstartup ms"
+	for _, model := range models {
+		header += "\t" + model
+	}
+	fmt.Fprintln(tw, header+"\t")
+
+	for _, result := range results {
+		var video qoe.AdaptationSetSummary
+		if len(result.Summary.AdaptationSets) > 0 {
+			video = result.Summary.AdaptationSets[0]
+		}
+		row := fmt.Sprintf("%s\t%s\t%d\t%.0f\t%d\t%d\t%d\t%d\t%d", result.Trace, result.Adapt, video.Segments,
+			video.AverageBitrateKbps, video.StallCount, video.StallDurationMs, video.SwitchCount, video.AbortedSegments,
+			result.Summary.StartupDelayMs)
+		for _, model := range models {
+			score, ok := result.Summary.Models[model]
+			if !ok {
+				row += "\t-"
+				continue
+			}
+			row += "\t" + strconv.FormatFloat(score, 'f', 3, 64)
+		}
+		fmt.Fprintln(tw, row+"\t")
+	}
+	return tw.Flush()
+}
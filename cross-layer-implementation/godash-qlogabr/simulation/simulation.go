@@ -0,0 +1,697 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package simulation
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	algo "github.com/uccmisl/godash/algorithms"
+	xlayer "github.com/uccmisl/godash/crosslayer"
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/ladder"
+	"github.com/uccmisl/godash/logging"
+	"github.com/uccmisl/godash/predictor"
+	"github.com/uccmisl/godash/qoe"
+	"github.com/uccmisl/godash/utils"
+)
+
+// packetBytes : the payload of a simulated packet, the size of a full QUIC packet
+const packetBytes = 1252
+
+// abandonCheckInterval : time between two checks of the abandonment rule of BOLA-E, as in the player
+const abandonCheckInterval = 50 * time.Millisecond
+
+// simulationProtocol : the HTTP protocol of the log of a simulated segment
+const simulationProtocol = "simulation"
+
+// defaultStartupBytes : the size of the MPD and the init segment the fast start measures the link on
+const defaultStartupBytes = 16000
+
+// Config : a simulated stream, the zero values are the defaults of the goDASH flags
+type Config struct {
+	Adapt     string
+	Predictor string
+	// StreamDuration in seconds, 0 streams every segment of the video
+	StreamDuration int
+	StreamSpeed    float64
+	// MaxBuffer in seconds and InitBuffer in segments
+	MaxBuffer        int
+	InitBuffer       int
+	ExponentialRatio float64
+	BBA2             algo.BBA2Params
+	PredictionWindow float64
+	// AbortLogic of the stall predictor, the logic of Adapt if empty
+	AbortLogic string
+	MPC        algo.MPCParams
+	LoLP       algo.LoLPParams
+	// Pensieve : the learned policy of the Pensieve algorithms
+	Pensieve *algo.PensieveModel
+	// FastStart : the first rep_rate and the initial buffer from the startup downloads,
+	// the MPD and the init segment of StartupBytes are then downloaded over the link before the first segment
+	FastStart    algo.FastStartParams
+	StartupBytes int
+	// Constraints on the rep_rates of the video, replaced by the screens of ScreenSchedule on the clock of the simulation
+	Constraints    ladder.Constraints
+	ScreenSchedule []ladder.Change
+	// VBR : the throughput algorithms compare against the actual bitrate of the next segments
+	VBR algo.VBRParams
+}
+
+// Result : the log and the QoE of a simulated stream, as a live run logs and summarises them
+type Result struct {
+	Trace   string
+	Video   string
+	Adapt   string
+	Log     map[int]logging.SegPrintLogInformation
+	Summary qoe.SessionSummary
+	// Reservoirs : the reservoirs of every rep_rate BBA-2 selected, with the aborted segments of Log they show how the reservoirs and aborts interact
+	Reservoirs []algo.BBA2Reservoirs
+}
+
+// stream : the state of the player during a simulated stream
+type stream struct {
+	cfg        Config
+	trace      Trace
+	video      Video
+	bandwidths []int
+	lowest     int
+	// initBuffer in segments, the one of the fast start if it is enabled
+	initBuffer int
+
+	// now : the time since the start of the stream, the clock of the accountant
+	now        time.Duration
+	accountant *xlayer.CrossLayerAccountant
+
+	// ABR state
+	abr algo.State
+}
+
+// download : a simulated segment download
+type download struct {
+	bytes        int
+	deliveryTime int // in milliseconds
+	rtt          time.Duration
+	aborted      bool
+	// the decision of the abandonment rule of BOLA-E, nil if the rule did not abort the download
+	abandon *abandonment
+}
+
+// abandonment : the rep_rate BOLA-E downloads the segment at instead, and the prediction it abandoned on
+type abandonment struct {
+	repRate       int
+	receivedBytes int
+	predicted     int
+	bufferLevel   int
+}
+
+// Run :
+/*
+ * stream video over the link of trace with the algorithm of cfg, in simulated time
+ * the segments are downloaded in packets, so the cross-layer algorithms see the packets of a segment arrive
+ * Arbiter+ and BBA are not simulated, they request the segment sizes over HTTP
+ */
+func Run(trace Trace, video Video, cfg Config) (Result, error) {
+	cfg = cfg.withDefaults()
+	if err := trace.check(); err != nil {
+		return Result{}, err
+	}
+	video.Representations = append([]Representation(nil), video.Representations...)
+	if err := video.check(); err != nil {
+		return Result{}, err
+	}
+	switch cfg.Adapt {
+	case glob.ArbiterAlg, glob.BBAAlg:
+		return Result{}, errors.New(cfg.Adapt + " requests the segment sizes over HTTP and cannot be simulated")
+	}
+	if cfg.Predictor != "" && predictor.New(cfg.Predictor) == nil {
+		return Result{}, errors.New("there is no predictor " + cfg.Predictor)
+	}
+	if !cfg.Constraints.Valid() {
+		return Result{}, errors.New("the ladder constraints need limits that are not negative and a dynamic range of " + strings.Join(ladder.DynamicRanges, ", "))
+	}
+	if cfg.VBR.Horizon < 0 {
+		return Result{}, errors.New("the VBR layer needs a positive horizon")
+	}
+
+	s, err := newStream(trace, video, cfg)
+	if err != nil {
+		return Result{}, err
+	}
+	log, err := s.run()
+	if err != nil {
+		return Result{}, err
+	}
+
+	logs := []map[int]logging.SegPrintLogInformation{log}
+	result := Result{
+		Trace:   trace.Name,
+		Video:   video.Name,
+		Adapt:   cfg.Adapt,
+		Log:     log,
+		Summary: qoe.NewSessionSummary(logs, s.qoeSession(), true),
+	}
+	if algo.BBA2Based(cfg.Adapt) {
+		result.Reservoirs = algo.BBA2Trajectory(&s.abr.BBA2)
+	}
+	return result, nil
+}
+
+// withDefaults : cfg with the defaults of the goDASH flags for its zero values
+func (cfg Config) withDefaults() Config {
+	if cfg.Adapt == "" {
+		cfg.Adapt = glob.ConventionalAlg
+	}
+	if cfg.StreamSpeed == 0 {
+		cfg.StreamSpeed = 1
+	}
+	if cfg.MaxBuffer == 0 {
+		cfg.MaxBuffer = 30
+	}
+	if cfg.InitBuffer == 0 {
+		cfg.InitBuffer = 2
+	}
+	defaultFastStart := algo.DefaultFastStartParams()
+	if cfg.FastStart.Safety == 0 {
+		cfg.FastStart.Safety = defaultFastStart.Safety
+	}
+	if cfg.FastStart.Margin == 0 {
+		cfg.FastStart.Margin = defaultFastStart.Margin
+	}
+	if cfg.FastStart.StartupDelay == 0 {
+		cfg.FastStart.StartupDelay = defaultFastStart.StartupDelay
+	}
+	if cfg.StartupBytes == 0 {
+		cfg.StartupBytes = defaultStartupBytes
+	}
+	if cfg.VBR.Horizon == 0 {
+		cfg.VBR.Horizon = algo.DefaultVBRParams().Horizon
+	}
+	defaultBBA2 := algo.DefaultBBA2Params()
+	if cfg.BBA2.MinReservoir == 0 {
+		cfg.BBA2.MinReservoir = defaultBBA2.MinReservoir
+	}
+	if cfg.BBA2.UpperReservoir == 0 {
+		cfg.BBA2.UpperReservoir = defaultBBA2.UpperReservoir
+	}
+	if cfg.BBA2.Horizon == 0 {
+		cfg.BBA2.Horizon = defaultBBA2.Horizon
+	}
+	if cfg.BBA2.MaxReservoir == 0 {
+		cfg.BBA2.MaxReservoir = defaultBBA2.MaxReservoir
+	}
+	if cfg.BBA2.VarianceWeight == 0 {
+		cfg.BBA2.VarianceWeight = defaultBBA2.VarianceWeight
+	}
+	if cfg.PredictionWindow == 0 {
+		cfg.PredictionWindow = 0.15
+	}
+	if cfg.MPC == (algo.MPCParams{}) {
+		cfg.MPC = algo.DefaultMPCParams()
+	}
+	if cfg.MPC.Horizon == 0 {
+		cfg.MPC.Horizon = algo.DefaultMPCParams().Horizon
+	}
+	if cfg.LoLP.TargetLatency == 0 {
+		cfg.LoLP = algo.DefaultLoLPParams()
+	}
+	return cfg
+}
+
+// newStream : the player state of the algorithm of cfg, as the player initialises it
+func newStream(trace Trace, video Video, cfg Config) (*stream, error) {
+	s := &stream{
+		cfg:        cfg,
+		trace:      trace,
+		video:      video,
+		bandwidths: video.bandwidths(),
+		initBuffer: cfg.InitBuffer,
+		abr:        algo.State{Predictor: predictor.New(cfg.Predictor)},
+	}
+	s.lowest = utils.GetLowestRepRateIndex(s.bandwidths)
+
+	// the accountant runs on the clock of the simulation
+	epoch := time.Unix(0, 0)
+	s.accountant = &xlayer.CrossLayerAccountant{}
+	s.accountant.SetClock(func() time.Time {
+		return epoch.Add(s.now)
+	})
+
+	segmentDuration_Milliseconds := video.SegmentDuration * glob.Conversion1000
+	switch cfg.Adapt {
+	case glob.BBA2Alg_AV, glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_rate, glob.BBA2Alg_AVXL_double:
+		s.abr.BBA2 = algo.NewBBA2Data(video.Representations[s.lowest].Sizes, video.maxAvgRatios(), nil, cfg.BBA2)
+	case glob.BOLABasicAlg, glob.BOLAEAlg, glob.BOLAEXLAlg:
+		s.abr.BOLA = algo.NewBOLAData(s.bandwidths, video.chunkLists(), segmentDuration_Milliseconds, cfg.MaxBuffer, cfg.Adapt != glob.BOLABasicAlg, cfg.PredictionWindow)
+	case glob.MPCAlg, glob.MPCXLAlg:
+		s.abr.MPC = algo.NewMPCData(s.bandwidths, video.chunkLists(), segmentDuration_Milliseconds, cfg.MaxBuffer, cfg.MPC)
+	case glob.PensieveAlg, glob.PensieveXLAlg:
+		if cfg.Pensieve == nil {
+			return nil, errors.New(cfg.Adapt + " needs a learned policy")
+		}
+		var err error
+		s.abr.Pensieve, err = algo.NewPensieveData(s.bandwidths, video.chunkLists(), video.segments(), cfg.Pensieve)
+		if err != nil {
+			return nil, err
+		}
+	case glob.L2AAlg:
+		s.abr.L2A = algo.NewL2AData(s.bandwidths, segmentDuration_Milliseconds)
+	case glob.LoLPAlg:
+		s.abr.LoLP = algo.NewLoLPData(s.bandwidths, segmentDuration_Milliseconds, cfg.StreamSpeed, cfg.LoLP)
+	}
+	if cfg.VBR.Enabled {
+		s.abr.SegmentSizes = algo.NewSegmentSizes(video.chunkLists(), segmentDuration_Milliseconds)
+		if algo.BBA2Based(cfg.Adapt) {
+			algo.SetBBA2SegmentSizes(&s.abr.BBA2, s.abr.SegmentSizes)
+		}
+	}
+
+	// the cross-layer algorithms predict stalls, with the abort logic of the algorithm unless one is set
+	var abortLogic xlayer.AbortLogic
+	predictStall := true
+	switch cfg.Adapt {
+	case glob.BBA1Alg_AVXL, glob.BBA2Alg_AVXL_base:
+		abortLogic = xlayer.Base
+	case glob.BBA2Alg_AVXL_rate:
+		abortLogic = xlayer.Rate
+	case glob.BBA2Alg_AVXL_double:
+		abortLogic = xlayer.Double
+	default:
+		predictStall = false
+	}
+	if logic, ok := xlayer.ParseAbortLogic(cfg.AbortLogic); ok {
+		abortLogic = logic
+	}
+	if predictStall {
+		s.accountant.InitialisePredictor(nil, abortLogic, float32(cfg.PredictionWindow))
+	}
+	return s, nil
+}
+
+// qoeSession : the stream values of the QoE models
+func (s *stream) qoeSession() qoe.Session {
+	return qoe.Session{
+		InitBuffer: s.initBuffer,
+		MaxRepRate: s.bandwidths[0],
+	}
+}
+
+// run :
+/*
+ * stream the segments like the stream loop of the player does for one adaptation set
+ * returns the log of every segment, from segment 1
+ */
+func (s *stream) run() (map[int]logging.SegPrintLogInformation, error) {
+	cfg := s.cfg
+	segmentDuration_Milliseconds := s.video.SegmentDuration * glob.Conversion1000
+
+	segments := s.video.segments()
+	if cfg.StreamDuration > 0 {
+		segments = utils.Min(segments, utils.Max(1, cfg.StreamDuration/s.video.SegmentDuration))
+	}
+
+	// every QoE model scores the segments
+	printHeadersData := make(map[string]string)
+	for _, model := range qoe.Models() {
+		printHeadersData[model.Name()] = "on"
+	}
+
+	log := make(map[int]logging.SegPrintLogInformation)
+	streamSpeed := cfg.StreamSpeed
+	repRate := s.lowest
+	if cfg.FastStart.Enabled {
+		repRate, s.initBuffer = s.fastStart()
+		switch cfg.Adapt {
+		case glob.BBA2Alg_AV, glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_rate, glob.BBA2Alg_AVXL_double:
+			if repRate != s.lowest {
+				algo.SetBBA2FastStart(&s.abr.BBA2)
+			}
+		}
+	}
+	if allowed := s.allowedRepRates(); allowed != nil {
+		repRate = ladder.Select(allowed, repRate)
+	}
+	// like the player, the arrival times run from the end of the startup downloads
+	start := s.now
+	bufferLevel := 0
+	lastArrival := start
+	playing := false
+	waitToPlayCounter := 0
+	segmentDurationTotal := 0
+	playPosition := 0
+
+	for segmentNumber := 1; segmentNumber <= segments; segmentNumber++ {
+		// the buffer does not drain before playback starts, so the initial buffer of the fast start is not aborted
+		d := s.download(repRate, segmentNumber, bufferLevel, !cfg.FastStart.Enabled || waitToPlayCounter >= s.initBuffer)
+
+		aborted := d.aborted
+		abortedBytes, abortedRepRate, abortElapsed, abortPredicted, abortBufferLevel := 0, 0, 0, 0, 0
+		if aborted {
+			// the bytes and time of the cancelled download are wasted
+			abortedBytes = s.accountant.ReceivedBytes()
+			abortedRepRate = s.bandwidths[repRate]
+			abortElapsed = d.deliveryTime
+			abortPredicted, abortBufferLevel = s.accountant.AbortPrediction()
+			if d.abandon != nil {
+				abortedBytes = d.abandon.receivedBytes
+				abortPredicted, abortBufferLevel = d.abandon.predicted, d.abandon.bufferLevel
+			}
+
+			// Reset BBA2 to startup parameters
+			switch cfg.Adapt {
+			case glob.BBA2Alg_AV, glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_rate, glob.BBA2Alg_AVXL_double:
+				algo.ResetBBAData_afterAbort(&s.abr.BBA2, bufferLevel)
+			}
+			// the segment is downloaded again at the lowest rep_rate, or at the rep_rate of BOLA-E, without aborts
+			repRate = s.lowest
+			if d.abandon != nil {
+				repRate = d.abandon.repRate
+			}
+			d = s.transfer(repRate, segmentNumber, nil)
+		}
+
+		arrivalTime := int((s.now - start).Milliseconds())
+		deliveryTime := d.deliveryTime
+		// like the player, the time of this segment runs from the arrival of the segment before it
+		thisRunTimeVal := int((s.now - lastArrival).Milliseconds())
+		lastArrival = s.now
+
+		stallTime := 0
+		if playing || s.initBuffer <= waitToPlayCounter {
+			playing = true
+			// get the current buffer (excluding the current segment)
+			ownBuffer := bufferLevel - int(float64(thisRunTimeVal)*streamSpeed)
+			if ownBuffer < 0 {
+				stallTime = ownBuffer
+			}
+			bufferLevel = utils.Max(ownBuffer, 0) + segmentDuration_Milliseconds
+		} else {
+			bufferLevel += segmentDuration_Milliseconds
+		}
+		waitToPlayCounter++
+
+		// wait until the buffer is down to the max buffer
+		if bufferLevel > cfg.MaxBuffer*glob.Conversion1000 {
+			sleepTime := int(float64(bufferLevel-(cfg.MaxBuffer*glob.Conversion1000)) / streamSpeed)
+			s.now += time.Duration(sleepTime) * time.Millisecond
+			bufferLevel -= int(float64(sleepTime) * streamSpeed)
+		}
+
+		if s.initBuffer < waitToPlayCounter {
+			playPosition = segmentDurationTotal + segmentDuration_Milliseconds - bufferLevel
+		}
+		segmentDurationTotal += segmentDuration_Milliseconds
+
+		segSize := d.bytes
+		thr := algo.CalculateThroughtput(segSize*8, deliveryTime)
+		rep := s.video.Representations[repRate]
+		log[segmentNumber] = s.logSegment(log, segmentNumber, logging.SegPrintLogInformation{
+			ArrivalTime:       arrivalTime,
+			DeliveryTime:      deliveryTime,
+			StallTime:         stallTime,
+			Bandwidth:         s.bandwidths[repRate],
+			DelRate:           thr,
+			ActRate:           (segSize * 8) / segmentDuration_Milliseconds,
+			SegSize:           segSize,
+			P1203Kbps:         float64(segSize*8) / float64(segmentDuration_Milliseconds),
+			BufferLevel:       bufferLevel,
+			Adapt:             cfg.Adapt,
+			SegmentDuration:   s.video.SegmentDuration,
+			ExtendPrintLog:    true,
+			RepCodec:          rep.Codec,
+			RepWidth:          rep.Width,
+			RepHeight:         rep.Height,
+			RepFps:            rep.FrameRate,
+			PlayStartPosition: segmentDurationTotal,
+			PlaybackTime:      playPosition,
+			Rtt:               float64(d.rtt.Nanoseconds()) / (glob.Conversion1000 * glob.Conversion1000),
+			RepIndex:          repRate,
+			SegmentIndex:      segmentNumber,
+			HTTPprotocol:      simulationProtocol,
+			MimeType:          rep.MimeType,
+			Aborted:           aborted,
+			AbortedBytes:      abortedBytes,
+			AbortedRepRate:    abortedRepRate,
+			AbortElapsed:      abortElapsed,
+			AbortPredicted:    abortPredicted,
+			AbortBufferLevel:  abortBufferLevel,
+		})
+		qoe.CreateQoE(&log, printHeadersData, s.qoeSession())
+
+		var err error
+		repRate, streamSpeed, err = s.decide(repRate, segmentNumber, bufferLevel, thr, deliveryTime, d.rtt, stallTime, streamSpeed, playing)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return log, nil
+}
+
+// logSegment : info with the rates, stalls and switches of the segments so far, as the player logs them with -QoE on
+func (s *stream) logSegment(log map[int]logging.SegPrintLogInformation, segmentNumber int, info logging.SegPrintLogInformation) logging.SegPrintLogInformation {
+	rate := float64(info.Bandwidth)
+	if segmentNumber == 1 {
+		info.SegmentRates = []float64{rate}
+		info.SumSegRate = rate
+		info.TotalStallDur = float64(info.StallTime)
+		if info.StallTime > 0 {
+			info.NumStalls = 1
+		}
+		return info
+	}
+
+	previous := log[segmentNumber-1]
+	info.SegmentRates = append(append([]float64(nil), previous.SegmentRates...), rate)
+	info.SumSegRate = previous.SumSegRate + rate
+	info.TotalStallDur = float64(previous.StallTime) + float64(info.StallTime)
+	info.NumStalls = previous.NumStalls
+	if info.StallTime > 0 {
+		info.NumStalls++
+	}
+	info.NumSwitches = previous.NumSwitches
+	if info.Bandwidth != previous.Bandwidth {
+		info.NumSwitches++
+	}
+	info.RateDifference = math.Abs(rate - float64(previous.Bandwidth))
+	info.SumRateChange = previous.SumRateChange + info.RateDifference
+	info.RateChange = append(append([]float64(nil), previous.RateChange...), info.RateDifference)
+	return info
+}
+
+// download :
+/*
+ * download segment segmentNumber at repRate, with the accounting and the aborts of the algorithm
+ * as the player sets them up before it requests the segment, the stall predictor only runs with predictStall
+ */
+func (s *stream) download(repRate int, segmentNumber int, bufferLevel int, predictStall bool) download {
+	cfg := s.cfg
+	rep := s.video.Representations[repRate]
+	nextSegmentLowerReprateChunkSize := rep.size(segmentNumber + 1)
+	if repRate > 0 {
+		nextSegmentLowerReprateChunkSize = s.video.Representations[repRate-1].size(segmentNumber + 1)
+	}
+
+	// the stall predictor of the accountant cancels the download
+	aborted := false
+	cancel := func() {}
+
+	timing := false
+	// a predictor and the dynamic reservoir of BBA-2 also sample the packet-level throughput of the segment
+	if s.abr.Predictor != nil || (cfg.BBA2.Dynamic && algo.BBA2Based(cfg.Adapt)) {
+		s.accountant.SegmentStart()
+		timing = true
+	}
+	switch cfg.Adapt {
+	case glob.MeanAverageXLAlg, glob.MeanAverageRecentXLAlg, glob.BBA1Alg_AV, glob.BBA2Alg_AV:
+		s.accountant.StartTiming()
+		timing = true
+	case glob.BBA1Alg_AVXL, glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_rate, glob.BBA2Alg_AVXL_double:
+		if repRate != s.lowest && predictStall {
+			s.accountant.SegmentStart_predictStall(s.video.SegmentDuration, s.bandwidths[repRate], bufferLevel, cancel, &aborted, cfg.MaxBuffer*glob.Conversion1000, s.bandwidths[s.lowest], rep.size(segmentNumber), nextSegmentLowerReprateChunkSize, algo.Get_BBA2_LowerReservoir(bufferLevel, cfg.MaxBuffer, s.bandwidths, s.video.SegmentDuration*glob.Conversion1000, segmentNumber, &s.abr.BBA2))
+			timing = true
+		}
+	}
+
+	// BOLA-E abandons a download on its rate so far, estimated from the last segment or measured by the accountant
+	var rate func(elapsed int) float64
+	if repRate != s.lowest {
+		switch cfg.Adapt {
+		case glob.BOLAEAlg:
+			if len(s.abr.ThrList) > 0 {
+				lastThr := float64(s.abr.ThrList[len(s.abr.ThrList)-1])
+				rate = func(elapsed int) float64 {
+					return lastThr
+				}
+			}
+		case glob.BOLAEXLAlg:
+			s.accountant.SegmentStart()
+			timing = true
+			rate = func(elapsed int) float64 {
+				if elapsed <= 0 {
+					return 0
+				}
+				return float64(s.accountant.ReceivedBytes()*8) / (float64(elapsed) / 1000)
+			}
+		}
+	}
+
+	// MPC-CL plans with the throughput of the packets of the segment, and it is an input of pensieveXL
+	if cfg.Adapt == glob.MPCXLAlg || cfg.Adapt == glob.PensieveXLAlg {
+		s.accountant.SegmentStart()
+		timing = true
+	}
+
+	var abandon *abandonment
+	nextCheck := int(abandonCheckInterval.Milliseconds())
+	d := s.transfer(repRate, segmentNumber, func(elapsed int) bool {
+		if aborted {
+			return true
+		}
+		if rate == nil || elapsed < nextCheck {
+			return false
+		}
+		nextCheck = elapsed + int(abandonCheckInterval.Milliseconds())
+		downloadRate := rate(elapsed)
+		newRepRate, predicted, abandoned := algo.BOLAAbandon(repRate, segmentNumber, downloadRate, elapsed, bufferLevel, &s.abr.BOLA)
+		if abandoned {
+			abandon = &abandonment{
+				repRate:       newRepRate,
+				receivedBytes: int(downloadRate * float64(elapsed) / 1000 / 8),
+				predicted:     predicted,
+				bufferLevel:   utils.Max(bufferLevel-elapsed, 0),
+			}
+		}
+		return abandoned
+	})
+	if timing {
+		s.accountant.StopTiming()
+	}
+	d.abandon = abandon
+	return d
+}
+
+// transfer :
+/*
+ * the packets of segment segmentNumber at repRate arrive over the link of the trace, one round trip after the request
+ * the accountant receives every packet, stop is asked after every packet with the milliseconds since the request
+ * and aborts the download when it returns true
+ */
+func (s *stream) transfer(repRate int, segmentNumber int, stop func(elapsed int) bool) download {
+	return s.transferBytes(s.accountant, s.video.Representations[repRate].size(segmentNumber)/8, stop)
+}
+
+// transferBytes : the packets of a response of bytes arrive over the link of the trace at accountant, see transfer
+func (s *stream) transferBytes(accountant *xlayer.CrossLayerAccountant, bytes int, stop func(elapsed int) bool) download {
+	start := s.now
+	point, _ := s.trace.at(start)
+	d := download{rtt: point.RTT}
+	s.now += point.RTT
+
+	remaining := bytes
+	for remaining > 0 {
+		point, next := s.trace.at(s.now)
+		if point.Bandwidth <= 0 {
+			// the link is down until the next point of the trace
+			s.now = next
+			continue
+		}
+		length := utils.Min(remaining, packetBytes)
+		s.now += time.Duration(float64(length*8) / float64(point.Bandwidth*glob.Conversion1000) * float64(time.Second))
+		remaining -= length
+		d.bytes += length
+		accountant.PacketReceived(length)
+
+		if stop != nil && remaining > 0 && stop(int((s.now - start).Milliseconds())) {
+			d.aborted = true
+			break
+		}
+	}
+	d.deliveryTime = utils.Max(int((s.now - start).Milliseconds()), 1)
+	return d
+}
+
+// fastStart :
+/*
+ * open the connection and download the MPD and the init segment over the link, as one response of StartupBytes
+ * returns the first rep_rate and the initial buffer the fast start selects on the capacity of the download
+ */
+func (s *stream) fastStart() (int, int) {
+	probe, done := s.accountant.StartupProbe()
+	// the handshake takes a round trip before the request goes out
+	point, _ := s.trace.at(s.now)
+	s.now += point.RTT
+	s.accountant.RTTMeasured(point.RTT, point.RTT)
+	s.transferBytes(probe, s.cfg.StartupBytes, nil)
+	done()
+
+	capacity := algo.StartupCapacity(s.accountant.StartupSamples(), s.accountant.HandshakeRTT(), s.accountant.ZeroRTT())
+	return algo.FastStart(capacity, s.bandwidths, 0, s.lowest, s.video.SegmentDuration*glob.Conversion1000, s.cfg.InitBuffer, s.cfg.FastStart)
+}
+
+// allowedRepRates : the rep_rates the ladder constraints allow now, nil without constraints
+func (s *stream) allowedRepRates() []bool {
+	constraints := ladder.At(s.cfg.Constraints, s.cfg.ScreenSchedule, s.now)
+	if !constraints.Active() {
+		return nil
+	}
+	return ladder.Allowed(s.video.rungs(), constraints, 0, s.lowest)
+}
+
+// decide :
+/*
+ * the rep_rate of the next segment, and the playback rate for LoL+, as the player selects them after a segment
+ */
+func (s *stream) decide(repRate int, segmentNumber int, bufferLevel int, thr int, deliveryTime int, rtt time.Duration, stallTime int, streamSpeed float64, playing bool) (int, float64, error) {
+	cfg := s.cfg
+	out, err := algo.Decide(algo.Decision{
+		Adapt:                        cfg.Adapt,
+		SegmentNumber:                segmentNumber,
+		RepRate:                      repRate,
+		BufferLevel:                  bufferLevel,
+		Throughput:                   thr,
+		DeliveryTime:                 deliveryTime,
+		StallTime:                    stallTime,
+		RTT:                          rtt,
+		SmoothedRTT:                  rtt,
+		StreamSpeed:                  streamSpeed,
+		MaxBuffer:                    cfg.MaxBuffer,
+		MaxBufferLevel:               cfg.MaxBuffer,
+		SegmentDuration_Milliseconds: s.video.SegmentDuration * glob.Conversion1000,
+		Bandwidths:                   s.bandwidths,
+		Lowest:                       s.lowest,
+		Allowed:                      s.allowedRepRates(),
+		ExponentialRatio:             cfg.ExponentialRatio,
+		VBR:                          cfg.VBR,
+		BBA2Dynamic:                  cfg.BBA2.Dynamic,
+		Accountant:                   s.accountant,
+	}, &s.abr)
+	if err != nil {
+		return repRate, streamSpeed, err
+	}
+	// the playback rate only changes once the playback started
+	if playing {
+		streamSpeed = out.PlaybackRate
+	}
+	return out.RepRate, streamSpeed, nil
+}
@@ -0,0 +1,276 @@
+package simulation
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	algo "github.com/uccmisl/godash/algorithms"
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/ladder"
+	"github.com/uccmisl/godash/utils"
+)
+
+// the rep_rates of the 4 second Big Buck Bunny of the paper
+var paperLadder = []int{45226, 88783, 128503, 177437, 217761, 255865, 323047, 378355, 509091, 577751, 782553, 1008699, 1207152, 1473801, 2087347}
+
+// paperVideo : the ladder of the paper, with segments from 70% to 130% of their bandwidth
+func paperVideo(segments int) Video {
+	video := ConstantBitrateVideo("bbb-4s", paperLadder, 4, segments)
+	for i := range video.Representations {
+		for j := range video.Representations[i].Sizes {
+			video.Representations[i].Sizes[j] = video.Representations[i].Sizes[j] * (70 + (j*37)%61) / 100
+		}
+	}
+	return video
+}
+
+func stallTime(t *testing.T, adapt string) int {
+	t.Helper()
+	result, err := Run(BBABufferingPaper, paperVideo(45), Config{Adapt: adapt, MaxBuffer: 60, InitBuffer: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Log) != 45 {
+		t.Fatalf("%s streamed %d segments and not 45", adapt, len(result.Log))
+	}
+	return result.Summary.AdaptationSets[0].StallDurationMs
+}
+
+func TestCrossLayerBBA2StallsNoMoreThanBBA2(t *testing.T) {
+	bba2 := stallTime(t, glob.BBA2Alg_AV)
+	for _, adapt := range []string{glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_double} {
+		if stall := stallTime(t, adapt); stall > bba2 {
+			t.Errorf("%s stalled for %d ms and BBA2 for %d ms", adapt, stall, bba2)
+		}
+	}
+}
+
+func TestFastStart(t *testing.T) {
+	for _, adapt := range []string{glob.BBA2Alg_AVXL_base, glob.BBA2Alg_AVXL_double} {
+		cfg := Config{Adapt: adapt, MaxBuffer: 60, InitBuffer: 2}
+		lowest, err := Run(BBABufferingPaper, paperVideo(45), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.FastStart = algo.FastStartParams{Enabled: true}
+		fast, err := Run(BBABufferingPaper, paperVideo(45), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fast.Log[1].RepIndex >= lowest.Log[1].RepIndex {
+			t.Errorf("%s starts at rep_rate %d with the fast start and at %d without", adapt, fast.Log[1].RepIndex, lowest.Log[1].RepIndex)
+		}
+		// the initial buffer downloads within the startup delay of the fast start, and a round trip per segment
+		if fast.Summary.StartupDelayMs > 1100 {
+			t.Errorf("%s starts after %d ms with the fast start", adapt, fast.Summary.StartupDelayMs)
+		}
+		if stall, stallLowest := fast.Summary.AdaptationSets[0].StallDurationMs, lowest.Summary.AdaptationSets[0].StallDurationMs; stall > stallLowest {
+			t.Errorf("%s stalled for %d ms with the fast start and for %d ms without", adapt, stall, stallLowest)
+		}
+	}
+}
+
+func TestDynamicBBA2Reservoir(t *testing.T) {
+	for _, adapt := range []string{glob.BBA2Alg_AV, glob.BBA2Alg_AVXL_double} {
+		cfg := Config{Adapt: adapt, MaxBuffer: 60, InitBuffer: 2}
+		static, err := Run(BBABufferingPaper, paperVideo(45), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.BBA2 = algo.BBA2Params{Dynamic: true}
+		dynamic, err := Run(BBABufferingPaper, paperVideo(45), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(dynamic.Reservoirs) == 0 {
+			t.Fatalf("%s logged no reservoirs", adapt)
+		}
+		grown := false
+		for i, reservoirs := range dynamic.Reservoirs {
+			if reservoirs.Lower > 30000 || reservoirs.Lower+reservoirs.Upper >= 60000 {
+				t.Errorf("%s has a lower reservoir of %d ms and an upper reservoir of %d ms in a 60 s buffer", adapt, reservoirs.Lower, reservoirs.Upper)
+			}
+			if reservoirs.Lower > static.Reservoirs[i].Lower {
+				grown = true
+			}
+		}
+		// the throughput of the trace drops, so the reservoir grows over the one of the chunk sizes
+		if !grown {
+			t.Errorf("the dynamic reservoir of %s never grows", adapt)
+		}
+		if stall, staticStall := dynamic.Summary.AdaptationSets[0].StallDurationMs, static.Summary.AdaptationSets[0].StallDurationMs; stall > staticStall {
+			t.Errorf("%s stalled for %d ms with the dynamic reservoir and for %d ms without", adapt, stall, staticStall)
+		}
+	}
+}
+
+func TestLadderConstraints(t *testing.T) {
+	trace := Trace{Name: "constant", Points: []TracePoint{{Bandwidth: 10000, RTT: 20 * time.Millisecond}}}
+	video := ConstantBitrateVideo("cbr", []int{8000000, 4000000, 1000000, 500000}, 2, 30)
+	for i, height := range []int{2160, 1080, 720, 360} {
+		video.Representations[i].Height = height
+	}
+
+	// the screen is a phone after 20 seconds, as if the window of the player was made smaller
+	schedule, err := ladder.Schedule(ladder.Constraints{}, []string{"0:tv", "20:phone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Run(trace, video, Config{Adapt: glob.ConventionalAlg, ScreenSchedule: schedule})
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone := false
+	for segment := 1; segment <= len(result.Log); segment++ {
+		bandwidth := result.Log[segment].Bandwidth
+		if phone && bandwidth > 1000000 {
+			t.Errorf("segment %d is %d bps on the phone", segment, bandwidth)
+		}
+		phone = phone || bandwidth == 1000000 && segment > 1
+	}
+	if result.Log[2].Bandwidth != 8000000 {
+		t.Errorf("the second segment is %d bps on the tv", result.Log[2].Bandwidth)
+	}
+	if !phone || result.Log[len(result.Log)].Bandwidth != 1000000 {
+		t.Errorf("the stream never streams the 720p rep_rate of the phone")
+	}
+
+	// the rep_rates of the algorithm are within the limit from the first segment on
+	result, err = Run(trace, video, Config{Adapt: glob.BBA2Alg_AV, Constraints: ladder.Constraints{MaxBitrate: 4000000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	highest := 0
+	for _, info := range result.Log {
+		highest = utils.Max(highest, info.Bandwidth)
+	}
+	if highest != 4000000 {
+		t.Errorf("the highest rep_rate is %d bps with a limit of 4000000 bps", highest)
+	}
+
+	// the algorithms with data of the ladder select among the rep_rates of the screen, before and after the change
+	for _, adapt := range []string{glob.BOLABasicAlg, glob.MPCAlg, glob.L2AAlg, glob.BBA2Alg_AV} {
+		result, err := Run(trace, video, Config{Adapt: adapt, ScreenSchedule: schedule})
+		if err != nil {
+			t.Fatal(adapt, err)
+		}
+		for segment := 1; segment <= len(result.Log); segment++ {
+			// the phone is set after 20 seconds of the stream, the segments of 2 seconds after it are on the phone
+			if result.Log[segment].ArrivalTime > 22000 && result.Log[segment].Bandwidth > 1000000 {
+				t.Errorf("%s streams segment %d at %d bps on the phone", adapt, segment, result.Log[segment].Bandwidth)
+			}
+		}
+		if last := result.Log[len(result.Log)].Bandwidth; last != 1000000 {
+			t.Errorf("%s streams the last segment at %d bps, want the 720p rep_rate of the phone", adapt, last)
+		}
+	}
+}
+
+func TestVBR(t *testing.T) {
+	trace := Trace{Name: "constant", Points: []TracePoint{{Bandwidth: 3000, RTT: 20 * time.Millisecond}}}
+	// the 2 Mbps rep_rate is encoded at 3.5 Mbps, over the capacity of the link
+	video := ConstantBitrateVideo("vbr", []int{4000000, 2000000, 1000000}, 2, 30)
+	for i := range video.Representations[1].Sizes {
+		video.Representations[1].Sizes[i] = 3500000 * 2
+	}
+
+	cfg := Config{Adapt: glob.ConventionalAlg, MaxBuffer: 10, InitBuffer: 2}
+	nominal, err := Run(trace, video, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.VBR = algo.VBRParams{Enabled: true}
+	actual, err := Run(trace, video, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nominal.Summary.AdaptationSets[0].StallDurationMs == 0 {
+		t.Errorf("the bandwidth of the MPD fits the link, so the stream should stall")
+	}
+	if stall := actual.Summary.AdaptationSets[0].StallDurationMs; stall != 0 {
+		t.Errorf("the stream stalled for %d ms on the actual bitrates", stall)
+	}
+	for segment := 2; segment <= len(actual.Log); segment++ {
+		if bandwidth := actual.Log[segment].Bandwidth; bandwidth != 1000000 {
+			t.Errorf("segment %d is %d bps, the actual bitrates only fit 1000000 bps", segment, bandwidth)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	adapts := []string{glob.ConventionalAlg, glob.ElasticAlg, glob.MeanAverageXLAlg, glob.BBA2Alg_AVXL_rate,
+		glob.BOLAEXLAlg, glob.MPCXLAlg, glob.L2AAlg, glob.LoLPAlg}
+	results, err := Compare([]Trace{BBABufferingPaper}, adapts, paperVideo(20), Config{MaxBuffer: 60, Predictor: "ewma"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var table bytes.Buffer
+	if err := WriteTable(&table, results); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + table.String())
+	if lines := strings.Count(table.String(), "\n"); lines != len(adapts)+1 {
+		t.Errorf("the table has %d lines and not %d", lines, len(adapts)+1)
+	}
+
+	if _, err := Run(BBABufferingPaper, paperVideo(20), Config{Adapt: glob.BBAAlg}); err == nil {
+		t.Error("BBA needs HTTP and was simulated")
+	}
+}
+
+func TestConstantLink(t *testing.T) {
+	trace := Trace{Name: "constant", Points: []TracePoint{{Bandwidth: 10000, RTT: 20 * time.Millisecond}}}
+	result, err := Run(trace, ConstantBitrateVideo("cbr", []int{8000000, 4000000, 1000000}, 2, 10), Config{Adapt: glob.ConventionalAlg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a 1 Mbps segment of 2 seconds takes 200 ms and a round trip at 10 Mbps
+	if first := result.Log[1]; first.Bandwidth != 1000000 || first.DeliveryTime != 220 {
+		t.Errorf("the first segment is %d bps in %d ms", first.Bandwidth, first.DeliveryTime)
+	}
+	if last := result.Log[10]; last.Bandwidth != 8000000 || last.StallTime != 0 {
+		t.Errorf("the last segment is %d bps with a stall of %d ms", last.Bandwidth, last.StallTime)
+	}
+}
+
+func TestParseNetemScenario(t *testing.T) {
+	file, err := os.Open("../../../tc-netem-shaper/scenarios/bba_buffering_paper.sh")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer file.Close()
+	trace, err := ParseNetemScenario(BBABufferingPaper.Name, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(trace, BBABufferingPaper) {
+		t.Errorf("the scenario is %v and not %v", trace, BBABufferingPaper)
+	}
+}
+
+func TestLoadTrace(t *testing.T) {
+	fileName := t.TempDir() + "/trace.txt"
+	data := "# seconds kbps rtt\n0 5000 30\n2.5 0\n3 800 50\n"
+	if err := os.WriteFile(fileName, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	trace, err := LoadTrace(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TracePoint{
+		{At: 0, Bandwidth: 5000, RTT: 30 * time.Millisecond},
+		{At: 2500 * time.Millisecond, Bandwidth: 0, RTT: 30 * time.Millisecond},
+		{At: 3 * time.Second, Bandwidth: 800, RTT: 50 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(trace.Points, want) {
+		t.Errorf("the trace is %v and not %v", trace.Points, want)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+// Package simulation : run the ABR algorithms over recorded bandwidth traces, without HTTP
+package simulation
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TracePoint : the link from At until the next point of the trace
+type TracePoint struct {
+	At time.Duration
+	// Bandwidth in kbps, like the rates of the tc-netem scenarios
+	Bandwidth int
+	RTT       time.Duration
+}
+
+// Trace : a bandwidth and RTT time series, the last point lasts until the end of the stream
+type Trace struct {
+	Name   string
+	Points []TracePoint
+}
+
+// BBABufferingPaper :
+/*
+ * the bba_buffering_paper scenario of the tc-netem shaper, 20ms of delay each way
+ * 2 Mbps, 100 kbps for 30 seconds, 1 Mbps, 100 kbps for 30 seconds and 2 Mbps again
+ */
+var BBABufferingPaper = Trace{
+	Name: "bba_buffering_paper",
+	Points: []TracePoint{
+		{At: 0, Bandwidth: 2000, RTT: 40 * time.Millisecond},
+		{At: 10 * time.Second, Bandwidth: 100, RTT: 40 * time.Millisecond},
+		{At: 40 * time.Second, Bandwidth: 1000, RTT: 40 * time.Millisecond},
+		{At: 50 * time.Second, Bandwidth: 100, RTT: 40 * time.Millisecond},
+		{At: 80 * time.Second, Bandwidth: 2000, RTT: 40 * time.Millisecond},
+	},
+}
+
+// LoadTrace :
+/*
+ * read a trace file, one point per line
+ * "<seconds> <bandwidth in kbps> [<RTT in milliseconds>]", lines starting with # are comments
+ * a point without RTT keeps the RTT of the point before it
+ */
+func LoadTrace(fileName string) (Trace, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return Trace{}, err
+	}
+	defer f.Close()
+
+	trace := Trace{Name: strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))}
+	var rtt time.Duration
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return Trace{}, errors.New(fileName + ":" + strconv.Itoa(line) + ": want <seconds> <kbps> [<rtt ms>]")
+		}
+		at, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return Trace{}, errors.New(fileName + ":" + strconv.Itoa(line) + ": " + err.Error())
+		}
+		bandwidth, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return Trace{}, errors.New(fileName + ":" + strconv.Itoa(line) + ": " + err.Error())
+		}
+		if len(fields) == 3 {
+			rttMs, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return Trace{}, errors.New(fileName + ":" + strconv.Itoa(line) + ": " + err.Error())
+			}
+			rtt = time.Duration(rttMs * float64(time.Millisecond))
+		}
+		trace.Points = append(trace.Points, TracePoint{At: time.Duration(at * float64(time.Second)), Bandwidth: bandwidth, RTT: rtt})
+	}
+	if err := scanner.Err(); err != nil {
+		return Trace{}, err
+	}
+	return trace, trace.check()
+}
+
+// ParseNetemScenario :
+/*
+ * the trace of a scenario script of the tc-netem shaper, from its "setNetwork <delay ms> <kbit> <loss>" and "sleep <seconds>" lines
+ * the delay is added in both directions, so the RTT is twice the delay, the loss is not simulated
+ */
+func ParseNetemScenario(name string, r io.Reader) (Trace, error) {
+	trace := Trace{Name: name}
+	var at time.Duration
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// the definition of setNetwork is not a change of the link
+		if len(fields) < 2 || strings.HasPrefix(fields[1], "(") {
+			continue
+		}
+		switch fields[0] {
+		case "setNetwork":
+			if len(fields) < 3 {
+				return Trace{}, errors.New(name + ": setNetwork needs a delay and a rate")
+			}
+			delay, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return Trace{}, errors.New(name + ": " + err.Error())
+			}
+			bandwidth, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return Trace{}, errors.New(name + ": " + err.Error())
+			}
+			point := TracePoint{At: at, Bandwidth: bandwidth, RTT: time.Duration(2 * delay * float64(time.Millisecond))}
+			// a change without a sleep replaces the point before it
+			if n := len(trace.Points); n > 0 && trace.Points[n-1].At == at {
+				trace.Points[n-1] = point
+			} else {
+				trace.Points = append(trace.Points, point)
+			}
+		case "sleep":
+			seconds, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return Trace{}, errors.New(name + ": " + err.Error())
+			}
+			at += time.Duration(seconds * float64(time.Second))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Trace{}, err
+	}
+	return trace, trace.check()
+}
+
+// check : a trace starts at 0, is in order and its last point has bandwidth
+func (t Trace) check() error {
+	if len(t.Points) == 0 {
+		return errors.New(t.Name + ": the trace has no points")
+	}
+	if t.Points[0].At != 0 {
+		return errors.New(t.Name + ": the trace does not start at 0")
+	}
+	for i, point := range t.Points {
+		if point.Bandwidth < 0 || point.RTT < 0 {
+			return errors.New(t.Name + ": negative bandwidth or RTT at " + point.At.String())
+		}
+		if i > 0 && point.At < t.Points[i-1].At {
+			return errors.New(t.Name + ": the points are not in order at " + point.At.String())
+		}
+	}
+	if t.Points[len(t.Points)-1].Bandwidth == 0 {
+		return errors.New(t.Name + ": the link is down at the end of the trace")
+	}
+	return nil
+}
+
+// at : the point of the link at time d, and the time of the next point, 0 for the last point
+func (t Trace) at(d time.Duration) (TracePoint, time.Duration) {
+	i := 0
+	for i+1 < len(t.Points) && t.Points[i+1].At <= d {
+		i++
+	}
+	if i+1 < len(t.Points) {
+		return t.Points[i], t.Points[i+1].At
+	}
+	return t.Points[i], 0
+}
@@ -0,0 +1,229 @@
+/*
+ *	goDASH, golang client emulator for DASH video streaming
+ *	Copyright (c) 2019, Jason Quinlan, Darijo Raca, University College Cork
+ *											[j.quinlan,d.raca]@cs.ucc.ie)
+ *                      Maëlle Manifacier, MISL Summer of Code 2019, UCC
+ *	This program is free software; you can redistribute it and/or
+ *	modify it under the terms of the GNU General Public License
+ *	as published by the Free Software Foundation; either version 2
+ *	of the License, or (at your option) any later version.
+ *
+ *	This program is distributed in the hope that it will be useful,
+ *	but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *	GNU General Public License for more details.
+ *
+ *	You should have received a copy of the GNU General Public License
+ *	along with this program; if not, write to the Free Software
+ *	Foundation, Inc., 51 Franklin Street, Fifth Floor, Boston, MA
+ *	02110-1301, USA.
+ */
+
+package simulation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	glob "github.com/uccmisl/godash/global"
+	"github.com/uccmisl/godash/http"
+	"github.com/uccmisl/godash/ladder"
+	"github.com/uccmisl/godash/utils"
+)
+
+// simulationMimeType : the mime type of a representation that has none
+const simulationMimeType = "video/mp4"
+
+// Representation : a rep_rate of the simulated video
+type Representation struct {
+	// Bandwidth in bps
+	Bandwidth int
+	// Codec as in the log, e.g. h264
+	Codec     string
+	MimeType  string
+	Width     int
+	Height    int
+	FrameRate int
+	HDR       bool
+	// Sizes of the segments in bits, from segment 1
+	Sizes []int
+}
+
+// Video : the segment size table of an adaptation set
+type Video struct {
+	Name string
+	// SegmentDuration in seconds
+	SegmentDuration int
+	// Representations with the highest bandwidth first, the order of the rep_rate indexes of the player
+	Representations []Representation
+}
+
+// VideoFromMPD :
+/*
+ * the video of an adaptation set of an MPD, with the segment sizes of its <chunks>
+ * see http.BuildSegmentSizeIndex to fill in the chunks of an MPD without them
+ */
+func VideoFromMPD(name string, mpd http.MPD, adaptationSet int) (Video, error) {
+	if len(mpd.Periods) == 0 || adaptationSet >= len(mpd.Periods[0].AdaptationSet) {
+		return Video{}, errors.New(name + ": the MPD has no adaptation set " + strconv.Itoa(adaptationSet))
+	}
+	_, segmentDurations := http.GetSegmentDetails([]http.MPD{mpd}, 0, adaptationSet)
+
+	video := Video{Name: name, SegmentDuration: segmentDurations[0]}
+	for _, rep := range mpd.Periods[0].AdaptationSet[adaptationSet].Representation {
+		sizes, err := utils.GetChunkList(rep.Chunks)
+		if err != nil || len(sizes) == 0 {
+			return Video{}, errors.New(name + ": rep_rate " + strconv.Itoa(rep.BandWidth) + " has no segment sizes")
+		}
+		video.Representations = append(video.Representations, Representation{
+			Bandwidth: rep.BandWidth,
+			Codec:     logCodec(rep.Codecs),
+			MimeType:  rep.MimeType,
+			Width:     rep.Width,
+			Height:    rep.Height,
+			FrameRate: rep.FrameRate,
+			HDR:       ladder.HDR(mpd.Periods[0].AdaptationSet[adaptationSet], rep),
+			Sizes:     sizes,
+		})
+	}
+	return video, video.check()
+}
+
+// LoadVideo : the video of a segment size file of http.BuildSegmentSizeIndex, with segments of segmentDuration seconds
+func LoadVideo(fileName string, segmentDuration int) (Video, error) {
+	if _, err := os.Stat(fileName); err != nil {
+		return Video{}, err
+	}
+	video := Video{
+		Name:            strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName)),
+		SegmentDuration: segmentDuration,
+	}
+	for bandwidth, sizes := range http.ReadSegmentSizeFile(fileName) {
+		video.Representations = append(video.Representations, Representation{Bandwidth: bandwidth, Sizes: sizes})
+	}
+	return video, video.check()
+}
+
+// ConstantBitrateVideo : a video of segments that are all the size of their bandwidth
+func ConstantBitrateVideo(name string, bandwidths []int, segmentDuration int, segments int) Video {
+	video := Video{Name: name, SegmentDuration: segmentDuration}
+	for _, bandwidth := range bandwidths {
+		sizes := make([]int, segments)
+		for i := range sizes {
+			sizes[i] = bandwidth * segmentDuration
+		}
+		video.Representations = append(video.Representations, Representation{Bandwidth: bandwidth, Sizes: sizes})
+	}
+	video.sort()
+	return video
+}
+
+// check : a video has segments of every rep_rate, the highest bandwidth first
+func (v *Video) check() error {
+	if v.SegmentDuration <= 0 {
+		return errors.New(v.Name + ": the segment duration must be a positive number of seconds")
+	}
+	if len(v.Representations) == 0 {
+		return errors.New(v.Name + ": the video has no rep_rates")
+	}
+	v.sort()
+	for i := range v.Representations {
+		if v.Representations[i].MimeType == "" {
+			v.Representations[i].MimeType = simulationMimeType
+		}
+	}
+	if v.segments() == 0 {
+		return errors.New(v.Name + ": a rep_rate of the video has no segments")
+	}
+	return nil
+}
+
+// sort : the highest bandwidth first
+func (v *Video) sort() {
+	sort.SliceStable(v.Representations, func(i, j int) bool {
+		return v.Representations[i].Bandwidth > v.Representations[j].Bandwidth
+	})
+}
+
+// segments : the number of segments every rep_rate has
+func (v Video) segments() int {
+	segments := len(v.Representations[0].Sizes)
+	for _, rep := range v.Representations {
+		if len(rep.Sizes) < segments {
+			segments = len(rep.Sizes)
+		}
+	}
+	return segments
+}
+
+// bandwidths : the bandwithList of the player
+func (v Video) bandwidths() []int {
+	var bandwidths []int
+	for _, rep := range v.Representations {
+		bandwidths = append(bandwidths, rep.Bandwidth)
+	}
+	return bandwidths
+}
+
+// size : the size in bits of segment segmentNumber, from 1, 0 past the last segment like utils.GetChunk
+func (r Representation) size(segmentNumber int) int {
+	if segmentNumber < 1 || segmentNumber > len(r.Sizes) {
+		return 0
+	}
+	return r.Sizes[segmentNumber-1]
+}
+
+// chunkLists : the segment sizes in bits of every rep_rate
+func (v Video) chunkLists() [][]int {
+	var chunkLists [][]int
+	for _, rep := range v.Representations {
+		chunkLists = append(chunkLists, rep.Sizes)
+	}
+	return chunkLists
+}
+
+// rungs : the rungs of the representations, for the ladder constraints
+func (v Video) rungs() []ladder.Rung {
+	rungs := make([]ladder.Rung, len(v.Representations))
+	for i, rep := range v.Representations {
+		rungs[i] = ladder.Rung{Width: rep.Width, Height: rep.Height, FrameRate: rep.FrameRate, Bandwidth: rep.Bandwidth, HDR: rep.HDR}
+	}
+	return rungs
+}
+
+// maxAvgRatios : the ratio of the largest to the average segment of every rep_rate, as BBA-2 reads it from the MPD
+func (v Video) maxAvgRatios() []float32 {
+	var ratios []float32
+	for _, rep := range v.Representations {
+		sum, max := 0, 0
+		for _, size := range rep.Sizes {
+			sum += size
+			max = utils.Max(max, size)
+		}
+		ratio := 0.0
+		if sum > 0 {
+			ratio = float64(max) / (float64(sum) / float64(len(rep.Sizes)))
+		}
+		ratios = append(ratios, float32(ratio))
+	}
+	return ratios
+}
+
+// logCodec : the codec of the log of an MPD codecs attribute
+func logCodec(codecs string) string {
+	switch {
+	case strings.Contains(codecs, "avc"):
+		return glob.RepRateCodecAVC
+	case strings.Contains(codecs, "hev"), strings.Contains(codecs, "hvc1"):
+		return glob.RepRateCodecHEVC
+	case strings.Contains(codecs, "vp"):
+		return glob.RepRateCodecVP9
+	case strings.Contains(codecs, "av1"):
+		return glob.RepRateCodecAV1
+	}
+	return codecs
+}
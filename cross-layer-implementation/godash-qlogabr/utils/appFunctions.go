@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -67,7 +68,8 @@ func RecoverPanic() {
 // * Setup the debug log file
 func WriteFile(fileLocation string) {
 
-	// create the debug log file
+	// create the debug log file, and the folder it is in
+	os.MkdirAll(filepath.Dir(fileLocation), os.ModePerm)
 	f, err := os.Create(fileLocation)
 	if err != nil {
 		// print an error
@@ -147,10 +149,16 @@ func CheckFloatVal(p *float64, name *float64) {
 	}
 }
 
+// GetChunk :
+// * returns the size in bits of segment pos from a comma separated chunk list
+// * returns 0 if the representation has no chunk sizes for this segment
 func GetChunk(chunkList string, pos int) int {
 	// Segments start at 1, but list index starts at 0. Offset the pos
 	pos = pos - 1
 	chunkListSplit := strings.Split(chunkList, ",")
+	if chunkList == "" || pos < 0 || pos >= len(chunkListSplit) {
+		return 0
+	}
 	val, err := strconv.Atoi(chunkListSplit[pos])
 	if err != nil {
 		fmt.Println(err)
@@ -159,6 +167,23 @@ func GetChunk(chunkList string, pos int) int {
 	return val
 }
 
+// GetChunkList :
+// * returns all segment sizes in bits of a comma separated chunk list
+func GetChunkList(chunkList string) ([]int, error) {
+	var sizes []int
+	if strings.TrimSpace(chunkList) == "" {
+		return sizes, nil
+	}
+	for _, chunk := range strings.Split(chunkList, ",") {
+		val, err := strconv.Atoi(strings.TrimSpace(chunk))
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, val)
+	}
+	return sizes, nil
+}
+
 func GetLowestRepRateIndex(bandwithList []int) int {
 	lowestindex := 0
 	for i := 0; i < len(bandwithList); i++ {
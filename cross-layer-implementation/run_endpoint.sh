@@ -19,44 +19,23 @@ echo "STREAM_SPEED: \"$STREAM_SPEED\""
 echo "REQUESTS: \"$REQUESTS\""
 echo "ROLE: \"$ROLE\""
 
-if [[ -z "${ABR}" ]]; then
-  ABR="arbiter"
-fi
-
-if [[ -z "${CODEC}" ]]; then
-  CODEC="h264"
-fi
-
-if [[ -z "${INIT_BUFFER}" ]]; then
-  INIT_BUFFER="2"
-fi
-
-if [[ -z "${MAX_BUFFER}" ]]; then
-  MAX_BUFFER="20"
-fi
-
-if [[ -z "${MAX_HEIGHT}" ]]; then
-  MAX_HEIGHT="1080"
-fi
-
-if [[ -z "${EXP_RATIO}" ]]; then
-  EXP_RATIO="0.2"
-fi
-
-if [[ -z "${STREAM_SPEED}" ]]; then
-  STREAM_SPEED="1"
-fi
-
-if [[ -n "$STREAM_DURATION" ]]; then
-	STREAM_DURATION_STRING="-streamDuration $STREAM_DURATION"
-fi
+# the settings of the endpoint are in /endpoint.json, the variables that are set override them
+export GODASH_CONFIG="/endpoint.json"
+[[ -n "${ABR}" ]] && export GODASH_ADAPT="$ABR"
+[[ -n "${CODEC}" ]] && export GODASH_CODEC="$CODEC"
+[[ -n "${INIT_BUFFER}" ]] && export GODASH_INITBUFFER="$INIT_BUFFER"
+[[ -n "${MAX_BUFFER}" ]] && export GODASH_MAXBUFFER="$MAX_BUFFER"
+[[ -n "${MAX_HEIGHT}" ]] && export GODASH_MAXHEIGHT="$MAX_HEIGHT"
+[[ -n "${EXP_RATIO}" ]] && export GODASH_EXPRATIO="$EXP_RATIO"
+[[ -n "${STREAM_SPEED}" ]] && export GODASH_STREAMSPEED="$STREAM_SPEED"
+[[ -n "${STREAM_DURATION}" ]] && export GODASH_STREAMDURATION="$STREAM_DURATION"
 
 if [ "$ROLE" == "client" ]; then
     # Wait for the simulator to start up.
     /wait-for-it.sh sim:57832 -s -t 30
 	REQUESTS_LIST=${REQUESTS// /,}
 	# echo "rquesting: [$REQUESTS_LIST]"
-    godash -url "[$REQUESTS]" -adapt $ABR -codec $CODEC -initBuffer $INIT_BUFFER -maxBuffer $MAX_BUFFER -maxHeight $MAX_HEIGHT -expRatio $EXP_RATIO $STREAM_DURATION_STRING -streamSpeed $STREAM_SPEED -QoE on -quic on -outputFolder ../logs/files/ -storeDASH on -debug on -terminalPrint on -logFile "godash.log" -printHeader "{\"Algorithm\":\"on\",\"Seg_Dur\":\"off\",\"Codec\":\"on\",\"Width\":\"on\",\"Height\":\"on\",\"FPS\":\"off\",\"Play_Pos\":\"off\",\"RTT\":\"off\",\"Seg_Repl\":\"off\",\"Protocol\":\"on\",\"P.1203\":\"on\",\"Clae\":\"on\",\"Duanmu\":\"on\",\"Yin\":\"on\",\"Yu\":\"on\"}" -useTestbed off -serveraddr off -getHeaders off
+    godash -url "[$REQUESTS]"
 elif [ "$ROLE" == "server" ]; then
 	echo "godash does not support the server role!"
     return 127